@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// People who are logged in get a feed of what's changed since they last
+// looked: new issues going by their preferences, claims on issues they've
+// bookmarked being let go, and issues they've bookmarked closing. Each visit
+// collects whatever happened since the one before into activity, a row per
+// event, and activity_visits remembers when that was.
+
+// activityDays is how far back the feed goes, and how far back the first
+// visit looks.
+const activityDays = 7
+
+// activityMax is the most events the feed gives back, and the most new
+// issues one visit looks through.
+const activityMax = 100
+
+// The kinds of thing that can happen to an issue in someone's feed.
+const (
+	activityOpened   = "opened"
+	activityReleased = "released"
+	activityClosed   = "closed"
+)
+
+// activityFeed is what someone's feed looks like.
+type activityFeed struct {
+	// LastVisit is when they looked before this, null if they never have
+	LastVisit *time.Time `json:"last_visit"`
+
+	// Events are newest first
+	Events []activityEvent `json:"events"`
+}
+
+// activityEvent is something that happened to an issue: it was opened, its
+// claim was released or it closed.
+type activityEvent struct {
+	Kind  string    `json:"kind"`
+	At    time.Time `json:"at"`
+	Issue Issue     `json:"issue"`
+
+	// New is true if it happened since their last visit
+	New bool `json:"new"`
+}
+
+// getActivity gives the feed of whoever is logged in and marks it seen.
+func getActivity(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	now := time.Now()
+
+	var feed activityFeed
+	var visited time.Time
+	err := db.QueryRow("SELECT visited_at FROM activity_visits WHERE user_id = $1", u.UserID).Scan(&visited)
+	switch {
+	case err == sql.ErrNoRows:
+		visited = now.AddDate(0, 0, -activityDays)
+	case err != nil:
+		databaseError(w, r, errors.Wrap(err, "could not query visit"))
+		return
+	default:
+		feed.LastVisit = &visited
+	}
+
+	p, err := loadPreferences(u.UserID)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	events, err := collectActivity(u.UserID, p, visited, now)
+	if err == nil {
+		err = saveActivity(u.UserID, events, now)
+	}
+	if err == nil {
+		feed.Events, err = storedActivity(u.UserID, now)
+	}
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	markNew(feed.Events, feed.LastVisit)
+
+	_, err = db.Exec(
+		`INSERT INTO activity_visits (user_id, visited_at) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET visited_at = $2`,
+		u.UserID,
+		now,
+	)
+	if err != nil {
+		databaseError(w, r, errors.Wrap(err, "could not save visit"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// collectActivity finds what happened for user after since and up to now.
+// Issues first seen then are new if they go by p and haven't closed since,
+// and ones user bookmarked had their claim released or closed if someone
+// else's claim ran out or was let go, or we recorded them closing.
+func collectActivity(user string, p preferences, since, now time.Time) ([]activityEvent, error) {
+	var events []activityEvent
+
+	rows, err := db.Query(
+		`SELECT data, first_seen FROM issues WHERE first_seen > $1 AND first_seen <= $2
+		ORDER BY first_seen DESC LIMIT $3`,
+		since,
+		now,
+		activityMax,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query new issues")
+	}
+	defer rows.Close()
+
+	f := preferenceFilter(p)
+	closed := closures.urls()
+	for rows.Next() {
+		e := activityEvent{Kind: activityOpened}
+		var data []byte
+		if err := rows.Scan(&data, &e.At); err != nil {
+			return nil, errors.Wrap(err, "could not scan new issue")
+		}
+		if err := json.Unmarshal(data, &e.Issue); err != nil {
+			return nil, errors.Wrap(err, "could not decode new issue")
+		}
+		if f.keep(e.Issue) && tracked(e.Issue.Repo) && !closed[e.Issue.URL] {
+			events = append(events, e)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over new issues")
+	}
+
+	rows, err = db.Query(
+		`SELECT c.url, c.expires_at, i.data FROM claims c
+		JOIN bookmarks b ON b.url = c.url LEFT JOIN issues i ON i.url = c.url
+		WHERE b.user_id = $1 AND c.user_id <> $1 AND c.expires_at > $2 AND c.expires_at <= $3`,
+		user,
+		since,
+		now,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query released claims")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := activityEvent{Kind: activityReleased}
+		var data []byte
+		if err := rows.Scan(&e.Issue.URL, &e.At, &data); err != nil {
+			return nil, errors.Wrap(err, "could not scan released claim")
+		}
+		if data != nil {
+			if err := json.Unmarshal(data, &e.Issue); err != nil {
+				return nil, errors.Wrap(err, "could not decode released issue")
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over released claims")
+	}
+
+	rows, err = db.Query(
+		`SELECT c.data, c.closed_at FROM closed_issues c JOIN bookmarks b ON b.url = c.url
+		WHERE b.user_id = $1 AND c.closed_at > $2 AND c.closed_at <= $3`,
+		user,
+		since,
+		now,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query closed bookmarks")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := activityEvent{Kind: activityClosed}
+		var data []byte
+		if err := rows.Scan(&data, &e.At); err != nil {
+			return nil, errors.Wrap(err, "could not scan closed bookmark")
+		}
+		if err := json.Unmarshal(data, &e.Issue); err != nil {
+			return nil, errors.Wrap(err, "could not decode closed bookmark")
+		}
+		events = append(events, e)
+	}
+	return events, errors.Wrap(rows.Err(), "could not iterate over closed bookmarks")
+}
+
+// preferenceFilter is the issueFilter keeping issues that go by p: in one of
+// their languages, found by one of their labels and not in an org they've
+// left out. Empty preferences keep everything.
+func preferenceFilter(p preferences) issueFilter {
+	var f issueFilter
+	for _, l := range p.Languages {
+		f.langs = append(f.langs, strings.ToLower(l))
+	}
+	if len(p.Labels) > 0 {
+		f.matched = make(map[string]bool)
+		for _, l := range p.Labels {
+			f.matched[strings.ToLower(l)] = true
+		}
+	}
+	if len(p.ExcludedOrgs) > 0 {
+		f.excludedRepos = make(map[string]bool)
+		for _, o := range p.ExcludedOrgs {
+			f.excludedRepos[strings.ToLower(o)] = true
+		}
+	}
+	return f
+}
+
+// saveActivity adds events to user's feed, dropping any that are too old to
+// be in it at now. Collecting the same event twice is fine.
+func saveActivity(user string, events []activityEvent, now time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer tx.Rollback()
+
+	for _, e := range events {
+		data, err := json.Marshal(e.Issue)
+		if err != nil {
+			return errors.Wrap(err, "could not encode issue")
+		}
+		_, err = tx.Exec(
+			`INSERT INTO activity (user_id, kind, url, data, happened_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, kind, url, happened_at) DO NOTHING`,
+			user,
+			e.Kind,
+			e.Issue.URL,
+			data,
+			e.At,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not save activity")
+		}
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM activity WHERE user_id = $1 AND happened_at < $2",
+		user,
+		now.AddDate(0, 0, -activityDays),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not delete old activity")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit activity")
+}
+
+// storedActivity gives user's feed as of now, newest first.
+func storedActivity(user string, now time.Time) ([]activityEvent, error) {
+	rows, err := db.Query(
+		`SELECT kind, data, happened_at FROM activity WHERE user_id = $1 AND happened_at >= $2
+		ORDER BY happened_at DESC, url LIMIT $3`,
+		user,
+		now.AddDate(0, 0, -activityDays),
+		activityMax,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query activity")
+	}
+	defer rows.Close()
+
+	events := []activityEvent{}
+	for rows.Next() {
+		var e activityEvent
+		var data []byte
+		if err := rows.Scan(&e.Kind, &data, &e.At); err != nil {
+			return nil, errors.Wrap(err, "could not scan activity")
+		}
+		if err := json.Unmarshal(data, &e.Issue); err != nil {
+			return nil, errors.Wrap(err, "could not decode activity")
+		}
+		events = append(events, e)
+	}
+	return events, errors.Wrap(rows.Err(), "could not iterate over activity")
+}
+
+// markNew sets New on the events that happened after lastVisit, which is all
+// of them if there wasn't one.
+func markNew(events []activityEvent, lastVisit *time.Time) {
+	for n := range events {
+		events[n].New = lastVisit == nil || events[n].At.After(*lastVisit)
+	}
+}
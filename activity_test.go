@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreferenceFilter(t *testing.T) {
+	p := preferences{Languages: []string{"Go"}, Labels: []string{"Good First Issue"}, ExcludedOrgs: []string{"Spammer"}}
+	f := preferenceFilter(p)
+
+	tests := []struct {
+		issue Issue
+		want  bool
+	}{
+		{Issue{Repo: Repo{Owner: "devict"}, Languages: []string{"Go"}, MatchedLabels: []string{"good first issue"}}, true},
+		{Issue{Repo: Repo{Owner: "devict"}, Languages: []string{"Ruby"}, MatchedLabels: []string{"good first issue"}}, false},
+		{Issue{Repo: Repo{Owner: "devict"}, Languages: []string{"Go"}, MatchedLabels: []string{"hacktoberfest"}}, false},
+		{Issue{Repo: Repo{Owner: "spammer"}, Languages: []string{"Go"}, MatchedLabels: []string{"good first issue"}}, false},
+	}
+	for _, test := range tests {
+		if got := f.keep(test.issue); got != test.want {
+			t.Errorf("%+v: got %v, want %v", test.issue, got, test.want)
+		}
+	}
+
+	if !preferenceFilter(preferences{}).keep(Issue{Repo: Repo{Owner: "devict"}}) {
+		t.Error("empty preferences should keep everything")
+	}
+}
+
+func TestMarkNew(t *testing.T) {
+	last := time.Date(2017, 10, 5, 12, 0, 0, 0, time.UTC)
+	events := []activityEvent{{At: last.Add(time.Hour)}, {At: last}, {At: last.Add(-time.Hour)}}
+
+	markNew(events, &last)
+	if !events[0].New || events[1].New || events[2].New {
+		t.Errorf("got %+v, want only the one after the last visit new", events)
+	}
+
+	markNew(events, nil)
+	for _, e := range events {
+		if !e.New {
+			t.Errorf("got %+v, want everything new on the first visit", e)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/markbates/goth"
+	"github.com/pkg/errors"
+)
+
+// admins are the GitHub usernames allowed to use the admin API. setConfig
+// sets them from config.Admins.
+var admins = set(config.Admins)
+
+// reGitHubLogin matches what GitHub allows in a username.
+var reGitHubLogin = regexp.MustCompile("^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$")
+
+// findAdmin is findUser for people on the admins list. They have to have
+// logged in with GitHub, since the same username on GitLab could be anyone.
+func findAdmin(r *http.Request) (goth.User, bool) {
+	u, _, ok := findUser(r)
+	if !ok || u.Provider != "github" || !admins[u.NickName] {
+		return goth.User{}, false
+	}
+	if k, ok := usedKey(r); ok && k.Scope != scopeAdmin {
+		return goth.User{}, false
+	}
+	return u, true
+}
+
+// trackedChange is an org or project an admin added or removed, or an owner
+// or repo they excluded or let back in. They're kept in the tracked table and
+// applied on top of the Tracking from loadTracking.
+type trackedChange struct {
+	Kind   string // "org", "project" or "excluded"
+	Name   string
+	Active bool
+}
+
+// applyChanges gives a copy of t with changes made. Later changes win.
+func applyChanges(t Tracking, changes []trackedChange) Tracking {
+	out := Tracking{
+		Orgs:     make(map[string]bool),
+		Projects: make(map[string]bool),
+		Labels:   t.Labels,
+		Excluded: make(map[string]bool),
+		Events:   t.Events,
+	}
+	for k := range t.Orgs {
+		out.Orgs[k] = true
+	}
+	for k := range t.Projects {
+		out.Projects[k] = true
+	}
+	for k := range t.Excluded {
+		out.Excluded[k] = true
+	}
+
+	for _, c := range changes {
+		m := out.Orgs
+		switch c.Kind {
+		case "project":
+			m = out.Projects
+		case "excluded":
+			m = out.Excluded
+		}
+
+		if c.Active {
+			m[c.Name] = true
+		} else {
+			delete(m, c.Name)
+		}
+	}
+
+	return out
+}
+
+// trackedChanges loads every change admins have made.
+func trackedChanges() ([]trackedChange, error) {
+	rows, err := db.Query("SELECT kind, name, active FROM tracked ORDER BY updated_at")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query tracked changes")
+	}
+	defer rows.Close()
+
+	var changes []trackedChange
+	for rows.Next() {
+		var c trackedChange
+		if err := rows.Scan(&c.Kind, &c.Name, &c.Active); err != nil {
+			return nil, errors.Wrap(err, "could not scan tracked change")
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over tracked changes")
+	}
+
+	return changes, nil
+}
+
+// buildTracking is loadTracking with the admins' changes applied.
+func buildTracking() (Tracking, error) {
+	t, err := loadTracking()
+	if err != nil {
+		return Tracking{}, err
+	}
+
+	changes, err := trackedChanges()
+	if err != nil {
+		return Tracking{}, err
+	}
+
+	return applyChanges(t, changes), nil
+}
+
+// saveChange records c and puts it into effect straight away.
+func saveChange(c trackedChange, by string) error {
+	_, err := db.Exec(
+		`INSERT INTO tracked (kind, name, active, updated_by, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, name) DO UPDATE SET active = $3, updated_by = $4, updated_at = $5`,
+		c.Kind,
+		c.Name,
+		c.Active,
+		by,
+		time.Now(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not save tracked change")
+	}
+
+	// Rebuild from scratch rather than patching the current Tracking so two
+	// admins saving at once can't lose each other's changes
+	t, err := buildTracking()
+	if err != nil {
+		return err
+	}
+	setTracking(t)
+	return nil
+}
+
+// trackingView is what admins are shown of what's being tracked.
+type trackingView struct {
+	Orgs     []string `json:"orgs"`
+	Projects []string `json:"projects"`
+	Labels   []string `json:"labels"`
+	Excluded []string `json:"excluded"`
+}
+
+func adminTracking(w http.ResponseWriter, r *http.Request) {
+	t := tracking()
+	data := trackingView{
+		Orgs:     sortedKeys(t.Orgs),
+		Projects: sortedKeys(t.Projects),
+		Labels:   t.labelList(),
+		Excluded: sortedKeys(t.Excluded),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+func addOrg(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Org string `json:"org"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Org) == "" {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+
+	changeTracking(w, r, trackedChange{Kind: "org", Name: strings.TrimSpace(body.Org), Active: true})
+}
+
+func removeOrg(w http.ResponseWriter, r *http.Request) {
+	changeTracking(w, r, trackedChange{Kind: "org", Name: r.URL.Query().Get(":name")})
+}
+
+func addRepo(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Repo string `json:"repo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.Count(body.Repo, "/") != 1 {
+		invalidRequest(w, r, "invalid request, repo should look like owner/name")
+		return
+	}
+
+	changeTracking(w, r, trackedChange{Kind: "project", Name: strings.TrimSpace(body.Repo), Active: true})
+}
+
+func removeRepo(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get(":owner") + "/" + r.URL.Query().Get(":repo")
+	changeTracking(w, r, trackedChange{Kind: "project", Name: name})
+}
+
+// addExcluded leaves an owner, or one of their repos as owner/name, out of
+// every listing.
+func addExcluded(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Name) == "" || strings.Count(body.Name, "/") > 1 {
+		invalidRequest(w, r, "invalid request, name should look like owner or owner/name")
+		return
+	}
+
+	changeTracking(w, r, trackedChange{Kind: "excluded", Name: strings.TrimSpace(body.Name), Active: true})
+}
+
+// removeExcluded lets an excluded owner, or owner/name with a repo, back in.
+func removeExcluded(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get(":owner")
+	if repo := r.URL.Query().Get(":repo"); repo != "" {
+		name += "/" + repo
+	}
+	changeTracking(w, r, trackedChange{Kind: "excluded", Name: name})
+}
+
+// changeTracking is the shared part of the add and remove handlers.
+func changeTracking(w http.ResponseWriter, r *http.Request, c trackedChange) {
+	u := currentUser(r)
+
+	if err := saveChange(c, u.NickName); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	audit(r, trackedAction(c), c.Name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/markbates/goth"
+)
+
+func TestApplyChanges(t *testing.T) {
+	base := Tracking{
+		Orgs:     map[string]bool{"devict": true, "MakeICT": true},
+		Projects: map[string]bool{"br0xen/boltbrowser": true},
+		Labels:   map[string]bool{"hacktoberfest": true},
+		Excluded: map[string]bool{"devict/spam": true},
+	}
+
+	changes := []trackedChange{
+		{Kind: "org", Name: "openwichita", Active: true},
+		{Kind: "org", Name: "MakeICT", Active: false},
+		{Kind: "project", Name: "chrisl8/ArloBot", Active: true},
+		{Kind: "project", Name: "chrisl8/ArloBot", Active: false},
+		{Kind: "project", Name: "benblankley/fort-rpg", Active: true},
+		{Kind: "excluded", Name: "devict/spam", Active: false},
+		{Kind: "excluded", Name: "spammer", Active: true},
+	}
+
+	want := Tracking{
+		Orgs:     map[string]bool{"devict": true, "openwichita": true},
+		Projects: map[string]bool{"br0xen/boltbrowser": true, "benblankley/fort-rpg": true},
+		Labels:   map[string]bool{"hacktoberfest": true},
+		Excluded: map[string]bool{"spammer": true},
+	}
+	got := applyChanges(base, changes)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+		t.Errorf("want %+v", want)
+	}
+
+	// The original must not change since it may be in use
+	if !base.Orgs["MakeICT"] || base.Orgs["openwichita"] {
+		t.Errorf("base was modified: %+v", base)
+	}
+}
+
+func TestFindAdmin(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	oldAdmins := admins
+	defer func() { admins = oldAdmins }()
+	admins = map[string]bool{"octocat": true}
+
+	tests := []struct {
+		u    goth.User
+		want bool
+	}{
+		{goth.User{NickName: "octocat", Provider: "github"}, true},
+		{goth.User{NickName: "octocat", Provider: "gitlab"}, false},
+		{goth.User{NickName: "tanuki", Provider: "github"}, false},
+	}
+	for _, test := range tests {
+		r := withCookie("GET", "/api/admin/tracking", userCookie(t, store, time.Now(), test.u))
+		if _, got := findAdmin(r); got != test.want {
+			t.Errorf("%s on %s: got admin %v, want %v", test.u.NickName, test.u.Provider, got, test.want)
+		}
+	}
+
+	// Neither can they see the debug endpoints
+	w := httptest.NewRecorder()
+	debugOnly(nil)(w, withCookie("GET", "/debug/vars", userCookie(t, store, time.Now(), tests[1].u)))
+	if w.Code != 403 {
+		t.Errorf("got status %d for a GitLab account named like an admin, want 403", w.Code)
+	}
+}
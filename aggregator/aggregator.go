@@ -0,0 +1,203 @@
+// Package aggregator finds open issues with any of a set of labels across
+// GitHub orgs and repos, along with the languages of the repos they're in.
+// It's what the hacktoberfest server lists issues with, for tools that want
+// them without the server. A GitHub does the searching, with retries,
+// conditional requests and a rate limit budget, using GraphQL when it has a
+// token, and a Search runs it alongside any other Sources. Client puts the
+// two together for the simple case:
+//
+//	c := aggregator.New(aggregator.Options{
+//		Labels: []string{"hacktoberfest"},
+//		Orgs:   []string{"devict"},
+//		Tokens: aggregator.StaticToken(os.Getenv("GITHUB_TOKEN")),
+//	})
+//	issues, err := c.Issues(ctx)
+package aggregator
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenSource gives the GitHub token to make a request with, or "" to make
+// it anonymously.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that's always the same token.
+type StaticToken string
+
+// Token gives t.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Options say what a Client searches for, where, and how.
+type Options struct {
+	// Labels are searched for one at a time, so issues with any of them are
+	// found. Labels that aren't one of these are what an Issue's Labels
+	// have.
+	Labels []string
+
+	// Orgs and Repos are where to search, Repos as owner/name
+	Orgs  []string
+	Repos []string
+
+	// HTTPClient makes the requests, http.DefaultClient if it's nil
+	HTTPClient *http.Client
+
+	// Tokens gives the token for each request. Requests are anonymous if
+	// it's nil, which GitHub limits to very few.
+	Tokens TokenSource
+
+	// BaseURL is GitHub's API, https://api.github.com unless it's set
+	BaseURL string
+
+	// MaxPages is the most pages of each search to read, 10 unless it's set
+	MaxPages int
+
+	// MaxLanguages is how many of each repo's languages to list, 3 unless
+	// it's set, or all of them if it's negative
+	MaxLanguages int
+
+	// LanguageTTL is how long a repo's languages are remembered, a day
+	// unless it's set
+	LanguageTTL time.Duration
+
+	// Timeout is how long all the searches together get, a minute unless
+	// it's set
+	Timeout time.Duration
+
+	// Concurrency is the most searches that run at once, 4 unless it's set
+	Concurrency int
+
+	// Observer is told what the searches are up to, nobody if it's nil
+	Observer Observer
+}
+
+// Client finds issues on GitHub. It is safe for concurrent use.
+type Client struct {
+	github *GitHub
+	search *Search
+	query  Query
+}
+
+// New makes a Client with opts, filling in defaults for anything not set.
+func New(opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://api.github.com"
+	}
+	if opts.MaxPages == 0 {
+		opts.MaxPages = 10
+	}
+	if opts.MaxLanguages == 0 {
+		opts.MaxLanguages = 3
+	}
+	if opts.LanguageTTL == 0 {
+		opts.LanguageTTL = 24 * time.Hour
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = time.Minute
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 4
+	}
+
+	langs := NewLanguages(opts.LanguageTTL)
+	langs.Observer = opts.Observer
+	gh := &GitHub{
+		BaseURL:   strings.TrimSuffix(opts.BaseURL, "/"),
+		HTTP:      opts.HTTPClient,
+		Orgs:      set(opts.Orgs),
+		Projects:  set(opts.Repos),
+		Hidden:    set(opts.Labels),
+		MaxPages:  opts.MaxPages,
+		Languages: langs,
+		Responses: NewResponseCache(1000),
+		Budget:    NewBudget(),
+		Observer:  opts.Observer,
+
+		Retries:      3,
+		RetryWait:    500 * time.Millisecond,
+		MaxRetryWait: 30 * time.Second,
+	}
+
+	// A token that's always the same can be told apart from no token at all,
+	// which GraphQL won't take
+	if t, ok := opts.Tokens.(StaticToken); ok {
+		gh.Token = string(t)
+	} else {
+		gh.Tokens = opts.Tokens
+	}
+
+	q := Query{Scope: gh.Scope(), Labels: opts.Labels, MaxLangs: opts.MaxLanguages}
+	if q.MaxLangs < 0 {
+		q.MaxLangs = 0
+	}
+	return &Client{
+		github: gh,
+		search: &Search{Sources: []Source{gh}, Timeout: opts.Timeout, Concurrency: opts.Concurrency, Observer: opts.Observer},
+		query:  q,
+	}
+}
+
+// set makes a set of names.
+func set(names []string) map[string]bool {
+	s := make(map[string]bool, len(names))
+	for _, n := range names {
+		s[n] = true
+	}
+	return s
+}
+
+// Scope is the search qualifiers covering the orgs and repos.
+func (c *Client) Scope() string {
+	return c.query.Scope
+}
+
+// Issues collects every issue Stream finds, newest first. An issue found by
+// more than one search is the richest copy, matching all their labels, so
+// it comes out the same whichever search got there first. If some searches
+// failed it still gives back what the others found, along with an
+// *IncompleteError.
+func (c *Client) Issues(ctx context.Context) ([]Issue, error) {
+	return c.search.Fetch(ctx, c.query)
+}
+
+// Stream searches for each label at once, passing issues to found as soon as
+// they're found. An issue with more than one of the labels is only passed
+// along the first time. If found gives an error the searches are stopped and
+// that's the error given back, and if ctx is done it's ctx.Err().
+//
+// A search that fails, or is still going after Timeout, doesn't stop the
+// others. Once they're all done an *IncompleteError says which failed,
+// unless all of them did, in which case it's the first one's error.
+func (c *Client) Stream(ctx context.Context, found func(Issue) error) error {
+	return c.search.Stream(ctx, c.query, found)
+}
+
+// SearchIssues finds open issues with label in the orgs and repos, reading
+// up to MaxPages pages of results. Issues are fed into ch as they're found,
+// with their repo's languages. If ctx is done it stops early with ctx.Err().
+func (c *Client) SearchIssues(ctx context.Context, label string, ch chan<- Issue) error {
+	return searchLabel(ctx, c.github, label, c.query, ch)
+}
+
+// RepoLanguages gives the number of bytes of code in each language in repo,
+// asking GitHub unless we've done so within the LanguageTTL.
+func (c *Client) RepoLanguages(ctx context.Context, repo Repo) (map[string]int, error) {
+	f, ok, err := c.github.Languages.fetch(ctx, c.github, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return map[string]int{}, nil
+	}
+	return f.Bytes, nil
+}
@@ -0,0 +1,172 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeGitHub answers searches for good first issue and hacktoberfest with
+// one issue in common, fails ones for anything else, and says every repo is
+// mostly Go. It answers the REST API and GraphQL alike, counting the
+// requests for languages either makes.
+func fakeGitHub(t *testing.T, languageCalls *int32) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/languages") {
+			atomic.AddInt32(languageCalls, 1)
+			w.Write([]byte(`{"Go": 100, "Shell": 10, "Makefile": 1, "HTML": 5}`))
+			return
+		}
+
+		q := r.URL.Query().Get("q")
+		if r.URL.Path == "/graphql" {
+			if got := r.Header.Get("Authorization"); got != "token secret" {
+				t.Errorf("got Authorization %q, want token secret", got)
+			}
+			var body struct {
+				Query     string                 `json:"query"`
+				Variables map[string]interface{} `json:"variables"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if !strings.Contains(body.Query, "search(") {
+				atomic.AddInt32(languageCalls, 1)
+				w.Write([]byte(`{"data": {"r0": {"stargazerCount": 3, "languages": {"edges": [
+					{"size": 100, "node": {"name": "Go"}}, {"size": 10, "node": {"name": "Shell"}},
+					{"size": 5, "node": {"name": "HTML"}}, {"size": 1, "node": {"name": "Makefile"}}]}}}}`))
+				return
+			}
+			q, _ = body.Variables["q"].(string)
+		} else if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("got Authorization %q, want none for the REST API", got)
+		}
+
+		if !strings.Contains(q, "org:devict repo:MakeICT/hub") {
+			t.Errorf("query %q should be scoped to the orgs and repos", q)
+		}
+		item := func(n, label string) string {
+			if r.URL.Path == "/graphql" {
+				return `{"title": "Issue ` + n + `", "url": "https://github.com/devict/site/issues/` + n + `",
+					"repository": {"name": "site", "owner": {"login": "devict"}},
+					"labels": {"nodes": [{"name": "` + label + `"}, {"name": "docs", "color": "0075ca"}]}}`
+			}
+			return `{"title": "Issue ` + n + `", "html_url": "https://github.com/devict/site/issues/` + n + `",
+				"repository_url": "` + srv.URL + `/repos/devict/site",
+				"labels": [{"name": "` + label + `"}, {"name": "docs", "color": "0075ca"}]}`
+		}
+		page := func(items ...string) []byte {
+			if r.URL.Path == "/graphql" {
+				return []byte(`{"data": {"search": {"nodes": [` + strings.Join(items, ", ") + `]}}}`)
+			}
+			return []byte(`{"items": [` + strings.Join(items, ", ") + `]}`)
+		}
+		switch {
+		case strings.Contains(q, `label:"good first issue"`):
+			w.Write(page(item("1", "good first issue"), item("2", "good first issue")))
+		case strings.Contains(q, `label:"hacktoberfest"`):
+			w.Write(page(item("2", "hacktoberfest")))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	}))
+	return srv
+}
+
+func TestIssues(t *testing.T) {
+	// Without a token it's the REST API, with one it's GraphQL
+	for _, token := range []StaticToken{"", "secret"} {
+		testIssues(t, token)
+	}
+}
+
+func testIssues(t *testing.T, token StaticToken) {
+	var calls int32
+	srv := fakeGitHub(t, &calls)
+	defer srv.Close()
+
+	c := New(Options{
+		Labels:  []string{"good first issue", "hacktoberfest"},
+		Orgs:    []string{"devict"},
+		Repos:   []string{"MakeICT/hub"},
+		Tokens:  token,
+		BaseURL: srv.URL,
+	})
+	issues, err := c.Issues(context.Background())
+	if err != nil {
+		t.Fatalf("%q: error should be nil, got %v", token, err)
+	}
+
+	var titles []string
+	for _, i := range issues {
+		titles = append(titles, i.Title)
+	}
+	if want := []string{"Issue 1", "Issue 2"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("got %q, want %q once each, in order", titles, want)
+	}
+	if want := []string{"good first issue", "hacktoberfest"}; !reflect.DeepEqual(issues[1].MatchedLabels, want) {
+		t.Errorf("got matched labels %v, want %v for the issue both found", issues[1].MatchedLabels, want)
+	}
+
+	i := issues[0]
+	if i.Repo.Owner != "devict" || i.Repo.Name != "site" {
+		t.Errorf("got repo %+v, want devict/site", i.Repo)
+	}
+	if want := map[string]string{"docs": "0075ca"}; !reflect.DeepEqual(i.Labels, want) {
+		t.Errorf("got labels %v, want only the ones not searched for, %v", i.Labels, want)
+	}
+	if want := []string{"Go", "Shell", "HTML"}; !reflect.DeepEqual(i.Languages, want) {
+		t.Errorf("%q: got languages %v, want %v", token, i.Languages, want)
+	}
+	if token != "" && i.Repo.Stars != 3 {
+		t.Errorf("got %d stars, want what GraphQL said", i.Repo.Stars)
+	}
+
+	// Every issue is in the same repo so its languages are only asked for
+	// once, give or take searches getting there at the same time
+	if _, err := c.Issues(context.Background()); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n > 2 {
+		t.Errorf("%q: languages were asked for %d times, want them remembered", token, n)
+	}
+}
+
+func TestIssuesIncomplete(t *testing.T) {
+	var calls int32
+	srv := fakeGitHub(t, &calls)
+	defer srv.Close()
+
+	c := New(Options{
+		Labels:  []string{"hacktoberfest", "broken"},
+		Orgs:    []string{"devict"},
+		Repos:   []string{"MakeICT/hub"},
+		Tokens:  StaticToken("secret"),
+		BaseURL: srv.URL,
+	})
+	issues, err := c.Issues(context.Background())
+
+	e, ok := err.(*IncompleteError)
+	if !ok {
+		t.Fatalf("got error %v, want an *IncompleteError", err)
+	}
+	if len(e.Failed) != 1 || e.Failed[0].Label != "broken" {
+		t.Errorf("got %+v, want just the broken search", e.Failed)
+	}
+	if se, ok := e.Failed[0].Err.(*StatusError); !ok || se.Code != http.StatusUnprocessableEntity || e.Failed[0].Source != "github" {
+		t.Errorf("got %v, want a *StatusError for 422", e.Failed[0].Err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("got %d issues, want the one the other search found", len(issues))
+	}
+
+	// With every search failing there's nothing to give back
+	c = New(Options{Labels: []string{"broken"}, Tokens: StaticToken("secret"), BaseURL: srv.URL, Orgs: []string{"devict"}, Repos: []string{"MakeICT/hub"}})
+	if issues, err := c.Issues(context.Background()); err == nil || issues != nil {
+		t.Errorf("got %v and %v, want no issues and an error", issues, err)
+	}
+}
@@ -0,0 +1,255 @@
+package aggregator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Budget keeps track of how much of each rate limit GitHub says every
+// token has left, so we can tell before starting a search whether it will
+// run out halfway through. Clients with Tokens, like a GitHub App's, have a
+// budget of their own shared by all of those tokens. It is safe for
+// concurrent use.
+type Budget struct {
+	mu     sync.Mutex
+	limits map[budgetKey]rateLimit
+}
+
+type budgetKey struct {
+	who      string
+	resource string
+}
+
+// rateLimit is what's left of one rate limit until it resets, out of limit
+// if GitHub said.
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewBudget makes a Budget that knows nothing yet. It can be shared by every
+// client since each token's limits are kept apart.
+func NewBudget() *Budget {
+	return &Budget{limits: make(map[budgetKey]rateLimit)}
+}
+
+// BudgetReserve is how much of a rate limit we leave alone rather than spend
+// on languages, which we can do without.
+const BudgetReserve = 10
+
+// BudgetError is a search we didn't start because there isn't enough rate
+// limit left to finish it.
+type BudgetError struct {
+	Resource  string
+	Remaining int
+	Need      int
+	Reset     time.Time
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("only %d of the %s rate limit left until %v, need %d", e.Remaining, e.Resource, e.Reset.Format(time.RFC3339), e.Need)
+}
+
+// Record notes the rate limit in a response to who. Limits that have reset
+// are forgotten while we're at it, so people who've gone away don't hang
+// around.
+func (b *Budget) Record(who string, h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	b.note(who, RateResource(h), rateLimit{limit: limit, remaining: remaining, reset: time.Unix(reset, 0)})
+}
+
+// note keeps l as what's left of resource for who.
+func (b *Budget) note(who, resource string, l rateLimit) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for k, old := range b.limits {
+		if now.After(old.reset) {
+			delete(b.limits, k)
+		}
+	}
+	b.limits[budgetKey{who, resource}] = l
+}
+
+// left is how much of resource who has left and when it resets. ok is false
+// if we don't know, because we haven't heard or it's reset since.
+func (b *Budget) left(who, resource string) (l rateLimit, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok = b.limits[budgetKey{who, resource}]
+	if ok && time.Now().After(l.reset) {
+		return rateLimit{}, false
+	}
+	return l, ok
+}
+
+// spend takes n off what who has left of resource, for requests we're about
+// to make, so workers starting at once don't all count the same budget.
+func (b *Budget) spend(who, resource string, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	k := budgetKey{who, resource}
+	if l, ok := b.limits[k]; ok {
+		l.remaining -= n
+		if l.remaining < 0 {
+			l.remaining = 0
+		}
+		b.limits[k] = l
+	}
+}
+
+// RateResource is which rate limit a response counted against. GraphQL and
+// search have their own, separate from the core one.
+func RateResource(h http.Header) string {
+	if r := h.Get("X-RateLimit-Resource"); r != "" {
+		return r
+	}
+	return "core"
+}
+
+// budgetWho is whose rate limit c's requests count against.
+func (c *GitHub) budgetWho() string {
+	if c.Tokens != nil {
+		return "tokens"
+	}
+	if c.Token == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(c.Token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// searchResource is the rate limit c's searches count against.
+func (c *GitHub) searchResource() string {
+	if c.CanGraphQL() {
+		return "graphql"
+	}
+	return "search"
+}
+
+// Reserve makes sure there's enough of the search rate limit left for
+// searching labels, at a request each. If there isn't but it resets within
+// MaxRetryWait we wait for it, otherwise a *BudgetError says so. Searches
+// that go on to more pages can still run out, but at least we don't start
+// what we can't get going.
+func (c *GitHub) Reserve(ctx context.Context, labels int) error {
+	if c.Budget == nil {
+		return nil
+	}
+
+	who, resource := c.budgetWho(), c.searchResource()
+	l, ok := c.Budget.left(who, resource)
+	if ok && l.remaining < labels {
+		wait := time.Until(l.reset)
+		if wait > c.MaxRetryWait {
+			return &BudgetError{Resource: resource, Remaining: l.remaining, Need: labels, Reset: l.reset}
+		}
+
+		observe(c.Observer).Log(ctx, Info, "waiting for rate limit", "resource", resource, "reset", l.reset)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	c.Budget.spend(who, resource, labels)
+	return nil
+}
+
+// CanSpare reports whether c has enough of resource's rate limit left to
+// spend a request on something we could do without.
+func (c *GitHub) CanSpare(resource string) bool {
+	if c.Budget == nil {
+		return true
+	}
+	l, ok := c.Budget.left(c.budgetWho(), resource)
+	return !ok || l.remaining > BudgetReserve
+}
+
+// RateLimit is how much of one of GitHub's rate limits is left for a client.
+type RateLimit struct {
+	// Resource is which one it is: core, search, graphql and so on
+	Resource string `json:"resource"`
+
+	// Limit is how many requests it allows until Reset, if GitHub said, and
+	// Remaining is how many of them are left
+	Limit     int       `json:"limit,omitempty"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+
+	// Low is true when it's down to the BudgetReserve, so nothing that can
+	// be done without is being spent from it
+	Low bool `json:"low"`
+}
+
+func newRateLimit(resource string, l rateLimit) RateLimit {
+	return RateLimit{
+		Resource:  resource,
+		Limit:     l.limit,
+		Remaining: l.remaining,
+		Reset:     l.reset,
+		Low:       l.remaining <= BudgetReserve,
+	}
+}
+
+// SearchRateLimit is what we last heard of the rate limit c's searches
+// count against, from the headers of its responses. ok is false if we
+// haven't heard or it's reset since.
+func (c *GitHub) SearchRateLimit() (l RateLimit, ok bool) {
+	if c.Budget == nil {
+		return RateLimit{}, false
+	}
+	resource := c.searchResource()
+	left, ok := c.Budget.left(c.budgetWho(), resource)
+	if !ok {
+		return RateLimit{}, false
+	}
+	return newRateLimit(resource, left), true
+}
+
+// RateLimits asks GitHub how much of each of c's rate limits is left, which
+// doesn't count against any of them, sorted by resource. What it says goes
+// in the Budget too.
+func (c *GitHub) RateLimits(ctx context.Context) ([]RateLimit, error) {
+	var data struct {
+		Resources map[string]struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
+		} `json:"resources"`
+	}
+	if err := c.Get(ctx, "/rate_limit", nil, &data); err != nil {
+		return nil, errors.Wrap(err, "could not get rate limits")
+	}
+
+	who := c.budgetWho()
+	limits := []RateLimit{}
+	for resource, r := range data.Resources {
+		l := rateLimit{limit: r.Limit, remaining: r.Remaining, reset: time.Unix(r.Reset, 0)}
+		if c.Budget != nil {
+			c.Budget.note(who, resource, l)
+		}
+		limits = append(limits, newRateLimit(resource, l))
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i].Resource < limits[j].Resource })
+	return limits, nil
+}
@@ -0,0 +1,117 @@
+package aggregator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func rateHeader(resource string, remaining int, reset time.Time) http.Header {
+	h := http.Header{}
+	h.Set("X-RateLimit-Resource", resource)
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return h
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget()
+	if _, ok := b.left("a", "search"); ok {
+		t.Errorf("budget we haven't heard about shouldn't be known")
+	}
+
+	b.Record("a", rateHeader("search", 10, time.Now().Add(time.Hour)))
+	b.Record("b", rateHeader("search", 3, time.Now().Add(-time.Second)))
+	b.spend("a", "search", 4)
+
+	if l, ok := b.left("a", "search"); !ok || l.remaining != 6 {
+		t.Errorf("got %+v, want 6 left after spending 4", l)
+	}
+	if _, ok := b.left("a", "core"); ok {
+		t.Errorf("limits should be kept by resource")
+	}
+	if _, ok := b.left("b", "search"); ok {
+		t.Errorf("budget that's reset shouldn't be known")
+	}
+}
+
+func TestGitHubReserve(t *testing.T) {
+	c := &GitHub{Token: "t", Budget: NewBudget(), MaxRetryWait: time.Second}
+	who := c.budgetWho()
+
+	c.Budget.Record(who, rateHeader("graphql", 1, time.Now().Add(time.Hour)))
+	if err := c.Reserve(context.Background(), 2); err == nil {
+		t.Errorf("reserving more than is left should fail")
+	} else if _, ok := err.(*BudgetError); !ok {
+		t.Errorf("got %v, want a *BudgetError", err)
+	}
+
+	// Resetting soon is worth waiting for
+	c.Budget.Record(who, rateHeader("graphql", 0, time.Now().Add(time.Second)))
+	if err := c.Reserve(context.Background(), 2); err != nil {
+		t.Errorf("should wait for a limit that resets soon, got %v", err)
+	}
+
+	c.Budget.Record(who, rateHeader("core", BudgetReserve, time.Now().Add(time.Hour)))
+	if c.CanSpare("core") {
+		t.Errorf("shouldn't spare requests from the reserve")
+	}
+}
+
+func TestSearchBudget(t *testing.T) {
+	c := &GitHub{Token: "t", Budget: NewBudget()}
+	c.Budget.Record(c.budgetWho(), rateHeader("graphql", 0, time.Now().Add(time.Hour)))
+
+	s := &Search{Sources: []Source{c, fakeSource{{URL: "https://gitlab.com/a/b/-/issues/1"}}}}
+	issues, err := s.Fetch(context.Background(), Query{Labels: []string{"hacktoberfest", "help wanted"}})
+
+	e, _ := err.(*IncompleteError)
+	if e == nil || len(e.Failed) != 2 {
+		t.Fatalf("both GitHub searches should fail for lack of budget, got %v", err)
+	}
+	if _, ok := e.Failed[0].Err.(*BudgetError); !ok {
+		t.Errorf("got %v, want a *BudgetError", e.Failed[0].Err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("should still get issues from the other source, got %+v", issues)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"resources": {
+			"search": {"limit": 30, "remaining": 4, "reset": ` + strconv.FormatInt(reset.Unix(), 10) + `},
+			"core": {"limit": 5000, "remaining": 4000, "reset": ` + strconv.FormatInt(reset.Unix(), 10) + `}
+		}}`))
+	}))
+	defer srv.Close()
+	c := &GitHub{BaseURL: srv.URL, HTTP: http.DefaultClient, Budget: NewBudget()}
+
+	if _, ok := c.SearchRateLimit(); ok {
+		t.Errorf("search rate limit we haven't heard about shouldn't be known")
+	}
+
+	limits, err := c.RateLimits(context.Background())
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	want := []RateLimit{
+		{Resource: "core", Limit: 5000, Remaining: 4000, Reset: reset},
+		{Resource: "search", Limit: 30, Remaining: 4, Reset: reset, Low: true},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("got %+v, want %+v", limits, want)
+	}
+
+	if l, ok := c.SearchRateLimit(); !ok || l != want[1] {
+		t.Errorf("got %+v, %v, want what GitHub said of search", l, ok)
+	}
+}
@@ -0,0 +1,192 @@
+package aggregator
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Hacktoberfest spam often opens the same issue, like "Add your name to
+// CONTRIBUTORS.md", across many repos. MarkDuplicates finds issues whose
+// titles are the same or nearly so and says which issue each is a copy of,
+// so listings can collapse them into one or rank them lower.
+//
+// Titles are compared by the character trigrams of their words, ignoring
+// case, numbers and punctuation. Ones whose trigrams are alike enough are
+// copies. Comparing every pair would be slow for a big listing, so titles
+// are only compared if their MinHash signatures agree on one of a few bands,
+// which titles that alike almost always do and different ones rarely do.
+
+// duplicateSimilarity is how much of their trigrams two titles have to share,
+// out of all the trigrams either has, to be copies.
+const duplicateSimilarity = 0.7
+
+// minDuplicateWords is the fewest words a title needs to be counted as a copy
+// of another. Short titles like "Fix typo" are the same by chance.
+const minDuplicateWords = 4
+
+// The MinHash signature of a title is signatureBands bands of bandRows hashes.
+const (
+	signatureBands = 8
+	bandRows       = 3
+)
+
+// MarkDuplicates sets DuplicateOf and Duplicates on issues. The first issue
+// opened of each set of copies is the one the rest are copies of.
+func MarkDuplicates(issues []Issue) {
+	parent := make([]int, len(issues))
+	for n := range parent {
+		parent[n] = n
+	}
+	var find func(int) int
+	find = func(n int) int {
+		if parent[n] != n {
+			parent[n] = find(parent[n])
+		}
+		return parent[n]
+	}
+	union := func(a, b int) {
+		if a, b = find(a), find(b); a != b {
+			parent[b] = a
+		}
+	}
+
+	// Titles that are the same once normalized are copies straight away, so
+	// only one of each needs comparing with the rest
+	first := make(map[string]int)
+	var titles []string
+	var of []int
+	for n, i := range issues {
+		t := normalizeTitle(i.Title)
+		if strings.Count(t, " ")+1 < minDuplicateWords {
+			continue
+		}
+		if m, ok := first[t]; ok {
+			union(m, n)
+			continue
+		}
+		first[t] = n
+		titles = append(titles, t)
+		of = append(of, n)
+	}
+
+	shingles := make([]map[uint64]bool, len(titles))
+	buckets := make(map[uint64][]int)
+	for n, t := range titles {
+		shingles[n] = trigrams(t)
+		sig := minHash(shingles[n])
+		for b := 0; b < signatureBands; b++ {
+			h := fnv.New64a()
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], uint64(b))
+			h.Write(buf[:])
+			for _, v := range sig[b*bandRows : (b+1)*bandRows] {
+				binary.LittleEndian.PutUint64(buf[:], v)
+				h.Write(buf[:])
+			}
+			key := h.Sum64()
+			buckets[key] = append(buckets[key], n)
+		}
+	}
+	for _, b := range buckets {
+		for x := 0; x < len(b); x++ {
+			for y := x + 1; y < len(b); y++ {
+				if find(of[b[x]]) != find(of[b[y]]) && jaccard(shingles[b[x]], shingles[b[y]]) >= duplicateSimilarity {
+					union(of[b[x]], of[b[y]])
+				}
+			}
+		}
+	}
+
+	clusters := make(map[int][]int)
+	for n := range issues {
+		issues[n].DuplicateOf, issues[n].Duplicates = "", 0
+		r := find(n)
+		clusters[r] = append(clusters[r], n)
+	}
+	for _, members := range clusters {
+		if len(members) == 1 {
+			continue
+		}
+		sort.Slice(members, func(x, y int) bool {
+			a, b := issues[members[x]], issues[members[y]]
+			if !a.Date.Equal(b.Date) {
+				return a.Date.Before(b.Date)
+			}
+			return a.URL < b.URL
+		})
+		original := issues[members[0]].URL
+		for k, n := range members {
+			issues[n].Duplicates = len(members) - 1
+			if k > 0 {
+				issues[n].DuplicateOf = original
+			}
+		}
+	}
+}
+
+// normalizeTitle is title's words lower cased, leaving out numbers and
+// punctuation, so "Add your name to CONTRIBUTORS.md #3" and "add your name
+// to contributors.md" are the same.
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// trigrams hashes every three characters in a row in t.
+func trigrams(t string) map[uint64]bool {
+	r := []rune(t)
+	out := make(map[uint64]bool, len(r))
+	for n := 0; n+3 <= len(r); n++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(r[n : n+3])))
+		out[h.Sum64()] = true
+	}
+	return out
+}
+
+// minHash is the MinHash signature of shingles: for each of the hash
+// functions, the smallest hash of any of them.
+func minHash(shingles map[uint64]bool) [signatureBands * bandRows]uint64 {
+	var sig [signatureBands * bandRows]uint64
+	for n := range sig {
+		sig[n] = ^uint64(0)
+	}
+	for s := range shingles {
+		for n := range sig {
+			if h := mix(s ^ (uint64(n+1) * 0x9e3779b97f4a7c15)); h < sig[n] {
+				sig[n] = h
+			}
+		}
+	}
+	return sig
+}
+
+// mix scrambles x, which makes each seed given to it a different hash
+// function. It's the finalizer of SplitMix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// jaccard is how many of a and b's shingles they share, out of all of them.
+func jaccard(a, b map[uint64]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	var both int
+	for s := range a {
+		if b[s] {
+			both++
+		}
+	}
+	return float64(both) / float64(len(a)+len(b)-both)
+}
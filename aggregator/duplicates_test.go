@@ -0,0 +1,49 @@
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMarkDuplicates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 10, d, 0, 0, 0, 0, time.UTC) }
+	issues := []Issue{
+		{URL: "a", Date: day(3), Title: "Add your name to CONTRIBUTORS.md"},
+		{URL: "b", Date: day(1), Title: "add your name to contributors.md #2"},
+		{URL: "c", Date: day(2), Title: "Add your names to the CONTRIBUTORS.md"},
+		{URL: "d", Date: day(1), Title: "Add a dark mode to the settings page"},
+		{URL: "e", Date: day(1), Title: "Fix typo"},
+		{URL: "f", Date: day(2), Title: "Fix typo"},
+	}
+	MarkDuplicates(issues)
+
+	want := map[string]struct {
+		of string
+		n  int
+	}{
+		"a": {"b", 2},
+		"b": {"", 2},
+		"c": {"b", 2},
+		"d": {"", 0},
+		"e": {"", 0},
+		"f": {"", 0},
+	}
+	for _, i := range issues {
+		if w := want[i.URL]; i.DuplicateOf != w.of || i.Duplicates != w.n {
+			t.Errorf("%s: got a copy of %q with %d others, want a copy of %q with %d", i.URL, i.DuplicateOf, i.Duplicates, w.of, w.n)
+		}
+	}
+}
+
+func TestMarkDuplicatesDifferent(t *testing.T) {
+	var issues []Issue
+	for n := 0; n < 200; n++ {
+		issues = append(issues, Issue{URL: fmt.Sprint(n), Title: fmt.Sprintf("Support exporting %c%c files in the editor", 'a'+n%26, 'a'+n/26)})
+	}
+	issues = append(issues, Issue{URL: "x", Title: "Write tests for the login page"})
+	MarkDuplicates(issues)
+	if issues[200].Duplicates != 0 {
+		t.Errorf("an issue unlike the others has %d duplicates", issues[200].Duplicates)
+	}
+}
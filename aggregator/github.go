@@ -0,0 +1,514 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Doer sends requests to GitHub. An *http.Client is one, and tests use one
+// that answers from fixtures instead.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Breaker stops requests for a while once they keep failing, or once the
+// rate limit's used up, so a GitHub that's struggling isn't made worse.
+type Breaker interface {
+	// Allow gives an error if requests should not be made right now.
+	Allow() error
+
+	// Success says a request worked.
+	Success()
+
+	// Failure says a request failed in a way that could go away by itself.
+	Failure()
+
+	// Trip stops requests until the given time, however many have failed.
+	Trip(until time.Time)
+}
+
+// GitHub makes requests to the GitHub API for issues and repo details. Each
+// search it makes is limited to the Orgs and Projects it holds. It is safe
+// for concurrent use once it's set up.
+type GitHub struct {
+	// BaseURL is the root of the API, without a trailing slash
+	BaseURL string
+
+	// GraphQLURL is the GraphQL endpoint. It defaults to BaseURL/graphql but
+	// Enterprise keeps it somewhere else.
+	GraphQLURL string
+
+	// HTTP sends every request, searches and languages alike
+	HTTP Doer
+
+	// Token is sent with each request if it's set
+	Token string
+
+	// Tokens, if set, gives the token for each request instead of Token, like
+	// a GitHub App's installation tokens
+	Tokens TokenSource
+
+	Orgs     map[string]bool
+	Projects map[string]bool
+
+	// Hidden are labels left out of each issue's Labels, usually the ones
+	// that were searched for
+	Hidden map[string]bool
+
+	// MaxPages caps how many pages of results each search will read. Zero
+	// means read them all.
+	MaxPages int
+
+	// Languages remembers the languages of repos we've already asked about
+	Languages *Languages
+
+	// Responses holds the ETags of earlier GET responses so repeating them can
+	// cost nothing. Nil means don't make conditional requests.
+	Responses *ResponseCache
+
+	// Retries is how many more times to try a request that failed in a way
+	// that might not happen again: server errors, dropped connections, and
+	// rate limits that reset within MaxRetryWait. RetryWait is how long to wait
+	// before the first retry, doubling after that.
+	Retries      int
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+
+	// CallTimeout is the most each attempt at a request gets, if it's set
+	CallTimeout time.Duration
+
+	// Breaker stops requests for a while once they keep failing. Nil means
+	// always try.
+	Breaker Breaker
+
+	// Budget keeps track of how much rate limit we have left so we don't start
+	// what we can't finish. Nil means don't keep track.
+	Budget *Budget
+
+	// Observer is told about every request, nobody if it's nil
+	Observer Observer
+}
+
+// Scope is the search qualifiers covering every org and project c tracks.
+// They are sorted so the same set always gives the same string.
+func (c *GitHub) Scope() string {
+	var quals []string
+	for o := range c.Orgs {
+		quals = append(quals, "org:"+o)
+	}
+	for p := range c.Projects {
+		quals = append(quals, "repo:"+p)
+	}
+	sort.Strings(quals)
+	return strings.Join(quals, " ")
+}
+
+// Get makes a GET request for path and decodes the JSON response into v.
+func (c *GitHub) Get(ctx context.Context, path string, vals url.Values, v interface{}) error {
+	u := c.BaseURL + path
+	if vals != nil {
+		u += "?" + vals.Encode()
+	}
+	_, err := c.GetURL(ctx, u, v)
+	return err
+}
+
+// Post sends body as JSON in a POST request to path and decodes the JSON
+// response into v.
+func (c *GitHub) Post(ctx context.Context, path string, body, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "could not encode request")
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "could not build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(ctx, req, v)
+	return err
+}
+
+// GetURL makes a GET request for the absolute URL u and decodes the JSON
+// response into v. If the response is one page of many the URL of the next
+// page is returned. An error is returned if we could not complete the request
+// or GitHub responds with anything but a 200.
+func (c *GitHub) GetURL(ctx context.Context, u string, v interface{}) (string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not build request")
+	}
+
+	h, err := c.Do(ctx, req, v)
+	if err != nil {
+		return "", err
+	}
+
+	return NextLink(h.Get("Link")), nil
+}
+
+// Do sends req with our token and decodes the JSON response into v, giving
+// back the response headers. Failures that might go away on their own are
+// retried, see try.
+func (c *GitHub) Do(ctx context.Context, req *http.Request, v interface{}) (h http.Header, err error) {
+	ctx, s := observe(c.Observer).StartSpan(ctx, req.Method+" "+req.URL.Path, true)
+	s.Set("http.method", req.Method)
+	s.Set("http.url", req.URL.String())
+	defer func() { s.Finish(err) }()
+
+	// Tell the request to use our context so we can cancel it in-flight if needed
+	req = req.WithContext(ctx)
+
+	if c.Breaker != nil {
+		if err := c.Breaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	token := c.Token
+	if c.Tokens != nil {
+		var err error
+		if token, err = c.Tokens.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	for attempt := 1; ; attempt++ {
+		s.Set("attempts", strconv.Itoa(attempt))
+		h, wait, err := c.tryWithin(req, v)
+		if err == nil {
+			if c.Breaker != nil {
+				c.Breaker.Success()
+			}
+			return h, nil
+		}
+
+		// Being cancelled isn't a failure of the request so say so plainly
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if wait < 0 || attempt > c.Retries {
+			c.gaveUp(err, wait)
+			return nil, err
+		}
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		// The last attempt used up the body so we need a fresh one
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.Wrap(err, "could not rewind request body")
+			}
+			req.Body = body
+		}
+	}
+}
+
+// gaveUp tells the breaker about a request we've stopped retrying. Running out
+// of rate limit opens it until the limit resets, and failures that could have
+// gone away by themselves count towards opening it. Anything else, like a
+// 404, means the other end is working fine.
+func (c *GitHub) gaveUp(err error, wait time.Duration) {
+	if c.Breaker == nil {
+		return
+	}
+	if rl, ok := err.(*RateLimitError); ok {
+		c.Breaker.Trip(rl.Reset)
+	} else if wait >= 0 {
+		c.Breaker.Failure()
+	}
+}
+
+// tryWithin is try given no more than CallTimeout.
+func (c *GitHub) tryWithin(req *http.Request, v interface{}) (http.Header, time.Duration, error) {
+	if c.CallTimeout <= 0 {
+		return c.try(req, v)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.CallTimeout)
+	defer cancel()
+	return c.try(req.WithContext(ctx), v)
+}
+
+// try makes one attempt at req. If it fails, wait says whether to try again:
+// negative means don't, zero means after the usual backoff, and anything else
+// is how long GitHub asked us to wait.
+func (c *GitHub) try(req *http.Request, v interface{}) (h http.Header, wait time.Duration, err error) {
+	// Only GETs can be conditional. GitHub doesn't give ETags for GraphQL.
+	var key string
+	var cached cachedResponse
+	var haveCached bool
+	if c.Responses != nil && req.Method == "GET" {
+		key = responseKey(req)
+		if cached, haveCached = c.Responses.get(key); haveCached {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not execute request")
+	}
+	defer resp.Body.Close()
+	if c.Budget != nil {
+		c.Budget.Record(c.budgetWho(), resp.Header)
+	}
+
+	// Nothing changed since last time so we can use the body we kept
+	if resp.StatusCode == http.StatusNotModified && haveCached {
+		if err := json.Unmarshal(cached.body, v); err != nil {
+			return nil, -1, errors.Wrap(err, "could not decode json")
+		}
+		return cached.header, 0, nil
+	}
+
+	// Secondary rate limits come with a Retry-After in seconds
+	if s := resp.Header.Get("Retry-After"); s != "" && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+		secs, _ := strconv.Atoi(s)
+		wait = time.Duration(secs) * time.Second
+		if wait > c.MaxRetryWait {
+			wait = -1
+		}
+		return nil, wait, &RateLimitError{Reset: time.Now().Add(time.Duration(secs) * time.Second), Secondary: true}
+	}
+
+	// The primary rate limit tells us when it resets, which is worth waiting
+	// for if it's soon
+	if resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		wait = time.Until(time.Unix(reset, 0))
+		if wait <= 0 {
+			wait = 0
+		} else if wait > c.MaxRetryWait {
+			wait = -1
+		}
+		return nil, wait, &RateLimitError{Reset: time.Unix(reset, 0)}
+	}
+
+	if resp.StatusCode >= 500 {
+		return nil, 0, &StatusError{Code: resp.StatusCode}
+	}
+
+	// Making something, like a comment, gives a 201
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, -1, &StatusError{Code: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not read response")
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, -1, errors.Wrap(err, "could not decode json")
+	}
+
+	if etag := resp.Header.Get("ETag"); key != "" && etag != "" {
+		c.Responses.set(key, cachedResponse{etag: etag, body: body, header: resp.Header})
+	}
+
+	return resp.Header, 0, nil
+}
+
+// StatusError is a response with a status we didn't expect, like a 404 for a
+// repo that isn't there or that we can't see.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status was %d, not 200", e.Code)
+}
+
+// RateLimitError is a response saying we've used up the rate limit until
+// Reset. Secondary limits are the ones for making too many requests at once.
+type RateLimitError struct {
+	Reset     time.Time
+	Secondary bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Secondary {
+		return fmt.Sprintf("secondary rate limit exceeded, retry after %v", time.Until(e.Reset).Round(time.Second))
+	}
+	return fmt.Sprintf("rate limit exceeded until %v", e.Reset)
+}
+
+// backoff is how long to wait before the given retry: RetryWait doubled for
+// each attempt so far, give or take up to half again so a crowd of workers
+// don't all come back at once.
+func (c *GitHub) backoff(attempt int) time.Duration {
+	d := c.RetryWait << uint(attempt-1)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// SearchIssues asks the github search api for open issues with label in
+// q.Scope, reading up to MaxPages pages of results. Issues are fed into ch as
+// they are found. A ctx is provided so we know if we need to quit early, in
+// which case its error is returned.
+//
+// With a token we use the GraphQL API since it can tell us the languages of a
+// whole page of repos at once. It won't take anonymous requests though, so
+// without one we fall back to the REST API and a request per repo for
+// languages.
+func (c *GitHub) SearchIssues(ctx context.Context, label string, q Query, ch chan<- Issue) error {
+	if c.CanGraphQL() {
+		return c.searchIssuesGraphQL(ctx, label, q, ch)
+	}
+	return c.searchIssuesREST(ctx, label, q, ch)
+}
+
+// CanGraphQL reports whether c can use the GraphQL API, which needs a token.
+func (c *GitHub) CanGraphQL() bool {
+	return c.Token != "" || c.Tokens != nil
+}
+
+// searchIssuesREST is SearchIssues using the REST API.
+func (c *GitHub) searchIssuesREST(ctx context.Context, label string, q Query, ch chan<- Issue) error {
+	vals := url.Values{}
+	vals.Add("q", SearchQuery(label, q.Scope, q.Created, q.Updated))
+	vals.Add("sort", "updated")
+	vals.Add("order", "asc")
+	vals.Add("per_page", "100")
+
+	// Search results come 100 at a time so keep following the next page until
+	// there isn't one or we've read as many as we're allowed
+	next := c.BaseURL + "/search/issues?" + vals.Encode()
+	for page := 1; next != "" && (c.MaxPages == 0 || page <= c.MaxPages); page++ {
+		var data struct {
+			Items []RESTIssue `json:"items"`
+		}
+
+		var err error
+		next, err = c.GetURL(ctx, next, &data)
+		if err != nil {
+			return err
+		}
+
+		// A page of issues is usually spread over a handful of repos so ask
+		// about each of them once, a few at a time
+		repos := make([]Repo, len(data.Items))
+		var unique []Repo
+		seen := make(map[string]bool)
+		for i, item := range data.Items {
+			repo, err := RepoFromURL(item.RepoURL)
+			if err != nil {
+				return errors.Wrapf(err, "could not identify repo from %s", item.RepoURL)
+			}
+			repos[i] = repo
+			if !seen[repo.ID()] {
+				seen[repo.ID()] = true
+				unique = append(unique, repo)
+			}
+		}
+
+		languages, err := c.Languages.ReposLanguages(ctx, c, unique, q.MaxLangs)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range data.Items {
+			langs, known := languages[repos[i].ID()]
+			issue := item.Issue(c.Languages.Details(repos[i]), langs, c.Hidden)
+			issue.LanguagesUnknown = !known
+
+			select {
+
+			// Stop early because another worker failed or the caller gave up
+			case <-ctx.Done():
+				return ctx.Err()
+
+			// Send our issue on ch if we can
+			case ch <- issue:
+			}
+		}
+	}
+	return nil
+}
+
+// RESTIssue is an issue the way the REST API describes it, in search results
+// and webhooks alike.
+type RESTIssue struct {
+	Title     string     `json:"title"`
+	Number    int        `json:"number"`
+	State     string     `json:"state"`
+	Body      string     `json:"body"`
+	Comments  int        `json:"comments"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	HTMLURL   string     `json:"html_url"`
+	RepoURL   string     `json:"repository_url"`
+	Labels    `json:"labels"`
+	Reactions struct {
+		TotalCount int `json:"total_count"`
+	} `json:"reactions"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+}
+
+// Issue makes an Issue of item, which is in repo, leaving the hidden labels
+// out.
+func (item RESTIssue) Issue(repo Repo, languages []Language, hidden map[string]bool) Issue {
+	return Issue{
+		Title:     item.Title,
+		Number:    item.Number,
+		State:     item.State,
+		Date:      item.CreatedAt,
+		Updated:   item.UpdatedAt,
+		URL:       item.HTMLURL,
+		Repo:      repo,
+		Labels:    FilterLabels(item.Labels, hidden),
+		Languages: LanguageNames(languages),
+		Comments:  item.Comments,
+		Reactions: item.Reactions.TotalCount,
+		Assigned:  len(item.Assignees) > 0,
+		Body:      Excerpt(item.Body, ExcerptLength),
+
+		LanguageShares: languages,
+		Difficulty:     Difficulty(item.Labels),
+		Kind:           Kind(item.Labels, item.Title),
+		Participating:  repo.Participating(),
+
+		MentorAvailable:  Mentored(item.Labels),
+		NaturalLanguages: repo.NaturalLanguages(),
+	}
+}
+
+// RepoLanguages gives the number of bytes of code in each language in repo.
+func (c *GitHub) RepoLanguages(ctx context.Context, repo Repo) (map[string]int, error) {
+	data := make(map[string]int)
+	if err := c.Get(ctx, "/repos/"+repo.Owner+"/"+repo.Name+"/languages", nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
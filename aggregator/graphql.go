@@ -0,0 +1,341 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GraphQL runs query against the GitHub GraphQL API and decodes its data into
+// v. GraphQL reports most problems as a 200 with an errors list so the first
+// of those is returned as an error.
+func (c *GitHub) GraphQL(ctx context.Context, query string, vars map[string]interface{}, v interface{}) error {
+	return c.GraphQLTolerating(ctx, query, vars, v, "")
+}
+
+// GraphQLTolerating is GraphQL except errors of type tolerated don't count,
+// like NOT_FOUND for one of many repos asked about at once. The data we did
+// get is decoded as usual.
+func (c *GitHub) GraphQLTolerating(ctx context.Context, query string, vars map[string]interface{}, v interface{}, tolerated string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not encode query")
+	}
+
+	u := c.GraphQLURL
+	if u == "" {
+		u = c.BaseURL + "/graphql"
+	}
+
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+
+	for _, e := range resp.Errors {
+		if tolerated == "" || e.Type != tolerated {
+			return errors.Errorf("graphql error: %s", e.Message)
+		}
+	}
+
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		return errors.Wrap(err, "could not decode json")
+	}
+
+	return nil
+}
+
+// searchIssuesQuery finds a page of issues along with the labels on each.
+// What we need to know about their repos is asked for separately, once per
+// repo rather than once per issue, see reposQuery.
+const searchIssuesQuery = `query($q: String!, $after: String) {
+  search(query: $q, type: ISSUE, first: 100, after: $after) {
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+    nodes {
+      ... on Issue {
+        title
+        number
+        state
+        bodyText
+        createdAt
+        updatedAt
+        url
+        comments(last: 1) {
+          totalCount
+          nodes {
+            createdAt
+          }
+        }
+        reactions {
+          totalCount
+        }
+        assignees {
+          totalCount
+        }
+        labels(first: 20) {
+          nodes {
+            name
+            color
+          }
+        }
+        repository {
+          name
+          owner {
+            login
+          }
+        }
+      }
+    }
+  }
+}`
+
+// searchIssuesGraphQL is SearchIssues using the GraphQL API.
+func (c *GitHub) searchIssuesGraphQL(ctx context.Context, label string, q Query, ch chan<- Issue) error {
+	vars := map[string]interface{}{
+		"q": SearchQuery(label, q.Scope, q.Created, q.Updated),
+	}
+
+	for page := 1; c.MaxPages == 0 || page <= c.MaxPages; page++ {
+		var data struct {
+			Search struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Title     string    `json:"title"`
+					Number    int       `json:"number"`
+					State     string    `json:"state"`
+					BodyText  string    `json:"bodyText"`
+					CreatedAt time.Time `json:"createdAt"`
+					UpdatedAt time.Time `json:"updatedAt"`
+					URL       string    `json:"url"`
+					Comments  struct {
+						TotalCount int `json:"totalCount"`
+						Nodes      []struct {
+							CreatedAt time.Time `json:"createdAt"`
+						} `json:"nodes"`
+					} `json:"comments"`
+					Reactions struct {
+						TotalCount int `json:"totalCount"`
+					} `json:"reactions"`
+					Assignees struct {
+						TotalCount int `json:"totalCount"`
+					} `json:"assignees"`
+					Labels struct {
+						Nodes Labels `json:"nodes"`
+					} `json:"labels"`
+					Repository struct {
+						Name  string `json:"name"`
+						Owner struct {
+							Login string `json:"login"`
+						} `json:"owner"`
+					} `json:"repository"`
+				} `json:"nodes"`
+			} `json:"search"`
+		}
+		if err := c.GraphQL(ctx, searchIssuesQuery, vars, &data); err != nil {
+			return err
+		}
+
+		var repos []Repo
+		seen := make(map[string]bool)
+		for _, node := range data.Search.Nodes {
+			repo := Repo{Owner: node.Repository.Owner.Login, Name: node.Repository.Name}
+			if !seen[repo.ID()] {
+				seen[repo.ID()] = true
+				repos = append(repos, repo)
+			}
+		}
+		languages, err := c.Languages.ReposLanguages(ctx, c, repos, q.MaxLangs)
+		if err != nil {
+			return err
+		}
+
+		for _, node := range data.Search.Nodes {
+			repo := c.Languages.Details(Repo{Owner: node.Repository.Owner.Login, Name: node.Repository.Name})
+			langs, known := languages[repo.ID()]
+			issue := Issue{
+				Title:     node.Title,
+				Number:    node.Number,
+				State:     strings.ToLower(node.State),
+				Date:      node.CreatedAt,
+				Updated:   node.UpdatedAt,
+				URL:       node.URL,
+				Repo:      repo,
+				Labels:    FilterLabels(node.Labels.Nodes, c.Hidden),
+				Languages: LanguageNames(langs),
+				Comments:  node.Comments.TotalCount,
+				Reactions: node.Reactions.TotalCount,
+				Assigned:  node.Assignees.TotalCount > 0,
+				Body:      Excerpt(node.BodyText, ExcerptLength),
+
+				LanguageShares: langs,
+				Difficulty:     Difficulty(node.Labels.Nodes),
+				Kind:           Kind(node.Labels.Nodes, node.Title),
+				Participating:  repo.Participating(),
+
+				LanguagesUnknown: !known,
+
+				MentorAvailable:  Mentored(node.Labels.Nodes),
+				NaturalLanguages: repo.NaturalLanguages(),
+			}
+
+			if n := len(node.Comments.Nodes); n > 0 {
+				last := node.Comments.Nodes[n-1].CreatedAt
+				issue.LastComment = &last
+			}
+
+			select {
+
+			// Stop early because another worker failed or the caller gave up
+			case <-ctx.Done():
+				return ctx.Err()
+
+			// Send our issue on ch if we can
+			case ch <- issue:
+			}
+		}
+
+		if !data.Search.PageInfo.HasNextPage {
+			break
+		}
+		vars["after"] = data.Search.PageInfo.EndCursor
+	}
+
+	return nil
+}
+
+// maxGraphQLLangs is the most languages GraphQL will give us for a repo.
+const maxGraphQLLangs = 100
+
+// maxReposQuery is the most repos we ask about in one query.
+const maxReposQuery = 50
+
+// repoFields is what reposQuery asks about each repo.
+const repoFields = `fragment repoFields on Repository {
+  stargazerCount
+  description
+  isArchived
+  isDisabled
+  repositoryTopics(first: 20) {
+    nodes {
+      topic {
+        name
+      }
+    }
+  }
+  languages(first: 100, orderBy: {field: SIZE, direction: DESC}) {
+    edges {
+      size
+      node {
+        name
+      }
+    }
+  }
+}`
+
+// reposQuery asks about every one of repos at once, each under an alias of
+// its own: r0 for the first, r1 for the next and so on.
+func reposQuery(repos []Repo) (string, map[string]interface{}) {
+	var params, fields bytes.Buffer
+	vars := make(map[string]interface{}, 2*len(repos))
+	for i, r := range repos {
+		n := strconv.Itoa(i)
+		if i > 0 {
+			params.WriteString(", ")
+		}
+		fmt.Fprintf(&params, "$o%s: String!, $n%s: String!", n, n)
+		fmt.Fprintf(&fields, "  r%s: repository(owner: $o%s, name: $n%s) {\n    ...repoFields\n  }\n", n, n, n)
+		vars["o"+n], vars["n"+n] = r.Owner, r.Name
+	}
+	return fmt.Sprintf("query(%s) {\n%s}\n%s", params.String(), fields.String(), repoFields), vars
+}
+
+// graphqlRepo is a repo the way reposQuery describes it.
+type graphqlRepo struct {
+	StargazerCount   int    `json:"stargazerCount"`
+	Description      string `json:"description"`
+	IsArchived       bool   `json:"isArchived"`
+	IsDisabled       bool   `json:"isDisabled"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	Languages struct {
+		Edges []struct {
+			Size int `json:"size"`
+			Node struct {
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"languages"`
+}
+
+// RepoDetails asks about all of repos in as few requests as we can, giving
+// back what we found for each. Repos GitHub can't find, because they've gone
+// or gone private, are left out.
+func (c *GitHub) RepoDetails(ctx context.Context, repos []Repo) (map[string]RepoInfo, error) {
+	details := make(map[string]RepoInfo, len(repos))
+	for len(repos) > 0 {
+		batch := repos
+		if len(batch) > maxReposQuery {
+			batch = batch[:maxReposQuery]
+		}
+		repos = repos[len(batch):]
+
+		query, vars := reposQuery(batch)
+		var data map[string]*graphqlRepo
+		if err := c.GraphQLTolerating(ctx, query, vars, &data, "NOT_FOUND"); err != nil {
+			return nil, err
+		}
+
+		for i, r := range batch {
+			gr := data["r"+strconv.Itoa(i)]
+			if gr == nil {
+				continue
+			}
+			f := RepoInfo{
+				Bytes:   make(map[string]int, len(gr.Languages.Edges)),
+				Details: Repo{Owner: r.Owner, Name: r.Name, Stars: gr.StargazerCount, Description: gr.Description, Archived: gr.IsArchived || gr.IsDisabled, Topics: []string{}},
+				Fetched: time.Now(),
+			}
+			for _, t := range gr.RepositoryTopics.Nodes {
+				f.Details.Topics = append(f.Details.Topics, t.Topic.Name)
+			}
+			for _, e := range gr.Languages.Edges {
+				f.Bytes[e.Node.Name] = e.Size
+			}
+			details[r.ID()] = f
+		}
+	}
+	return details, nil
+}
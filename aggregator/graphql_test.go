@@ -0,0 +1,204 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGitHub is a GitHub for srv that tracks a single org and project and
+// hides the hacktoberfest label. It has no token so searches use the REST API.
+func testGitHub(srv *httptest.Server) *GitHub {
+	return &GitHub{
+		BaseURL:   srv.URL,
+		HTTP:      http.DefaultClient,
+		Orgs:      map[string]bool{"devict": true},
+		Projects:  map[string]bool{"imacrayon/eventsinwichita": true},
+		Hidden:    map[string]bool{"hacktoberfest": true},
+		Languages: NewLanguages(time.Hour),
+
+		Retries:      2,
+		RetryWait:    time.Millisecond,
+		MaxRetryWait: 2 * time.Second,
+	}
+}
+
+func TestSearchIssuesGraphQL(t *testing.T) {
+	var requests []map[string]interface{}
+	var repoQueries int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Header.Get("Authorization") != "token abc123" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		// Both pages are in the same repo so we're only asked about it once
+		if strings.Contains(body.Query, "repoFields") {
+			repoQueries++
+			w.Write([]byte(`{"data": {"r0": {
+				"stargazerCount": 12,
+				"description": "Find issues to work on",
+				"isArchived": false,
+				"repositoryTopics": {"nodes": [{"topic": {"name": "hacktoberfest"}}, {"topic": {"name": "Spanish"}}]},
+				"languages": {"edges": [
+					{"size": 300, "node": {"name": "Go"}},
+					{"size": 200, "node": {"name": "JavaScript"}},
+					{"size": 100, "node": {"name": "CSS"}}
+				]}
+			}}}`))
+			return
+		}
+		requests = append(requests, body.Variables)
+
+		// Two pages of one issue each
+		if body.Variables["after"] == nil {
+			w.Write([]byte(`{"data": {"search": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "abc"},
+				"nodes": [{
+					"title": "Fix it",
+					"number": 1,
+					"state": "OPEN",
+					"bodyText": "It's broken",
+					"comments": {"totalCount": 3, "nodes": [{"createdAt": "2017-10-05T12:00:00Z"}]},
+					"reactions": {"totalCount": 4},
+					"assignees": {"totalCount": 1},
+					"url": "https://github.com/devict/hacktoberfest/issues/1",
+					"labels": {"nodes": [{"name": "hacktoberfest", "color": "ff8ae2"}, {"name": "bug", "color": "ee0701"}]},
+					"repository": {"name": "hacktoberfest", "owner": {"login": "devict"}}
+				}]
+			}}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"search": {
+			"pageInfo": {"hasNextPage": false},
+			"nodes": [{
+				"title": "Fix that",
+				"url": "https://github.com/devict/hacktoberfest/issues/2",
+				"repository": {"name": "hacktoberfest", "owner": {"login": "devict"}}
+			}]
+		}}}`))
+	}))
+	defer srv.Close()
+
+	c := testGitHub(srv)
+	c.Token = "abc123"
+
+	ch := make(chan Issue, 10)
+	q := Query{Scope: c.Scope(), MaxLangs: 2}
+	if err := c.SearchIssues(context.Background(), "hacktoberfest", q, ch); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	close(ch)
+
+	var got []Issue
+	for i := range ch {
+		got = append(got, i)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d issues, want 2", len(got))
+	}
+
+	commented := time.Date(2017, 10, 5, 12, 0, 0, 0, time.UTC)
+	want := Issue{
+		Title:       "Fix it",
+		Number:      1,
+		State:       "open",
+		Body:        "It's broken",
+		Comments:    3,
+		Reactions:   4,
+		LastComment: &commented,
+		Assigned:    true,
+		URL:         "https://github.com/devict/hacktoberfest/issues/1",
+		Repo: Repo{
+			Owner:       "devict",
+			Name:        "hacktoberfest",
+			Stars:       12,
+			Description: "Find issues to work on",
+			Topics:      []string{"hacktoberfest", "Spanish"},
+		},
+		Labels:         map[string]string{"bug": "ee0701"},
+		Languages:      []string{"Go", "JavaScript"},
+		LanguageShares: []Language{{"Go", 50}, {"JavaScript", 33.3}},
+		Kind:           Bug,
+		Participating:  true,
+
+		NaturalLanguages: []string{"Spanish"},
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v", got[0])
+		t.Errorf("want %+v", want)
+	}
+
+	if repoQueries != 1 {
+		t.Errorf("got %d queries for the repo, want 1", repoQueries)
+	}
+	if len(requests) != 2 || requests[1]["after"] != "abc" {
+		t.Errorf("second page should be requested after the cursor, got %v", requests)
+	}
+	if q, _ := requests[0]["q"].(string); !strings.Contains(q, `label:"hacktoberfest"`) {
+		t.Errorf("got query %q", q)
+	}
+}
+
+func TestSearchIssuesGraphQLErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`))
+	}))
+	defer srv.Close()
+
+	c := testGitHub(srv)
+	c.Token = "abc123"
+
+	err := c.SearchIssues(context.Background(), "hacktoberfest", Query{Scope: c.Scope()}, make(chan Issue))
+	if err == nil || !strings.Contains(err.Error(), "API rate limit exceeded") {
+		t.Errorf("error should mention the rate limit, got %v", err)
+	}
+}
+
+func TestRepoDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Variables["o0"] != "devict" || body.Variables["n1"] != "gone" {
+			t.Errorf("got variables %v", body.Variables)
+		}
+
+		w.Write([]byte(`{
+			"data": {
+				"r0": {"stargazerCount": 5, "description": "Find issues", "languages": {"edges": [{"size": 10, "node": {"name": "Go"}}]}},
+				"r1": null
+			},
+			"errors": [{"type": "NOT_FOUND", "message": "Could not resolve to a Repository"}]
+		}`))
+	}))
+	defer srv.Close()
+
+	c := testGitHub(srv)
+	c.Token = "abc123"
+
+	a, gone := Repo{Owner: "devict", Name: "hacktoberfest"}, Repo{Owner: "devict", Name: "gone"}
+	details, err := c.RepoDetails(context.Background(), []Repo{a, gone})
+	if err != nil {
+		t.Fatalf("missing repos shouldn't be an error, got %v", err)
+	}
+	if d := details[a.ID()]; d.Details.Stars != 5 || d.Details.Description != "Find issues" || d.Bytes["Go"] != 10 {
+		t.Errorf("got %+v", d)
+	}
+	if _, ok := details[gone.ID()]; ok {
+		t.Errorf("missing repo should be left out")
+	}
+}
@@ -0,0 +1,346 @@
+package aggregator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Issue is an open issue with one of the labels searched for.
+type Issue struct {
+	Title     string            `json:"title"`
+	Number    int               `json:"number"`
+	State     string            `json:"state"`
+	Date      time.Time         `json:"date"`
+	Updated   time.Time         `json:"updated"`
+	Repo      Repo              `json:"repo"`
+	Labels    map[string]string `json:"labels"`
+	Languages []string          `json:"languages"`
+
+	// LanguageShares are Languages with how much of the repo's code is in
+	// each of them
+	LanguageShares []Language `json:"language_shares"`
+
+	// LanguagesUnknown is true if we couldn't get the repo's languages, so
+	// Languages is empty whether or not it has any
+	LanguagesUnknown bool `json:"languages_unknown"`
+
+	// MatchedLabels are which of the labels searched for found the issue,
+	// which Labels leaves out
+	MatchedLabels []string `json:"matched_labels"`
+
+	// URL is the issue's page on github.com (the search API's html_url, not its
+	// API url). It's also what tells issues apart.
+	URL string `json:"url"`
+
+	// Comments is how many comments there are on the issue
+	Comments int `json:"comments"`
+
+	// Reactions is how many reactions there are on the issue itself, and
+	// LastComment when its latest comment was made. LastComment is null if
+	// there are no comments, or the issue came from the REST API, which
+	// doesn't say.
+	Reactions   int        `json:"reactions"`
+	LastComment *time.Time `json:"last_comment"`
+
+	// Assigned is true if someone on GitHub is assigned the issue
+	Assigned bool `json:"assigned"`
+
+	// Body is the start of the issue's description, see Excerpt
+	Body string `json:"body"`
+
+	// Difficulty is easy, medium or hard going by the issue's labels, or empty
+	// if they don't say
+	Difficulty string `json:"difficulty"`
+
+	// Kind is the kind of work the issue is going by its labels and title:
+	// docs, bug, feature, tests or translation, or empty if they don't say
+	Kind string `json:"kind"`
+
+	// Participating is true if the issue's repo has opted in to Hacktoberfest,
+	// see Repo.Participating
+	Participating bool `json:"participating"`
+
+	// MentorAvailable is true if the issue's labels say someone on the
+	// project will help whoever takes it on, see Mentored
+	MentorAvailable bool `json:"mentor_available"`
+
+	// DuplicateOf is the URL of the issue this one looks like a copy of, and
+	// Duplicates is how many others look like copies of the same one, going
+	// by their titles. See MarkDuplicates.
+	DuplicateOf string `json:"duplicate_of"`
+	Duplicates  int    `json:"duplicates"`
+
+	// NaturalLanguages are the spoken languages the issue's repo's topics
+	// say it's in or translated to, unlike Languages which are programming
+	// languages. See Repo.NaturalLanguages.
+	NaturalLanguages []string `json:"natural_languages"`
+
+	// The rest is never set by a search. It's for whoever serves the issues
+	// to fill in for the person asking.
+
+	// Bookmarked is true if whoever asked has bookmarked the issue
+	Bookmarked bool `json:"bookmarked"`
+
+	// Claimed is true if someone has claimed the issue to work on, and
+	// ClaimedBy is their GitHub username
+	Claimed   bool   `json:"claimed"`
+	ClaimedBy string `json:"claimed_by"`
+
+	// Closed is true if the issue has closed since it was found
+	Closed bool `json:"closed"`
+
+	// Mentors are the usernames of a few people in the mentors directory
+	// who'll help with one of the issue's languages, for issues that are
+	// easy or have a mentor available
+	Mentors []string `json:"mentors"`
+}
+
+// Labels are labels on an issue.
+type Labels []struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// FilterLabels gives the names and colors of lbs, leaving out the hidden
+// ones, usually the labels that were searched for.
+func FilterLabels(lbs Labels, hidden map[string]bool) map[string]string {
+	issueLabels := make(map[string]string)
+	for _, label := range lbs {
+		if !hidden[label.Name] {
+			issueLabels[label.Name] = label.Color
+		}
+	}
+	return issueLabels
+}
+
+// Repo is a repository on Github. Owner can be either an organization or user.
+type Repo struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+
+	// The rest is only known when GitHub tells us along with the languages,
+	// which is when we ask with GraphQL or get a webhook
+	Stars       int      `json:"stars"`
+	Description string   `json:"description"`
+	Topics      []string `json:"topics"`
+
+	// Archived is true if the repo is read-only, because it's been archived or
+	// GitHub has disabled it, so nobody can contribute to it
+	Archived bool `json:"archived"`
+}
+
+// ParticipationTopic is the topic a repo needs for pull requests to it to
+// count towards Hacktoberfest, since 2020.
+const ParticipationTopic = "hacktoberfest"
+
+// Participating reports whether r has opted in to Hacktoberfest with its
+// topics. Maintainers can also count a pull request by giving it the
+// hacktoberfest-accepted label, but there's no telling who will until they
+// do. We only know the topics of repos on GitHub.
+func (r Repo) Participating() bool {
+	return r.HasTopic([]string{ParticipationTopic})
+}
+
+// HasTopic reports whether r has any of topics, ignoring case.
+func (r Repo) HasTopic(topics []string) bool {
+	for _, t := range r.Topics {
+		for _, want := range topics {
+			if strings.EqualFold(t, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// naturalLanguages maps lower cased topics projects commonly use to the
+// spoken language they mean.
+var naturalLanguages = map[string]string{
+	"arabic":     "Arabic",
+	"bengali":    "Bengali",
+	"chinese":    "Chinese",
+	"dutch":      "Dutch",
+	"english":    "English",
+	"french":     "French",
+	"francais":   "French",
+	"german":     "German",
+	"deutsch":    "German",
+	"hindi":      "Hindi",
+	"indonesian": "Indonesian",
+	"italian":    "Italian",
+	"japanese":   "Japanese",
+	"korean":     "Korean",
+	"persian":    "Persian",
+	"polish":     "Polish",
+	"portuguese": "Portuguese",
+	"pt-br":      "Portuguese",
+	"russian":    "Russian",
+	"spanish":    "Spanish",
+	"espanol":    "Spanish",
+	"turkish":    "Turkish",
+	"ukrainian":  "Ukrainian",
+	"vietnamese": "Vietnamese",
+}
+
+// NaturalLanguages gives the spoken languages r's topics name, sorted, so
+// people translating can find repos in theirs. We only know the topics of
+// repos on GitHub.
+func (r Repo) NaturalLanguages() []string {
+	found := make(map[string]bool)
+	for _, t := range r.Topics {
+		if l, ok := naturalLanguages[strings.ToLower(t)]; ok {
+			found[l] = true
+		}
+	}
+	langs := []string{}
+	for l := range found {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ID tells repos apart, whatever else we know about them.
+func (r Repo) ID() string {
+	return r.Owner + "/" + r.Name
+}
+
+// reRepo matches the API URL of a repo on github.com or an Enterprise server.
+var reRepo = regexp.MustCompile("^https?://[^/]+(?:/api/v3)?/repos/([^/]+)/([^/]+)")
+
+// RepoFromURL gets the repo from its API URL, which ends in
+// /repos/owner/name.
+func RepoFromURL(url string) (Repo, error) {
+	if !reRepo.MatchString(url) {
+		return Repo{}, fmt.Errorf("url %q did not match regexp", url)
+	}
+
+	matches := reRepo.FindStringSubmatch(url)
+	return Repo{
+		Name:  matches[2],
+		Owner: matches[1],
+	}, nil
+}
+
+// Difficulty tiers an issue can be put in. Issues with none of the
+// difficultyLabels have no difficulty.
+const (
+	Easy   = "easy"
+	Medium = "medium"
+	Hard   = "hard"
+)
+
+// difficultyLabels maps lower cased label names projects commonly use to the
+// tier they mean.
+var difficultyLabels = map[string]string{
+	"good first issue":   Easy,
+	"good-first-issue":   Easy,
+	"beginner friendly":  Easy,
+	"beginner-friendly":  Easy,
+	"beginner":           Easy,
+	"first-timers-only":  Easy,
+	"easy":               Easy,
+	"difficulty: easy":   Easy,
+	"intermediate":       Medium,
+	"medium":             Medium,
+	"difficulty: medium": Medium,
+	"advanced":           Hard,
+	"hard":               Hard,
+	"difficulty: hard":   Hard,
+}
+
+// tiers are the difficulties from easiest to hardest.
+var tiers = []string{Easy, Medium, Hard}
+
+// Difficulty gives the tier lbs put an issue in. If the labels disagree the
+// easiest wins, since a project calling something beginner friendly is the
+// stronger signal for people looking for a first issue.
+func Difficulty(lbs Labels) string {
+	found := make(map[string]bool)
+	for _, l := range lbs {
+		if d, ok := difficultyLabels[strings.ToLower(l.Name)]; ok {
+			found[d] = true
+		}
+	}
+
+	for _, d := range tiers {
+		if found[d] {
+			return d
+		}
+	}
+	return ""
+}
+
+// mentorLabels are lower cased label names projects use to say someone will
+// help whoever takes the issue on.
+var mentorLabels = map[string]bool{
+	"mentor available": true,
+	"mentor-available": true,
+	"mentored":         true,
+	"mentorship":       true,
+	"has mentor":       true,
+}
+
+// Mentored reports whether lbs say a mentor is available for the issue.
+func Mentored(lbs Labels) bool {
+	for _, l := range lbs {
+		if mentorLabels[strings.ToLower(l.Name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeIssues is one issue from two copies of it found by different
+// searches: the richer of them, going by which was updated last, then which
+// knows more languages, labels and comments, with the labels both matched.
+func mergeIssues(a, b Issue) Issue {
+	matched := append(append([]string{}, a.MatchedLabels...), b.MatchedLabels...)
+	if richer(b, a) {
+		a = b
+	}
+	a.MatchedLabels = uniqueSorted(matched)
+	return a
+}
+
+// uniqueSorted is names sorted without repeats.
+func uniqueSorted(names []string) []string {
+	sort.Strings(names)
+	out := names[:0]
+	for n, name := range names {
+		if n == 0 || name != names[n-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// richer reports whether a has more to say than b, another copy of the
+// same issue.
+func richer(a, b Issue) bool {
+	switch {
+	case !a.Updated.Equal(b.Updated):
+		return a.Updated.After(b.Updated)
+	case len(a.Languages) != len(b.Languages):
+		return len(a.Languages) > len(b.Languages)
+	case len(a.Labels) != len(b.Labels):
+		return len(a.Labels) > len(b.Labels)
+	}
+	return a.Comments > b.Comments
+}
+
+// sortIssues puts issues in the order they're listed in unless asked for
+// another: newest first, then by URL so issues opened at the same time
+// always come out the same way.
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.After(b.Date)
+		}
+		return a.URL < b.URL
+	})
+}
@@ -0,0 +1,141 @@
+package aggregator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepoFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		repo Repo
+		ok   bool
+	}{
+		{"https://api.github.com/repos/spf13/hugo", Repo{Owner: "spf13", Name: "hugo"}, true},
+		{"https://api.github.com/repos/exercism/xgo", Repo{Owner: "exercism", Name: "xgo"}, true},
+		{"https://api.github.com/", Repo{}, false},
+		{"https://github.example.com/api/v3/repos/devict/hacktoberfest", Repo{Owner: "devict", Name: "hacktoberfest"}, true},
+		{"https://example.com/not/repos/devict/hacktoberfest", Repo{}, false},
+	}
+
+	for i, test := range tests {
+		got, err := RepoFromURL(test.url)
+		if test.ok && err != nil {
+			t.Errorf("%d: error should be nil, got %v", i, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("%d: error should not be nil, but it was", i)
+		} else if !reflect.DeepEqual(got, test.repo) {
+			t.Errorf("%d: got != want:\n%+v\n%+v", i, got, test.repo)
+		}
+	}
+}
+
+func TestRepoParticipating(t *testing.T) {
+	tests := []struct {
+		topics []string
+		want   bool
+	}{
+		{nil, false},
+		{[]string{"go", "cli"}, false},
+		{[]string{"go", "Hacktoberfest"}, true},
+		{[]string{"hacktoberfest2020"}, false},
+	}
+
+	for _, test := range tests {
+		if got := (Repo{Topics: test.topics}).Participating(); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.topics, got, test.want)
+		}
+	}
+}
+
+func TestRepoNaturalLanguages(t *testing.T) {
+	tests := []struct {
+		topics []string
+		want   []string
+	}{
+		{nil, []string{}},
+		{[]string{"go", "translations"}, []string{}},
+		{[]string{"Spanish", "espanol", "french"}, []string{"French", "Spanish"}},
+	}
+
+	for _, test := range tests {
+		if got := (Repo{Topics: test.topics}).NaturalLanguages(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.topics, got, test.want)
+		}
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"bug", "hacktoberfest"}, ""},
+		{[]string{"Good First Issue"}, Easy},
+		{[]string{"beginner friendly", "bug"}, Easy},
+		{[]string{"difficulty: medium"}, Medium},
+		{[]string{"advanced"}, Hard},
+		{[]string{"hard", "good first issue"}, Easy},
+	}
+
+	for _, test := range tests {
+		var lbs Labels
+		for _, name := range test.labels {
+			lbs = append(lbs, struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			}{Name: name})
+		}
+
+		if got := Difficulty(lbs); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.labels, got, test.want)
+		}
+	}
+}
+
+func TestMentored(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   bool
+	}{
+		{nil, false},
+		{[]string{"bug", "mentor"}, false},
+		{[]string{"good first issue", "Mentor Available"}, true},
+		{[]string{"mentorship"}, true},
+	}
+
+	for _, test := range tests {
+		var lbs Labels
+		for _, name := range test.labels {
+			lbs = append(lbs, struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			}{Name: name})
+		}
+
+		if got := Mentored(lbs); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.labels, got, test.want)
+		}
+	}
+}
+
+func TestRicher(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		a, b Issue
+		want bool
+	}{
+		{Issue{Updated: now}, Issue{Updated: now.Add(-time.Minute), Languages: []string{"Go"}}, true},
+		{Issue{Updated: now}, Issue{Updated: now, Languages: []string{"Go"}}, false},
+		{Issue{Updated: now, Labels: map[string]string{"bug": "red"}}, Issue{Updated: now}, true},
+		{Issue{Updated: now, Comments: 2}, Issue{Updated: now, Comments: 1}, true},
+		{Issue{Updated: now}, Issue{Updated: now}, false},
+	}
+	for n, test := range tests {
+		if got := richer(test.a, test.b); got != test.want {
+			t.Errorf("%d: got %v, want %v", n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package aggregator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kinds of work an issue can be. Issues neither their labels nor their
+// title place have no kind.
+const (
+	Docs        = "docs"
+	Bug         = "bug"
+	Feature     = "feature"
+	Tests       = "tests"
+	Translation = "translation"
+)
+
+// kinds are the kinds in the order they win when an issue looks like more
+// than one. The narrower ones come first: fixing a typo in the docs is docs
+// work, whatever else the issue says.
+var kinds = []string{Translation, Docs, Tests, Bug, Feature}
+
+// kindLabels maps lower cased label names projects commonly use, once any
+// "type: " or "kind/" prefix is taken off, to the kind of work they mean.
+var kindLabels = map[string]string{
+	"documentation":   Docs,
+	"docs":            Docs,
+	"doc":             Docs,
+	"bug":             Bug,
+	"defect":          Bug,
+	"regression":      Bug,
+	"enhancement":     Feature,
+	"feature":         Feature,
+	"feature request": Feature,
+	"new feature":     Feature,
+	"test":            Tests,
+	"tests":           Tests,
+	"testing":         Tests,
+	"translation":     Translation,
+	"translations":    Translation,
+	"i18n":            Translation,
+	"l10n":            Translation,
+	"localization":    Translation,
+}
+
+// kindWords maps lower cased words in titles to the kind of work they
+// suggest, for issues whose labels don't say.
+var kindWords = map[string]string{
+	"docs":          Docs,
+	"documentation": Docs,
+	"document":      Docs,
+	"readme":        Docs,
+	"typo":          Docs,
+	"bug":           Bug,
+	"crash":         Bug,
+	"crashes":       Bug,
+	"broken":        Bug,
+	"fails":         Bug,
+	"feature":       Feature,
+	"implement":     Feature,
+	"support":       Feature,
+	"test":          Tests,
+	"tests":         Tests,
+	"testing":       Tests,
+	"coverage":      Tests,
+	"translate":     Translation,
+	"translation":   Translation,
+	"translations":  Translation,
+	"i18n":          Translation,
+	"l10n":          Translation,
+	"localize":      Translation,
+	"localization":  Translation,
+}
+
+// kindPrefixes are what projects put in front of label names to say the
+// label is about the kind of work.
+var kindPrefixes = []string{"type:", "type/", "kind:", "kind/"}
+
+// IsKind reports whether k is one of the kinds.
+func IsKind(k string) bool {
+	for _, want := range kinds {
+		if k == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind gives the kind of work an issue with lbs and title is. Its labels
+// are the stronger signal, so only if none of them say is the title looked
+// at. Either way if more than one kind fits the first of kinds wins.
+func Kind(lbs Labels, title string) string {
+	found := make(map[string]bool)
+	for _, l := range lbs {
+		name := strings.ToLower(strings.TrimSpace(l.Name))
+		for _, p := range kindPrefixes {
+			name = strings.TrimSpace(strings.TrimPrefix(name, p))
+		}
+		if k, ok := kindLabels[name]; ok {
+			found[k] = true
+		}
+	}
+
+	if len(found) == 0 {
+		words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			if k, ok := kindWords[w]; ok {
+				found[k] = true
+			}
+		}
+	}
+
+	for _, k := range kinds {
+		if found[k] {
+			return k
+		}
+	}
+	return ""
+}
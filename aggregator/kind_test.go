@@ -0,0 +1,38 @@
+package aggregator
+
+import "testing"
+
+func TestKind(t *testing.T) {
+	tests := []struct {
+		labels []string
+		title  string
+		want   string
+	}{
+		{nil, "Make it faster", ""},
+		{[]string{"hacktoberfest"}, "", ""},
+		{[]string{"Documentation"}, "Crash on start", Docs},
+		{[]string{"type: bug"}, "", Bug},
+		{[]string{"kind/feature"}, "", Feature},
+		{[]string{"enhancement", "i18n"}, "", Translation},
+		{[]string{"good first issue"}, "Fix typo in the README", Docs},
+		{nil, "Add tests for the parser", Tests},
+		{nil, "App crashes when offline", Bug},
+		{nil, "Support dark mode", Feature},
+		{nil, "Translate the site to Spanish", Translation},
+		{nil, "Retesting isn't a word we look for", ""},
+	}
+
+	for _, test := range tests {
+		var lbs Labels
+		for _, name := range test.labels {
+			lbs = append(lbs, struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			}{Name: name})
+		}
+
+		if got := Kind(lbs, test.title); got != test.want {
+			t.Errorf("%q %q: got %q, want %q", test.labels, test.title, got, test.want)
+		}
+	}
+}
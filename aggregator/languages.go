@@ -0,0 +1,339 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Language is one of a repo's languages, with how much of its code is in it.
+type Language struct {
+	Name string `json:"name"`
+
+	// Percent is the share of all the repo's code, not just of the
+	// languages listed, to one decimal place
+	Percent float64 `json:"percent"`
+}
+
+// TopLanguages gives the n languages with the most code in data, which is
+// how much there is of each, or all of them if n is zero. Languages with the
+// same amount are sorted by name so ties don't take turns at the cut off.
+func TopLanguages(n int, data map[string]int) []Language {
+	var total int
+	for _, size := range data {
+		total += size
+	}
+
+	langs := make([]Language, 0, len(data))
+	for name, size := range data {
+		var pct float64
+		if total > 0 {
+			pct = math.Floor(float64(size)*1000/float64(total)+0.5) / 10
+		}
+		langs = append(langs, Language{Name: name, Percent: pct})
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if a, b := data[langs[i].Name], data[langs[j].Name]; a != b {
+			return a > b
+		}
+		return langs[i].Name < langs[j].Name
+	})
+
+	if n > 0 && n < len(langs) {
+		langs = langs[:n]
+	}
+	return langs
+}
+
+// Top is the names of TopLanguages, most code first.
+func Top(n int, data map[string]int) []string {
+	return LanguageNames(TopLanguages(n, data))
+}
+
+// LanguageNames gives the names of langs in the same order.
+func LanguageNames(langs []Language) []string {
+	names := make([]string, len(langs))
+	for i, l := range langs {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Store is somewhere to keep what Languages has fetched instead of in
+// memory, so everything sharing it knows.
+type Store interface {
+	// Get gives the value at key and whether there was one
+	Get(key string) ([]byte, bool, error)
+
+	// Set puts value at key for ttl
+	Set(key string, value []byte, ttl time.Duration) error
+
+	Del(keys ...string) error
+}
+
+// Languages gets the languages of repos and remembers them for a while. One
+// can be shared by every search so each repo is only asked about once in a
+// while. It is safe for concurrent use, but Store, Prefix and Observer
+// should be set before it's used.
+type Languages struct {
+	ttl time.Duration
+
+	// Store, if set, is where what's fetched is kept instead of in memory,
+	// under keys starting with Prefix. Whatever else uses the store has to
+	// keep to other prefixes.
+	Store  Store
+	Prefix string
+
+	// Observer is told about what's fetched, nobody if it's nil
+	Observer Observer
+
+	mu           sync.Mutex
+	fetchedRepos map[string]RepoInfo
+}
+
+// RepoInfo is what we know about a repo: its languages, and the rest of its
+// details if GitHub told us.
+type RepoInfo struct {
+	// Bytes is how much code there is in each language
+	Bytes   map[string]int `json:"bytes"`
+	Details Repo           `json:"details"`
+	Fetched time.Time      `json:"fetched"`
+}
+
+// NewLanguages makes a Languages that remembers what it fetches for ttl.
+func NewLanguages(ttl time.Duration) *Languages {
+	return &Languages{
+		ttl:          ttl,
+		fetchedRepos: make(map[string]RepoInfo),
+	}
+}
+
+// Size is how many repos are kept in memory.
+func (lf *Languages) Size() int {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	return len(lf.fetchedRepos)
+}
+
+// RepoLanguages gives the top max languages of repo, or all of them if max is
+// zero, using c to ask GitHub if we don't already know.
+func (lf *Languages) RepoLanguages(ctx context.Context, c *GitHub, repo Repo, max int) (langs []Language, err error) {
+	f, ok, err := lf.fetch(ctx, c, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []Language{}, nil
+	}
+	return TopLanguages(max, f.Bytes), nil
+}
+
+// fetch gives what we know about repo, asking GitHub with c if we don't know
+// or it's been too long. ok is false if we would have had to ask but c is
+// short of rate limit.
+func (lf *Languages) fetch(ctx context.Context, c *GitHub, repo Repo) (f RepoInfo, ok bool, err error) {
+	o := observe(lf.Observer)
+	ctx, s := o.StartSpan(ctx, "repoLanguages", false)
+	s.Set("repo", repo.Owner+"/"+repo.Name)
+	defer func() { s.Finish(err) }()
+
+	// Return cached languages if already fetched from repo. We keep the byte
+	// counts rather than the top few so any max can be served from them.
+	f, ok = lf.Load(repo.ID())
+
+	s.Set("cached", strconv.FormatBool(ok && time.Since(f.Fetched) <= lf.ttl))
+	if ok && time.Since(f.Fetched) <= lf.ttl {
+		return f, true, nil
+	}
+
+	// Languages are nice to have but not worth running out of rate limit
+	// for, so go without
+	if !c.CanSpare("core") {
+		s.Set("skipped", "true")
+		o.Log(ctx, Debug, "skipping languages, rate limit is low", "repo", repo.Owner+"/"+repo.Name)
+		return RepoInfo{}, false, nil
+	}
+
+	// If not cached, get languages from repo. We don't hold the lock while
+	// we do since it could take a while.
+	data, err := c.RepoLanguages(ctx, repo)
+	if err != nil {
+		return RepoInfo{}, false, err
+	}
+
+	f = RepoInfo{Bytes: data, Details: repo, Fetched: time.Now()}
+	lf.Save(repo.ID(), f)
+	return f, true, nil
+}
+
+// languageWorkers is the most repos we ask GitHub the languages of at once.
+const languageWorkers = 4
+
+// ReposLanguages is RepoLanguages for each of repos. With GraphQL we can ask
+// about all the ones we don't know yet at once. Otherwise, or if that fails,
+// we ask about up to languageWorkers of them at a time.
+//
+// Languages are only nice to have, so repos we couldn't get the languages of
+// are logged and left out rather than failing the rest, and their issues go
+// without. The only error is ctx's, if it's done.
+func (lf *Languages) ReposLanguages(ctx context.Context, c *GitHub, repos []Repo, max int) (map[string][]Language, error) {
+	o := observe(lf.Observer)
+	if c.CanGraphQL() {
+		if err := lf.prefetch(ctx, c, repos); err != nil && ctx.Err() == nil {
+			o.Log(ctx, Warn, "could not prefetch languages", "repos", len(repos), "err", err)
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		langs = make(map[string][]Language, len(repos))
+	)
+
+	sem := make(chan struct{}, languageWorkers)
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(repo Repo) {
+			defer func() { <-sem; wg.Done() }()
+			l, err := lf.RepoLanguages(ctx, c, repo, max)
+			if err != nil {
+				if ctx.Err() == nil {
+					o.Log(ctx, Warn, "could not get languages", "repo", repo.ID(), "err", err)
+				}
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			langs[repo.ID()] = l
+		}(repo)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return langs, nil
+}
+
+// prefetch asks GitHub about whichever of repos we don't know the languages
+// of in one go. Repos it couldn't find are remembered as having none, so we
+// don't keep asking.
+func (lf *Languages) prefetch(ctx context.Context, c *GitHub, repos []Repo) error {
+	var unknown []Repo
+	for _, r := range repos {
+		if f, ok := lf.Load(r.ID()); !ok || time.Since(f.Fetched) > lf.ttl {
+			unknown = append(unknown, r)
+		}
+	}
+	if len(unknown) == 0 || !c.CanSpare("graphql") {
+		return nil
+	}
+
+	ctx, s := observe(lf.Observer).StartSpan(ctx, "prefetchLanguages", false)
+	s.Set("repos", strconv.Itoa(len(unknown)))
+	details, err := c.RepoDetails(ctx, unknown)
+	s.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range unknown {
+		f, ok := details[r.ID()]
+		if !ok {
+			f = RepoInfo{Bytes: map[string]int{}, Details: r, Fetched: time.Now()}
+		}
+		lf.Save(r.ID(), f)
+	}
+	return nil
+}
+
+// Details gives repo with whatever else we know about it filled in.
+func (lf *Languages) Details(repo Repo) Repo {
+	if f, ok := lf.Load(repo.ID()); ok {
+		return f.Details
+	}
+	return repo
+}
+
+// Remember keeps repo's details, as a webhook told us them, alongside its
+// languages if we know those.
+func (lf *Languages) Remember(repo Repo) {
+	if f, ok := lf.Load(repo.ID()); ok {
+		f.Details = repo
+		lf.Save(repo.ID(), f)
+	}
+}
+
+// Invalidate forgets the languages of repo.
+func (lf *Languages) Invalidate(repo Repo) {
+	if lf.Store != nil {
+		if err := lf.Store.Del(lf.Prefix + repo.ID()); err != nil {
+			observe(lf.Observer).Log(context.Background(), Error, errors.Wrap(err, "could not forget shared languages").Error())
+		}
+		return
+	}
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	delete(lf.fetchedRepos, repo.ID())
+}
+
+// Load gives what we know about the repo with id, however old. Not being able
+// to reach the Store is the same as not knowing.
+func (lf *Languages) Load(id string) (RepoInfo, bool) {
+	if lf.Store == nil {
+		lf.mu.Lock()
+		defer lf.mu.Unlock()
+		f, ok := lf.fetchedRepos[id]
+		return f, ok
+	}
+
+	b, ok, err := lf.Store.Get(lf.Prefix + id)
+	if err == nil && ok {
+		var f RepoInfo
+		if err = json.Unmarshal(b, &f); err == nil {
+			return f, true
+		}
+	}
+	if err != nil {
+		observe(lf.Observer).Log(context.Background(), Error, errors.Wrap(err, "could not load shared languages").Error(), "repo", id)
+	}
+	return RepoInfo{}, false
+}
+
+// Save keeps f as what we know about the repo with id. In the Store it's only
+// kept until it would be too old to use.
+func (lf *Languages) Save(id string, f RepoInfo) {
+	if lf.Store == nil {
+		lf.mu.Lock()
+		defer lf.mu.Unlock()
+		lf.fetchedRepos[id] = f
+		return
+	}
+
+	keep := time.Until(f.Fetched.Add(lf.ttl))
+	if keep <= 0 {
+		return
+	}
+	b, err := json.Marshal(f)
+	if err == nil {
+		err = lf.Store.Set(lf.Prefix+id, b, keep)
+	}
+	if err != nil {
+		observe(lf.Observer).Log(context.Background(), Error, errors.Wrap(err, "could not save shared languages").Error(), "repo", id)
+	}
+}
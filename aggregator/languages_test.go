@@ -0,0 +1,159 @@
+package aggregator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTop(t *testing.T) {
+	data := map[string]int{
+		"apple":      1234,
+		"banana":     234,
+		"cherry":     999,
+		"date":       423,
+		"elderberry": 1234,
+	}
+
+	// Ties are sorted by name, so it's the same every time
+	want := []string{"apple", "elderberry", "cherry"}
+	for i := 0; i < 10; i++ {
+		if got := Top(3, data); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// Don't panic if we have fewer values than asked for
+	if got := Top(33, data); len(got) != len(data) {
+		t.Errorf("got %v, want all of them", got)
+	}
+}
+
+func TestTopLanguages(t *testing.T) {
+	data := map[string]int{"Go": 2, "CSS": 1}
+
+	want := []Language{{"Go", 66.7}, {"CSS", 33.3}}
+	if got := TopLanguages(0, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := TopLanguages(1, data); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("got %v, want %v", got, want[:1])
+	}
+	if got := TopLanguages(3, map[string]int{}); len(got) != 0 {
+		t.Errorf("got %v for a repo with no code", got)
+	}
+}
+
+func TestRepoLanguages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Go": 5000, "JavaScript": 4000, "CSS": 300, "Shell": 20}`))
+	}))
+	defer srv.Close()
+	c := testGitHub(srv)
+
+	// Percentages are of all the code, however many are listed
+	goLang, js, css, shell := Language{"Go", 53.6}, Language{"JavaScript", 42.9}, Language{"CSS", 3.2}, Language{"Shell", 0.2}
+	tests := []struct {
+		max  int
+		want []Language
+	}{
+		{1, []Language{goLang}},
+		{3, []Language{goLang, js, css}},
+		{0, []Language{goLang, js, css, shell}},
+	}
+
+	for _, test := range tests {
+		got, err := c.Languages.RepoLanguages(context.Background(), c, Repo{Owner: "devict", Name: "hacktoberfest"}, test.max)
+		if err != nil {
+			t.Errorf("max %d: error should be nil, got %v", test.max, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("max %d: got %v, want %v", test.max, got, test.want)
+		}
+	}
+}
+
+func TestLanguagesCaches(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"Go": 100}`))
+	}))
+	defer srv.Close()
+	c := testGitHub(srv)
+	c.Languages = NewLanguages(50 * time.Millisecond)
+	repo := Repo{Owner: "devict", Name: "hacktoberfest"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Languages.RepoLanguages(context.Background(), c, repo, 3); err != nil {
+			t.Fatalf("error should be nil, got %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests for the same repo, want 1", requests)
+	}
+
+	c.Languages.Invalidate(repo)
+	c.Languages.RepoLanguages(context.Background(), c, repo, 3)
+	if requests != 2 {
+		t.Errorf("got %d requests after invalidating, want 2", requests)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	c.Languages.RepoLanguages(context.Background(), c, repo, 3)
+	if requests != 3 {
+		t.Errorf("got %d requests after expiring, want 3", requests)
+	}
+}
+
+func TestReposLanguages(t *testing.T) {
+	var inFlight, most int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if r.URL.Path == "/repos/devict/broken/languages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Go": 100}`))
+	}))
+	defer srv.Close()
+	c := testGitHub(srv)
+
+	var repos []Repo
+	for i := 0; i < 3*languageWorkers; i++ {
+		repos = append(repos, Repo{Owner: "devict", Name: strconv.Itoa(i)})
+	}
+	langs, err := c.Languages.ReposLanguages(context.Background(), c, repos, 3)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(langs) != len(repos) || !reflect.DeepEqual(langs[repos[0].ID()], []Language{{"Go", 100}}) {
+		t.Errorf("got %v, want Go for every repo", langs)
+	}
+	if most < 2 || most > languageWorkers {
+		t.Errorf("got %d requests at once, want between 2 and %d", most, languageWorkers)
+	}
+
+	repos = append(repos, Repo{Owner: "devict", Name: "broken"})
+	c.Languages = NewLanguages(time.Hour)
+	langs, err = c.Languages.ReposLanguages(context.Background(), c, repos, 3)
+	if err != nil {
+		t.Fatalf("one repo failing shouldn't fail them all, got %v", err)
+	}
+	if _, ok := langs["devict/broken"]; ok || len(langs) != len(repos)-1 {
+		t.Errorf("got %v, want every repo but devict/broken", langs)
+	}
+}
@@ -0,0 +1,65 @@
+package aggregator
+
+import (
+	"context"
+	"time"
+)
+
+// Observer is told what searches and the clients making them are up to, so
+// whoever's running them can log, trace and measure it. Its methods are
+// called from many goroutines at once.
+type Observer interface {
+	// StartSpan starts timing name, a request over the network if remote is
+	// true, giving back a context for whatever it does in turn.
+	StartSpan(ctx context.Context, name string, remote bool) (context.Context, Span)
+
+	// Log says something worth knowing happened, with key/value pairs
+	// describing it.
+	Log(ctx context.Context, level Level, msg string, kv ...interface{})
+
+	// Searched is a search for label finishing, however it went, after took.
+	Searched(label string, took time.Duration)
+}
+
+// Span is one timed operation an Observer started.
+type Span interface {
+	// Set notes something about the operation.
+	Set(key, value string)
+
+	// Finish ends the operation, which failed if err isn't nil.
+	Finish(err error)
+}
+
+// Level is how much a log line matters.
+type Level int
+
+// Levels from least to most important.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+// observe is o, or one that ignores everything if there isn't one.
+func observe(o Observer) Observer {
+	if o == nil {
+		return nobody{}
+	}
+	return o
+}
+
+// nobody is an Observer that ignores everything.
+type nobody struct{}
+
+func (nobody) StartSpan(ctx context.Context, name string, remote bool) (context.Context, Span) {
+	return ctx, nobody{}
+}
+
+func (nobody) Log(ctx context.Context, level Level, msg string, kv ...interface{}) {}
+
+func (nobody) Searched(label string, took time.Duration) {}
+
+func (nobody) Set(key, value string) {}
+
+func (nobody) Finish(err error) {}
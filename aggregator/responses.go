@@ -0,0 +1,66 @@
+package aggregator
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ResponseCache remembers the bodies of GitHub responses that came with an
+// ETag so we can make the same request again with If-None-Match. GitHub
+// answers those with a 304 when nothing changed, which doesn't count against
+// the rate limit, and we decode the body we already have. It is safe for
+// concurrent use.
+type ResponseCache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	etag   string
+	body   []byte
+	header http.Header
+}
+
+// NewResponseCache makes a ResponseCache holding up to max responses.
+func NewResponseCache(max int) *ResponseCache {
+	return &ResponseCache{
+		max:     max,
+		entries: make(map[string]cachedResponse),
+	}
+}
+
+// Size is how many responses are cached.
+func (rc *ResponseCache) Size() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.entries)
+}
+
+// responseKey identifies req in the cache. What GitHub gives back can depend
+// on who's asking so the Authorization header is part of it.
+func responseKey(req *http.Request) string {
+	return req.URL.String() + "\n" + req.Header.Get("Authorization")
+}
+
+func (rc *ResponseCache) get(key string) (cachedResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	r, ok := rc.entries[key]
+	return r, ok
+}
+
+// set stores r under key. When the cache is full an arbitrary entry makes
+// room, the worst that can happen is we pay for that request again.
+func (rc *ResponseCache) set(key string, r cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if _, ok := rc.entries[key]; !ok && len(rc.entries) >= rc.max {
+		for k := range rc.entries {
+			delete(rc.entries, k)
+			break
+		}
+	}
+	rc.entries[key] = r
+}
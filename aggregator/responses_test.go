@@ -0,0 +1,57 @@
+package aggregator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	var conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		w.Write([]byte(`{"Go": 100, "HTML": 10}`))
+	}))
+	defer srv.Close()
+	c := &GitHub{BaseURL: srv.URL, HTTP: http.DefaultClient, Responses: NewResponseCache(10)}
+
+	want := map[string]int{"Go": 100, "HTML": 10}
+	for i := 0; i < 3; i++ {
+		got, err := c.RepoLanguages(context.Background(), Repo{Owner: "devict", Name: "hacktoberfest"})
+		if err != nil {
+			t.Fatalf("%d: error should be nil, got %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: got %v, want %v", i, got, want)
+		}
+	}
+
+	if conditional != 2 {
+		t.Errorf("got %d conditional requests, want 2", conditional)
+	}
+}
+
+func TestResponseCacheMax(t *testing.T) {
+	rc := NewResponseCache(2)
+	rc.set("a", cachedResponse{etag: "1"})
+	rc.set("b", cachedResponse{etag: "2"})
+	rc.set("b", cachedResponse{etag: "3"})
+	if rc.Size() != 2 {
+		t.Errorf("replacing an entry should not evict, got %d entries", rc.Size())
+	}
+
+	rc.set("c", cachedResponse{etag: "4"})
+	if rc.Size() != 2 {
+		t.Errorf("got %d entries, want 2", rc.Size())
+	}
+	if r, ok := rc.get("c"); !ok || r.etag != "4" {
+		t.Errorf("got %v, %v, want the newest entry", r, ok)
+	}
+}
@@ -0,0 +1,427 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExcerptLength is how many characters of an issue's body are kept.
+const ExcerptLength = 280
+
+// Query is what a search looks for.
+type Query struct {
+	// Scope is the search qualifiers limiting which repos are searched
+	Scope string
+
+	// Labels are searched for one at a time. Issues with any of them are found.
+	Labels []string
+
+	// MaxLangs is how many languages to list per repo. Zero means all of them.
+	MaxLangs int
+
+	// Topics, if set, are what a repo needs one of to be Participating, for
+	// events other than Hacktoberfest
+	Topics []string
+
+	// Created and Updated, unless they're zero, limit the search to issues
+	// opened or last changed on or after that day
+	Created time.Time
+	Updated time.Time
+}
+
+// InRange reports whether i was created and updated late enough for q.
+func (q Query) InRange(i Issue) bool {
+	return !i.Date.Before(q.Created) && !i.Updated.Before(q.Updated)
+}
+
+// Source is somewhere to find issues. GitHub is one.
+type Source interface {
+	// SearchIssues finds open issues with label, feeding them into ch as they
+	// are found. It stops early with ctx.Err() if ctx is done.
+	SearchIssues(ctx context.Context, label string, q Query, ch chan<- Issue) error
+}
+
+// Reserver is a Source with a rate limit to check before searching it.
+// Reserve gives an error if there isn't enough left to search for that many
+// labels.
+type Reserver interface {
+	Reserve(ctx context.Context, labels int) error
+}
+
+// SearchError is a search for one label in one source that didn't finish.
+type SearchError struct {
+	Source string
+	Label  string
+	Err    error
+}
+
+// IncompleteError is what a Search gives back when some searches failed but
+// the issues from the rest were all passed along.
+type IncompleteError struct {
+	Failed []SearchError
+}
+
+func (e *IncompleteError) Error() string {
+	var msgs []string
+	for _, f := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s %q: %v", f.Source, f.Label, f.Err))
+	}
+	return "some searches did not finish: " + strings.Join(msgs, "; ")
+}
+
+// Warning is how a failed search is described to whoever wanted the issues.
+type Warning struct {
+	Source string `json:"source"`
+	Label  string `json:"label"`
+	Error  string `json:"error"`
+}
+
+// Warnings describes each search that failed. It's empty, not nil, if e is
+// nil so it encodes as [].
+func (e *IncompleteError) Warnings() []Warning {
+	ws := []Warning{}
+	if e == nil {
+		return ws
+	}
+	for _, f := range e.Failed {
+		ws = append(ws, Warning{Source: f.Source, Label: f.Label, Error: f.Err.Error()})
+	}
+	return ws
+}
+
+// Search looks for issues in each of its Sources at once, a label at a time.
+// GitHub's search API won't let us search for something label:A OR label:B
+// only label:A AND label:B so we have to make multiple requests.
+type Search struct {
+	Sources []Source
+
+	// Timeout is how long the searches get, all together, unless it's zero
+	Timeout time.Duration
+
+	// Concurrency is the most searches that run at once, at least one
+	Concurrency int
+
+	// Priority says which labels to search for first, when not all of the
+	// searches can run at once. Labels it doesn't mention come last.
+	Priority []string
+
+	// Exclude, if set, says which repos to leave out issues from
+	Exclude func(Repo) bool
+
+	// Name, if set, is how a source is referred to in logs and errors. GitHub
+	// is github and anything else is its type.
+	Name func(Source) string
+
+	// Observer is told how each search goes, nobody if it's nil
+	Observer Observer
+}
+
+// Fetch collects every issue Stream finds for q. The same issue found by
+// more than one search is merged into one, so what we end up with doesn't
+// depend on which search finished first, and copies of issues are marked,
+// see MarkDuplicates. If some searches didn't finish we still give back what
+// the others found, along with the *IncompleteError.
+func (s *Search) Fetch(ctx context.Context, q Query) (issues []Issue, err error) {
+	ctx, sp := observe(s.Observer).StartSpan(ctx, "fetchIssues", false)
+	sp.Set("scope", q.Scope)
+	sp.Set("labels", strings.Join(q.Labels, ","))
+	defer func() { sp.Finish(err) }()
+
+	at := make(map[string]int)
+	issues = []Issue{}
+	err = s.each(ctx, q, func(i Issue) error {
+		if n, ok := at[i.URL]; ok {
+			issues[n] = mergeIssues(issues[n], i)
+			return nil
+		}
+		at[i.URL] = len(issues)
+		issues = append(issues, i)
+		return nil
+	})
+	if _, ok := err.(*IncompleteError); err != nil && !ok {
+		return nil, err
+	}
+	MarkDuplicates(issues)
+	sortIssues(issues)
+	sp.Set("issues", strconv.Itoa(len(issues)))
+	return issues, err
+}
+
+// Stream makes concurrent requests to each of the Sources to get issues with
+// each of q.Labels, limited to the qualifiers in q.Scope. No more than
+// Concurrency run at once, going by Priority.
+//
+// Issues are passed to found as soon as a worker finds them. The same issue can
+// come back from more than one search so we only pass along the first one we
+// see, using the URL field for identity. Which that is comes down to which
+// search gets there first, so Fetch merges them instead. Issues in excluded
+// repos aren't passed along at all. If found returns an error the searches
+// are stopped and that error is returned. If ctx is done we stop and return
+// ctx.Err() no matter what the workers were up to at the time.
+//
+// A search that fails, is still going after Timeout, or isn't started because
+// its source is short of rate limit doesn't stop the others. Once they're all
+// done we return an *IncompleteError saying which of them didn't finish,
+// unless none of them did, in which case that's an error like any other.
+func (s *Search) Stream(ctx context.Context, q Query, found func(Issue) error) error {
+	seen := make(map[string]bool)
+	return s.each(ctx, q, func(i Issue) error {
+		if seen[i.URL] {
+			return nil
+		}
+		seen[i.URL] = true
+		return found(i)
+	})
+}
+
+// each is Stream passing along every copy of an issue found, each with the
+// label of the search that found it in MatchedLabels.
+func (s *Search) each(ctx context.Context, q Query, found func(Issue) error) error {
+	o := observe(s.Observer)
+
+	// main chan where workers send their results
+	ch := make(chan Issue)
+
+	// errors is where workers will report failure. It has to have sufficient
+	// buffer space to prevent deadlocks because we only receive from it once
+	errors := make(chan SearchError, len(s.Sources)*len(q.Labels))
+
+	// cCtx is a new context derived from our own. We use it to signal workers to
+	// stop early in the case of an error, or when they've run out of time.
+	cCtx, cancel := context.WithCancel(ctx)
+	if s.Timeout > 0 {
+		cCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+	}
+	defer cancel()
+
+	var queue []labelSearch
+	for _, src := range s.Sources {
+		// Don't start searches that would run out of rate limit partway
+		if r, ok := src.(Reserver); ok {
+			if err := r.Reserve(cCtx, len(q.Labels)); err != nil {
+				for _, l := range q.Labels {
+					errors <- SearchError{Source: s.name(src), Label: l, Err: err}
+				}
+				continue
+			}
+		}
+		for _, l := range q.Labels {
+			queue = append(queue, labelSearch{src: src, label: l})
+		}
+	}
+
+	// At most Concurrency searches run at once, taking them in order of their
+	// label's priority so the ones that matter most are done first when
+	// searches are slow or short of rate limit
+	sort.SliceStable(queue, func(i, j int) bool {
+		return s.priority(queue[i].label) < s.priority(queue[j].label)
+	})
+	next := make(chan labelSearch, len(queue))
+	for _, l := range queue {
+		next <- l
+	}
+	close(next)
+
+	workers := s.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(queue) {
+		workers = len(queue)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for n := 0; n < workers; n++ {
+		go func() {
+			defer wg.Done()
+			for l := range next {
+				start := time.Now()
+				ctx, sp := o.StartSpan(cCtx, "SearchIssues", false)
+				sp.Set("label", l.label)
+				sp.Set("source", s.name(l.src))
+				err := searchLabel(ctx, l.src, l.label, q, ch)
+				sp.Finish(err)
+				o.Searched(l.label, time.Since(start))
+				if err != nil {
+					errors <- SearchError{Source: s.name(l.src), Label: l.label, Err: err}
+				}
+			}
+		}()
+	}
+
+	// When all searches are done close the channel so we stop trying to read it
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	// failure notes one of the workers failing. If that was because our
+	// caller gave up then report that, since whichever worker noticed first
+	// is down to chance. Otherwise we carry on with what the others find.
+	var failed []SearchError
+	failure := func(f SearchError) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		o.Log(ctx, Warn, "search failed", "source", f.Source, "label", f.Label, "err", f.Err)
+		failed = append(failed, f)
+		return nil
+	}
+
+	for {
+		select {
+
+		// Our caller gave up. The workers see the same thing through cCtx and
+		// will stop on their own.
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case f := <-errors:
+			if err := failure(f); err != nil {
+				return err
+			}
+
+		// Read from ch. If it was closed then we know we're done. If it was open
+		// pass the value on.
+		case i, open := <-ch:
+			if !open {
+				// Every worker has finished but select could have picked this
+				// over failures still waiting to be looked at
+				for len(errors) > 0 {
+					if err := failure(<-errors); err != nil {
+						return err
+					}
+				}
+				switch {
+				case len(failed) == 0:
+					return nil
+				case len(failed) == len(s.Sources)*len(q.Labels):
+					return failed[0].Err
+				}
+				return &IncompleteError{Failed: failed}
+			}
+
+			// select picks at random when more than one case is ready so don't
+			// hand out anything more once our caller has given up
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if s.Exclude != nil && s.Exclude(i.Repo) {
+				continue
+			}
+			if q.Topics != nil {
+				i.Participating = i.Repo.HasTopic(q.Topics)
+			}
+			if err := found(i); err != nil {
+				cancel()
+				return err
+			}
+		}
+	}
+}
+
+// labelSearch is one search for issues with label in src.
+type labelSearch struct {
+	src   Source
+	label string
+}
+
+// priority is where label comes in Priority, lowest first. Labels it doesn't
+// mention come after all of those that it does.
+func (s *Search) priority(label string) int {
+	for n, l := range s.Priority {
+		if strings.EqualFold(l, label) {
+			return n
+		}
+	}
+	return len(s.Priority)
+}
+
+// name is how we refer to src in logs and errors.
+func (s *Search) name(src Source) string {
+	if s.Name != nil {
+		return s.Name(src)
+	}
+	if _, ok := src.(*GitHub); ok {
+		return "github"
+	}
+	return fmt.Sprintf("%T", src)
+}
+
+// searchLabel is src.SearchIssues for label, marking each issue it finds
+// as matching label on its way to ch.
+func searchLabel(ctx context.Context, src Source, label string, q Query, ch chan<- Issue) error {
+	found := make(chan Issue)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range found {
+			i.MatchedLabels = []string{label}
+			select {
+			case <-ctx.Done():
+			case ch <- i:
+			}
+		}
+	}()
+	err := src.SearchIssues(ctx, label, q, found)
+	close(found)
+	<-done
+	return err
+}
+
+// dateFormat is how search qualifiers give days.
+const dateFormat = "2006-01-02"
+
+// SearchQuery is the search for open issues with label in scope, opened on
+// or after created and updated on or after updated unless they're zero.
+func SearchQuery(label, scope string, created, updated time.Time) string {
+	q := fmt.Sprintf(`is:open type:issue label:"%s" %s`, label, scope)
+	if !created.IsZero() {
+		q += " created:>=" + created.Format(dateFormat)
+	}
+	if !updated.IsZero() {
+		q += " updated:>=" + updated.Format(dateFormat)
+	}
+	return q
+}
+
+// NextLink finds the rel="next" URL in a Link header like
+//
+//	<https://api.github.com/search/issues?page=2>; rel="next", <...>; rel="last"
+//
+// It's empty if there isn't one, meaning this is the last page.
+func NextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// Excerpt shortens body to at most n characters, breaking on a space if there
+// is one near the end and marking that it was cut off.
+func Excerpt(body string, n int) string {
+	body = strings.TrimSpace(body)
+	r := []rune(body)
+	if len(r) <= n {
+		return body
+	}
+
+	cut := string(r[:n])
+	if i := strings.LastIndexAny(cut, " \n\t"); i > n/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
@@ -0,0 +1,58 @@
+package aggregator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`<https://api.github.com/search/issues?page=2>; rel="next", <https://api.github.com/search/issues?page=5>; rel="last"`, "https://api.github.com/search/issues?page=2"},
+		{`<https://api.github.com/search/issues?page=1>; rel="first", <https://api.github.com/search/issues?page=4>; rel="prev"`, ""},
+		{"", ""},
+		{"garbage", ""},
+	}
+
+	for i, test := range tests {
+		if got := NextLink(test.header); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		body string
+		n    int
+		want string
+	}{
+		{"Short and sweet", 20, "Short and sweet"},
+		{"  padded\n", 20, "padded"},
+		{"The quick brown fox jumps", 18, "The quick brown…"},
+		{"Supercalifragilistic", 10, "Supercalif…"},
+		{"héllo wörld", 7, "héllo…"},
+	}
+
+	for _, test := range tests {
+		if got := Excerpt(test.body, test.n); got != test.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", test.body, test.n, got, test.want)
+		}
+	}
+}
+
+// fakeSource gives the same issues whatever it's asked for.
+type fakeSource []Issue
+
+func (f fakeSource) SearchIssues(ctx context.Context, label string, q Query, ch chan<- Issue) error {
+	for _, i := range f {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ch <- i:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// The issues API is versioned under /api/v1, where every response is an
+// apiEnvelope and keys are snake_case. The old /api/issues routes still work,
+// giving back the shapes they always have, but say they're deprecated and
+// where to go instead.
+
+// apiV1Prefix is where version 1 of the API lives.
+const apiV1Prefix = "/api/v1"
+
+// apiEnvelope is what every /api/v1 response looks like, whatever's in it.
+type apiEnvelope struct {
+	// Data is what was asked for
+	Data interface{} `json:"data"`
+
+	// Meta says more about Data, like which page of it this is
+	Meta apiMeta `json:"meta"`
+
+	// Errors are what went wrong, like searches that failed so issues could
+	// be missing. It's never null.
+	Errors []searchWarning `json:"errors"`
+}
+
+// apiMeta is what an apiEnvelope says about its data. Page, PerPage and Pages
+// are only given for paged listings.
+type apiMeta struct {
+	Total   int `json:"total"`
+	Page    int `json:"page,omitempty"`
+	PerPage int `json:"per_page,omitempty"`
+	Pages   int `json:"pages,omitempty"`
+
+	// Stale is true if the issues came from the cache because GitHub
+	// couldn't be reached
+	Stale bool `json:"stale"`
+
+	// RateLimit is what we last heard of the GitHub rate limit the issues
+	// were searched with. When it's low issues can come back without
+	// languages, and once it runs out searches fail and the cache is served
+	// instead.
+	RateLimit *aggregator.RateLimit `json:"rate_limit,omitempty"`
+
+	// Warnings are anything else that's missing from the data, like the
+	// languages of repos we couldn't get them for
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// apiV1 reports whether r was made to version 1 of the API, rather than to
+// the unversioned routes it replaced.
+func apiV1(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, apiV1Prefix+"/")
+}
+
+// writeEnvelope writes env out as JSON.
+func writeEnvelope(w http.ResponseWriter, r *http.Request, env apiEnvelope) {
+	if env.Errors == nil {
+		env.Errors = []searchWarning{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// deprecated marks responses from h as coming from an unversioned route,
+// linking to the same route under /api/v1.
+func deprecated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := apiV1Prefix + strings.TrimPrefix(r.URL.Path, "/api")
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		h(w, r)
+	}
+}
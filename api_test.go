@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIV1(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/api/v1/issues", true},
+		{"/api/v1/issues/devict/hacktoberfest", true},
+		{"/api/issues", false},
+		{"/api/v10/issues", false},
+	}
+
+	for _, test := range tests {
+		if got := apiV1(httptest.NewRequest("GET", test.path, nil)); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	h := deprecated(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/api/issues/devict/hacktoberfest?sort=score", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusTeapot)
+	}
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("got Deprecation %q, want true", got)
+	}
+	if got, want := w.Header().Get("Link"), `</api/v1/issues/devict/hacktoberfest>; rel="successor-version"`; got != want {
+		t.Errorf("got Link %q, want %q", got, want)
+	}
+}
+
+func TestWriteEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeEnvelope(w, httptest.NewRequest("GET", "/api/v1/issues", nil), apiEnvelope{
+		Data: []Issue{{Title: "Fix it", Repo: Repo{Owner: "devict", Name: "hacktoberfest"}}},
+		Meta: apiMeta{Total: 1},
+	})
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`"data":[{"title":"Fix it",`,
+		`"repo":{"owner":"devict","name":"hacktoberfest",`,
+		`"meta":{"total":1,"stale":false}`,
+		`"errors":[]`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got %s, want it to contain %s", body, want)
+		}
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q, want application/json", got)
+	}
+}
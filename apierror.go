@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// API handlers say what went wrong as JSON, like
+//
+//	{"code": "github_rate_limited", "message": "...", "details": {"reset": "..."}}
+//
+// so frontends can branch on the code rather than the status or the wording.
+
+// Codes an apiError can have.
+const (
+	codeUnauthenticated  = "unauthenticated"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeInvalidParameter = "invalid_parameter"
+	codeInvalidRequest   = "invalid_request"
+	codeDisabled         = "disabled"
+	codeRateLimited      = "github_rate_limited"
+	codeThrottled        = "too_many_requests"
+	codeUpstream         = "upstream_unavailable"
+	codeDatabase         = "database_error"
+	codeInternal         = "internal_error"
+)
+
+// apiError is the body of an API response that failed. Details, if there are
+// any, depend on the code.
+type apiError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// writeError writes out an apiError with the given status.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(apiError{Code: code, Message: message, Details: details}); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// notLoggedIn is the response to requests that need someone to be logged in.
+func notLoggedIn(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusUnauthorized, codeUnauthenticated, "you are not logged in", nil)
+}
+
+// noGitHub is the response to requests that need a GitHub token from someone
+// who logged in with GitLab and hasn't connected GitHub.
+func noGitHub(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusForbidden, codeForbidden, "this needs you to log in with GitHub", nil)
+}
+
+// notAdmin is the response to requests only admins can make.
+func notAdmin(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusForbidden, codeForbidden, "you are not an admin", nil)
+}
+
+// invalidParameter is the response to a query parameter we couldn't make
+// sense of, with err saying which and why.
+func invalidParameter(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, http.StatusBadRequest, codeInvalidParameter, err.Error(), nil)
+}
+
+// invalidRequest is the response to a request body we couldn't make sense of.
+func invalidRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeError(w, r, http.StatusBadRequest, codeInvalidRequest, message, nil)
+}
+
+// databaseError logs err and responds without saying any more about it.
+func databaseError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r.Context(), err)
+	writeError(w, r, http.StatusInternalServerError, codeDatabase, "Database error", nil)
+}
+
+// upstreamError logs err, which came from calling GitHub or another source,
+// and responds with what kind of failure it was. Running out of rate limit
+// is a 429 saying when it resets, a source we've stopped calling is a 503,
+// and anything else a 502.
+func upstreamError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r.Context(), err)
+
+	switch e := errors.Cause(err).(type) {
+	case *aggregator.RateLimitError:
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter(e.Reset)))
+		writeError(w, r, http.StatusTooManyRequests, codeRateLimited, "GitHub's rate limit has run out", map[string]interface{}{
+			"reset":     e.Reset,
+			"secondary": e.Secondary,
+		})
+	case *breakerOpenError:
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter(e.until)))
+		writeError(w, r, http.StatusServiceUnavailable, codeUpstream, "we've stopped calling "+e.host+" for now after it kept failing", map[string]interface{}{
+			"host":  e.host,
+			"retry": e.until,
+		})
+	default:
+		var details interface{}
+		switch e := e.(type) {
+		case *aggregator.StatusError:
+			details = map[string]interface{}{"status": e.Code}
+		case *incompleteError:
+			details = map[string]interface{}{"searches": e.Warnings()}
+		}
+		if e == context.DeadlineExceeded {
+			details = map[string]interface{}{"timeout": true}
+		}
+		writeError(w, r, http.StatusBadGateway, codeUpstream, "we couldn't get an answer from GitHub", details)
+	}
+}
+
+// internalError logs err and responds without saying any more about it.
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r.Context(), err)
+	writeError(w, r, http.StatusInternalServerError, codeInternal, "something went wrong", nil)
+}
+
+// retryAfter is how many whole seconds there are until t, at least one.
+func retryAfter(t time.Time) int {
+	s := int(time.Until(t).Seconds() + 0.999)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	notLoggedIn(w, httptest.NewRequest("GET", "/api/v1/issues", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q, want application/json", got)
+	}
+	if got, want := w.Body.String(), `{"code":"unauthenticated","message":"you are not logged in"}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpstreamError(t *testing.T) {
+	soon := time.Now().Add(time.Minute)
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		details string
+		retry   bool
+	}{
+		{"rate limit", errors.Wrap(&aggregator.RateLimitError{Reset: soon}, "could not search"), http.StatusTooManyRequests, codeRateLimited, `{"reset":"` + soon.Format(time.RFC3339Nano) + `","secondary":false}`, true},
+		{"breaker", &breakerOpenError{host: config.GitHubURL, until: soon}, http.StatusServiceUnavailable, codeUpstream, `{"host":"` + config.GitHubURL + `","retry":"` + soon.Format(time.RFC3339Nano) + `"}`, true},
+		{"status", errors.Wrap(&aggregator.StatusError{Code: 502}, "could not search"), http.StatusBadGateway, codeUpstream, `{"status":502}`, false},
+		{"timeout", context.DeadlineExceeded, http.StatusBadGateway, codeUpstream, `{"timeout":true}`, false},
+		{"other", errors.New("nope"), http.StatusBadGateway, codeUpstream, ``, false},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		upstreamError(w, httptest.NewRequest("GET", "/api/v1/issues", nil), test.err)
+
+		if w.Code != test.status {
+			t.Errorf("%q: got status %d, want %d", test.name, w.Code, test.status)
+		}
+		var body struct {
+			Code    string
+			Message string
+			Details json.RawMessage
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Errorf("%q: could not decode body: %v", test.name, err)
+			continue
+		}
+		if body.Code != test.code || body.Message == "" {
+			t.Errorf("%q: got code %q and message %q, want code %q and a message", test.name, body.Code, body.Message, test.code)
+		}
+		if string(body.Details) != test.details {
+			t.Errorf("%q: got details %s, want %s", test.name, body.Details, test.details)
+		}
+		if got := w.Header().Get("Retry-After") != ""; got != test.retry {
+			t.Errorf("%q: got Retry-After %v, want %v", test.name, got, test.retry)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter(time.Now().Add(-time.Minute)); got != 1 {
+		t.Errorf("got %d for the past, want 1", got)
+	}
+	if got := retryAfter(time.Now().Add(90 * time.Second)); got != 90 {
+		t.Errorf("got %d, want 90", got)
+	}
+}
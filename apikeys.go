@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/markbates/goth"
+	"github.com/pkg/errors"
+)
+
+// Scripts and bots can use the API without logging in through a browser by
+// sending an API key as "Authorization: Bearer hfk_...". People make and
+// revoke their own at /api/me/tokens. A key acts as whoever made it, except
+// that a read key can only make GET requests and only an admin key can use
+// the admin routes, which only admins can make. Each key gets its own rate
+// limit in place of the one for sessions and addresses. Keys are a row each
+// in api_keys, which only keeps a hash of them, so they need a database.
+
+// keyPrefix starts every API key so they can be told apart from GitHub
+// tokens, and spotted if they leak.
+const keyPrefix = "hfk_"
+
+// The scopes a key can have.
+const (
+	scopeRead  = "read"
+	scopeAdmin = "admin"
+)
+
+// maxKeys is the most keys one person can have.
+const maxKeys = 10
+
+// apiKey is one of someone's API keys, as they see it.
+type apiKey struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+
+	// RateLimit is how many issue searches a minute it gets, zero for no
+	// limit
+	RateLimit int `json:"rate_limit"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used"`
+
+	// Key is the key itself, only given back when it's made
+	Key string `json:"key,omitempty"`
+
+	// user is who made it, who it acts as
+	user goth.User
+}
+
+// keyRequest is the body of making a key. Scope is read unless given, and
+// RateLimit config.IssueRate, which is also the most anyone but an admin can
+// ask for.
+type keyRequest struct {
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	RateLimit int    `json:"rate_limit"`
+}
+
+// check fills in the defaults of k and makes sure it's a key someone who is
+// admin or not can have.
+func (k *keyRequest) check(admin bool) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" || len(k.Name) > 100 {
+		return errors.New("name should be between 1 and 100 characters")
+	}
+
+	if k.Scope == "" {
+		k.Scope = scopeRead
+	}
+	switch k.Scope {
+	case scopeRead:
+	case scopeAdmin:
+		if !admin {
+			return errors.New("only admins can make admin keys")
+		}
+	default:
+		return fmt.Errorf("scope %q should be read or admin", k.Scope)
+	}
+
+	if k.RateLimit == 0 {
+		k.RateLimit = config.IssueRate
+	}
+	if k.RateLimit < 0 {
+		return fmt.Errorf("rate limit can't be negative, not %d", k.RateLimit)
+	}
+	if !admin && config.IssueRate > 0 && k.RateLimit > config.IssueRate {
+		return fmt.Errorf("rate limit can be at most %d", config.IssueRate)
+	}
+	return nil
+}
+
+// allows reports whether k can be used for r.
+func (k apiKey) allows(r *http.Request) bool {
+	if k.Scope == scopeAdmin {
+		return true
+	}
+	return r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS"
+}
+
+// hashKey is how key is kept in api_keys.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerKey gives the API key r was sent with, if it was.
+func bearerKey(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+	key := strings.TrimSpace(auth[7:])
+	return key, strings.HasPrefix(key, keyPrefix)
+}
+
+// apiKeyKey is the context key of the API key a request was made with.
+type apiKeyKey struct{}
+
+// usedKey gives the API key r was made with, which apiKeys checked.
+func usedKey(r *http.Request) (apiKey, bool) {
+	k, ok := r.Context().Value(apiKeyKey{}).(apiKey)
+	return k, ok
+}
+
+// apiKeys lets requests with an API key through as whoever made it, turning
+// away ones with a key we don't know or that can't be used for what they're
+// asking.
+func apiKeys(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := bearerKey(r)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if db == nil {
+			writeError(w, r, http.StatusUnauthorized, codeUnauthenticated, "API keys aren't turned on", nil)
+			return
+		}
+
+		k, ok, err := findKey(key, time.Now())
+		if err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, codeUnauthenticated, "that API key isn't valid", nil)
+			return
+		}
+		if !k.allows(r) {
+			writeError(w, r, http.StatusForbidden, codeForbidden, "read keys can only make GET requests", nil)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, k)))
+	})
+}
+
+// findKey gives the key in api_keys that key is, noting it was used at now.
+// ok is false if there isn't one.
+func findKey(key string, now time.Time) (k apiKey, ok bool, err error) {
+	var lastUsed pq.NullTime
+	err = db.QueryRow(
+		`SELECT id, name, scope, rate_limit, created_at, last_used, user_id, username, provider
+		FROM api_keys WHERE hash = $1`,
+		hashKey(key),
+	).Scan(&k.ID, &k.Name, &k.Scope, &k.RateLimit, &k.CreatedAt, &lastUsed, &k.user.UserID, &k.user.NickName, &k.user.Provider)
+	if err == sql.ErrNoRows {
+		return apiKey{}, false, nil
+	}
+	if err != nil {
+		return apiKey{}, false, errors.Wrap(err, "could not query API key")
+	}
+	if lastUsed.Valid {
+		k.LastUsed = &lastUsed.Time
+	}
+
+	// Once a minute is plenty to say when it was last used
+	_, err = db.Exec(
+		"UPDATE api_keys SET last_used = $2 WHERE id = $1 AND (last_used IS NULL OR last_used < $3)",
+		k.ID,
+		now,
+		now.Add(-time.Minute),
+	)
+	return k, true, errors.Wrap(err, "could not save API key use")
+}
+
+// getKeys lists the keys of whoever is logged in, newest first.
+func getKeys(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	rows, err := db.Query(
+		`SELECT id, name, scope, rate_limit, created_at, last_used FROM api_keys
+		WHERE user_id = $1 ORDER BY created_at DESC`,
+		u.UserID,
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	keys := []apiKey{}
+	for rows.Next() {
+		var k apiKey
+		var lastUsed pq.NullTime
+		if err := rows.Scan(&k.ID, &k.Name, &k.Scope, &k.RateLimit, &k.CreatedAt, &lastUsed); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		if lastUsed.Valid {
+			k.LastUsed = &lastUsed.Time
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// createKey makes a key for whoever is logged in. It's the only time the key
+// itself is given back.
+func createKey(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var req keyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+	_, admin := findAdmin(r)
+	if err := req.check(admin); err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM api_keys WHERE user_id = $1", u.UserID).Scan(&n); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	if n >= maxKeys {
+		writeError(w, r, http.StatusConflict, codeConflict, fmt.Sprintf("you can have at most %d keys", maxKeys), nil)
+		return
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if _, err := rand.Read(secret); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	k := apiKey{
+		ID:        hex.EncodeToString(id),
+		Name:      req.Name,
+		Scope:     req.Scope,
+		RateLimit: req.RateLimit,
+		CreatedAt: time.Now(),
+		Key:       keyPrefix + base64.RawURLEncoding.EncodeToString(secret),
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO api_keys (id, user_id, username, provider, name, scope, rate_limit, hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		k.ID,
+		u.UserID,
+		u.NickName,
+		u.Provider,
+		k.Name,
+		k.Scope,
+		k.RateLimit,
+		hashKey(k.Key),
+		k.CreatedAt,
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(k); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// revokeKey deletes one of the keys of whoever is logged in, given as /{id}.
+func revokeKey(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	id := r.URL.Query().Get(":id")
+
+	res, err := db.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, u.UserID)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "you don't have a key "+id, nil)
+		return
+	}
+	keyLimiters.forget(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keyLimiters are the rate limiters of the keys that have been used.
+var keyLimiters = &keyLimiterSet{limiters: make(map[string]*rateLimiter)}
+
+// keyLimiterSet is a rateLimiter for each key, each at its own rate, safe to
+// use from more than one goroutine.
+type keyLimiterSet struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+// get gives k's rate limiter, nil if it isn't limited. Keys get bursts of
+// config.IssueBurst like everyone else, but no more than their rate.
+func (s *keyLimiterSet) get(k apiKey) *rateLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.limiters[k.ID]
+	if !ok {
+		burst := config.IssueBurst
+		if burst > k.RateLimit {
+			burst = k.RateLimit
+		}
+		l = newRateLimiter(k.RateLimit, burst)
+		s.limiters[k.ID] = l
+	}
+	return l
+}
+
+// forget drops the rate limiter of the key with id, which has been revoked.
+func (s *keyLimiterSet) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters, id)
+}
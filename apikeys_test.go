@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/markbates/goth"
+)
+
+func TestKeyRequestCheck(t *testing.T) {
+	old := config.IssueRate
+	defer func() { config.IssueRate = old }()
+	config.IssueRate = 60
+
+	k := keyRequest{Name: " bot "}
+	if err := k.check(false); err != nil {
+		t.Fatal(err)
+	}
+	if k.Name != "bot" || k.Scope != scopeRead || k.RateLimit != 60 {
+		t.Errorf("got %+v, want a read key at the usual rate", k)
+	}
+
+	k = keyRequest{Name: "admin bot", Scope: scopeAdmin, RateLimit: 600}
+	if err := k.check(true); err != nil {
+		t.Errorf("admins should be able to make fast admin keys, got %v", err)
+	}
+
+	invalid := []keyRequest{
+		{},
+		{Name: "bot", Scope: "write"},
+		{Name: "bot", Scope: scopeAdmin},
+		{Name: "bot", RateLimit: -1},
+		{Name: "bot", RateLimit: 61},
+	}
+	for _, k := range invalid {
+		if err := k.check(false); err == nil {
+			t.Errorf("%+v should be invalid", k)
+		}
+	}
+}
+
+func TestBearerKey(t *testing.T) {
+	tests := []struct {
+		auth string
+		key  string
+		ok   bool
+	}{
+		{"", "", false},
+		{"Bearer hfk_abc", "hfk_abc", true},
+		{"bearer  hfk_abc ", "hfk_abc", true},
+		{"Bearer ghp_abc", "ghp_abc", false},
+		{"Basic hfk_abc", "", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/v1/issues", nil)
+		r.Header.Set("Authorization", test.auth)
+		if key, ok := bearerKey(r); ok != test.ok || (ok && key != test.key) {
+			t.Errorf("%q: got %q %v, want %q %v", test.auth, key, ok, test.key, test.ok)
+		}
+	}
+}
+
+func TestAPIKeysWithoutDatabase(t *testing.T) {
+	h := apiKeys(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("GET", "/api/v1/issues", nil)
+	r.Header.Set("Authorization", "Bearer hfk_abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d for a key with no database, want 401", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/api/v1/issues", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d without a key, want it let through", w.Code)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	oldAdmins := admins
+	defer func() { admins = oldAdmins }()
+	admins = map[string]bool{"octocat": true}
+	defer func() { keyLimiters = &keyLimiterSet{limiters: make(map[string]*rateLimiter)} }()
+
+	with := func(method string, k apiKey) *http.Request {
+		r := httptest.NewRequest(method, "/api/v1/issues", nil)
+		return r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, k))
+	}
+	read := apiKey{ID: "1", Scope: scopeRead, RateLimit: 1, user: goth.User{UserID: "42", NickName: "octocat", Provider: "github"}}
+
+	if u, _, ok := findUser(with("GET", read)); !ok || u.UserID != "42" {
+		t.Errorf("got %+v %v, want the key's owner", u, ok)
+	}
+	if _, ok := findAdmin(with("GET", read)); ok {
+		t.Error("read keys shouldn't get into admin routes")
+	}
+	admin := read
+	admin.Scope = scopeAdmin
+	if _, ok := findAdmin(with("GET", admin)); !ok {
+		t.Error("admin keys of admins should get into admin routes")
+	}
+	if read.allows(with("POST", read)) || !admin.allows(with("POST", admin)) {
+		t.Error("only admin keys should be able to make changes")
+	}
+
+	// Each key has its own limit, whatever the address
+	h := limited(func(w http.ResponseWriter, r *http.Request) {})
+	w := httptest.NewRecorder()
+	h(w, with("GET", read))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d for the first request", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h(w, with("GET", read))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want 429 once the key's limit is used", w.Code)
+	}
+	other := read
+	other.ID = "2"
+	w = httptest.NewRecorder()
+	h(w, with("GET", other))
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want other keys to have their own limit", w.Code)
+	}
+}
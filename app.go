@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// app is our GitHub App installation, if one is configured. When it is every
+// search is made with its tokens and people's own tokens are only used to
+// find out who they are.
+var app *appTokens
+
+// appTokens mints installation tokens for a GitHub App. Each one lasts an
+// hour and has its own rate limit that doesn't depend on who's logged in.
+// It is safe for concurrent use.
+type appTokens struct {
+	id             string
+	installationID string
+	key            *rsa.PrivateKey
+
+	// BaseURL is the root of the REST API, like Client.BaseURL
+	BaseURL string
+	HTTP    *http.Client
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+}
+
+// loadApp reads the GitHub App config has, if it has one. validate has already
+// checked all of its settings are there.
+func loadApp() (*appTokens, error) {
+	if config.GitHubAppID == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(config.GitHubAppKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read app private key")
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, _ := githubAPI(config.GitHubURL)
+	return newAppTokens(config.GitHubAppID, config.GitHubAppInstallationID, key, rest), nil
+}
+
+func newAppTokens(id, installationID string, key *rsa.PrivateKey, baseURL string) *appTokens {
+	return &appTokens{
+		id:             id,
+		installationID: installationID,
+		key:            key,
+		BaseURL:        baseURL,
+		HTTP:           upstream,
+	}
+}
+
+// parsePrivateKey reads an RSA key in PEM, either PKCS #1 like GitHub gives
+// out or PKCS #8.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("app private key is not PEM")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse app private key")
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("app private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Token gives an installation token good for a while yet, minting a new one
+// if the last is close to expiring.
+func (a *appTokens) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Renew early so a token never runs out partway through a search
+	if a.current != "" && time.Until(a.expires) > 5*time.Minute {
+		return a.current, nil
+	}
+
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", a.BaseURL+"/app/installations/"+a.installationID+"/access_tokens", nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not build request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := a.HTTP.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "could not execute request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.Errorf("could not mint installation token, status was %d", resp.StatusCode)
+	}
+
+	var data struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", errors.Wrap(err, "could not decode json")
+	}
+
+	a.current, a.expires = data.Token, data.ExpiresAt
+	return a.current, nil
+}
+
+// jwt is the short lived token that proves we're the app, used to ask for
+// installation tokens. It's backdated a minute in case our clock is ahead of
+// GitHub's, and GitHub won't take one that lasts more than ten.
+func (a *appTokens) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, "could not encode jwt header")
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.id,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "could not encode jwt claims")
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", errors.Wrap(err, "could not sign jwt")
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var minted int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/app/installations/42/access_tokens" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+
+		// Check the JWT is signed by our key and says who we are
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			t.Fatalf("got malformed jwt %q", r.Header.Get("Authorization"))
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			t.Errorf("jwt signature should verify, got %v", err)
+		}
+		var claims struct {
+			Iss string `json:"iss"`
+			Iat int64  `json:"iat"`
+			Exp int64  `json:"exp"`
+		}
+		data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(data, &claims)
+		if claims.Iss != "1234" || claims.Exp-claims.Iat > 600 {
+			t.Errorf("got claims %+v", claims)
+		}
+
+		minted++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, minted, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	a := newAppTokens("1234", "42", key, srv.URL)
+	for i := 0; i < 3; i++ {
+		token, err := a.Token(context.Background())
+		if err != nil {
+			t.Fatalf("%d: error should be nil, got %v", i, err)
+		}
+		if token != "ghs_1" {
+			t.Errorf("%d: got %q, want ghs_1", i, token)
+		}
+	}
+
+	// Close to expiring gets a new one
+	a.expires = time.Now().Add(time.Minute)
+	if token, _ := a.Token(context.Background()); token != "ghs_2" {
+		t.Errorf("got %q, want ghs_2", token)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, data := range map[string][]byte{"pkcs1": pkcs1, "pkcs8": pkcs8} {
+		got, err := parsePrivateKey(data)
+		if err != nil {
+			t.Errorf("%s: error should be nil, got %v", name, err)
+		} else if got.N.Cmp(key.N) != 0 {
+			t.Errorf("%s: got a different key", name)
+		}
+	}
+
+	if _, err := parsePrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("error should not be nil, but it was")
+	}
+}
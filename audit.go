@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// With more than one admin it's worth knowing who changed what. Changes to
+// what's tracked, decisions on submissions, imports and issue claims each add
+// a row to audit_log, which nothing changes or deletes, and admins can look
+// back through it at /api/admin/audit.
+
+// maxAuditEntries is the most entries one look at the audit log gives.
+const maxAuditEntries = 200
+
+// auditEntry is one thing someone did.
+type auditEntry struct {
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Target string    `json:"target"`
+	At     time.Time `json:"at"`
+}
+
+// trackedAction is the audit action for c, like org.added or
+// excluded.removed.
+func trackedAction(c trackedChange) string {
+	if c.Active {
+		return c.Kind + ".added"
+	}
+	return c.Kind + ".removed"
+}
+
+// audit adds what whoever r is from did to the audit log. What they did has
+// already happened by now, so failing to note it is logged rather than
+// failing the request.
+func audit(r *http.Request, action, target string) {
+	_, err := db.Exec(
+		"INSERT INTO audit_log (actor, action, target, at) VALUES ($1, $2, $3, $4)",
+		currentUser(r).NickName,
+		action,
+		target,
+		time.Now(),
+	)
+	if err != nil {
+		logError(r.Context(), errors.Wrap(err, "could not add to audit log"), "action", action, "target", target)
+	}
+}
+
+// auditQuery is which entries of the audit log to look at.
+type auditQuery struct {
+	actor  string
+	action string
+	before time.Time
+}
+
+// parseAuditQuery builds an auditQuery from the actor, action and before
+// query parameters. Without before it's the newest entries, and before is
+// the at of the last one seen to go further back.
+func parseAuditQuery(r *http.Request) (auditQuery, error) {
+	q := r.URL.Query()
+	a := auditQuery{actor: strings.TrimSpace(q.Get("actor")), action: strings.TrimSpace(q.Get("action"))}
+	if v := q.Get("before"); v != "" {
+		before, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return auditQuery{}, fmt.Errorf("before %q should be a time like 2017-10-20T15:04:05Z", v)
+		}
+		a.before = before
+	}
+	return a, nil
+}
+
+// sql gives the query for the entries a is for, newest first, and its
+// arguments.
+func (a auditQuery) sql() (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if a.actor != "" {
+		add("lower(actor) = lower($%d)", a.actor)
+	}
+	if a.action != "" {
+		add("action = $%d", a.action)
+	}
+	if !a.before.IsZero() {
+		add("at < $%d", a.before)
+	}
+
+	q := "SELECT actor, action, target, at FROM audit_log"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, maxAuditEntries)
+	return q + fmt.Sprintf(" ORDER BY at DESC, id DESC LIMIT $%d", len(args)), args
+}
+
+// adminAudit gives the newest entries of the audit log, only those by actor
+// or of action if they're given.
+func adminAudit(w http.ResponseWriter, r *http.Request) {
+	a, err := parseAuditQuery(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	q, args := a.sql()
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	entries := []auditEntry{}
+	for rows.Next() {
+		var e auditEntry
+		if err := rows.Scan(&e.Actor, &e.Action, &e.Target, &e.At); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		logError(r.Context(), err)
+	}
+}
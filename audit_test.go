@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrackedAction(t *testing.T) {
+	if got := trackedAction(trackedChange{Kind: "org", Name: "devict", Active: true}); got != "org.added" {
+		t.Errorf("got %q, want org.added", got)
+	}
+	if got := trackedAction(trackedChange{Kind: "excluded", Name: "spammer"}); got != "excluded.removed" {
+		t.Errorf("got %q, want excluded.removed", got)
+	}
+}
+
+func TestAuditQuery(t *testing.T) {
+	a, err := parseAuditQuery(httptest.NewRequest("GET", "/api/admin/audit", nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	q, args := a.sql()
+	if want := "SELECT actor, action, target, at FROM audit_log ORDER BY at DESC, id DESC LIMIT $1"; q != want {
+		t.Errorf("got %q, want %q", q, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{maxAuditEntries}) {
+		t.Errorf("got args %v", args)
+	}
+
+	a, err = parseAuditQuery(httptest.NewRequest("GET", "/api/admin/audit?actor=Octocat&action=claim.made&before=2017-10-20T15:04:05.5Z", nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	q, args = a.sql()
+	if want := "SELECT actor, action, target, at FROM audit_log WHERE lower(actor) = lower($1) AND action = $2 AND at < $3 ORDER BY at DESC, id DESC LIMIT $4"; q != want {
+		t.Errorf("got %q, want %q", q, want)
+	}
+	before := time.Date(2017, 10, 20, 15, 4, 5, 5e8, time.UTC)
+	if want := []interface{}{"Octocat", "claim.made", before, maxAuditEntries}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+
+	if _, err := parseAuditQuery(httptest.NewRequest("GET", "/api/admin/audit?before=yesterday", nil)); err == nil {
+		t.Error("before=yesterday should be an error")
+	}
+}
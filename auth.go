@@ -1,16 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/markbates/goth"
-	"github.com/markbates/goth/gothic"
 	"github.com/markbates/goth/providers/github"
 	"github.com/pkg/errors"
 )
@@ -18,43 +16,117 @@ import (
 var sess sessions.Store
 
 func init() {
-	sess = sessions.NewCookieStore(
-		[]byte(os.Getenv("SESSION_SECRET")),
-	)
+	setupAuth(config)
+}
 
-	gothic.Store = sess
+// setupAuth makes the session store and the logins for c: GitHub, and GitLab
+// if it has an OAuth application.
+func setupAuth(c Config) {
+	sess = newSessionStore(c)
+	// validate has already checked the key
+	tokenCipher, _ = newTokenCipher(c.TokenKey)
 
-	goth.UseProviders(
-		github.New(
-			os.Getenv("GITHUB_KEY"),
-			os.Getenv("GITHUB_SECRET"),
-			os.Getenv("GITHUB_CALLBACK")+"/auth/github/callback",
-			"user:email",
-		),
+	// These are goth's defaults unless GITHUB_URL points at Enterprise
+	api, _ := githubAPI(c.GitHubURL)
+	gh := github.NewCustomisedURL(
+		c.ClientID,
+		c.ClientSecret,
+		c.SiteURL+"/auth/github/callback",
+		c.GitHubURL+"/login/oauth/authorize",
+		c.GitHubURL+"/login/oauth/access_token",
+		api+"/user",
+		api+"/user/emails",
+		loginScopes()...,
 	)
+	loginProviders = map[string]*loginProvider{
+		"github": {
+			name:         "github",
+			title:        "GitHub",
+			clientID:     c.ClientID,
+			clientSecret: c.ClientSecret,
+			authURL:      c.GitHubURL + "/login/oauth/authorize",
+			tokenURL:     c.GitHubURL + "/login/oauth/access_token",
+			redirectURL:  c.SiteURL + "/auth/github/callback",
+			scopes:       loginScopes(),
+			granted: func(ctx context.Context, t tokenResponse) ([]string, error) {
+				return grantedScopes(ctx, c, t.AccessToken)
+			},
+			user: func(ctx context.Context, token string) (goth.User, error) {
+				return gh.FetchUser(&github.Session{AccessToken: token})
+			},
+			revoke: func(ctx context.Context, token string) error {
+				return revokeToken(ctx, c, token)
+			},
+		},
+	}
+	if c.GitLabClientID != "" {
+		loginProviders["gitlab"] = gitlabLogin(c)
+	}
 }
 
+// loginScopes are the OAuth scopes we ask for when people log in, only what
+// the features that are turned on need. Searching public repos needs none, so
+// a token from a plain login can't do anything they couldn't do logged out.
+// Digests need their email address even if it's private, and claim comments
+// need to comment as them.
+func loginScopes() []string {
+	scopes := []string{}
+	if digestsEnabled() {
+		scopes = append(scopes, "user:email")
+	}
+	if config.ClaimComments {
+		scopes = append(scopes, "public_repo")
+	}
+	return scopes
+}
+
+// authCallback logs people in with the provider they've come back from.
+// Someone who's already logged in with a different one connects it instead,
+// keeping who they are and adding its token to their session.
 func authCallback(w http.ResponseWriter, r *http.Request) {
-	user, err := gothic.CompleteUserAuth(w, r)
+	user, err := completeAuth(w, r)
 	if err != nil {
-		fmt.Fprintln(w, err)
+		loginFailed(w, r, err)
 		return
 	}
 
 	// Throw this away because it makes cookies too large
 	user.RawData = make(map[string]interface{})
+	token := user.AccessToken
+	user.AccessToken = ""
+
+	s, _ := sess.Get(r, sessionName)
+	owner := user
+	current, _, _, loggedIn := sessionUser(r)
+	connecting := loggedIn && current.Provider != user.Provider
+	if connecting {
+		owner = current
+	} else {
+		s.Values = make(map[interface{}]interface{})
+		if err := startSession(s, time.Now()); err != nil {
+			logError(r.Context(), err)
+			http.Error(w, "could not start session", http.StatusInternalServerError)
+			return
+		}
+		s.Values["user"] = user
+		s.Values["new"], err = saveUser(user)
+		if err != nil {
+			logError(r.Context(), err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+	}
 
-	s, _ := sess.Get(r, "session")
-	s.Values["user"] = user
-	s.Values["new"], err = saveUser(user)
+	sealed, err := sealToken(tokenOwner(owner, user.Provider), token)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "database error", http.StatusInternalServerError)
+		logError(r.Context(), err)
+		http.Error(w, "could not start session", http.StatusInternalServerError)
 		return
 	}
+	s.Values[tokenName(user.Provider)] = sealed
 
 	if err := s.Save(r, w); err != nil {
-		log.Println(err)
+		logError(r.Context(), err)
 		http.Error(w, "could not save cookie", http.StatusInternalServerError)
 		return
 	}
@@ -62,6 +134,42 @@ func authCallback(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/profile", http.StatusTemporaryRedirect)
 }
 
+// tokenName is the session value provider's token is kept in.
+func tokenName(provider string) string {
+	return "token:" + provider
+}
+
+// tokenOwner is what u's token from provider is sealed for, so it can't be
+// moved to someone else's session or passed off as another provider's.
+func tokenOwner(u goth.User, provider string) string {
+	return u.UserID + "/" + provider
+}
+
+// loginFailed shows a page saying why someone couldn't log in, with a link
+// to try again.
+func loginFailed(w http.ResponseWriter, r *http.Request, err error) {
+	name, title := "github", "GitHub"
+	if p, ok := loginProviderOf(r); ok {
+		name, title = p.name, p.title
+	}
+
+	status, message := http.StatusBadGateway, "We couldn't log you in with "+title+". It might be having trouble, so try again in a bit."
+	switch e := err.(type) {
+	case *scopeError:
+		status, message = http.StatusForbidden, title+" didn't give us the "+strings.Join(e.missing, ", ")+" access we asked for, so you're not logged in."
+	default:
+		switch err {
+		case errLoginDeclined:
+			status, message = http.StatusForbidden, "You didn't authorize us on "+title+", so you're not logged in. You can still browse issues without logging in."
+		case errLoginState:
+			status, message = http.StatusBadRequest, "That login link has expired or wasn't one we started."
+		default:
+			logError(r.Context(), errors.Wrap(err, "could not log in"))
+		}
+	}
+	v.HTML(w, status, "login", struct{ Message, Provider string }{message, name})
+}
+
 func saveUser(u goth.User) (bool, error) {
 	var found int
 	err := db.QueryRow("SELECT id FROM users WHERE id = $1", u.UserID).Scan(&found)
@@ -102,22 +210,63 @@ func saveUser(u goth.User) (bool, error) {
 	return false, nil
 }
 
+// findUser gives who's logged in, whether they've only just signed up, and
+// whether anyone is. Sessions that have expired or logged out are no one, and
+// so are ones whose tokens won't open with the TokenKey. Their AccessToken is
+// their GitHub token, which is empty if they logged in with GitLab and haven't
+// connected GitHub. Requests made with an API key are whoever made it, with
+// no tokens.
 func findUser(r *http.Request) (goth.User, bool, bool) {
-	s, err := sess.Get(r, "session")
-	if err != nil {
-		return goth.User{}, false, false
+	if k, ok := usedKey(r); ok {
+		return k.user, false, true
 	}
+	u, n, tokens, ok := sessionUser(r)
+	u.AccessToken = tokens["github"]
+	return u, n, ok
+}
 
-	val, ok := s.Values["user"]
-	if !ok {
-		return goth.User{}, false, false
+// userTokens gives the tokens of whoever's logged in by provider, like
+// "gitlab", or none if nobody is.
+func userTokens(r *http.Request) map[string]string {
+	if _, ok := usedKey(r); ok {
+		return nil
+	}
+	_, _, tokens, _ := sessionUser(r)
+	return tokens
+}
+
+// sessionUser is findUser with all of their tokens.
+func sessionUser(r *http.Request) (goth.User, bool, map[string]string, bool) {
+	s, err := sess.Get(r, sessionName)
+	if err != nil || !sessionValid(s, time.Now()) {
+		return goth.User{}, false, nil, false
 	}
 
-	n, ok := s.Values["new"].(bool)
+	u, ok := s.Values["user"].(goth.User)
 	if !ok {
-		n = false
+		return goth.User{}, false, nil, false
 	}
+	n, _ := s.Values["new"].(bool)
 
-	u, ok := val.(goth.User)
-	return u, n, ok
+	// Sessions from before there was more than one provider keep their
+	// GitHub token on the user
+	tokens := make(map[string]string)
+	if u.AccessToken != "" {
+		if tokens["github"], err = openToken(u.UserID, u.AccessToken); err != nil {
+			logWarn(r.Context(), "could not open session's token", "err", err)
+			return goth.User{}, false, nil, false
+		}
+		u.AccessToken = ""
+	}
+	for name := range loginProviders {
+		sealed, _ := s.Values[tokenName(name)].(string)
+		if sealed == "" {
+			continue
+		}
+		if tokens[name], err = openToken(tokenOwner(u, name), sealed); err != nil {
+			logWarn(r.Context(), "could not open session's token", "provider", name, "err", err)
+			return goth.User{}, false, nil, false
+		}
+	}
+	return u, n, tokens, true
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Admins can export what only lives in the database and can't be searched for
+// again, which is the changes admins made to what's tracked, people's
+// preferences, bookmarks and claims, as one JSON archive. Importing it into
+// another instance, or the same one after losing data, puts it all back.
+// Issues, snapshots and the rest are left out since refreshing rebuilds them.
+
+// archiveVersion is the version of archive exports make, and the only one
+// imports take.
+const archiveVersion = 1
+
+// maxArchive is the biggest archive imports take. It's well over what even a
+// busy instance exports, so anything bigger isn't one of ours.
+const maxArchive = 32 << 20
+
+// archive is everything an export has in it.
+type archive struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+
+	Tracked     []archivedChange      `json:"tracked"`
+	Preferences []archivedPreferences `json:"preferences"`
+	Bookmarks   []archivedBookmark    `json:"bookmarks"`
+	Claims      []archivedClaim       `json:"claims"`
+}
+
+// archivedChange is a row of tracked: an org, project or exclusion an admin
+// added or removed.
+type archivedChange struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// archivedPreferences are someone's preferences.
+type archivedPreferences struct {
+	UserID      string      `json:"user_id"`
+	Preferences preferences `json:"preferences"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// archivedBookmark is an issue someone bookmarked.
+type archivedBookmark struct {
+	UserID    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// archivedClaim is an issue someone claimed, whether or not it's run out.
+type archivedClaim struct {
+	URL       string    `json:"url"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// check makes sure a is an archive we can import, tidying up the
+// preferences in it.
+func (a *archive) check() error {
+	if a.Version != archiveVersion {
+		return fmt.Errorf("archive is version %d, we can only import version %d", a.Version, archiveVersion)
+	}
+	for _, c := range a.Tracked {
+		if c.Kind != "org" && c.Kind != "project" && c.Kind != "excluded" {
+			return fmt.Errorf("tracked %q is a %q, not an org, project or excluded", c.Name, c.Kind)
+		}
+		if c.Name == "" {
+			return errors.New("tracked changes need a name")
+		}
+	}
+	for n := range a.Preferences {
+		p := &a.Preferences[n]
+		if p.UserID == "" {
+			return errors.New("preferences need a user_id")
+		}
+		if err := p.Preferences.check(); err != nil {
+			return errors.Wrapf(err, "preferences of %s", p.UserID)
+		}
+	}
+	for _, b := range a.Bookmarks {
+		if b.UserID == "" || b.URL == "" {
+			return errors.New("bookmarks need a user_id and url")
+		}
+	}
+	for _, c := range a.Claims {
+		if c.UserID == "" || c.URL == "" {
+			return errors.New("claims need a user_id and url")
+		}
+	}
+	return nil
+}
+
+// exportArchive gives everything there is to export as of now.
+func exportArchive(ctx context.Context, now time.Time) (archive, error) {
+	a := archive{
+		Version:     archiveVersion,
+		ExportedAt:  now,
+		Tracked:     []archivedChange{},
+		Preferences: []archivedPreferences{},
+		Bookmarks:   []archivedBookmark{},
+		Claims:      []archivedClaim{},
+	}
+
+	// Everything is read in one transaction so the archive is all from the
+	// same moment
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return archive{}, errors.Wrap(err, "could not begin transaction")
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT kind, name, active, updated_by, updated_at FROM tracked ORDER BY updated_at")
+	if err != nil {
+		return archive{}, errors.Wrap(err, "could not query tracked changes")
+	}
+	for rows.Next() {
+		var c archivedChange
+		if err := rows.Scan(&c.Kind, &c.Name, &c.Active, &c.UpdatedBy, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return archive{}, errors.Wrap(err, "could not scan tracked change")
+		}
+		a.Tracked = append(a.Tracked, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return archive{}, errors.Wrap(err, "could not iterate over tracked changes")
+	}
+
+	rows, err = tx.Query("SELECT user_id, data, updated_at FROM preferences ORDER BY user_id")
+	if err != nil {
+		return archive{}, errors.Wrap(err, "could not query preferences")
+	}
+	for rows.Next() {
+		var p archivedPreferences
+		var data []byte
+		if err := rows.Scan(&p.UserID, &data, &p.UpdatedAt); err != nil {
+			rows.Close()
+			return archive{}, errors.Wrap(err, "could not scan preferences")
+		}
+		if err := json.Unmarshal(data, &p.Preferences); err != nil {
+			rows.Close()
+			return archive{}, errors.Wrap(err, "could not decode preferences")
+		}
+		a.Preferences = append(a.Preferences, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return archive{}, errors.Wrap(err, "could not iterate over preferences")
+	}
+
+	rows, err = tx.Query("SELECT user_id, url, created_at FROM bookmarks ORDER BY user_id, created_at")
+	if err != nil {
+		return archive{}, errors.Wrap(err, "could not query bookmarks")
+	}
+	for rows.Next() {
+		var b archivedBookmark
+		if err := rows.Scan(&b.UserID, &b.URL, &b.CreatedAt); err != nil {
+			rows.Close()
+			return archive{}, errors.Wrap(err, "could not scan bookmark")
+		}
+		a.Bookmarks = append(a.Bookmarks, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return archive{}, errors.Wrap(err, "could not iterate over bookmarks")
+	}
+
+	rows, err = tx.Query("SELECT url, user_id, username, claimed_at, expires_at FROM claims ORDER BY claimed_at")
+	if err != nil {
+		return archive{}, errors.Wrap(err, "could not query claims")
+	}
+	for rows.Next() {
+		var c archivedClaim
+		if err := rows.Scan(&c.URL, &c.UserID, &c.Username, &c.ClaimedAt, &c.ExpiresAt); err != nil {
+			rows.Close()
+			return archive{}, errors.Wrap(err, "could not scan claim")
+		}
+		a.Claims = append(a.Claims, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return archive{}, errors.Wrap(err, "could not iterate over claims")
+	}
+
+	return a, nil
+}
+
+// importArchive puts everything in a into the database, in one transaction
+// so a bad archive changes nothing. What's already there is only replaced by
+// what's newer in a: tracked changes made later, preferences saved later and
+// claims that run out later.
+func importArchive(a archive) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer tx.Rollback()
+
+	for _, c := range a.Tracked {
+		_, err := tx.Exec(
+			`INSERT INTO tracked (kind, name, active, updated_by, updated_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (kind, name) DO UPDATE SET active = $3, updated_by = $4, updated_at = $5
+			WHERE tracked.updated_at < $5`,
+			c.Kind,
+			c.Name,
+			c.Active,
+			c.UpdatedBy,
+			c.UpdatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not import tracked change")
+		}
+	}
+
+	for _, p := range a.Preferences {
+		data, err := json.Marshal(p.Preferences)
+		if err != nil {
+			return errors.Wrap(err, "could not encode preferences")
+		}
+		_, err = tx.Exec(
+			`INSERT INTO preferences (user_id, data, updated_at) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id) DO UPDATE SET data = $2, updated_at = $3
+			WHERE preferences.updated_at < $3`,
+			p.UserID,
+			data,
+			p.UpdatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not import preferences")
+		}
+	}
+
+	for _, b := range a.Bookmarks {
+		_, err := tx.Exec(
+			`INSERT INTO bookmarks (user_id, url, created_at) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, url) DO NOTHING`,
+			b.UserID,
+			b.URL,
+			b.CreatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not import bookmark")
+		}
+	}
+
+	for _, c := range a.Claims {
+		_, err := tx.Exec(
+			`INSERT INTO claims (url, user_id, username, claimed_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (url) DO UPDATE SET user_id = $2, username = $3, claimed_at = $4, expires_at = $5
+			WHERE claims.expires_at < $5`,
+			c.URL,
+			c.UserID,
+			c.Username,
+			c.ClaimedAt,
+			c.ExpiresAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not import claim")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit import")
+}
+
+// adminExport gives an archive of everything there is to export.
+func adminExport(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	a, err := exportArchive(r.Context(), now)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="hacktoberfest-`+now.UTC().Format("2006-01-02")+`.json"`)
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// adminImport imports an archive adminExport gave, then puts the tracked
+// changes in it into effect.
+func adminImport(w http.ResponseWriter, r *http.Request) {
+	var a archive
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchive)
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		invalidRequest(w, r, "invalid request, body should be an export of at most 32MB")
+		return
+	}
+	if err := a.check(); err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	if err := importArchive(a); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	t, err := buildTracking()
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	setTracking(t)
+
+	audit(r, "archive.imported", fmt.Sprintf("%d tracked, %d preferences, %d bookmarks, %d claims", len(a.Tracked), len(a.Preferences), len(a.Bookmarks), len(a.Claims)))
+	logInfo(r.Context(), "imported archive",
+		"by", currentUser(r).NickName,
+		"tracked", len(a.Tracked),
+		"preferences", len(a.Preferences),
+		"bookmarks", len(a.Bookmarks),
+		"claims", len(a.Claims),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArchiveCheck(t *testing.T) {
+	a := archive{
+		Version:     archiveVersion,
+		Tracked:     []archivedChange{{Kind: "org", Name: "devict", Active: true}},
+		Preferences: []archivedPreferences{{UserID: "1", Preferences: preferences{Languages: []string{"Go", " Go"}}}},
+		Bookmarks:   []archivedBookmark{{UserID: "1", URL: "https://github.com/devict/hacktoberfest/issues/1"}},
+		Claims:      []archivedClaim{{UserID: "1", URL: "https://github.com/devict/hacktoberfest/issues/1"}},
+	}
+	if err := a.check(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Preferences[0].Preferences.Languages; !reflect.DeepEqual(got, []string{"Go"}) {
+		t.Errorf("got languages %v, want them tidied up", got)
+	}
+
+	invalid := []archive{
+		{Version: 2},
+		{Version: archiveVersion, Tracked: []archivedChange{{Kind: "user", Name: "someone"}}},
+		{Version: archiveVersion, Tracked: []archivedChange{{Kind: "org"}}},
+		{Version: archiveVersion, Preferences: []archivedPreferences{{Preferences: preferences{}}}},
+		{Version: archiveVersion, Preferences: []archivedPreferences{{UserID: "1", Preferences: preferences{Languages: []string{"Go,Rust"}}}}},
+		{Version: archiveVersion, Bookmarks: []archivedBookmark{{UserID: "1"}}},
+		{Version: archiveVersion, Claims: []archivedClaim{{URL: "https://github.com/devict/hacktoberfest/issues/1"}}},
+	}
+	for _, a := range invalid {
+		if err := a.check(); err == nil {
+			t.Errorf("%+v should be invalid", a)
+		}
+	}
+}
+
+func TestAdminImportTooBig(t *testing.T) {
+	body := `{"version": 1, "padding": "` + strings.Repeat("a", maxArchive) + `"}`
+	w := httptest.NewRecorder()
+	adminImport(w, httptest.NewRequest("POST", "/api/admin/import", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "32MB") {
+		t.Errorf("got %d %s for an archive over the limit, want 400", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// People who are logged in can bookmark issues to come back to. A bookmark is
+// a row in bookmarks, keyed by the user's GitHub ID and the issue's URL. What
+// we know about the issue comes out of the issues table, so closed issues
+// stay where they were left.
+
+// getBookmarks lists the issues someone has bookmarked, newest first. Ones we
+// never saved only have their URL.
+func getBookmarks(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	rows, err := db.Query(
+		`SELECT b.url, i.data FROM bookmarks b LEFT JOIN issues i ON i.url = b.url
+		WHERE b.user_id = $1 ORDER BY b.created_at DESC`,
+		u.UserID,
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	issues := []Issue{}
+	for rows.Next() {
+		var url string
+		var data []byte
+		if err := rows.Scan(&url, &data); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+
+		i := Issue{URL: url}
+		if data != nil {
+			if err := json.Unmarshal(data, &i); err != nil {
+				logError(r.Context(), errors.Wrap(err, "could not decode issue"), "url", url)
+			}
+		}
+		i.Bookmarked = true
+		issues = append(issues, i)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(issues); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// addBookmark bookmarks an issue for whoever is logged in. Bookmarking one
+// twice is fine.
+func addBookmark(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	url, err := parseIssueURL(r)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO bookmarks (user_id, url, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, url) DO NOTHING`,
+		u.UserID,
+		url,
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeBookmark takes an issue off whoever is logged in's bookmarks.
+func removeBookmark(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	url, err := parseIssueURL(r)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM bookmarks WHERE user_id = $1 AND url = $2", u.UserID, url); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
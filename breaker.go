@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// breaker is the aggregator.Breaker our clients use. It stops us making
+// requests to a host that keeps failing, or whose rate limit we've used up,
+// so we don't dig the hole any deeper. It opens after threshold failures in a
+// row and stays open for cooldown, or until the rate limit resets. After that
+// requests are let through again and the first failure opens it straight
+// back up. It is safe for concurrent use.
+type breaker struct {
+	host      string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// breakerOpenError is what requests get while a breaker is open.
+type breakerOpenError struct {
+	host  string
+	until time.Time
+}
+
+func (e *breakerOpenError) Error() string {
+	return fmt.Sprintf("not calling %s until %v after repeated failures", e.host, e.until.Format(time.RFC3339))
+}
+
+// githubBreaker is shared by every client that talks to GitHub, since they
+// all suffer when it's down.
+var githubBreaker = newBreaker(config.GitHubURL)
+
+// newBreaker makes a breaker for host with config.BreakerThreshold and
+// config.BreakerCooldown.
+func newBreaker(host string) *breaker {
+	return &breaker{host: host, threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown}
+}
+
+// Allow gives an error if b is open.
+func (b *breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.openUntil) {
+		return &breakerOpenError{host: b.host, until: b.openUntil}
+	}
+	return nil
+}
+
+// Success closes b.
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.setOpen(time.Time{})
+}
+
+// Failure counts a failed request, opening b if there have been enough.
+func (b *breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.setOpen(time.Now().Add(b.cooldown))
+	}
+}
+
+// Trip opens b until the given time, however many failures there have been.
+func (b *breaker) Trip(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = b.threshold
+	if until.After(b.openUntil) {
+		b.setOpen(until)
+	}
+}
+
+func (b *breaker) setOpen(until time.Time) {
+	b.openUntil = until
+	open := 0.0
+	if time.Now().Before(until) {
+		open = 1
+	}
+	breakerOpen.set(labels("host", b.host), open)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := &breaker{host: "test", threshold: 2, cooldown: 20 * time.Millisecond}
+
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Errorf("one failure shouldn't open the breaker, got %v", err)
+	}
+	b.Failure()
+	if err := b.Allow(); err == nil {
+		t.Errorf("two failures should open the breaker")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Errorf("breaker should let requests through after the cooldown, got %v", err)
+	}
+	b.Failure()
+	if err := b.Allow(); err == nil {
+		t.Errorf("a failure after the cooldown should open the breaker again")
+	}
+
+	b.Success()
+	if err := b.Allow(); err != nil {
+		t.Errorf("a success should close the breaker, got %v", err)
+	}
+
+	b.Trip(time.Now().Add(time.Hour))
+	if err := b.Allow(); err == nil {
+		t.Errorf("tripped breaker should be open")
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	var calls int32
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if status == http.StatusForbidden {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := testClient(srv)
+	c.Retries = 0
+	c.Breaker = &breaker{host: "test", threshold: 2, cooldown: time.Hour}
+
+	var data struct{}
+	for i := 0; i < 3; i++ {
+		c.Get(context.Background(), "/failing", nil, &data)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want the breaker to stop the third", calls)
+	}
+	if _, ok := c.Get(context.Background(), "/failing", nil, &data).(*breakerOpenError); !ok {
+		t.Errorf("open breaker should give a *breakerOpenError")
+	}
+
+	// Not found means GitHub is up, it just hasn't got what we asked for
+	status = http.StatusNotFound
+	c.Breaker = &breaker{host: "test", threshold: 1, cooldown: time.Hour}
+	c.Get(context.Background(), "/missing", nil, &data)
+	if err := c.Breaker.Allow(); err != nil {
+		t.Errorf("a 404 shouldn't open the breaker, got %v", err)
+	}
+
+	// Running out of rate limit opens it straight away
+	status = http.StatusForbidden
+	c.Breaker = &breaker{host: "test", threshold: 5, cooldown: time.Millisecond}
+	c.Get(context.Background(), "/limited", nil, &data)
+	if err := c.Breaker.Allow(); err == nil {
+		t.Errorf("running out of rate limit should open the breaker until it resets")
+	}
+}
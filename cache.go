@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultCacheTTL is how long fetched issues are served before we go back to
+// GitHub for fresh ones, unless ISSUE_CACHE_TTL says otherwise.
+const defaultCacheTTL = 5 * time.Minute
+
+// issueCache holds the most recent results of fetchIssues, keyed by
+// searchParams.key, so we don't have to fan out to GitHub on every page load.
+// Entries expire after ttl and are dropped early when the GitHub webhook
+// tells us something changed.
+//
+// With a sharedStore the entries are kept there instead, so every instance
+// serves what any of them fetched.
+type issueCache struct {
+	ttl    time.Duration
+	shared sharedStore
+
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	params  searchParams
+	issues  []Issue
+	fetched time.Time
+
+	// incomplete says which searches failed, if any did
+	incomplete *incompleteError
+
+	// fresh, if it's longer than the ttl, is how long after fetched the
+	// entry is served instead
+	fresh time.Duration
+}
+
+var cache = newIssueCache(config.CacheTTL)
+
+func newIssueCache(ttl time.Duration) *issueCache {
+	return &issueCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// size is how many entries are kept in memory.
+func (c *issueCache) size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// get returns the cached issues for key and whether they were there and
+// still fresh.
+func (c *issueCache) get(key string) ([]Issue, bool) {
+	e, ok := c.entry(key)
+	return e.issues, ok
+}
+
+// last is the entry for key however old it is.
+func (c *issueCache) last(key string) (cacheEntry, bool) {
+	if c.shared != nil {
+		return c.loadShared(key)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+// entry is get with everything we know about the issues.
+func (c *issueCache) entry(key string) (cacheEntry, bool) {
+	e, ok := c.last(key)
+	ttl := c.ttl
+	if e.fresh > ttl {
+		ttl = e.fresh
+	}
+	if !ok || time.Since(e.fetched) > ttl {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// stale is the entry for key if it expired no longer than within ago, which
+// can be served while it's fetched again.
+func (c *issueCache) stale(key string, within time.Duration) (cacheEntry, bool) {
+	if within <= 0 {
+		return cacheEntry{}, false
+	}
+	e, ok := c.last(key)
+	ttl := c.ttl
+	if e.fresh > ttl {
+		ttl = e.fresh
+	}
+	if !ok || time.Since(e.fetched) > ttl+within {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// setIncomplete is set for issues from searches that didn't all finish. They're
+// only kept for incompleteRetry so we try again soon.
+func (c *issueCache) setIncomplete(p searchParams, issues []Issue, err *incompleteError) {
+	c.save(cacheEntry{
+		params:     p,
+		issues:     issues,
+		fetched:    time.Now().Add(incompleteRetry - c.ttl),
+		incomplete: err,
+	})
+}
+
+func (c *issueCache) set(p searchParams, issues []Issue) {
+	c.setFetched(p, issues, time.Now())
+}
+
+// setFetched is set for issues that were fetched a while ago, so they expire
+// when they would have if we'd cached them then.
+func (c *issueCache) setFetched(p searchParams, issues []Issue, fetched time.Time) {
+	c.save(cacheEntry{params: p, issues: issues, fetched: fetched})
+}
+
+// setRefreshed is set for issues the background refresher fetched. They're
+// served until it next comes round, next from now, even when that's past the
+// ttl, since otherwise requests would go to GitHub in between.
+func (c *issueCache) setRefreshed(p searchParams, issues []Issue, next time.Duration) {
+	c.save(cacheEntry{params: p, issues: issues, fetched: time.Now(), fresh: next})
+}
+
+func (c *issueCache) save(e cacheEntry) {
+	if c.shared != nil {
+		c.saveShared(e)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[e.params.key()] = e
+}
+
+// update replaces the issues in every entry with what fn gives back for them.
+// The cached slices are shared with whoever got them before so fn has to
+// return a new one rather than change the one it's given. Entries keep the
+// time they were fetched.
+func (c *issueCache) update(fn func(p searchParams, issues []Issue) []Issue) {
+	if c.shared != nil {
+		// Another instance could be saving one of them as we go, but then
+		// what it saves is newer than what we'd have changed anyway
+		for _, key := range c.sharedKeys() {
+			if e, ok := c.loadShared(strings.TrimPrefix(key, sharedIssuePrefix)); ok {
+				e.issues = fn(e.params, e.issues)
+				c.saveShared(e)
+			}
+		}
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		e.issues = fn(e.params, e.issues)
+		c.entries[key] = e
+	}
+}
+
+// invalidate drops every cached result so the next requests fetch fresh
+// ones. A change in one repo can affect any search that covers it so we
+// don't try to be clever about which entries to keep.
+func (c *issueCache) invalidate() {
+	if c.shared != nil {
+		if err := c.shared.del(c.sharedKeys()...); err != nil {
+			logError(context.Background(), errors.Wrap(err, "could not invalidate shared issues"))
+		}
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
+
+// sharedIssuePrefix starts the keys of cache entries in the sharedStore, and
+// the searchParams.key follows it.
+const sharedIssuePrefix = sharedPrefix + "issues:"
+
+// sharedIssueKeep is how long entries are kept in the sharedStore after
+// they're fetched, long past ttl, so last still has them to fall back on.
+const sharedIssueKeep = 24 * time.Hour
+
+// sharedEntry is a cacheEntry as it's kept in the sharedStore.
+type sharedEntry struct {
+	Scope    string          `json:"scope"`
+	Labels   []string        `json:"labels"`
+	MaxLangs int             `json:"max_langs"`
+	Topics   []string        `json:"topics,omitempty"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+	As       string          `json:"as,omitempty"`
+	Issues   []Issue         `json:"issues"`
+	Fetched  time.Time       `json:"fetched"`
+	Failed   []sharedFailure `json:"failed,omitempty"`
+	Fresh    time.Duration   `json:"fresh,omitempty"`
+}
+
+// sharedFailure is a failedSearch in a sharedEntry. Only the error's message
+// survives the trip.
+type sharedFailure struct {
+	Source string `json:"source"`
+	Label  string `json:"label"`
+	Error  string `json:"error"`
+}
+
+// loadShared is last for the sharedStore. Not being able to reach it is the
+// same as it not having the entry, since we can always search again.
+func (c *issueCache) loadShared(key string) (cacheEntry, bool) {
+	b, ok, err := c.shared.get(sharedIssuePrefix + key)
+	if err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not load shared issues"))
+		return cacheEntry{}, false
+	}
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	var s sharedEntry
+	if err := json.Unmarshal(b, &s); err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not decode shared issues"), "key", key)
+		return cacheEntry{}, false
+	}
+	e := cacheEntry{
+		params: searchParams{
+			scope:    s.Scope,
+			labels:   s.Labels,
+			maxLangs: s.MaxLangs,
+			topics:   s.Topics,
+			created:  s.Created,
+			updated:  s.Updated,
+
+			credentials: s.As,
+		},
+		issues:  s.Issues,
+		fetched: s.Fetched,
+		fresh:   s.Fresh,
+	}
+	if len(s.Failed) > 0 {
+		e.incomplete = &incompleteError{}
+		for _, f := range s.Failed {
+			e.incomplete.Failed = append(e.incomplete.Failed, failedSearch{Source: f.Source, Label: f.Label, Err: errors.New(f.Error)})
+		}
+	}
+	return e, true
+}
+
+// saveShared is save for the sharedStore.
+func (c *issueCache) saveShared(e cacheEntry) {
+	keep := time.Until(e.fetched.Add(sharedIssueKeep))
+	if keep <= 0 {
+		return
+	}
+
+	s := sharedEntry{
+		Scope:    e.params.scope,
+		Labels:   e.params.labels,
+		MaxLangs: e.params.maxLangs,
+		Topics:   e.params.topics,
+		Created:  e.params.created,
+		Updated:  e.params.updated,
+		As:       e.params.credentials,
+		Issues:   e.issues,
+		Fetched:  e.fetched,
+		Fresh:    e.fresh,
+	}
+	if e.incomplete != nil {
+		for _, f := range e.incomplete.Failed {
+			s.Failed = append(s.Failed, sharedFailure{Source: f.Source, Label: f.Label, Error: f.Err.Error()})
+		}
+	}
+	b, err := json.Marshal(s)
+	if err == nil {
+		err = c.shared.set(sharedIssuePrefix+e.params.key(), b, keep)
+	}
+	if err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not save shared issues"))
+	}
+}
+
+// sharedKeys gives the keys of every entry in the sharedStore.
+func (c *issueCache) sharedKeys() []string {
+	keys, err := c.shared.keys(sharedIssuePrefix)
+	if err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not list shared issues"))
+	}
+	return keys
+}
+
+// loadIssues serves issues for p from the cache, or the database, when they're
+// fresh. Otherwise it fetches them from GitHub and saves them in both. Set
+// refresh to skip straight to GitHub. If GitHub fails we serve whatever the
+// database or the cache last had, however old, along with a *staleError.
+// Concurrent identical fetches share one set of requests. The fetch is
+// detached from any one request's context since other callers may be waiting
+// on it, but it's traced as part of ctx's.
+//
+// Within config.ServeStale of the cached issues expiring we don't wait for
+// the fetch at all: they're served straight away, along with a *staleError,
+// while it happens in the background.
+//
+// If only some of the searches worked we give back what they found along with
+// an *incompleteError saying which didn't.
+func loadIssues(ctx context.Context, c *Client, p searchParams, refresh bool) ([]Issue, error) {
+	// Only people spending their own rate limit get to skip the cache
+	if c.Shared {
+		refresh = false
+	}
+
+	p.credentials = c.credentials()
+	key := p.key()
+	if !refresh {
+		if e, ok := cache.entry(key); ok {
+			cacheLookups.add(labels("result", "hit"), 1)
+			if e.incomplete != nil {
+				return e.issues, e.incomplete
+			}
+			return e.issues, nil
+		}
+		if e, ok := cache.stale(key, config.ServeStale); ok {
+			cacheLookups.add(labels("result", "stale"), 1)
+			flights.start(key, func() ([]Issue, error) {
+				return refetchIssues(linkSpan(ctx), c, p, false)
+			})
+			return e.issues, &staleError{fetched: e.fetched, err: errRevalidating}
+		}
+		cacheLookups.add(labels("result", "miss"), 1)
+	}
+
+	return flights.do(key, func() ([]Issue, error) {
+		return refetchIssues(ctx, c, p, refresh)
+	})
+}
+
+// errRevalidating is why issues served while they're fetched again in the
+// background are stale.
+var errRevalidating = errors.New("fetching them again in the background")
+
+// refetchIssues is loadIssues once the cache doesn't have fresh issues for p.
+func refetchIssues(ctx context.Context, c *Client, p searchParams, refresh bool) ([]Issue, error) {
+	key := p.key()
+
+	// Without a database (like in tests) the cache is all we have
+	var stored []Issue
+	var fetched time.Time
+	var haveStored bool
+	if db != nil {
+		var err error
+		stored, fetched, haveStored, err = storedIssues(key)
+		if err != nil {
+			logError(ctx, err)
+		}
+		if haveStored && !refresh && time.Since(fetched) <= cache.ttl {
+			cache.setFetched(p, stored, fetched)
+			return stored, nil
+		}
+	}
+
+	issues, err := fetchIssues(linkSpan(ctx), c.Sources(), p)
+	if e := incomplete(err); e != nil {
+		// Serve what we got but try again soon, and don't keep it
+		cache.setIncomplete(p, issues, e)
+		return issues, e
+	}
+	if err != nil {
+		if haveStored {
+			logWarn(ctx, "could not fetch issues, serving stored ones", "err", err)
+			return stored, &staleError{fetched: fetched, err: err}
+		}
+		if e, ok := cache.last(key); ok {
+			logWarn(ctx, "could not fetch issues, serving cached ones", "err", err)
+			return e.issues, &staleError{fetched: e.fetched, err: err}
+		}
+		return nil, err
+	}
+
+	cache.set(p, issues)
+	if db != nil {
+		if err := saveIssues(key, issues); err != nil {
+			logError(ctx, err)
+		}
+	}
+	return issues, nil
+}
+
+// staleError is given along with issues that were fetched a while ago,
+// because we couldn't fetch them again.
+type staleError struct {
+	fetched time.Time
+	err     error
+}
+
+func (e *staleError) Error() string {
+	return fmt.Sprintf("serving issues from %v: %v", e.fetched.Format(time.RFC3339), e.err)
+}
+
+// servable reports whether loadIssues still gave us issues to show despite
+// err: because only some searches failed, or the ones we have are old.
+func servable(err error) bool {
+	switch err.(type) {
+	case nil, *incompleteError, *staleError:
+		return true
+	}
+	return false
+}
+
+// setStale says how old the issues are if err says they're stale.
+func setStale(w http.ResponseWriter, err error) {
+	e, ok := err.(*staleError)
+	if !ok {
+		return
+	}
+	age := int(time.Since(e.fetched).Seconds())
+	w.Header().Set("Age", strconv.Itoa(age))
+	w.Header().Add("Warning", `110 hacktoberfest "Response is Stale"`)
+}
+
+// refreshParam reports whether the request asked to skip the cache with
+// ?refresh=true.
+func refreshParam(r *http.Request) bool {
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	return refresh
+}
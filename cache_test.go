@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIssueCache(t *testing.T) {
+	c := newIssueCache(50 * time.Millisecond)
+	a := searchParams{scope: "a"}
+
+	if _, ok := c.get(a.key()); ok {
+		t.Errorf("empty cache should miss")
+	}
+
+	c.set(a, []Issue{{Title: "Fix it"}})
+	if issues, ok := c.get(a.key()); !ok || len(issues) != 1 {
+		t.Errorf("fresh entry should hit, got %v %v", issues, ok)
+	}
+	if _, ok := c.get(searchParams{scope: "b"}.key()); ok {
+		t.Errorf("other keys should miss")
+	}
+
+	c.invalidate()
+	if _, ok := c.get(a.key()); ok {
+		t.Errorf("invalidated entry should miss")
+	}
+
+	c.set(a, []Issue{{Title: "Fix it"}})
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.get(a.key()); ok {
+		t.Errorf("expired entry should miss")
+	}
+
+	c.setFetched(a, []Issue{{Title: "Fix it"}}, time.Now().Add(-time.Second))
+	if _, ok := c.get(a.key()); ok {
+		t.Errorf("entry fetched before the ttl should miss")
+	}
+
+	c.setRefreshed(a, []Issue{{Title: "Fix it"}}, time.Hour)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.get(a.key()); !ok {
+		t.Errorf("refreshed entry should hit until the next refresh")
+	}
+}
+
+func TestLoadIssuesShared(t *testing.T) {
+	p := searchParams{scope: "repo:devict/shared-test", labels: []string{"hacktoberfest"}}
+	cache.set(p, []Issue{{Title: "Fix it"}})
+	defer cache.invalidate()
+
+	// Asking to refresh with nowhere to search would fail if it were allowed
+	c := &Client{Shared: true}
+	issues, err := loadIssues(context.Background(), c, p, true)
+	if err != nil || len(issues) != 1 {
+		t.Errorf("shared client should be served from the cache, got %v %v", issues, err)
+	}
+}
+
+func TestLoadIssuesCredentials(t *testing.T) {
+	var searches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "alice") {
+			if r.URL.Path == "/graphql" {
+				atomic.AddInt32(&searches, 1)
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.URL.Path != "/graphql":
+			w.Write([]byte(`{"Go": 100}`))
+		case strings.Contains(string(body), "search("):
+			atomic.AddInt32(&searches, 1)
+			w.Write([]byte(`{"data": {"search": {"pageInfo": {"hasNextPage": false}, "nodes": [{
+				"title": "Only Alice can see it",
+				"url": "https://github.com/devict/secret/issues/1",
+				"repository": {"name": "secret", "owner": {"login": "devict"}}
+			}]}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer cache.invalidate()
+
+	as := func(token string) *Client {
+		c := testClient(srv)
+		c.Token, c.GraphQLURL, c.Retries = token, srv.URL+"/graphql", 0
+		return c
+	}
+	p := searchParams{scope: "repo:devict/secret", labels: []string{"hacktoberfest"}}
+
+	if issues, err := loadIssues(context.Background(), as("alice"), p, false); err != nil || len(issues) != 1 {
+		t.Fatalf("got %v, %v for alice, want her issue", issues, err)
+	}
+	for _, token := range []string{"bob", ""} {
+		if issues, err := loadIssues(context.Background(), as(token), p, false); err == nil || len(issues) != 0 {
+			t.Errorf("got %v, %v for %q, want alice's issues kept to her", issues, err, token)
+		}
+	}
+	before := atomic.LoadInt32(&searches)
+	if issues, err := loadIssues(context.Background(), as("alice"), p, false); err != nil || len(issues) != 1 {
+		t.Errorf("got %v, %v for alice again, want her issue", issues, err)
+	}
+	if atomic.LoadInt32(&searches) != before {
+		t.Error("alice should have been served from the cache")
+	}
+}
+
+func TestLoadIssuesIncomplete(t *testing.T) {
+	p := searchParams{scope: "repo:devict/incomplete-test", labels: []string{"hacktoberfest"}}
+	defer cache.invalidate()
+
+	// GitHub can't be reached but the other source can
+	c := &Client{
+		BaseURL: "http://127.0.0.1:1",
+		HTTP:    http.DefaultClient,
+		Others:  []IssueSource{fakeSource{{URL: "https://gitlab.com/a/b/issues/1"}}},
+	}
+
+	for _, attempt := range []string{"fetched", "cached"} {
+		issues, err := loadIssues(context.Background(), c, p, false)
+		warn := incomplete(err)
+		if warn == nil || len(warn.Failed) != 1 || warn.Failed[0].Source != "github" {
+			t.Errorf("%s: github's search should have failed, got %v", attempt, err)
+		}
+		if len(issues) != 1 {
+			t.Errorf("%s: should still get the other source's issues, got %+v", attempt, issues)
+		}
+	}
+
+	e, _ := cache.entry(p.key())
+	if left := cache.ttl - time.Since(e.fetched); left > incompleteRetry {
+		t.Errorf("incomplete issues should expire in %v, not %v", incompleteRetry, left)
+	}
+}
+
+func TestLoadIssuesStale(t *testing.T) {
+	p := searchParams{scope: "repo:devict/stale-test", labels: []string{"hacktoberfest"}}
+	fetched := time.Now().Add(-time.Hour)
+	cache.setFetched(p, []Issue{{Title: "Fix it"}}, fetched)
+	defer cache.invalidate()
+
+	// GitHub's breaker is open
+	c := &Client{Breaker: &breaker{openUntil: time.Now().Add(time.Hour)}}
+	issues, err := loadIssues(context.Background(), c, p, false)
+	stale, ok := err.(*staleError)
+	if !ok || !stale.fetched.Equal(fetched) {
+		t.Fatalf("error should be a *staleError from when the issues were fetched, got %v", err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("should get the last issues we had, got %+v", issues)
+	}
+
+	w := httptest.NewRecorder()
+	setStale(w, err)
+	if got := w.Header().Get("Age"); got != "3600" {
+		t.Errorf("got Age %q, want 3600", got)
+	}
+}
+
+func TestLoadIssuesServeStale(t *testing.T) {
+	defer func(d time.Duration) { config.ServeStale = d }(config.ServeStale)
+	config.ServeStale = time.Hour
+
+	searched := make(chan struct{}, 10)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/issues":
+			searched <- struct{}{}
+			<-release
+			w.Write([]byte(`{"items": [{
+				"title": "Fixed it",
+				"html_url": "https://github.com/devict/hacktoberfest/issues/2",
+				"repository_url": "https://api.github.com/repos/devict/hacktoberfest"
+			}]}`))
+		case "/repos/devict/hacktoberfest/languages":
+			w.Write([]byte(`{"Go": 100}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	p := searchParams{scope: c.Scope(), labels: []string{"hacktoberfest"}}
+	fetched := time.Now().Add(-cache.ttl - time.Minute)
+	cache.setFetched(p, []Issue{{Title: "Fix it"}}, fetched)
+	defer cache.invalidate()
+
+	// Everyone is served the old issues straight away while one search
+	// happens in the background
+	for n := 0; n < 3; n++ {
+		issues, err := loadIssues(context.Background(), c, p, false)
+		if stale, ok := err.(*staleError); !ok || stale.err != errRevalidating || !stale.fetched.Equal(fetched) {
+			t.Fatalf("error should be a *staleError while fetching again, got %v", err)
+		}
+		if len(issues) != 1 || issues[0].Title != "Fix it" {
+			t.Errorf("should get the old issues, got %+v", issues)
+		}
+	}
+	<-searched
+	close(release)
+	for flights.size() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if len(searched) > 0 {
+		t.Errorf("GitHub should only have been searched once")
+	}
+
+	issues, err := loadIssues(context.Background(), c, p, false)
+	if err != nil || len(issues) != 1 || issues[0].Title != "Fixed it" {
+		t.Errorf("should get the fetched issues, got %+v %v", issues, err)
+	}
+
+	// Too long after expiring we wait for them
+	cache.setFetched(p, []Issue{{Title: "Fix it"}}, time.Now().Add(-cache.ttl-2*time.Hour))
+	issues, err = loadIssues(context.Background(), c, p, false)
+	if err != nil || len(issues) != 1 || issues[0].Title != "Fixed it" {
+		t.Errorf("should wait for the fetched issues, got %+v %v", issues, err)
+	}
+}
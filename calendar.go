@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /events.ics is an iCalendar feed people can subscribe to so Hacktoberfest
+// shows up in their calendar. It always has the first and last days, and
+// with ?digest=daily or weekly a day marker for each digest so far, saying
+// what issues opened since the one before. Those take the same filters as
+// the listing, or with the token from a digest email, that subscription's
+// frequency and languages.
+
+// calendarEvent is a day long event in the calendar.
+type calendarEvent struct {
+	uid     string
+	day     time.Time
+	summary string
+	desc    string
+	url     string
+}
+
+// eventsCalendar serves the calendar for this October, or the one in the
+// year query parameter.
+func eventsCalendar(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	year := time.Now().Year()
+	if v := q.Get("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 2014 {
+			invalidParameter(w, r, fmt.Errorf("year %q should be a year since Hacktoberfest began", v))
+			return
+		}
+	}
+	f, err := parseFilter(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	s := subscriber{frequency: q.Get("digest")}
+	if token := q.Get("token"); token != "" {
+		if db == nil {
+			writeError(w, r, http.StatusBadRequest, codeDisabled, "digests aren't turned on", nil)
+			return
+		}
+		var ok bool
+		if s, ok, err = subscriberByToken(token); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		if !ok {
+			writeError(w, r, http.StatusNotFound, codeNotFound, "no digest has that token", nil)
+			return
+		}
+		// Their digest only goes by the languages they prefer
+		f = issueFilter{}
+	}
+	if _, ok := digestPeriods[s.frequency]; s.frequency != "" && !ok {
+		invalidParameter(w, r, fmt.Errorf("digest %q should be daily or weekly", s.frequency))
+		return
+	}
+
+	site := strings.TrimSuffix(config.SiteURL, "/")
+	if site == "" {
+		site = strings.TrimSuffix(requestURL(r), r.URL.RequestURI())
+	}
+	events := seasonEvents(year, site)
+	if s.frequency != "" {
+		c := sharedClient()
+		issues, err := loadIssues(r.Context(), c, defaultParams(c.Scope()), false)
+		if !servable(err) {
+			upstreamError(w, r, err)
+			return
+		}
+		events = append(events, digestEvents(f.apply(issues), s, year, time.Now(), site)...)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write(buildCalendar(events, time.Now()))
+}
+
+// subscriberByToken gives the digest subscription with token. ok is false if
+// there isn't one.
+func subscriberByToken(token string) (subscriber, bool, error) {
+	subs, err := subscribers()
+	if err != nil {
+		return subscriber{}, false, err
+	}
+	for _, s := range subs {
+		if s.token == token {
+			return s, true, nil
+		}
+	}
+	return subscriber{}, false, nil
+}
+
+// seasonEvents are the first and last days of Hacktoberfest in year.
+func seasonEvents(year int, site string) []calendarEvent {
+	y := strconv.Itoa(year)
+	return []calendarEvent{
+		{
+			uid:     "hacktoberfest-" + y + "-start",
+			day:     time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC),
+			summary: "Hacktoberfest " + y + " starts",
+			desc:    "Pull requests opened from today count. Find issues looking for help at " + site,
+			url:     site,
+		},
+		{
+			uid:     "hacktoberfest-" + y + "-end",
+			day:     time.Date(year, time.October, 31, 0, 0, 0, 0, time.UTC),
+			summary: "Last day of Hacktoberfest " + y,
+			desc:    "Pull requests have to be opened by the end of today to count.",
+			url:     site,
+		},
+	}
+}
+
+// digestEvents are a marker for each of s's digests in year's October up to
+// now, with the issues the digest would have had: the ones opened in the
+// period before, in the languages s prefers. Ones with nothing are left out.
+func digestEvents(issues []Issue, s subscriber, year int, now time.Time, site string) []calendarEvent {
+	period := digestPeriods[s.frequency]
+	start := time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	var events []calendarEvent
+	for day := start; day.Before(end) && !day.After(now); day = day.Add(period) {
+		var before []Issue
+		for _, i := range issues {
+			if i.Date.Before(day) {
+				before = append(before, i)
+			}
+		}
+		s.lastSent = day.Add(-period)
+		picked := digestIssues(before, s, day)
+		if len(picked) == 0 {
+			continue
+		}
+
+		var desc bytes.Buffer
+		for _, i := range picked {
+			desc.WriteString(i.Repo.Owner + "/" + i.Repo.Name + ": " + i.Title + "\n" + i.URL + "\n")
+		}
+		summary := strconv.Itoa(len(picked)) + " new issues looking for help"
+		if len(picked) == 1 {
+			summary = "1 new issue looking for help"
+		}
+		events = append(events, calendarEvent{
+			uid:     "hacktoberfest-" + s.frequency + "-" + day.Format("20060102"),
+			day:     day,
+			summary: summary,
+			desc:    strings.TrimSuffix(desc.String(), "\n"),
+			url:     site,
+		})
+	}
+	return events
+}
+
+// buildCalendar makes an iCalendar of events, stamped with now.
+func buildCalendar(events []calendarEvent, now time.Time) []byte {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//devICT//Hacktoberfest//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", "Hacktoberfest")
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.uid+"@hacktoberfest")
+		line("DTSTAMP", stamp)
+		line("DTSTART;VALUE=DATE", e.day.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", icsEscape(e.summary))
+		if e.desc != "" {
+			line("DESCRIPTION", icsEscape(e.desc))
+		}
+		if e.url != "" {
+			line("URL", e.url)
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// icsEscape escapes s to be an iCalendar text value.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsLineMax is the most octets an iCalendar line can have before it has to
+// be folded onto the next.
+const icsLineMax = 75
+
+// writeFolded writes l to b as an iCalendar content line, folding it onto
+// lines starting with a space so none is too long, without splitting up a
+// UTF-8 character.
+func writeFolded(b *bytes.Buffer, l string) {
+	max := icsLineMax
+	for len(l) > max {
+		n := max
+		for n > 0 && l[n]&0xC0 == 0x80 {
+			n--
+		}
+		b.WriteString(l[:n] + "\r\n ")
+		l = l[n:]
+		// The space starting the next line counts towards it
+		max = icsLineMax - 1
+	}
+	b.WriteString(l + "\r\n")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	now := time.Date(2017, 10, 14, 12, 30, 0, 0, time.UTC)
+	events := append(seasonEvents(2017, "https://example.com"), calendarEvent{
+		uid:     "long",
+		day:     time.Date(2017, 10, 14, 0, 0, 0, 0, time.UTC),
+		summary: "Fix; this, please",
+		desc:    strings.Repeat("é", 60) + "\nnext",
+	})
+	out := buildCalendar(events, now)
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:hacktoberfest-2017-start@hacktoberfest\r\nDTSTAMP:20171014T123000Z\r\nDTSTART;VALUE=DATE:20171001\r\nDTEND;VALUE=DATE:20171002\r\nSUMMARY:Hacktoberfest 2017 starts\r\n",
+		"DTSTART;VALUE=DATE:20171031\r\nDTEND;VALUE=DATE:20171101\r\nSUMMARY:Last day of Hacktoberfest 2017\r\n",
+		`SUMMARY:Fix\; this\, please`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("calendar should contain %q, got %q", want, out)
+		}
+	}
+
+	// Long lines are folded without splitting characters up
+	var desc []string
+	for _, l := range strings.Split(string(out), "\r\n") {
+		if len(l) > icsLineMax {
+			t.Errorf("line %q is %d octets, want at most %d", l, len(l), icsLineMax)
+		}
+		if strings.HasPrefix(l, "DESCRIPTION:") || (len(desc) > 0 && strings.HasPrefix(l, " ")) {
+			desc = append(desc, strings.TrimPrefix(l, " "))
+		}
+	}
+	if got, want := strings.Join(desc, ""), "DESCRIPTION:"+strings.Repeat("é", 60)+`\nnext`; !strings.HasSuffix(got, want) {
+		t.Errorf("got unfolded description %q, want %q", got, want)
+	}
+}
+
+func TestDigestEvents(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2017, 10, d, h, 0, 0, 0, time.UTC) }
+	issues := []Issue{
+		{Title: "go", URL: "https://github.com/a/b/issues/1", Repo: Repo{Owner: "a", Name: "b"}, Date: day(2, 3), Languages: []string{"Go"}},
+		{Title: "python", URL: "https://github.com/a/b/issues/2", Repo: Repo{Owner: "a", Name: "b"}, Date: day(2, 9), Languages: []string{"Python"}},
+		{Title: "later", URL: "https://github.com/a/b/issues/3", Repo: Repo{Owner: "a", Name: "b"}, Date: day(9, 1), Languages: []string{"Go"}},
+	}
+
+	events := digestEvents(issues, subscriber{frequency: "daily"}, 2017, day(5, 0), "")
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1 for the 3rd: %+v", len(events), events)
+	}
+	e := events[0]
+	if !e.day.Equal(day(3, 0)) || e.summary != "2 new issues looking for help" {
+		t.Errorf("got %+v", e)
+	}
+	if want := "a/b: python\nhttps://github.com/a/b/issues/2\na/b: go\nhttps://github.com/a/b/issues/1"; e.desc != want {
+		t.Errorf("got description %q, want %q", e.desc, want)
+	}
+
+	events = digestEvents(issues, subscriber{frequency: "weekly", languages: []string{"Go"}}, 2017, day(20, 0), "")
+	if len(events) != 2 || !events[0].day.Equal(day(8, 0)) || !events[1].day.Equal(day(15, 0)) {
+		t.Fatalf("got %+v, want the 8th and 15th", events)
+	}
+	if events[0].summary != "1 new issue looking for help" || strings.Contains(events[0].desc, "python") {
+		t.Errorf("got %+v, want just the Go issue", events[0])
+	}
+}
+
+func TestEventsCalendar(t *testing.T) {
+	for _, q := range []string{"?year=1999", "?year=soon", "?digest=hourly"} {
+		w := httptest.NewRecorder()
+		eventsCalendar(w, httptest.NewRequest("GET", "/events.ics"+q, nil))
+		if w.Code != 400 || !strings.Contains(w.Body.String(), codeInvalidParameter) {
+			t.Errorf("%s: got %d %s, want 400", q, w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	eventsCalendar(w, httptest.NewRequest("GET", "http://example.com/events.ics?year=2017", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("got status %d and type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); strings.Count(body, "BEGIN:VEVENT") != 2 || !strings.Contains(body, "URL:http://example.com\r\n") {
+		t.Errorf("got %q, want the first and last days linking here", body)
+	}
+}
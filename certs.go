@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// With TLS_HOST set we serve HTTPS ourselves, so a small instance doesn't
+// need a proxy in front of it just to have the OAuth callback be secure. The
+// certificate comes from Let's Encrypt, or whichever ACME certificate
+// authority is configured, and is renewed a month before it runs out. Plain
+// HTTP only answers the CA's challenges and sends everyone else to HTTPS.
+
+// renewBefore is how long before a certificate runs out we get a new one.
+const renewBefore = 30 * 24 * time.Hour
+
+// newCertManager makes an autocert.Manager that gets certificates for host
+// from the ACME certificate authority at directory, keeping them and the
+// account in dir so restarting doesn't need new ones.
+func newCertManager(host, dir, directory, email string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		HostPolicy:  autocert.HostWhitelist(host),
+		Cache:       autocert.DirCache(dir),
+		Email:       email,
+		RenewBefore: renewBefore,
+		Client:      &acme.Client{DirectoryURL: directory},
+	}
+}
+
+// challenges is what plain HTTP serves: answers to m's challenges, and a
+// redirect to HTTPS for host on tlsAddr for everything else.
+func challenges(m *autocert.Manager, host, tlsAddr string) http.Handler {
+	return m.HTTPHandler(redirectHTTPS(host, tlsAddr))
+}
+
+// redirectHTTPS sends everything to the same place on HTTPS for host on
+// tlsAddr. Only GETs can be moved permanently without browsers turning other
+// requests into them.
+func redirectHTTPS(host, tlsAddr string) http.Handler {
+	base := "https://" + host
+	if _, port, err := net.SplitHostPort(tlsAddr); err == nil && port != "443" {
+		base += ":" + port
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusPermanentRedirect
+		if r.Method == "GET" || r.Method == "HEAD" {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, base+r.URL.RequestURI(), status)
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// testCertManager makes a certificate manager for hacktoberfest.example.com
+// keeping what it gets in dir, which callers should remove.
+func testCertManager(t *testing.T) (m *autocert.Manager, dir string) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newCertManager("hacktoberfest.example.com", dir, "", ""), dir
+}
+
+func TestCertChallengesRedirect(t *testing.T) {
+	m, dir := testCertManager(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		method, addr, target string
+		status               int
+		location             string
+	}{
+		{"GET", ":443", "/api/issues?page=2", http.StatusMovedPermanently, "https://hacktoberfest.example.com/api/issues?page=2"},
+		{"POST", ":443", "/logout", http.StatusPermanentRedirect, "https://hacktoberfest.example.com/logout"},
+		{"GET", ":8443", "/", http.StatusMovedPermanently, "https://hacktoberfest.example.com:8443/"},
+		{"GET", ":443", "/.well-known/acme-challenge/unknown", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		challenges(m, "hacktoberfest.example.com", test.addr).ServeHTTP(w, httptest.NewRequest(test.method, "http://hacktoberfest.example.com"+test.target, nil))
+		if w.Code != test.status {
+			t.Errorf("%s %s got status %d, want %d", test.method, test.target, w.Code, test.status)
+		}
+		if got := w.Header().Get("Location"); got != test.location {
+			t.Errorf("%s %s went to %q, want %q", test.method, test.target, got, test.location)
+		}
+	}
+}
+
+func TestCertManagerOtherHosts(t *testing.T) {
+	m, dir := testCertManager(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"}); err == nil {
+		t.Error("got a certificate for a host we don't serve")
+	}
+}
@@ -1,9 +1,8 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
 
 	"github.com/pkg/errors"
 )
@@ -33,9 +32,9 @@ func check() error {
 	var succesful, unsuccessful int
 	fmt.Printf("   %20s %8s %8s\n", "Username", "Valid", "Invalid")
 	for i, u := range users {
-		prs, err := fetchPRs(u, os.Getenv("PAT"))
+		prs, err := fetchPRs(u, config.Token)
 		if err != nil {
-			log.Println("could not fetch PRs for", u, err)
+			logError(context.Background(), err, "user", u)
 			continue
 		}
 		var valid, invalid int
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// People who are logged in can claim an issue to say they're working on it,
+// so others can tell to leave it alone. Claims are a row each in claims and
+// run out after config.ClaimTTL, in case whoever claimed it gives up quietly,
+// but can be claimed again to keep them going.
+
+// claimComment is what we say on issues when someone claims one.
+const claimComment = "I'm working on this one for Hacktoberfest."
+
+// claimRequest is the body of claiming an issue.
+type claimRequest struct {
+	URL     string `json:"url"`
+	Comment bool   `json:"comment"`
+}
+
+// claim is who has claimed an issue and until when.
+type claim struct {
+	By    string    `json:"by"`
+	Until time.Time `json:"until"`
+}
+
+// claimedError is trying to claim an issue someone else already has.
+type claimedError struct {
+	claim
+}
+
+func (e *claimedError) Error() string {
+	return fmt.Sprintf("already claimed by %s until %v", e.By, e.Until.Format(time.RFC3339))
+}
+
+// claimFor claims url for user until the claim runs out. Their own claim is
+// renewed, but someone else's that's still going gives a *claimedError.
+func claimFor(userID, username, url string, now time.Time) (claim, error) {
+	c := claim{By: username, Until: now.Add(config.ClaimTTL)}
+	err := db.QueryRow(
+		`INSERT INTO claims (url, user_id, username, claimed_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (url) DO UPDATE SET user_id = $2, username = $3, claimed_at = $4, expires_at = $5
+		WHERE claims.user_id = $2 OR claims.expires_at <= $4
+		RETURNING url`,
+		url,
+		userID,
+		username,
+		now,
+		c.Until,
+	).Scan(&url)
+	if err == nil {
+		return c, nil
+	}
+	if err != sql.ErrNoRows {
+		return claim{}, errors.Wrap(err, "could not save claim")
+	}
+
+	// Nothing was returned because someone else has it
+	var held claimedError
+	err = db.QueryRow("SELECT username, expires_at FROM claims WHERE url = $1", url).Scan(&held.By, &held.Until)
+	if err != nil {
+		return claim{}, errors.Wrap(err, "could not query claim")
+	}
+	return claim{}, &held
+}
+
+// activeClaims gives the claims that haven't run out, by issue URL.
+func activeClaims(now time.Time) (map[string]claim, error) {
+	rows, err := db.Query("SELECT url, username, expires_at FROM claims WHERE expires_at > $1", now)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query claims")
+	}
+	defer rows.Close()
+
+	claims := make(map[string]claim)
+	for rows.Next() {
+		var url string
+		var c claim
+		if err := rows.Scan(&url, &c.By, &c.Until); err != nil {
+			return nil, errors.Wrap(err, "could not scan claim")
+		}
+		claims[url] = c
+	}
+	return claims, errors.Wrap(rows.Err(), "could not iterate over claims")
+}
+
+// markClaimed sets Claimed and ClaimedBy on the issues in claims. issues must
+// be a copy, not what's in the cache.
+func markClaimed(issues []Issue, claims map[string]claim) {
+	for n := range issues {
+		c, ok := claims[issues[n].URL]
+		issues[n].Claimed = ok
+		issues[n].ClaimedBy = c.By
+	}
+}
+
+// githubIssuePath gives the API path for the issue at u, like
+// /repos/devict/hacktoberfest/issues/1. ok is false if u isn't an issue on
+// config.GitHubURL.
+func githubIssuePath(u string) (path string, ok bool) {
+	rest := strings.TrimPrefix(u, config.GitHubURL+"/")
+	if rest == u {
+		return "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[2] != "issues" {
+		return "", false
+	}
+	if _, err := strconv.Atoi(parts[3]); err != nil {
+		return "", false
+	}
+	return "/repos/" + parts[0] + "/" + parts[1] + "/issues/" + parts[3], true
+}
+
+// claimIssue claims an issue for whoever is logged in, commenting on it for
+// them if they ask and we're allowed to. It gives back the claim, or a 409 if
+// someone else has it.
+func claimIssue(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var req claimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+	url, err := checkIssueURL(req.URL)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	var path string
+	if req.Comment {
+		if !config.ClaimComments {
+			writeError(w, r, http.StatusBadRequest, codeDisabled, "commenting on claimed issues isn't turned on", nil)
+			return
+		}
+		var ok bool
+		if path, ok = githubIssuePath(url); !ok {
+			invalidRequest(w, r, "we can only comment on GitHub issues")
+			return
+		}
+		if u.AccessToken == "" {
+			noGitHub(w, r)
+			return
+		}
+	}
+
+	c, err := claimFor(u.UserID, u.NickName, url, time.Now())
+	if held, ok := err.(*claimedError); ok {
+		writeError(w, r, http.StatusConflict, codeConflict, held.Error(), held.claim)
+		return
+	}
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	audit(r, "claim.made", url)
+
+	// The claim stands even if the comment doesn't go through, since it's only
+	// a courtesy. Comments aren't retried so they can't end up there twice.
+	if req.Comment {
+		gh := newClient(u.AccessToken)
+		gh.Retries = 0
+		var data struct{}
+		if err := gh.Post(r.Context(), path+"/comments", map[string]string{"body": claimComment}, &data); err != nil {
+			logError(r.Context(), errors.Wrap(err, "could not comment on claimed issue"), "url", url)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// releaseClaim lets go of an issue whoever is logged in has claimed.
+func releaseClaim(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	url, err := parseIssueURL(r)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	// The claim runs out now rather than going away so anyone who bookmarked
+	// the issue sees it's free in their feed
+	res, err := db.Exec(
+		"UPDATE claims SET expires_at = $3 WHERE url = $1 AND user_id = $2 AND expires_at > $3",
+		url,
+		u.UserID,
+		time.Now(),
+	)
+	var released int64
+	if err == nil {
+		released, err = res.RowsAffected()
+	}
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	if released > 0 {
+		audit(r, "claim.released", url)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
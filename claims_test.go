@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGitHubIssuePath(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{config.GitHubURL + "/devict/hacktoberfest/issues/12", "/repos/devict/hacktoberfest/issues/12", true},
+		{config.GitHubURL + "/devict/hacktoberfest/pull/12", "", false},
+		{config.GitHubURL + "/devict/hacktoberfest/issues/new", "", false},
+		{config.GitHubURL + "/devict/hacktoberfest/issues/12/extra", "", false},
+		{config.GitHubURL + "//hacktoberfest/issues/12", "", false},
+		{"https://gitlab.com/devict/hacktoberfest/issues/12", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := githubIssuePath(test.url)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%q: got %q, %v, want %q, %v", test.url, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestMarkClaimed(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	issues := []Issue{{URL: "a"}, {URL: "b", Claimed: true, ClaimedBy: "old"}}
+	markClaimed(issues, map[string]claim{"a": {By: "mattstratton", Until: until}})
+
+	if !issues[0].Claimed || issues[0].ClaimedBy != "mattstratton" {
+		t.Errorf("a: got %v, %q, want claimed by mattstratton", issues[0].Claimed, issues[0].ClaimedBy)
+	}
+	if issues[1].Claimed || issues[1].ClaimedBy != "" {
+		t.Errorf("b: got %v, %q, want not claimed", issues[1].Claimed, issues[1].ClaimedBy)
+	}
+}
+
+func TestClientPost(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/repos/devict/hacktoberfest/issues/12/comments" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer srv.Close()
+
+	var data struct {
+		ID int `json:"id"`
+	}
+	c := testClient(srv)
+	if err := c.Post(context.Background(), "/repos/devict/hacktoberfest/issues/12/comments", map[string]string{"body": claimComment}, &data); err != nil {
+		t.Fatal(err)
+	}
+	if got["body"] != claimComment {
+		t.Errorf("got body %q, want %q", got["body"], claimComment)
+	}
+	if data.ID != 1 {
+		t.Errorf("got id %d, want 1", data.ID)
+	}
+}
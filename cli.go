@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// errUsage is issuesCommand being given flags it can't make sense of.
+var errUsage = errors.New("bad usage")
+
+// issuesCommand is `hacktoberfest issues`: search GitHub once for the tracked
+// labels, orgs, and projects and print what's found to stdout, newest first.
+// It doesn't need the database or the server, so the admins' changes to what's
+// tracked aren't included, only TRACKING_FILE and the environment.
+//
+// If some searches fail the issues the rest found are still printed before
+// the error is given back.
+func issuesCommand(args []string, stdout, stderr io.Writer) error {
+	t, err := loadTracking()
+	if err != nil {
+		return errors.Wrap(err, "could not load tracking")
+	}
+	rest, _ := githubAPI(config.GitHubURL)
+
+	// Zero is all of them in the config but means the default here
+	langs := config.MaxLanguages
+	if langs == 0 {
+		langs = -1
+	}
+
+	fs := flag.NewFlagSet("issues", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		token    = fs.String("token", envOr("GITHUB_TOKEN", config.Token), "GitHub token to search with, $GITHUB_TOKEN or $PAT by default")
+		format   = fs.String("format", "table", "how to print the issues: table, json, or csv")
+		labels   = fs.String("labels", strings.Join(t.labelList(), ","), "comma separated labels to search for")
+		orgs     = fs.String("orgs", strings.Join(sortedKeys(t.Orgs), ","), "comma separated orgs to search")
+		repos    = fs.String("repos", strings.Join(sortedKeys(t.Projects), ","), "comma separated owner/name repos to search")
+		maxLangs = fs.Int("max-langs", langs, "how many languages to list per repo, or -1 for all of them")
+		timeout  = fs.Duration("timeout", config.FetchTimeout, "how long all the searches get together")
+		api      = fs.String("api", rest, "GitHub API to search")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: hacktoberfest issues [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		// The flag package has already said what was wrong
+		return errUsage
+	}
+
+	var write func(io.Writer, []aggregator.Issue) error
+	switch *format {
+	case "table":
+		write = printTable
+	case "json":
+		write = printJSON
+	case "csv":
+		write = printCSV
+	default:
+		return errors.Errorf("format should be table, json, or csv, not %q", *format)
+	}
+
+	c := aggregator.New(aggregator.Options{
+		Labels:       sortedKeys(set(strings.Split(*labels, ","))),
+		Orgs:         sortedKeys(set(strings.Split(*orgs, ","))),
+		Repos:        sortedKeys(set(strings.Split(*repos, ","))),
+		Tokens:       aggregator.StaticToken(*token),
+		BaseURL:      *api,
+		MaxLanguages: *maxLangs,
+		Timeout:      *timeout,
+	})
+	if len(c.Scope()) == 0 {
+		return errors.New("there are no orgs or repos to search")
+	}
+
+	found, searchErr := c.Issues(context.Background())
+	if _, ok := searchErr.(*aggregator.IncompleteError); searchErr != nil && !ok {
+		return errors.Wrap(searchErr, "could not search GitHub")
+	}
+
+	var issues []aggregator.Issue
+	for _, i := range found {
+		if !t.excludes(Repo{Owner: i.Repo.Owner, Name: i.Repo.Name}) {
+			issues = append(issues, i)
+		}
+	}
+	if err := write(stdout, issues); err != nil {
+		return err
+	}
+	return searchErr
+}
+
+// printTable lines issues up in columns for people to read.
+func printTable(w io.Writer, issues []aggregator.Issue) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tISSUE\tTITLE\tLANGUAGES\tCREATED")
+	for _, i := range issues {
+		fmt.Fprintf(tw, "%s/%s\t#%d\t%s\t%s\t%s\n",
+			i.Repo.Owner, i.Repo.Name,
+			i.Number,
+			aggregator.Excerpt(strings.Replace(i.Title, "\t", " ", -1), 60),
+			strings.Join(i.Languages, ", "),
+			i.Date.Format("2006-01-02"),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "could not write table")
+	}
+	fmt.Fprintf(w, "\n%d issues\n", len(issues))
+	return nil
+}
+
+// printJSON writes issues as an indented JSON array, [] if there aren't any.
+func printJSON(w io.Writer, issues []aggregator.Issue) error {
+	if issues == nil {
+		issues = []aggregator.Issue{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(issues), "could not write json")
+}
+
+// printCSV writes issues with the same columns as the ?format=csv download.
+func printCSV(w io.Writer, issues []aggregator.Issue) error {
+	local := make([]Issue, len(issues))
+	for n, i := range issues {
+		local[n] = Issue{
+			Title:     i.Title,
+			Repo:      Repo{Owner: i.Repo.Owner, Name: i.Repo.Name},
+			Languages: i.Languages,
+			Labels:    i.Labels,
+			URL:       i.URL,
+			Date:      i.Date,
+		}
+	}
+	return encodeCSV(w, local)
+}
+
+// runIssuesCommand runs issuesCommand with the flags after "issues" and exits.
+func runIssuesCommand(args []string) {
+	err := issuesCommand(args, os.Stdout, os.Stderr)
+	switch {
+	case err == flag.ErrHelp:
+		os.Exit(0)
+	case err == errUsage:
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "hacktoberfest issues:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// envOr is the environment variable key, or def if it isn't set.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
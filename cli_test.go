@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// runIssues runs issuesCommand against gh, giving back what it printed.
+func runIssues(t *testing.T, gh *fakeGitHub, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-api", gh.URL, "-token", "", "-labels", "hacktoberfest,good first issue"}, args...)
+	err := issuesCommand(args, &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestIssuesCommand(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+
+	out, err := runIssues(t, gh, "-format", "json")
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	var issues []aggregator.Issue
+	if err := json.Unmarshal([]byte(out), &issues); err != nil {
+		t.Fatalf("could not decode %q: %v", out, err)
+	}
+	if len(issues) != 4 {
+		t.Fatalf("got %d issues, want the 4 in the fixtures", len(issues))
+	}
+	if issues[0].URL != "https://github.com/devict/hacktoberfest/issues/3" {
+		t.Errorf("got %s first, want the newest", issues[0].URL)
+	}
+	if _, ok := issues[0].Labels["help wanted"]; !ok {
+		t.Errorf("got labels %v, want help wanted kept since it wasn't searched for", issues[0].Labels)
+	}
+
+	out, err = runIssues(t, gh, "-format", "csv")
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 5 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("got csv %q, want a header and 4 rows", out)
+	}
+
+	out, err = runIssues(t, gh)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if !strings.HasPrefix(out, "REPO ") || !strings.Contains(out, "devict/site") || !strings.HasSuffix(out, "\n4 issues\n") {
+		t.Errorf("got table %q", out)
+	}
+}
+
+func TestIssuesCommandFailures(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+	gh.failWith("good first issue", http.StatusBadGateway)
+
+	// What the other search found is still printed
+	out, err := runIssues(t, gh, "-format", "csv")
+	if _, ok := err.(*aggregator.IncompleteError); !ok {
+		t.Errorf("got error %v, want an *aggregator.IncompleteError", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 4 {
+		t.Errorf("got csv %q, want a header and the 3 hacktoberfest issues", out)
+	}
+
+	if _, err := runIssues(t, gh, "-format", "yaml"); err == nil || !strings.Contains(err.Error(), "yaml") {
+		t.Errorf("got error %v, want one about the format", err)
+	}
+	if _, err := runIssues(t, gh, "-nonsense"); err != errUsage {
+		t.Errorf("got error %v, want errUsage", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package client talks to the Wichita Hacktoberfest API. The types and most
+// methods are generated from the OpenAPI document the app serves at
+// /openapi.json, a copy of which is kept here. See the README for keeping it
+// up to date.
+package client
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the API at BaseURL, like https://hacktoberfest.devict.org.
+// Anything that needs someone logged in needs APIKey to be one of theirs, or
+// HTTP to have their session cookie in its Jar.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	// APIKey, if set, is sent with every request, see CreateKey
+	APIKey string
+}
+
+// New makes a Client for the API at baseURL using http.DefaultClient.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTP: http.DefaultClient}
+}
+
+// Error is what the API says went wrong. Code is the thing to branch on, see
+// the README for what each means.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// do makes a request to path with the query q, sending body as JSON if it
+// isn't nil and decoding the response into out if that isn't. Responses that
+// aren't a success give an *Error.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body, out interface{}) error {
+	u := c.BaseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		e := &Error{}
+		if err := json.NewDecoder(resp.Body).Decode(e); err != nil || e.Code == "" {
+			return &Error{Code: "unexpected_status", Message: fmt.Sprintf("%s %s gave %s", method, path, resp.Status)}
+		}
+		return e
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
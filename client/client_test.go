@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListIssues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/issues" {
+			t.Errorf("got path %q, want /api/v1/issues", r.URL.Path)
+		}
+		if got, want := r.URL.RawQuery, "lang=Go&per_page=10&unassigned=true"; got != want {
+			t.Errorf("got query %q, want %q", got, want)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer hfk_abc" {
+			t.Errorf("got Authorization %q, want the API key", got)
+		}
+		w.Write([]byte(`{"data": [{"title": "Fix it", "repo": {"owner": "devict", "name": "hacktoberfest"}, "claimed_by": "someone"}], "meta": {"total": 1}, "errors": []}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/")
+	c.APIKey = "hfk_abc"
+	list, err := c.ListIssues(context.Background(), ListIssuesParams{Lang: "Go", PerPage: 10, Unassigned: true})
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Title != "Fix it" || list.Data[0].Repo.Owner != "devict" || list.Data[0].ClaimedBy != "someone" {
+		t.Errorf("got %+v", list.Data)
+	}
+	if list.Meta.Total != 1 {
+		t.Errorf("got total %d, want 1", list.Meta.Total)
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{"code": "unauthenticated", "message": "you are not logged in"}`, "unauthenticated"},
+		{`not json`, "unexpected_status"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.EscapedPath() != "/api/admin/repos/devict/a%2Fb" {
+				t.Errorf("got path %q, want it escaped", r.URL.EscapedPath())
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(test.body))
+		}))
+
+		err := New(srv.URL).RemoveRepo(context.Background(), "devict", "a/b")
+		if e, ok := err.(*Error); !ok || e.Code != test.code {
+			t.Errorf("%q: got %v, want an *Error with code %q", test.body, err, test.code)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,265 @@
+//go:build ignore
+// +build ignore
+
+// gen writes api.go from openapi.json: a type for each schema and a method on
+// Client for each operation that answers with JSON or nothing at all.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Description          string             `json:"description"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+}
+
+type param struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description"`
+	Schema      schema `json:"schema"`
+}
+
+type content map[string]struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	ID          string  `json:"operationId"`
+	Summary     string  `json:"summary"`
+	Parameters  []param `json:"parameters"`
+	RequestBody *struct {
+		Content content `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content content `json:"content"`
+	} `json:"responses"`
+}
+
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+func main() {
+	data, err := ioutil.ReadFile("openapi.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		writeType(&b, name, doc.Components.Schemas[name])
+	}
+
+	type op struct {
+		method, path string
+		operation
+	}
+	var ops []op
+	for path, methods := range doc.Paths {
+		for method, o := range methods {
+			ops = append(ops, op{strings.ToUpper(method), path, o})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].ID < ops[j].ID })
+	for _, o := range ops {
+		writeMethod(&b, o.method, o.path, o.operation)
+	}
+
+	// Only import what's used
+	imports := []string{"context", "net/url"}
+	for _, pkg := range []string{"strconv", "time"} {
+		if bytes.Contains(b.Bytes(), []byte(pkg+".")) {
+			imports = append(imports, pkg)
+		}
+	}
+	head := "// Code generated by gen.go from openapi.json. DO NOT EDIT.\n\npackage client\n\nimport (\n"
+	for _, pkg := range imports {
+		head += fmt.Sprintf("%q\n", pkg)
+	}
+	head += ")\n\n"
+
+	src, err := format.Source(append([]byte(head), b.Bytes()...))
+	if err != nil {
+		log.Fatalf("%v\n%s", err, b.Bytes())
+	}
+	if err := ioutil.WriteFile("api.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func sortedKeys(m map[string]*schema) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// initialisms are words Go writes in capitals.
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API"}
+
+// goName turns a snake_case or camelCase name into an exported Go one.
+func goName(s string) string {
+	var b bytes.Buffer
+	for _, w := range strings.Split(s, "_") {
+		if up, ok := initialisms[w]; ok {
+			b.WriteString(up)
+		} else if w != "" {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
+
+// goType is the Go type for s.
+func goType(s *schema) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		return s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + goType(s.AdditionalProperties)
+		}
+		if len(s.Properties) > 0 {
+			var b bytes.Buffer
+			b.WriteString("struct {\n")
+			writeFields(&b, s)
+			b.WriteString("}")
+			return b.String()
+		}
+	}
+	return "interface{}"
+}
+
+func writeType(b *bytes.Buffer, name string, s *schema) {
+	if s.Description != "" {
+		fmt.Fprintf(b, "// %s is %s.\n", name, strings.ToLower(s.Description[:1])+s.Description[1:])
+	}
+	if s.Type != "object" || s.AdditionalProperties != nil {
+		fmt.Fprintf(b, "type %s %s\n\n", name, goType(s))
+		return
+	}
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	writeFields(b, s)
+	b.WriteString("}\n\n")
+}
+
+func writeFields(b *bytes.Buffer, s *schema) {
+	for _, p := range sortedKeys(s.Properties) {
+		fmt.Fprintf(b, "%s %s `json:\"%s\"`\n", goName(p), goType(s.Properties[p]), p)
+	}
+}
+
+var rePathParam = regexp.MustCompile(`{([a-z]+)}`)
+
+func writeMethod(b *bytes.Buffer, method, path string, o operation) {
+	var result string
+	success := false
+	for code, resp := range o.Responses {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		success = true
+		if c, ok := resp.Content["application/json"]; ok {
+			result = goType(c.Schema)
+		}
+	}
+	if !success {
+		return
+	}
+
+	name := goName(o.ID)
+	args := []string{"ctx context.Context"}
+	var query []param
+	for _, p := range o.Parameters {
+		switch p.In {
+		case "path":
+			args = append(args, p.Name+" string")
+		case "query":
+			query = append(query, p)
+		}
+	}
+	if len(query) > 0 {
+		fmt.Fprintf(b, "// %sParams are the query parameters %s takes. Zero values are left out.\n", name, name)
+		fmt.Fprintf(b, "type %sParams struct {\n", name)
+		for _, p := range query {
+			fmt.Fprintf(b, "// %s\n%s %s\n", p.Description, goName(p.Name), goType(&p.Schema))
+		}
+		b.WriteString("}\n\n")
+		args = append(args, "params "+name+"Params")
+	}
+	var body string
+	if o.RequestBody != nil {
+		body = "body"
+		args = append(args, "body "+goType(o.RequestBody.Content["application/json"].Schema))
+	} else {
+		body = "nil"
+	}
+
+	returns, out, ret := "error", "nil", "return "
+	if result != "" {
+		returns, out, ret = "("+result+", error)", "&out", "return out, "
+	}
+
+	goPath := `"` + rePathParam.ReplaceAllString(path, `" + url.PathEscape($1) + "`) + `"`
+	goPath = strings.Replace(goPath, ` + ""`, "", -1)
+
+	fmt.Fprintf(b, "// %s calls %s %s to %s.\n", name, method, path, strings.ToLower(o.Summary[:1])+o.Summary[1:])
+	fmt.Fprintf(b, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+	b.WriteString("q := url.Values{}\n")
+	for _, p := range query {
+		field := "params." + goName(p.Name)
+		switch goType(&p.Schema) {
+		case "string":
+			fmt.Fprintf(b, "if %s != \"\" {\nq.Set(%q, %s)\n}\n", field, p.Name, field)
+		case "int":
+			fmt.Fprintf(b, "if %s != 0 {\nq.Set(%q, strconv.Itoa(%s))\n}\n", field, p.Name, field)
+		case "bool":
+			fmt.Fprintf(b, "if %s {\nq.Set(%q, \"true\")\n}\n", field, p.Name)
+		}
+	}
+	if result != "" {
+		fmt.Fprintf(b, "var out %s\n", result)
+	}
+	fmt.Fprintf(b, "err := c.do(ctx, %q, %s, q, %s, %s)\n%serr\n}\n\n", method, goPath, body, out, ret)
+}
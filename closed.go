@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// We only search for open issues, so on their own we'd never know one had
+// been finished. When an issue we were listing closes, which the webhook
+// tells us straight away and each refresh checks with GitHub for the ones
+// that left the listing, it's kept as a ClosedIssue. They're given back by
+// /api/v1/issues/recently-closed so people can see things getting done, and
+// listings served from an old copy mark them Closed so nobody picks one up.
+
+// closedDays is how many days back recently closed issues go unless asked,
+// and maxClosedDays the most they can ask for.
+const (
+	closedDays    = 7
+	maxClosedDays = 31
+)
+
+// maxClosed is the most recently closed issues we keep in memory or give
+// back.
+const maxClosed = 500
+
+// maxClosureChecks is the most issues that left the listing a refresh asks
+// GitHub about. Any more are most likely a label going away, not closes.
+const maxClosureChecks = 20
+
+// ClosedIssue is an issue we were listing that's since been closed.
+type ClosedIssue struct {
+	Issue
+	ClosedAt time.Time `json:"closed_at"`
+}
+
+// closures are the issues that closed lately, in memory so listings can be
+// marked without a query. With a database they're in closed_issues too.
+var closures = &closedIssues{}
+
+// closedIssues is a list of ClosedIssues, newest first, safe to use from
+// more than one goroutine.
+type closedIssues struct {
+	mu     sync.Mutex
+	issues []ClosedIssue
+}
+
+// add puts i at the front, dropping any that are too old or too many. It
+// reports false if i was already there.
+func (l *closedIssues) add(i ClosedIssue) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, c := range l.issues {
+		if c.URL == i.URL {
+			return false
+		}
+	}
+	keep := []ClosedIssue{i}
+	oldest := i.ClosedAt.AddDate(0, 0, -maxClosedDays)
+	for _, c := range l.issues {
+		if len(keep) < maxClosed && c.ClosedAt.After(oldest) {
+			keep = append(keep, c)
+		}
+	}
+	l.issues = keep
+	return true
+}
+
+// forget drops the issue at url, which has been reopened.
+func (l *closedIssues) forget(url string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for n, c := range l.issues {
+		if c.URL == url {
+			l.issues = append(l.issues[:n:n], l.issues[n+1:]...)
+			return
+		}
+	}
+}
+
+// since gives the issues closed at or after t, newest first.
+func (l *closedIssues) since(t time.Time) []ClosedIssue {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := []ClosedIssue{}
+	for _, c := range l.issues {
+		if c.ClosedAt.Before(t) {
+			break
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// urls is the set of issues in l.
+func (l *closedIssues) urls() map[string]bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	urls := make(map[string]bool, len(l.issues))
+	for _, c := range l.issues {
+		urls[c.URL] = true
+	}
+	return urls
+}
+
+// markClosed sets Closed on the issues we know have closed. issues must be a
+// copy, not what's in the cache.
+func markClosed(issues []Issue, closed map[string]bool) {
+	for n := range issues {
+		issues[n].Closed = closed[issues[n].URL]
+	}
+}
+
+// recordClosed keeps i, as we last listed it, as having closed at at.
+func recordClosed(ctx context.Context, i Issue, at time.Time) {
+	i.State = "closed"
+	i.Closed = true
+	i.Bookmarked = false
+	i.Claimed, i.ClaimedBy = false, ""
+	c := ClosedIssue{Issue: i, ClosedAt: at}
+	if !closures.add(c) || db == nil {
+		return
+	}
+	if err := saveClosed(c); err != nil {
+		logError(ctx, err, "issue", i.URL)
+	}
+}
+
+// recordReopened forgets that the issue at url closed.
+func recordReopened(ctx context.Context, url string) {
+	closures.forget(url)
+	if db == nil {
+		return
+	}
+	if _, err := db.Exec("DELETE FROM closed_issues WHERE url = $1", url); err != nil {
+		logError(ctx, errors.Wrap(err, "could not delete closed issue"), "issue", url)
+	}
+}
+
+// saveClosed records c in closed_issues.
+func saveClosed(c ClosedIssue) error {
+	data, err := json.Marshal(c.Issue)
+	if err != nil {
+		return errors.Wrap(err, "could not encode issue")
+	}
+	_, err = db.Exec(
+		`INSERT INTO closed_issues (url, data, closed_at) VALUES ($1, $2, $3)
+		ON CONFLICT (url) DO UPDATE SET data = $2, closed_at = $3`,
+		c.URL,
+		data,
+		c.ClosedAt,
+	)
+	return errors.Wrap(err, "could not save closed issue")
+}
+
+// storedClosed gives the issues in closed_issues closed at or after t,
+// newest first.
+func storedClosed(t time.Time) ([]ClosedIssue, error) {
+	rows, err := db.Query(
+		"SELECT data, closed_at FROM closed_issues WHERE closed_at >= $1 ORDER BY closed_at DESC LIMIT $2",
+		t,
+		maxClosed,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query closed issues")
+	}
+	defer rows.Close()
+
+	out := []ClosedIssue{}
+	for rows.Next() {
+		var data []byte
+		var c ClosedIssue
+		if err := rows.Scan(&data, &c.ClosedAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan closed issue")
+		}
+		if err := json.Unmarshal(data, &c.Issue); err != nil {
+			return nil, errors.Wrap(err, "could not decode closed issue")
+		}
+		out = append(out, c)
+	}
+	return out, errors.Wrap(rows.Err(), "could not iterate over closed issues")
+}
+
+// checkClosed asks GitHub whether the issues that left the listing closed,
+// recording the ones that did. Issues elsewhere are left alone since we
+// can't tell, and so is the rest once the rate limit gets low.
+func checkClosed(ctx context.Context, c *Client, gone []Issue) {
+	if len(gone) > maxClosureChecks {
+		gone = gone[:maxClosureChecks]
+	}
+	for _, i := range gone {
+		path, ok := githubIssuePath(i.URL)
+		if !ok {
+			continue
+		}
+		if !c.CanSpare("core") {
+			logDebug(ctx, "skipping closure checks, rate limit is low", "left", len(gone))
+			return
+		}
+
+		var item aggregator.RESTIssue
+		if err := c.Get(ctx, path, nil, &item); err != nil {
+			logWarn(ctx, "could not check if issue closed", "issue", i.URL, "err", err)
+			continue
+		}
+		if item.State != "closed" {
+			continue
+		}
+		at := time.Now()
+		if item.ClosedAt != nil {
+			at = *item.ClosedAt
+		}
+		recordClosed(ctx, i, at)
+	}
+}
+
+// recentlyClosed gives the issues that closed in the last week, or in the
+// last however many days query parameter days says, newest first.
+func recentlyClosed(w http.ResponseWriter, r *http.Request) {
+	days := closedDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		var err error
+		if days, err = strconv.Atoi(v); err != nil || days < 1 || days > maxClosedDays {
+			invalidParameter(w, r, errors.New("days "+strconv.Quote(v)+" should be from 1 to "+strconv.Itoa(maxClosedDays)))
+			return
+		}
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	// The database has what other instances and the ones before a restart
+	// saw close too
+	var issues []ClosedIssue
+	if db != nil {
+		var err error
+		if issues, err = storedClosed(since); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+	} else {
+		issues = closures.since(since)
+	}
+
+	writeEnvelope(w, r, apiEnvelope{Data: issues, Meta: apiMeta{Total: len(issues)}})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClosedIssues(t *testing.T) {
+	now := time.Now()
+	l := &closedIssues{}
+	l.add(ClosedIssue{Issue: Issue{URL: "old"}, ClosedAt: now.AddDate(0, 0, -maxClosedDays-1)})
+	l.add(ClosedIssue{Issue: Issue{URL: "a"}, ClosedAt: now.Add(-time.Hour)})
+	if l.add(ClosedIssue{Issue: Issue{URL: "a"}, ClosedAt: now}) {
+		t.Error("adding an issue twice should report false")
+	}
+	l.add(ClosedIssue{Issue: Issue{URL: "b"}, ClosedAt: now})
+
+	if got := l.since(time.Time{}); len(got) != 2 || got[0].URL != "b" || got[1].URL != "a" {
+		t.Errorf("got %+v, want b then a with old dropped", got)
+	}
+	if got := l.since(now.Add(-time.Minute)); len(got) != 1 || got[0].URL != "b" {
+		t.Errorf("got %+v, want only b in the last minute", got)
+	}
+
+	l.forget("b")
+	issues := []Issue{{URL: "a"}, {URL: "b", Closed: true}}
+	markClosed(issues, l.urls())
+	if !issues[0].Closed || issues[1].Closed {
+		t.Errorf("got %+v, want only a closed", issues)
+	}
+}
+
+func TestCheckClosed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/devict/hacktoberfest/issues/1":
+			w.Write([]byte(`{"state": "closed", "closed_at": "2017-10-02T15:04:05Z"}`))
+		case "/repos/devict/hacktoberfest/issues/2":
+			w.Write([]byte(`{"state": "open"}`))
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func() { closures = &closedIssues{} }()
+
+	gone := []Issue{
+		{URL: "https://github.com/devict/hacktoberfest/issues/1", Title: "Closed", Claimed: true, ClaimedBy: "octocat"},
+		{URL: "https://github.com/devict/hacktoberfest/issues/2", Title: "Relabeled"},
+		{URL: "https://gitlab.com/devict/hacktoberfest/-/issues/3", Title: "Elsewhere"},
+	}
+	checkClosed(context.Background(), testClient(srv), gone)
+
+	got := closures.since(time.Time{})
+	if len(got) != 1 || got[0].Title != "Closed" {
+		t.Fatalf("got %+v, want only the closed issue", got)
+	}
+	if c := got[0]; !c.Closed || c.State != "closed" || c.Claimed || !c.ClosedAt.Equal(time.Date(2017, 10, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("got %+v, want it closed when GitHub says and unclaimed", c)
+	}
+}
+
+func TestRecentlyClosed(t *testing.T) {
+	defer func() { closures = &closedIssues{} }()
+	closures.add(ClosedIssue{Issue: Issue{URL: "a", Closed: true}, ClosedAt: time.Now().AddDate(0, 0, -10)})
+	closures.add(ClosedIssue{Issue: Issue{URL: "b", Closed: true}, ClosedAt: time.Now()})
+
+	tests := []struct {
+		query  string
+		status int
+		urls   int
+	}{
+		{"", http.StatusOK, 1},
+		{"?days=14", http.StatusOK, 2},
+		{"?days=0", http.StatusBadRequest, 0},
+		{"?days=week", http.StatusBadRequest, 0},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		recentlyClosed(w, httptest.NewRequest("GET", "/api/v1/issues/recently-closed"+test.query, nil))
+		if w.Code != test.status {
+			t.Errorf("%q: got status %d, want %d", test.query, w.Code, test.status)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var got closedList
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Data) != test.urls || got.Meta.Total != test.urls {
+			t.Errorf("%q: got %+v, want %d issues", test.query, got, test.urls)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Issue listings are big JSON that shrinks a lot, so responses are gzipped,
+// or deflated, for clients that say they can take it. Small responses aren't
+// worth it and neither are types that are already compressed, like images.
+
+// compressMinSize is the smallest response worth compressing.
+const compressMinSize = 1024
+
+// compress compresses what h writes when the request's Accept-Encoding
+// allows.
+func compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == "HEAD" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// acceptedEncoding is the encoding we'll use for an Accept-Encoding, gzip if
+// it's allowed, then deflate, or none.
+func acceptedEncoding(accept string) string {
+	q := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		weight := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					weight = v
+				}
+			}
+		}
+		q[name] = weight
+	}
+
+	for _, name := range []string{"gzip", "deflate"} {
+		weight, ok := q[name]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// compressible reports whether responses of contentType shrink enough to be
+// worth compressing. Event streams are left alone so each event goes out as
+// soon as it's flushed.
+func compressible(contentType string) bool {
+	t := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case t == "text/event-stream":
+		return false
+	case strings.HasPrefix(t, "text/"):
+		return true
+	}
+	switch t {
+	case "application/json", "application/javascript", "application/xml", "application/atom+xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// flushWriteCloser is a compressing writer, which gzip's and flate's both are.
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter holds the start of a response until there's enough of it
+// to know whether to compress it, then compresses the rest on the way
+// through if so.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     flushWriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < compressMinSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide sends the headers, compressing if what we have is big enough and
+// the response is the kind that's worth it, then what's been held back.
+func (w *compressWriter) decide(big bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	ok := compressible(h.Get("Content-Type"))
+	if ok {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	// Ranges are of the uncompressed body, and some statuses have none
+	switch {
+	case !big, !ok, h.Get("Content-Encoding") != "", h.Get("Content-Range") != "":
+		ok = false
+	case w.status == http.StatusPartialContent, w.status == http.StatusNoContent, w.status == http.StatusNotModified, w.status < 200:
+		ok = false
+	}
+	if ok {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.enc = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.enc, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	held := w.buf
+	w.buf = nil
+	if len(held) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(held)
+	} else {
+		_, err = w.ResponseWriter.Write(held)
+	}
+	return err
+}
+
+// close sends anything still held back, which is too small to compress, or
+// finishes compressing.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
+
+// Flush sends what's been written so far, so streamed responses still
+// stream.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes through so WebSockets still work.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response can't be hijacked")
+	}
+	w.decided = true
+	return hj.Hijack()
+}
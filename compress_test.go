@@ -0,0 +1,86 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                       "",
+		"gzip, deflate, br":      "gzip",
+		"deflate":                "deflate",
+		"gzip;q=0, deflate;q=.5": "deflate",
+		"*":                      "gzip",
+		"br, *;q=0":              "",
+		"identity":               "",
+	} {
+		if got := acceptedEncoding(accept); got != want {
+			t.Errorf("Accept-Encoding %q: got %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	big := `{"data": [` + strings.Repeat(`{"title": "Fix it"},`, 100) + `{}]}`
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(big[:10]))
+			w.Write([]byte(big[10:]))
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(big))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/big", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("got headers %v, want gzip varying on Accept-Encoding", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != big {
+		t.Errorf("gzipped body didn't come back the same")
+	}
+
+	w = get("/big", "deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("got headers %v, want deflate", w.Header())
+	}
+	if b, _ := ioutil.ReadAll(flate.NewReader(w.Body)); string(b) != big {
+		t.Errorf("deflated body didn't come back the same")
+	}
+
+	for _, path := range []string{"/small", "/image", "/empty"} {
+		if w := get(path, "gzip"); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s shouldn't be compressed, got %v", path, w.Header())
+		}
+	}
+	if w := get("/big", ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != big {
+		t.Errorf("clients that don't ask shouldn't get it compressed")
+	}
+	if w := get("/empty", "gzip"); w.Code != http.StatusNoContent {
+		t.Errorf("got status %d, want the 204 through", w.Code)
+	}
+}
@@ -0,0 +1,672 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config is what the app runs with. Each setting comes from its flag if it's
+// given, otherwise its environment variable, otherwise its default. See
+// loadConfig for the names.
+type Config struct {
+	// Addr is where the server listens, like :8080
+	Addr string
+
+	// DatabaseURL is the Postgres to connect to
+	DatabaseURL string
+
+	// Dev reloads templates on every request
+	Dev bool
+
+	// GitHubURL is where GitHub is, https://github.com unless it's Enterprise
+	GitHubURL string
+
+	// ClientID and ClientSecret are the OAuth application people log in with
+	ClientID     string
+	ClientSecret string
+
+	// GitLabURL is the GitLab people can log in with too, if GitLabClientID
+	// and GitLabClientSecret are its OAuth application
+	GitLabURL          string
+	GitLabClientID     string
+	GitLabClientSecret string
+
+	// SiteURL is where people reach us. GitHub sends them back to it after
+	// logging in, and emails link to it.
+	SiteURL string
+
+	// PKCE sends a code challenge when people log in, so a code that leaks on
+	// its way back is no use without the cookie it was started with. GitHub
+	// Enterprise Server may not support it.
+	PKCE bool
+
+	// SessionSecret signs and encrypts session cookies. Ones made with any of
+	// OldSessionSecrets are still accepted, so it can be changed without
+	// logging everyone out.
+	SessionSecret     string
+	OldSessionSecrets []string
+
+	// TokenKey, if set, is the base64 of a 16, 24 or 32 byte AES key that
+	// people's GitHub tokens are encrypted with in their session, on top of
+	// the session's own encryption
+	TokenKey string
+
+	// SessionMaxAge is how long a session lasts without being renewed, and
+	// one in use is renewed once it's SessionRenewAfter old
+	SessionMaxAge     time.Duration
+	SessionRenewAfter time.Duration
+
+	// Token is our own GitHub token. It keeps the cache warm and lets people
+	// browse issues without logging in.
+	Token string
+
+	// DebugToken, if set, lets requests with it as a bearer token see the
+	// /debug endpoints without logging in as an admin
+	DebugToken string
+
+	// Admins are the GitHub usernames allowed to use the admin API
+	Admins []string
+
+	// WebhookSecret is the secret configured on the GitHub webhook. When it
+	// isn't set the webhook is turned off and the issue cache only expires
+	// on its own.
+	WebhookSecret string
+
+	// TrackingFile, if set, is JSON saying what to track, and Orgs, Projects,
+	// Labels and Excluded replace what it says when they're set. See
+	// loadTracking.
+	TrackingFile string
+	Orgs         []string
+	Projects     []string
+	Labels       []string
+	Excluded     []string
+
+	// CacheTTL is how long fetched issues are served before searching again
+	CacheTTL time.Duration
+
+	// ServeStale is how long after they expire cached issues are still served
+	// straight away while they're fetched again in the background. Zero
+	// always waits for the fetch.
+	ServeStale time.Duration
+
+	// RefreshInterval is how often the standard listing is fetched in the
+	// background during October. It should be shorter than CacheTTL.
+	// OffSeasonInterval is how often the rest of the year.
+	RefreshInterval   time.Duration
+	OffSeasonInterval time.Duration
+
+	// WarmTimeout is how long startup waits for the first background refresh
+	// before serving anyway. Zero doesn't wait.
+	WarmTimeout time.Duration
+
+	// FetchTimeout is the most a whole search of every tracked repo gets.
+	// Searches still going when it runs out are given up on and we make do
+	// with what the others found.
+	FetchTimeout time.Duration
+
+	// SearchConcurrency is the most label searches one fetch runs at once,
+	// across every source. LabelPriority are labels to search for before the
+	// rest, most important first.
+	SearchConcurrency int
+	LabelPriority     []string
+
+	// MaxLanguages is how many of each repo's top languages listings give
+	// unless max_langs asks for another number. Zero is all of them.
+	MaxLanguages int
+
+	// MaxPerRepo is the most issues from one repo listings of more than one
+	// give, 0 for no limit. See parseQuota.
+	MaxPerRepo int
+
+	// CallTimeout is the most any one request to GitHub or another forge
+	// gets. One that runs out is retried like any other failure.
+	CallTimeout time.Duration
+
+	// UpstreamProxy, if set, is the proxy calls to GitHub and the other
+	// forges go through, like http://proxy.example.com:3128. Otherwise it's
+	// whatever HTTPS_PROXY says. UpstreamIdleConns is how many connections
+	// to each are kept open between calls.
+	UpstreamProxy     string
+	UpstreamIdleConns int
+
+	// ShutdownTimeout is how long requests and background work get to finish
+	// once we're asked to stop
+	ShutdownTimeout time.Duration
+
+	// RedisURL, if set, is a Redis like redis://:password@host:6379/0 to keep
+	// the issue cache, languages and logouts in, so every instance behind a
+	// load balancer shares them. Otherwise each keeps its own in memory.
+	RedisURL string
+
+	// IssueRate is how many issue searches a minute each session or address
+	// gets, IssueBurst of them at once. Zero is no limit.
+	IssueRate  int
+	IssueBurst int
+
+	// CORSOrigins are the origins, like https://app.example.com, whose pages
+	// can use the API with people's sessions
+	CORSOrigins []string
+
+	// TrustProxy takes people's addresses from X-Forwarded-For, for when
+	// we're behind a proxy that sets it
+	TrustProxy bool
+
+	// TLSHost, if set, is the host name we serve HTTPS as on TLSAddr, with a
+	// certificate from the ACME certificate authority at ACMEDirectory, Let's
+	// Encrypt unless it's changed. Addr then only answers its challenges and
+	// sends everyone else to HTTPS, so it needs to be port 80. Certificates
+	// are kept in TLSCacheDir, and the CA can tell ACMEEmail about problems
+	// with them.
+	TLSHost       string
+	TLSAddr       string
+	TLSCacheDir   string
+	ACMEDirectory string
+	ACMEEmail     string
+
+	// GitHubAppID, GitHubAppInstallationID and GitHubAppKeyFile, the path to
+	// the private key GitHub generated for it, are the GitHub App every
+	// search is made with, if there is one. See loadApp.
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppKeyFile        string
+
+	// GitLabProjects are namespace/project paths on GitLabURL to search
+	// alongside GitHub, with GitLabToken if it's set. GiteaProjects are
+	// owner/name repos on GiteaURL, with GiteaToken.
+	GitLabProjects []string
+	GitLabToken    string
+	GiteaURL       string
+	GiteaProjects  []string
+	GiteaToken     string
+
+	// SMTPAddr is the SMTP server digests are sent through, like
+	// smtp.example.com:587, logging in as SMTPUsername with SMTPPassword if
+	// they're set. Digests come from DigestFrom and are off without both.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	DigestFrom   string
+
+	// ClaimTTL is how long a claim lasts. ClaimComments lets people have us
+	// comment on the issues they claim, which means asking for permission to
+	// comment as them when they log in.
+	ClaimTTL      time.Duration
+	ClaimComments bool
+
+	// NotificationsFile, if set, is JSON saying where to post new issues.
+	// See loadNotifications.
+	NotificationsFile string
+
+	// CopiedTitles is how many open issues a repo can have with the same
+	// title before it's flagged
+	CopiedTitles int
+
+	// BreakerThreshold is how many failures in a row stop us calling a host
+	// for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// LogLevel is debug, info, warn or error, and LogFormat is text or json
+	LogLevel  string
+	LogFormat string
+
+	// OTelEndpoint, if set, is the OpenTelemetry collector traces are sent
+	// to, like http://localhost:4318, as OTelServiceName
+	OTelEndpoint    string
+	OTelServiceName string
+}
+
+// config is the Config everything runs with. It's the defaults until main
+// loads the real one.
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		GitHubURL:         "https://github.com",
+		GitLabURL:         "https://gitlab.com",
+		CacheTTL:          defaultCacheTTL,
+		RefreshInterval:   defaultRefreshInterval,
+		OffSeasonInterval: defaultOffSeasonInterval,
+		WarmTimeout:       30 * time.Second,
+		FetchTimeout:      30 * time.Second,
+		SearchConcurrency: 8,
+		MaxLanguages:      defaultMaxLangs,
+		MaxPerRepo:        defaultMaxPerRepo,
+		CallTimeout:       10 * time.Second,
+		UpstreamIdleConns: 16,
+		ShutdownTimeout:   defaultShutdownTimeout,
+		IssueRate:         60,
+		IssueBurst:        20,
+		TLSAddr:           ":443",
+		TLSCacheDir:       "certs",
+		ACMEDirectory:     "https://acme-v02.api.letsencrypt.org/directory",
+		GiteaURL:          "https://codeberg.org",
+		ClaimTTL:          72 * time.Hour,
+		CopiedTitles:      5,
+		BreakerThreshold:  5,
+		BreakerCooldown:   time.Minute,
+		LogLevel:          "info",
+		LogFormat:         "text",
+		OTelServiceName:   "hacktoberfest",
+
+		SessionMaxAge:     30 * 24 * time.Hour,
+		SessionRenewAfter: 24 * time.Hour,
+	}
+}
+
+// loadConfig reads the Config from the flags in args, falling back to the
+// environment through getenv and then the defaults. Anything that doesn't make
+// sense is an error saying what, all of them at once so they can be fixed
+// together. Bad flags are errUsage, and the flag package has already said
+// what was wrong with them.
+func loadConfig(args []string, getenv func(string) string) (Config, error) {
+	c := defaultConfig()
+	var problems []string
+
+	fs := flag.NewFlagSet("hacktoberfest", flag.ContinueOnError)
+	str := func(p *string, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			*p = v
+		}
+		fs.StringVar(p, name, *p, usage+", $"+env)
+	}
+	list := func(p *[]string, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			*p = sortedKeys(set(strings.Split(v, ",")))
+		}
+		fs.Var((*listFlag)(p), name, usage+", comma separated, $"+env)
+	}
+	ordered := func(p *[]string, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			*p = inOrder(v)
+		}
+		fs.Var((*orderedFlag)(p), name, usage+", comma separated, $"+env)
+	}
+	boolean := func(p *bool, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s should be true or false, not %q", env, v))
+			}
+			*p = b
+		}
+		fs.BoolVar(p, name, *p, usage+", $"+env)
+	}
+	integer := func(p *int, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s should be a whole number, not %q", env, v))
+			}
+			*p = n
+		}
+		fs.IntVar(p, name, *p, usage+", $"+env)
+	}
+	duration := func(p *time.Duration, name, env, usage string) {
+		if v := getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s should be a duration like 10s, not %q", env, v))
+			}
+			*p = d
+		}
+		fs.DurationVar(p, name, *p, usage+", $"+env)
+	}
+
+	if p := getenv("PORT"); p != "" {
+		c.Addr = ":" + p
+	}
+	fs.StringVar(&c.Addr, "addr", c.Addr, "address to listen on, or :$PORT")
+	str(&c.DatabaseURL, "database-url", "DATABASE_URL", "Postgres to connect to")
+	boolean(&c.Dev, "dev", "DEV", "reload templates on every request")
+
+	str(&c.GitHubURL, "github-url", "GITHUB_URL", "where GitHub is")
+	str(&c.ClientID, "github-key", "GITHUB_KEY", "OAuth client ID")
+	str(&c.ClientSecret, "github-secret", "GITHUB_SECRET", "OAuth client secret")
+	str(&c.GitLabURL, "gitlab-url", "GITLAB_URL", "where GitLab is")
+	str(&c.GitLabClientID, "gitlab-key", "GITLAB_KEY", "GitLab OAuth client ID")
+	str(&c.GitLabClientSecret, "gitlab-secret", "GITLAB_SECRET", "GitLab OAuth client secret")
+	str(&c.SiteURL, "site-url", "GITHUB_CALLBACK", "where people reach us")
+	boolean(&c.PKCE, "oauth-pkce", "OAUTH_PKCE", "send a PKCE code challenge when logging in")
+	str(&c.SessionSecret, "session-secret", "SESSION_SECRET", "key to sign and encrypt session cookies with")
+	str(&c.TokenKey, "token-key", "TOKEN_KEY", "base64 AES key to encrypt people's GitHub tokens with")
+	list(&c.OldSessionSecrets, "old-session-secrets", "OLD_SESSION_SECRETS", "keys sessions were made with before, still accepted")
+	duration(&c.SessionMaxAge, "session-max-age", "SESSION_MAX_AGE", "how long a session lasts")
+	duration(&c.SessionRenewAfter, "session-renew-after", "SESSION_RENEW_AFTER", "how old a session in use gets before it's renewed")
+	str(&c.Token, "token", "PAT", "our own GitHub token")
+	str(&c.DebugToken, "debug-token", "DEBUG_TOKEN", "bearer token for the /debug endpoints")
+	list(&c.Admins, "admins", "ADMINS", "GitHub usernames allowed to use the admin API")
+	str(&c.WebhookSecret, "webhook-secret", "GITHUB_WEBHOOK_SECRET", "secret configured on the GitHub webhook")
+
+	str(&c.TrackingFile, "tracking-file", "TRACKING_FILE", "JSON file saying what to track")
+	list(&c.Orgs, "orgs", "TRACKED_ORGS", "orgs to track")
+	list(&c.Projects, "projects", "TRACKED_PROJECTS", "owner/name repos to track")
+	list(&c.Labels, "labels", "TRACKED_LABELS", "labels to search for")
+	list(&c.Excluded, "excluded", "EXCLUDED", "owners and owner/name repos to leave out")
+
+	duration(&c.CacheTTL, "cache-ttl", "ISSUE_CACHE_TTL", "how long to serve fetched issues")
+	duration(&c.ServeStale, "serve-stale", "ISSUE_SERVE_STALE", "how long past the cache TTL to serve issues while fetching them in the background, 0 not to")
+	duration(&c.RefreshInterval, "refresh-interval", "ISSUE_REFRESH_INTERVAL", "how often to fetch the listing in the background in October")
+	duration(&c.OffSeasonInterval, "off-season-interval", "ISSUE_REFRESH_OFF_SEASON", "how often to fetch the listing in the background the rest of the year")
+	duration(&c.WarmTimeout, "warm-timeout", "WARM_TIMEOUT", "how long to wait for a warm cache before serving, 0 not to")
+	duration(&c.FetchTimeout, "fetch-timeout", "FETCH_TIMEOUT", "the most a whole search gets")
+	integer(&c.SearchConcurrency, "search-concurrency", "SEARCH_CONCURRENCY", "the most label searches one fetch runs at once")
+	ordered(&c.LabelPriority, "label-priority", "LABEL_PRIORITY", "labels to search for first, most important first")
+	integer(&c.MaxLanguages, "max-languages", "MAX_LANGUAGES", "how many of each repo's languages listings give, 0 for all of them")
+	integer(&c.MaxPerRepo, "max-per-repo", "MAX_PER_REPO", "the most issues from one repo listings give, 0 for no limit")
+	duration(&c.CallTimeout, "call-timeout", "API_CALL_TIMEOUT", "the most one request upstream gets")
+	str(&c.UpstreamProxy, "upstream-proxy", "UPSTREAM_PROXY", "proxy to call GitHub and the other forges through")
+	integer(&c.UpstreamIdleConns, "upstream-idle-conns", "UPSTREAM_IDLE_CONNS", "connections to keep open to each forge between calls")
+	duration(&c.ShutdownTimeout, "shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long to wait for work to finish when stopping")
+	str(&c.RedisURL, "redis-url", "REDIS_URL", "Redis to share state between instances in")
+	integer(&c.IssueRate, "issue-rate", "ISSUE_RATE_LIMIT", "issue searches a minute each client gets, 0 for no limit")
+	integer(&c.IssueBurst, "issue-burst", "ISSUE_RATE_BURST", "issue searches each client can make at once")
+	list(&c.CORSOrigins, "cors-origins", "CORS_ORIGINS", "origins allowed to use the API from the browser")
+	boolean(&c.TrustProxy, "trust-proxy", "TRUST_PROXY", "take client addresses from X-Forwarded-For")
+	str(&c.TLSHost, "tls-host", "TLS_HOST", "host name to serve HTTPS as with a certificate from Let's Encrypt")
+	str(&c.TLSAddr, "tls-addr", "TLS_ADDR", "address to serve HTTPS on")
+	str(&c.TLSCacheDir, "tls-cache-dir", "TLS_CACHE_DIR", "directory to keep certificates in")
+	str(&c.ACMEDirectory, "acme-directory", "ACME_DIRECTORY", "directory of the ACME certificate authority")
+	str(&c.ACMEEmail, "acme-email", "ACME_EMAIL", "email the certificate authority can tell about problems")
+	str(&c.GitHubAppID, "github-app-id", "GITHUB_APP_ID", "GitHub App to search with")
+	str(&c.GitHubAppInstallationID, "github-app-installation-id", "GITHUB_APP_INSTALLATION_ID", "installation of the GitHub App")
+	str(&c.GitHubAppKeyFile, "github-app-private-key-file", "GITHUB_APP_PRIVATE_KEY_FILE", "file with the GitHub App's private key")
+	list(&c.GitLabProjects, "gitlab-projects", "GITLAB_PROJECTS", "namespace/project paths on GitLab to search")
+	str(&c.GitLabToken, "gitlab-token", "GITLAB_TOKEN", "our own GitLab token")
+	str(&c.GiteaURL, "gitea-url", "GITEA_URL", "where Gitea is")
+	list(&c.GiteaProjects, "gitea-projects", "GITEA_PROJECTS", "owner/name repos on Gitea to search")
+	str(&c.GiteaToken, "gitea-token", "GITEA_TOKEN", "our own Gitea token")
+	str(&c.SMTPAddr, "smtp-addr", "SMTP_ADDR", "SMTP server to send digests through")
+	str(&c.SMTPUsername, "smtp-username", "SMTP_USERNAME", "who to log in to the SMTP server as")
+	str(&c.SMTPPassword, "smtp-password", "SMTP_PASSWORD", "password to log in to the SMTP server with")
+	str(&c.DigestFrom, "digest-from", "DIGEST_FROM", "who digests come from")
+	duration(&c.ClaimTTL, "claim-ttl", "CLAIM_TTL", "how long a claim lasts")
+	boolean(&c.ClaimComments, "claim-comments", "CLAIM_COMMENTS", "comment on issues people claim")
+	str(&c.NotificationsFile, "notifications-file", "NOTIFICATIONS_FILE", "JSON file saying where to post new issues")
+	integer(&c.CopiedTitles, "copied-titles", "COPIED_TITLES", "how many issues a repo can have with one title before it's flagged")
+	integer(&c.BreakerThreshold, "breaker-threshold", "BREAKER_THRESHOLD", "failures in a row before we stop calling a host")
+	duration(&c.BreakerCooldown, "breaker-cooldown", "BREAKER_COOLDOWN", "how long to stop calling a host for")
+	str(&c.LogLevel, "log-level", "LOG_LEVEL", "debug, info, warn or error")
+	str(&c.LogFormat, "log-format", "LOG_FORMAT", "text or json")
+	str(&c.OTelEndpoint, "otel-endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OpenTelemetry collector to send traces to")
+	str(&c.OTelServiceName, "otel-service-name", "OTEL_SERVICE_NAME", "service name to send traces as")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return Config{}, err
+		}
+		return Config{}, errUsage
+	}
+	if fs.NArg() > 0 {
+		problems = append(problems, fmt.Sprintf("unexpected arguments %q", fs.Args()))
+	}
+
+	c.GitHubURL = strings.TrimSuffix(c.GitHubURL, "/")
+	c.GitLabURL = strings.TrimSuffix(c.GitLabURL, "/")
+	c.GiteaURL = strings.TrimSuffix(c.GiteaURL, "/")
+	c.SiteURL = strings.TrimSuffix(c.SiteURL, "/")
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return Config{}, errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return c, nil
+}
+
+// validate says what's wrong with c, if anything.
+func (c Config) validate() []string {
+	var problems []string
+	if !absolute(c.GitHubURL) {
+		problems = append(problems, fmt.Sprintf("GitHub URL %q should be like https://github.com", c.GitHubURL))
+	}
+	if c.SiteURL != "" && !absolute(c.SiteURL) {
+		problems = append(problems, fmt.Sprintf("site URL %q should be like https://hacktoberfest.example.com", c.SiteURL))
+	}
+	if (c.ClientID == "") != (c.ClientSecret == "") {
+		problems = append(problems, "the OAuth client ID and secret need setting together")
+	}
+	if c.ClientID != "" && c.SiteURL == "" {
+		problems = append(problems, "logging in needs the site URL for GitHub to send people back to")
+	}
+	if c.ClientID != "" && c.SessionSecret == "" {
+		problems = append(problems, "logging in needs a session secret")
+	}
+	for _, a := range c.Admins {
+		if !reGitHubLogin.MatchString(a) {
+			problems = append(problems, fmt.Sprintf("admin %q should be a GitHub username", a))
+		}
+	}
+	if len(c.Admins) > 0 && c.ClientID == "" {
+		problems = append(problems, "admins need logging in with GitHub set up")
+	}
+	if c.WebhookSecret != "" && len(c.WebhookSecret) < minWebhookSecret {
+		problems = append(problems, fmt.Sprintf("the webhook secret should be at least %d characters", minWebhookSecret))
+	}
+	if !absolute(c.GitLabURL) {
+		problems = append(problems, fmt.Sprintf("GitLab URL %q should be like https://gitlab.com", c.GitLabURL))
+	}
+	if (c.GitLabClientID == "") != (c.GitLabClientSecret == "") {
+		problems = append(problems, "the GitLab OAuth client ID and secret need setting together")
+	}
+	if c.GitLabClientID != "" && c.SiteURL == "" {
+		problems = append(problems, "logging in with GitLab needs the site URL for it to send people back to")
+	}
+	if c.GitLabClientID != "" && c.SessionSecret == "" {
+		problems = append(problems, "logging in with GitLab needs a session secret")
+	}
+	if _, err := newTokenCipher(c.TokenKey); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.RedisURL != "" {
+		if _, err := newRedis(c.RedisURL); err != nil {
+			problems = append(problems, fmt.Sprintf("Redis URL %q should be like redis://host:6379/0", c.RedisURL))
+		}
+	}
+	for _, o := range c.CORSOrigins {
+		if u, err := url.Parse(o); err != nil || !absolute(o) || u.Path != "" {
+			problems = append(problems, fmt.Sprintf("CORS origin %q should be like https://app.example.com", o))
+		}
+	}
+	if c.ServeStale < 0 {
+		problems = append(problems, fmt.Sprintf("serving stale issues can't be for a negative time, not %v", c.ServeStale))
+	}
+	if c.WarmTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("warm timeout can't be negative, not %v", c.WarmTimeout))
+	}
+	if c.SearchConcurrency < 1 {
+		problems = append(problems, fmt.Sprintf("search concurrency should be at least 1, not %d", c.SearchConcurrency))
+	}
+	if c.MaxLanguages < 0 {
+		problems = append(problems, fmt.Sprintf("max languages can't be negative, not %d", c.MaxLanguages))
+	}
+	if c.MaxPerRepo < 0 {
+		problems = append(problems, fmt.Sprintf("max per repo can't be negative, not %d", c.MaxPerRepo))
+	}
+	if c.IssueRate < 0 || c.IssueBurst < 0 {
+		problems = append(problems, "the issue rate limit and burst can't be negative")
+	}
+	if c.IssueRate > 0 && c.IssueBurst == 0 {
+		problems = append(problems, "an issue rate limit needs a burst of at least 1")
+	}
+	if c.UpstreamProxy != "" && !absolute(c.UpstreamProxy) {
+		problems = append(problems, fmt.Sprintf("upstream proxy %q should be like http://proxy.example.com:3128", c.UpstreamProxy))
+	}
+	if c.UpstreamIdleConns < 1 {
+		problems = append(problems, fmt.Sprintf("upstream idle connections should be at least 1, not %d", c.UpstreamIdleConns))
+	}
+	if c.TLSHost != "" {
+		if strings.ContainsAny(c.TLSHost, ":/") || !strings.Contains(c.TLSHost, ".") {
+			problems = append(problems, fmt.Sprintf("TLS host %q should be a host name like hacktoberfest.example.com", c.TLSHost))
+		}
+		if c.SiteURL != "" && c.SiteURL != "https://"+c.TLSHost {
+			problems = append(problems, fmt.Sprintf("site URL %q should be https://%s to serve HTTPS as that", c.SiteURL, c.TLSHost))
+		}
+		if !absolute(c.ACMEDirectory) {
+			problems = append(problems, fmt.Sprintf("ACME directory %q should be like https://acme-v02.api.letsencrypt.org/directory", c.ACMEDirectory))
+		}
+		if c.TLSCacheDir == "" {
+			problems = append(problems, "serving HTTPS needs a directory to keep certificates in")
+		}
+	}
+	for _, p := range c.Projects {
+		if strings.Count(p, "/") != 1 {
+			problems = append(problems, fmt.Sprintf("project %q should look like owner/name", p))
+		}
+	}
+	if (c.GitHubAppID == "") != (c.GitHubAppInstallationID == "") || (c.GitHubAppID == "") != (c.GitHubAppKeyFile == "") {
+		problems = append(problems, "the GitHub App ID, installation ID and private key file need setting together")
+	}
+	for _, p := range c.GitLabProjects {
+		if !strings.Contains(strings.Trim(p, "/"), "/") {
+			problems = append(problems, fmt.Sprintf("GitLab project %q should look like namespace/project", p))
+		}
+	}
+	if !absolute(c.GiteaURL) {
+		problems = append(problems, fmt.Sprintf("Gitea URL %q should be like https://codeberg.org", c.GiteaURL))
+	}
+	for _, p := range c.GiteaProjects {
+		if strings.Count(p, "/") != 1 {
+			problems = append(problems, fmt.Sprintf("Gitea project %q should look like owner/name", p))
+		}
+	}
+	if (c.SMTPAddr == "") != (c.DigestFrom == "") {
+		problems = append(problems, "digests need both the SMTP server and who they come from")
+	}
+	if _, _, err := net.SplitHostPort(c.SMTPAddr); c.SMTPAddr != "" && err != nil {
+		problems = append(problems, fmt.Sprintf("SMTP server %q should be like smtp.example.com:587", c.SMTPAddr))
+	}
+	if c.DigestFrom != "" {
+		if _, err := mail.ParseAddress(c.DigestFrom); err != nil {
+			problems = append(problems, fmt.Sprintf("digests should come from an email address, not %q", c.DigestFrom))
+		}
+	}
+	if c.CopiedTitles < 1 {
+		problems = append(problems, fmt.Sprintf("copied titles should be at least 1, not %d", c.CopiedTitles))
+	}
+	if c.BreakerThreshold < 1 {
+		problems = append(problems, fmt.Sprintf("breaker threshold should be at least 1, not %d", c.BreakerThreshold))
+	}
+	if !validLevel(c.LogLevel) {
+		problems = append(problems, fmt.Sprintf("log level should be debug, info, warn or error, not %q", c.LogLevel))
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		problems = append(problems, fmt.Sprintf("log format should be text or json, not %q", c.LogFormat))
+	}
+	if c.OTelEndpoint != "" && !absolute(c.OTelEndpoint) {
+		problems = append(problems, fmt.Sprintf("OpenTelemetry endpoint %q should be like http://localhost:4318", c.OTelEndpoint))
+	}
+
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"cache TTL", c.CacheTTL},
+		{"refresh interval", c.RefreshInterval},
+		{"off season refresh interval", c.OffSeasonInterval},
+		{"fetch timeout", c.FetchTimeout},
+		{"call timeout", c.CallTimeout},
+		{"shutdown timeout", c.ShutdownTimeout},
+		{"session max age", c.SessionMaxAge},
+		{"session renewal", c.SessionRenewAfter},
+		{"claim TTL", c.ClaimTTL},
+		{"breaker cooldown", c.BreakerCooldown},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			problems = append(problems, fmt.Sprintf("%s should be more than 0, not %v", d.name, d.d))
+		}
+	}
+	if c.SessionRenewAfter >= c.SessionMaxAge {
+		problems = append(problems, "sessions need renewing before they expire")
+	}
+	return problems
+}
+
+// absolute reports whether u is an http or https URL with a host.
+func absolute(u string) bool {
+	p, err := url.Parse(u)
+	return err == nil && (p.Scheme == "http" || p.Scheme == "https") && p.Host != ""
+}
+
+// listFlag is a flag holding a comma separated list without duplicates.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = sortedKeys(set(strings.Split(v, ",")))
+	return nil
+}
+
+// orderedFlag is a listFlag that keeps what it's given in order.
+type orderedFlag []string
+
+func (l *orderedFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *orderedFlag) Set(v string) error {
+	*l = inOrder(v)
+	return nil
+}
+
+// inOrder splits a comma separated list, leaving out blanks and repeats.
+func inOrder(v string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// setConfig makes c the Config everything runs with, remaking what was built
+// from the one before.
+func setConfig(c Config) {
+	config = c
+	v = newRender(c.Dev)
+	logLevel, logJSON = parseLevel(c.LogLevel), c.LogFormat == "json"
+
+	shared = nil
+	if r, err := newRedis(c.RedisURL); c.RedisURL != "" && err == nil {
+		shared = r
+	}
+	cache = newIssueCache(c.CacheTTL)
+	cache.shared = shared
+	languages.Store = nil
+	if shared != nil {
+		languages.Store = languageStore{shared}
+	}
+	languages.Prefix = sharedPrefix + "languages:"
+	loggedOut.shared = shared
+	useUpstream(newUpstream(c))
+	githubBreaker = newBreaker(c.GitHubURL)
+	reIssueRef = issueRefPattern(c.GitHubURL)
+	issueLimiter = newRateLimiter(c.IssueRate, c.IssueBurst)
+	// Browsers only send SameSite=None cookies over HTTPS
+	sameSite = "Lax"
+	if len(c.CORSOrigins) > 0 && strings.HasPrefix(c.SiteURL, "https://") {
+		sameSite = "None"
+	}
+	admins = set(c.Admins)
+	setupAuth(c)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env is a getenv for a fixed environment.
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoadConfig(t *testing.T) {
+	getenv := env(map[string]string{
+		"PORT":                  "3000",
+		"GITHUB_URL":            "https://github.example.com/",
+		"GITHUB_KEY":            "id",
+		"GITHUB_SECRET":         "secret",
+		"GITHUB_CALLBACK":       "https://hacktoberfest.example.com",
+		"SESSION_SECRET":        "banana",
+		"TRACKED_LABELS":        "hacktoberfest, bug,hacktoberfest",
+		"ISSUE_CACHE_TTL":       "10m",
+		"ISSUE_SERVE_STALE":     "1h",
+		"LABEL_PRIORITY":        "hacktoberfest, help wanted,hacktoberfest, bug",
+		"ADMINS":                "octocat,hubot",
+		"GITHUB_WEBHOOK_SECRET": "123abc123abc",
+		"MAX_LANGUAGES":         "0",
+		"TLS_HOST":              "hacktoberfest.example.com",
+		"GITEA_PROJECTS":        "devict/site,devict/site",
+		"SMTP_ADDR":             "smtp.example.com:587",
+		"DIGEST_FROM":           "Hacktoberfest <hacktoberfest@example.com>",
+		"CLAIM_TTL":             "24h",
+		"BREAKER_THRESHOLD":     "3",
+		"LOG_FORMAT":            "json",
+	})
+
+	c, err := loadConfig([]string{"-cache-ttl", "1m", "-orgs", "devict,MakeICT", "-log-level", "debug"}, getenv)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	want := defaultConfig()
+	want.Addr = ":3000"
+	want.GitHubURL = "https://github.example.com"
+	want.ClientID, want.ClientSecret = "id", "secret"
+	want.SiteURL = "https://hacktoberfest.example.com"
+	want.SessionSecret = "banana"
+	want.Labels = []string{"bug", "hacktoberfest"}
+	want.Orgs = []string{"MakeICT", "devict"}
+	want.CacheTTL = time.Minute
+	want.ServeStale = time.Hour
+	want.LabelPriority = []string{"hacktoberfest", "help wanted", "bug"}
+	want.Admins = []string{"hubot", "octocat"}
+	want.WebhookSecret = "123abc123abc"
+	want.MaxLanguages = 0
+	want.TLSHost = "hacktoberfest.example.com"
+	want.GiteaProjects = []string{"devict/site"}
+	want.SMTPAddr = "smtp.example.com:587"
+	want.DigestFrom = "Hacktoberfest <hacktoberfest@example.com>"
+	want.ClaimTTL = 24 * time.Hour
+	want.BreakerThreshold = 3
+	want.LogLevel, want.LogFormat = "debug", "json"
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v", c)
+		t.Errorf("want %+v", want)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	c, err := loadConfig(nil, env(nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if !reflect.DeepEqual(c, defaultConfig()) {
+		t.Errorf("got %+v, want the defaults", c)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	getenv := env(map[string]string{
+		"GITHUB_URL":                  "github.com",
+		"GITHUB_KEY":                  "id",
+		"TRACKED_PROJECTS":            "boltbrowser",
+		"FETCH_TIMEOUT":               "soon",
+		"DEV":                         "yes please",
+		"REDIS_URL":                   "memcached://localhost",
+		"TOKEN_KEY":                   "short",
+		"GITLAB_KEY":                  "gl-id",
+		"ISSUE_RATE_LIMIT":            "lots",
+		"CORS_ORIGINS":                "*",
+		"SEARCH_CONCURRENCY":          "0",
+		"MAX_LANGUAGES":               "-1",
+		"UPSTREAM_PROXY":              "proxy:3128",
+		"ADMINS":                      "octocat,github.com/hubot",
+		"GITHUB_WEBHOOK_SECRET":       "abc",
+		"TLS_HOST":                    "localhost:443",
+		"GITHUB_APP_ID":               "12345",
+		"GITLAB_PROJECTS":             "site",
+		"SMTP_ADDR":                   "smtp.example.com",
+		"CLAIM_TTL":                   "-1h",
+		"BREAKER_THRESHOLD":           "0",
+		"COPIED_TITLES":               "few",
+		"LOG_LEVEL":                   "loud",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4318",
+	})
+
+	_, err := loadConfig([]string{"-shutdown-timeout", "0s"}, getenv)
+	if err == nil {
+		t.Fatal("error should not be nil, but it was")
+	}
+	for _, problem := range []string{
+		"github.com", "FETCH_TIMEOUT", "DEV", "ID and secret", "site URL for GitHub",
+		"session secret", "boltbrowser", "shutdown timeout", "memcached", "token key",
+		"GitLab OAuth client ID and secret", "ISSUE_RATE_LIMIT", "CORS origin",
+		"search concurrency", "upstream proxy", "github.com/hubot", "webhook secret",
+		"max languages", "TLS host", "GitHub App", "GitLab project", "SMTP server",
+		"who they come from", "claim TTL", "breaker threshold", "COPIED_TITLES", "log level",
+		"OpenTelemetry endpoint",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error %q should mention %s", err, problem)
+		}
+	}
+
+	if _, err := loadConfig([]string{"-nonsense"}, env(nil)); err != errUsage {
+		t.Errorf("got error %v for a flag that doesn't exist, want errUsage", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Frontends hosted somewhere else can use the API from the browser if their
+// origin is in CORSOrigins. They can send the session cookie along too, so
+// someone logged in here is logged in there. Since that means the cookie goes
+// with requests from other sites, requests that change anything are turned
+// away when they come from an origin that isn't allowed.
+
+// corsMaxAge is how long browsers can remember a preflight's answer.
+const corsMaxAge = 600
+
+// corsExposed are the response headers scripts on other origins can read.
+var corsExposed = []string{"Age", "Deprecation", "ETag", "Link", "Retry-After", "Warning", "X-Request-ID"}
+
+// cors answers preflights and marks API responses as readable by the
+// origins in config.CORSOrigins, turning away changes from anywhere else.
+func cors(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(config.CORSOrigins) == 0 || sameOrigin(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !corsAllowed(origin) {
+			// Reads are only hidden from the other site, but changes can't
+			// be allowed to happen at all
+			if r.Method != "GET" && r.Method != "HEAD" && r.Method != "OPTIONS" {
+				writeError(w, r, http.StatusForbidden, codeForbidden, "requests from "+origin+" aren't allowed", nil)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			if hs := r.Header.Get("Access-Control-Request-Headers"); hs != "" {
+				w.Header().Set("Access-Control-Allow-Headers", hs)
+			}
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposed, ", "))
+		h.ServeHTTP(w, r)
+	})
+}
+
+// corsAllowed reports whether origin is one of config.CORSOrigins.
+func corsAllowed(origin string) bool {
+	for _, o := range config.CORSOrigins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	defer keepSessions()()
+	config.CORSOrigins = []string{"https://app.example.com"}
+
+	var called int
+	h := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called++ }))
+	from := func(method, path, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Origin", origin)
+		if method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+			r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := from("OPTIONS", "/api/me/bookmarks", "https://app.example.com")
+	if w.Code != http.StatusNoContent || called != 0 {
+		t.Errorf("got status %d, calling the handler %d times, want the preflight answered", w.Code, called)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Vary":                             "Origin",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("preflight got %s %q, want %q", name, got, want)
+		}
+	}
+
+	w = from("GET", "/api/v1/issues", "https://app.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Expose-Headers") == "" || called != 1 {
+		t.Errorf("got headers %v, want the response readable by the app", w.Header())
+	}
+
+	// Other sites can't read anything or change anything
+	w = from("GET", "/api/v1/issues", "https://evil.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || called != 2 {
+		t.Errorf("got headers %v, want no CORS for other origins", w.Header())
+	}
+	for _, path := range []string{"/api/me/bookmarks", "/logout"} {
+		if w := from("POST", path, "https://evil.example.com"); w.Code != http.StatusForbidden || called != 2 {
+			t.Errorf("got status %d posting to %s from another site, want 403", w.Code, path)
+		}
+	}
+
+	// Our own pages say where they're from too
+	r := httptest.NewRequest("POST", "http://hacktoberfest.example.com/api/me/bookmarks", nil)
+	r.Header.Set("Origin", "http://hacktoberfest.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || called != 3 {
+		t.Errorf("got status %d for our own origin", w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// csvHeader names the columns writeCSV writes.
+var csvHeader = []string{"title", "repo", "languages", "labels", "url", "created"}
+
+// writeCSV sends issues as a CSV download, one row per issue. Languages and
+// labels are joined with ", " in their own columns.
+func writeCSV(w http.ResponseWriter, issues []Issue) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="issues.csv"`)
+	return encodeCSV(w, issues)
+}
+
+// encodeCSV writes issues to w with a header row, the way writeCSV sends them.
+func encodeCSV(w io.Writer, issues []Issue) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, i := range issues {
+		cw.Write([]string{
+			csvSafe(i.Title),
+			i.Repo.Owner + "/" + i.Repo.Name,
+			strings.Join(i.Languages, ", "),
+			csvSafe(strings.Join(sortedKeys(labelSet(i.Labels)), ", ")),
+			i.URL,
+			i.Date.Format("2006-01-02"),
+		})
+	}
+
+	cw.Flush()
+	return errors.Wrap(cw.Error(), "could not write csv")
+}
+
+// csvSafe stops text anyone on GitHub can write from being taken for a
+// formula when the file is opened in a spreadsheet.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsAny(s[:1], "=+-@") {
+		return "'" + s
+	}
+	return s
+}
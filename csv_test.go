@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeCSV(w, []Issue{{
+		Title:     `Say "hi", nicely`,
+		Repo:      Repo{Owner: "devict", Name: "hacktoberfest"},
+		Languages: []string{"Go", "HTML"},
+		Labels:    map[string]string{"ui": "ffffff", "bug": "ee0701"},
+		URL:       "https://github.com/devict/hacktoberfest/issues/1",
+		Date:      time.Date(2017, 10, 2, 12, 0, 0, 0, time.UTC),
+	}})
+
+	want := "title,repo,languages,labels,url,created\n" +
+		`"Say ""hi"", nicely",devict/hacktoberfest,"Go, HTML","bug, ui",https://github.com/devict/hacktoberfest/issues/1,2017-10-02` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("got content type %q", got)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"Fix it":            "Fix it",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"-1":                "'-1",
+	}
+	for in, want := range tests {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
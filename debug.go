@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// /debug/pprof has the runtime's profiles and /debug/vars how many goroutines
+// there are, how big the caches are and how many calls to GitHub are out, so
+// we can see what's piling up when the search fan-out leaks. They're only for
+// admins, or whoever has config.DebugToken as a bearer token, since profiles
+// show a lot about the server and can be slow to take.
+
+// githubCalls is how many requests to GitHub are waiting on an answer.
+var githubCalls int64
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+	expvar.Publish("github_calls_in_flight", expvar.Func(func() interface{} { return atomic.LoadInt64(&githubCalls) }))
+	expvar.Publish("issue_fetches_in_flight", expvar.Func(func() interface{} { return flights.size() }))
+	expvar.Publish("issue_cache_entries", expvar.Func(func() interface{} { return cache.size() }))
+	expvar.Publish("response_cache_entries", expvar.Func(func() interface{} { return responses.Size() }))
+	expvar.Publish("language_cache_entries", expvar.Func(func() interface{} { return languages.Size() }))
+}
+
+// debugAllowed reports whether r can see the debug endpoints.
+func debugAllowed(r *http.Request) bool {
+	if t := config.DebugToken; t != "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") && subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(t)) == 1 {
+			return true
+		}
+	}
+	_, ok := findAdmin(r)
+	return ok
+}
+
+// debugOnly serves h to the people debugAllowed lets in.
+func debugOnly(h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !debugAllowed(r) {
+			notAdmin(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDebugOnly(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	config.DebugToken = "sekrit"
+	oldAdmins := admins
+	defer func() { admins = oldAdmins }()
+	admins = map[string]bool{}
+
+	h := debugOnly(expvar.Handler())
+	tests := []struct {
+		name string
+		auth string
+		want int
+	}{
+		{"nobody", "", 403},
+		{"wrong token", "Bearer nope", 403},
+		{"token", "Bearer sekrit", 200},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/debug/vars", nil)
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != test.want {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code, test.want)
+		}
+	}
+
+	// Logged in people have to be admins
+	w := httptest.NewRecorder()
+	h(w, withCookie("GET", "/debug/vars", sessionCookie(t, store, time.Now())))
+	if w.Code != 403 {
+		t.Errorf("got status %d for someone who isn't an admin, want 403", w.Code)
+	}
+	admins["octocat"] = true
+	w = httptest.NewRecorder()
+	h(w, withCookie("GET", "/debug/vars", sessionCookie(t, store, time.Now())))
+	if w.Code != 200 {
+		t.Fatalf("got status %d for an admin, want 200", w.Code)
+	}
+
+	var vars map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&vars); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	for _, name := range []string{"goroutines", "github_calls_in_flight", "issue_fetches_in_flight", "issue_cache_entries", "response_cache_entries", "language_cache_entries"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("vars should have %s, got %v", name, vars)
+		}
+	}
+	if n, _ := vars["goroutines"].(float64); n < 1 {
+		t.Errorf("got %v goroutines, want at least 1", vars["goroutines"])
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// When an issue an admin expects isn't being listed it helps to see exactly
+// what we ask GitHub. /api/admin/diagnostics gives the search for each label
+// the standard listing makes, without making it, along with how many issues
+// each found and which failed the last time they were made.
+
+// maxSearchRuns is the most sets of searches we remember the last run of.
+// Listings with their own labels or dates are each a set, so there's no
+// telling how many there'll be.
+const maxSearchRuns = 100
+
+// searchRun is how the last run of one set of searches went.
+type searchRun struct {
+	finished time.Time
+
+	// found is how many issues each label's searches found, left out repos
+	// aside
+	found map[string]int
+
+	// failed are the searches that didn't finish, or err if none of them
+	// did
+	failed []aggregator.SearchError
+	err    error
+}
+
+// searchRuns are the last runs of the searches fetchIssues has made, by
+// searchParams.key.
+var searchRuns = &searchLog{runs: make(map[string]searchRun)}
+
+// searchLog remembers how searches went, safe to use from more than one
+// goroutine.
+type searchLog struct {
+	mu   sync.Mutex
+	runs map[string]searchRun
+}
+
+// record notes fetching issues for p just finished with err, forgetting the
+// set of searches that finished longest ago if there are too many.
+func (l *searchLog) record(p searchParams, issues []Issue, err error, now time.Time) {
+	run := searchRun{finished: now, found: make(map[string]int)}
+	for _, i := range issues {
+		for _, m := range i.MatchedLabels {
+			run.found[m]++
+		}
+	}
+	if e := incomplete(err); e != nil {
+		run.failed = e.Failed
+	} else {
+		run.err = err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runs[p.key()] = run
+	if len(l.runs) <= maxSearchRuns {
+		return
+	}
+	var oldest string
+	for k, r := range l.runs {
+		if oldest == "" || r.finished.Before(l.runs[oldest].finished) {
+			oldest = k
+		}
+	}
+	delete(l.runs, oldest)
+}
+
+// last gives how the searches for p went the last time they were made.
+func (l *searchLog) last(p searchParams) (searchRun, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.runs[p.key()]
+	return r, ok
+}
+
+// searchDiagnostics is what /api/admin/diagnostics gives back.
+type searchDiagnostics struct {
+	Scope string `json:"scope"`
+
+	// LastRun is when the searches last finished, null if they haven't been
+	// made since we started
+	LastRun *time.Time `json:"last_run"`
+
+	// Error is why every search failed the last time, if they all did
+	Error string `json:"error,omitempty"`
+
+	Searches []labelDiagnostics `json:"searches"`
+}
+
+// labelDiagnostics is the search for one label.
+type labelDiagnostics struct {
+	Label string `json:"label"`
+	Query string `json:"query"`
+
+	// Found is how many issues it found last time, null if it hasn't been
+	// made
+	Found *int `json:"found"`
+
+	// Errors are the sources it failed in last time
+	Errors []searchWarning `json:"errors"`
+}
+
+// diagnose describes the searches for p, and how they went in run if it's
+// not nil.
+func diagnose(p searchParams, run *searchRun) searchDiagnostics {
+	d := searchDiagnostics{Scope: p.scope, Searches: []labelDiagnostics{}}
+	if run != nil {
+		finished := run.finished
+		d.LastRun = &finished
+		if run.err != nil {
+			d.Error = run.err.Error()
+		}
+	}
+
+	for _, l := range p.labels {
+		ld := labelDiagnostics{
+			Label:  l,
+			Query:  aggregator.SearchQuery(l, p.scope, p.created, p.updated),
+			Errors: []searchWarning{},
+		}
+		if run != nil && run.err == nil {
+			found := run.found[l]
+			ld.Found = &found
+			for _, f := range run.failed {
+				if f.Label == l {
+					ld.Errors = append(ld.Errors, searchWarning{Source: f.Source, Label: f.Label, Error: f.Err.Error()})
+				}
+			}
+		}
+		d.Searches = append(d.Searches, ld)
+	}
+	return d
+}
+
+// adminDiagnostics gives the searches the standard listing makes, shaped by
+// the same max_langs, labels, max_age and since query parameters, and how they went the
+// last time they were made. Nothing is searched for.
+func adminDiagnostics(w http.ResponseWriter, r *http.Request) {
+	p, err := parseParams(r, defaultParams(serverClient().Scope()))
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	var run *searchRun
+	if last, ok := searchRuns.last(p); ok {
+		run = &last
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(diagnose(p, run)); err != nil {
+		logError(r.Context(), err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestDiagnose(t *testing.T) {
+	p := searchParams{scope: "org:devict", labels: []string{"hacktoberfest", "help wanted"}, created: time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)}
+
+	d := diagnose(p, nil)
+	if d.LastRun != nil || len(d.Searches) != 2 {
+		t.Fatalf("got %+v before any run", d)
+	}
+	if want := `is:open type:issue label:"help wanted" org:devict created:>=2017-10-01`; d.Searches[1].Query != want {
+		t.Errorf("got query %q, want %q", d.Searches[1].Query, want)
+	}
+	if d.Searches[0].Found != nil {
+		t.Errorf("found should be null before the searches are made")
+	}
+
+	log := &searchLog{runs: make(map[string]searchRun)}
+	issues := []Issue{
+		{URL: "a", MatchedLabels: []string{"hacktoberfest", "help wanted"}},
+		{URL: "b", MatchedLabels: []string{"hacktoberfest"}},
+	}
+	failed := &incompleteError{Failed: []aggregator.SearchError{{Source: "gitlab", Label: "help wanted", Err: errors.New("timed out")}}}
+	now := time.Now()
+	log.record(p, issues, failed, now)
+
+	run, ok := log.last(p)
+	if !ok {
+		t.Fatal("the run should have been recorded")
+	}
+	d = diagnose(p, &run)
+	if d.LastRun == nil || !d.LastRun.Equal(now) {
+		t.Errorf("got last run %v, want %v", d.LastRun, now)
+	}
+	for n, want := range []int{2, 1} {
+		if s := d.Searches[n]; s.Found == nil || *s.Found != want {
+			t.Errorf("%s: got found %v, want %d", s.Label, s.Found, want)
+		}
+	}
+	if e := d.Searches[1].Errors; len(e) != 1 || e[0].Source != "gitlab" || len(d.Searches[0].Errors) != 0 {
+		t.Errorf("got errors %+v and %+v", d.Searches[0].Errors, e)
+	}
+
+	log.record(p, nil, errors.New("bad credentials"), now)
+	run, _ = log.last(p)
+	if d = diagnose(p, &run); d.Error != "bad credentials" || d.Searches[0].Found != nil {
+		t.Errorf("got %+v when every search failed", d)
+	}
+}
+
+func TestSearchLogForgets(t *testing.T) {
+	log := &searchLog{runs: make(map[string]searchRun)}
+	start := time.Now()
+	for n := 0; n <= maxSearchRuns; n++ {
+		log.record(searchParams{scope: "org:devict", maxLangs: n}, nil, nil, start.Add(time.Duration(n)*time.Second))
+	}
+	if len(log.runs) != maxSearchRuns {
+		t.Errorf("got %d runs, want %d", len(log.runs), maxSearchRuns)
+	}
+	if _, ok := log.last(searchParams{scope: "org:devict"}); ok {
+		t.Errorf("the run that finished first should have been forgotten")
+	}
+}
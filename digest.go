@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// People who are logged in can sign up for an email, daily or weekly, of the
+// issues opened since the last one in the languages they've said they prefer.
+// Subscriptions are a row each in digests. Mail goes out through the SMTP
+// server at config.SMTPAddr, as config.DigestFrom, and digests are off unless
+// both are set.
+
+// digestsEnabled reports whether we can send digests.
+func digestsEnabled() bool {
+	return config.SMTPAddr != "" && config.DigestFrom != ""
+}
+
+// digestPeriods are how often each frequency of digest goes out.
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// digestInterval is how often we check for digests that are due.
+const digestInterval = 15 * time.Minute
+
+// digestMax is the most issues one digest has in it.
+const digestMax = 25
+
+// Digest is someone's digest subscription, as they see it. An empty Frequency
+// means they don't get one.
+type Digest struct {
+	Frequency string `json:"frequency"`
+	Email     string `json:"email"`
+}
+
+// subscriber is someone who gets digests.
+type subscriber struct {
+	userID    string
+	email     string
+	frequency string
+	token     string
+	languages []string
+
+	// lastSent is when they were last sent one, zero if never
+	lastSent time.Time
+}
+
+// due reports whether s should get a digest at now.
+func (s subscriber) due(now time.Time) bool {
+	return s.lastSent.IsZero() || now.Sub(s.lastSent) >= digestPeriods[s.frequency]
+}
+
+// subscribers gives everyone who gets digests, with the languages they
+// prefer.
+func subscribers() ([]subscriber, error) {
+	rows, err := db.Query(
+		`SELECT d.user_id, d.email, d.frequency, d.token, d.last_sent, p.data
+		FROM digests d LEFT JOIN preferences p ON p.user_id = d.user_id`,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query digests")
+	}
+	defer rows.Close()
+
+	var subs []subscriber
+	for rows.Next() {
+		var s subscriber
+		var lastSent pq.NullTime
+		var data []byte
+		if err := rows.Scan(&s.userID, &s.email, &s.frequency, &s.token, &lastSent, &data); err != nil {
+			return nil, errors.Wrap(err, "could not scan digest")
+		}
+		s.lastSent = lastSent.Time
+
+		if data != nil {
+			var p preferences
+			if err := json.Unmarshal(data, &p); err != nil {
+				return nil, errors.Wrap(err, "could not decode preferences")
+			}
+			s.languages = p.Languages
+		}
+		subs = append(subs, s)
+	}
+	return subs, errors.Wrap(rows.Err(), "could not iterate over digests")
+}
+
+// digestIssues picks the issues for s's digest at now: the newest ones opened
+// since their last, or in the last period if they've never had one, in the
+// languages they prefer if they've said.
+func digestIssues(issues []Issue, s subscriber, now time.Time) []Issue {
+	since := s.lastSent
+	if since.IsZero() {
+		since = now.Add(-digestPeriods[s.frequency])
+	}
+
+	var f issueFilter
+	for _, l := range s.languages {
+		f.langs = append(f.langs, strings.ToLower(l))
+	}
+
+	picked := []Issue{}
+	for _, i := range issues {
+		if i.Date.After(since) && f.keep(i) {
+			picked = append(picked, i)
+		}
+	}
+	sort.SliceStable(picked, func(a, b int) bool { return picked[a].Date.After(picked[b].Date) })
+	if len(picked) > digestMax {
+		picked = picked[:digestMax]
+	}
+	return picked
+}
+
+// digestData is what the digest templates are given.
+type digestData struct {
+	Frequency   string
+	Issues      []Issue
+	Site        string
+	Unsubscribe string
+}
+
+var digestText = template.Must(template.New("digest").Parse(`Here are the newest issues looking for help{{ if eq .Frequency "daily" }} today{{ else }} this week{{ end }}:
+{{ range .Issues }}
+* {{ .Title }} ({{ .Repo.Owner }}/{{ .Repo.Name }}{{ if .Languages }}, {{ index .Languages 0 }}{{ end }})
+  {{ .URL }}
+{{ end }}
+Find more at {{ .Site }}
+
+You're getting this because you signed up for a {{ .Frequency }} digest.
+Unsubscribe: {{ .Unsubscribe }}
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<p>Here are the newest issues looking for help{{ if eq .Frequency "daily" }} today{{ else }} this week{{ end }}:</p>
+<ul>
+{{ range .Issues }}  <li><a href="{{ .URL }}">{{ .Title }}</a> in {{ .Repo.Owner }}/{{ .Repo.Name }}{{ if .Languages }} ({{ index .Languages 0 }}){{ end }}</li>
+{{ end }}</ul>
+<p>Find more at <a href="{{ .Site }}">{{ .Site }}</a></p>
+<p><small>You're getting this because you signed up for a {{ .Frequency }} digest. <a href="{{ .Unsubscribe }}">Unsubscribe</a></small></p>
+</body>
+</html>
+`))
+
+// digestMessage builds the email for s's digest of issues, with plain text
+// and HTML versions and a way to unsubscribe in one click.
+func digestMessage(s subscriber, issues []Issue) ([]byte, error) {
+	data := digestData{
+		Frequency:   s.frequency,
+		Issues:      issues,
+		Site:        config.SiteURL,
+		Unsubscribe: config.SiteURL + "/digest/unsubscribe?token=" + s.token,
+	}
+
+	var msg bytes.Buffer
+	body := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "From: %s\r\n", config.DigestFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", s.email)
+	fmt.Fprintf(&msg, "Subject: %d new Hacktoberfest issues\r\n", len(issues))
+	fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", data.Unsubscribe)
+	fmt.Fprintf(&msg, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	parts := []struct {
+		contentType string
+		execute     func(*quotedprintable.Writer) error
+	}{
+		{"text/plain", func(w *quotedprintable.Writer) error { return digestText.Execute(w, data) }},
+		{"text/html", func(w *quotedprintable.Writer) error { return digestHTML.Execute(w, data) }},
+	}
+	for _, p := range parts {
+		pw, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not start part")
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if err := p.execute(qw); err != nil {
+			return nil, errors.Wrapf(err, "could not render %s digest", p.contentType)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, errors.Wrap(err, "could not encode digest")
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not finish digest")
+	}
+	return msg.Bytes(), nil
+}
+
+// sendMail sends msg to to through config.SMTPAddr.
+func sendMail(to string, msg []byte) error {
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		host := strings.Split(config.SMTPAddr, ":")[0]
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, host)
+	}
+	return errors.Wrap(smtp.SendMail(config.SMTPAddr, auth, config.DigestFrom, []string{to}, msg), "could not send mail")
+}
+
+// sendDigests sends everyone whose digest is due what's new for them every
+// interval, until ctx is done. Issues come from the standard listing, so it
+// needs the server's credentials.
+func sendDigests(ctx context.Context, interval time.Duration) {
+	send := func() {
+		c := serverClient()
+		issues, err := loadIssues(ctx, c, defaultParams(c.Scope()), false)
+		if !servable(err) {
+			logError(ctx, err)
+			return
+		}
+
+		subs, err := subscribers()
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		now := time.Now()
+		sent := 0
+		for _, s := range subs {
+			if ctx.Err() != nil {
+				return
+			}
+			if !s.due(now) {
+				continue
+			}
+
+			// Nothing new still counts as their digest so the next one only
+			// has what's new since now
+			if picked := digestIssues(issues, s, now); len(picked) > 0 {
+				msg, err := digestMessage(s, picked)
+				if err == nil {
+					err = sendMail(s.email, msg)
+				}
+				if err != nil {
+					logError(ctx, err, "user", s.userID)
+					continue
+				}
+				sent++
+			}
+
+			if _, err := db.Exec("UPDATE digests SET last_sent = $1 WHERE user_id = $2", now, s.userID); err != nil {
+				logError(ctx, errors.Wrap(err, "could not update digest"), "user", s.userID)
+			}
+		}
+		if sent > 0 {
+			logInfo(ctx, "sent digests", "digests", sent)
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	send()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			send()
+		}
+	}
+}
+
+// getDigest gives the digest subscription of whoever is logged in.
+func getDigest(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var d Digest
+	err := db.QueryRow("SELECT frequency, email FROM digests WHERE user_id = $1", u.UserID).Scan(&d.Frequency, &d.Email)
+	if err != nil && err != sql.ErrNoRows {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// updateDigest signs whoever is logged in up for a daily or weekly digest,
+// sent to the email address GitHub gave us, or stops it with an empty
+// frequency.
+func updateDigest(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var d Digest
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+
+	if d.Frequency == "" {
+		if _, err := db.Exec("DELETE FROM digests WHERE user_id = $1", u.UserID); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if _, ok := digestPeriods[d.Frequency]; !ok {
+		invalidRequest(w, r, fmt.Sprintf("frequency %q should be daily or weekly", d.Frequency))
+		return
+	}
+	if !digestsEnabled() {
+		writeError(w, r, http.StatusBadRequest, codeDisabled, "digests aren't turned on", nil)
+		return
+	}
+	if u.Email == "" {
+		invalidRequest(w, r, "GitHub didn't give us an email address for you")
+		return
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	// Changing how often keeps the unsubscribe links already sent working
+	_, err := db.Exec(
+		`INSERT INTO digests (user_id, email, frequency, token, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET email = $2, frequency = $3`,
+		u.UserID,
+		u.Email,
+		d.Frequency,
+		hex.EncodeToString(token),
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unsubscribeDigest stops the digest whose token is given, for the links in
+// emails. It doesn't need anyone to be logged in. Mail clients that
+// unsubscribe in one click POST to it.
+func unsubscribeDigest(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM digests WHERE token = $1", token); err != nil {
+		logError(r.Context(), err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("You won't get any more digests.\n"))
+}
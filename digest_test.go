@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscriberDue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		s    subscriber
+		want bool
+	}{
+		{subscriber{frequency: "daily"}, true},
+		{subscriber{frequency: "daily", lastSent: now.Add(-25 * time.Hour)}, true},
+		{subscriber{frequency: "daily", lastSent: now.Add(-time.Hour)}, false},
+		{subscriber{frequency: "weekly", lastSent: now.Add(-25 * time.Hour)}, false},
+		{subscriber{frequency: "weekly", lastSent: now.Add(-8 * 24 * time.Hour)}, true},
+	}
+
+	for _, test := range tests {
+		if got := test.s.due(now); got != test.want {
+			t.Errorf("%s sent %v: got %v, want %v", test.s.frequency, test.s.lastSent, got, test.want)
+		}
+	}
+}
+
+func TestDigestIssues(t *testing.T) {
+	now := time.Now()
+	issues := []Issue{
+		{Title: "old", Date: now.Add(-48 * time.Hour), Languages: []string{"Go"}},
+		{Title: "new go", Date: now.Add(-2 * time.Hour), Languages: []string{"Go"}},
+		{Title: "newer python", Date: now.Add(-time.Hour), Languages: []string{"Python"}},
+		{Title: "newest go", Date: now.Add(-time.Minute), Languages: []string{"JavaScript", "Go"}},
+	}
+
+	tests := []struct {
+		s    subscriber
+		want []string
+	}{
+		{subscriber{frequency: "daily"}, []string{"newest go", "newer python", "new go"}},
+		{subscriber{frequency: "daily", languages: []string{"go"}}, []string{"newest go", "new go"}},
+		{subscriber{frequency: "daily", lastSent: now.Add(-90 * time.Minute)}, []string{"newest go", "newer python"}},
+		{subscriber{frequency: "weekly", languages: []string{"Go"}}, []string{"newest go", "new go", "old"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, i := range digestIssues(issues, test.s, now) {
+			got = append(got, i.Title)
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%+v: got %v, want %v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestDigestMessage(t *testing.T) {
+	s := subscriber{email: "someone@example.com", frequency: "weekly", token: "abc123"}
+	issues := []Issue{{
+		Title:     "Fix <b>it</b>",
+		URL:       "https://github.com/devict/hacktoberfest/issues/1",
+		Repo:      Repo{Owner: "devict", Name: "hacktoberfest"},
+		Languages: []string{"Go"},
+	}}
+
+	data, err := digestMessage(s, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("To"); got != s.email {
+		t.Errorf("got To %q, want %q", got, s.email)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); !strings.Contains(got, "/digest/unsubscribe?token=abc123") {
+		t.Errorf("got List-Unsubscribe %q", got)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	want := map[string]string{
+		"text/plain; charset=utf-8": "* Fix <b>it</b> (devict/hacktoberfest, Go)",
+		"text/html; charset=utf-8":  `<a href="https://github.com/devict/hacktoberfest/issues/1">Fix &lt;b&gt;it&lt;/b&gt;</a>`,
+	}
+	for {
+		p, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		// Parts are quoted-printable, which NextPart decodes for us
+		body, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ct := p.Header.Get("Content-Type")
+		if !strings.Contains(string(body), want[ct]) {
+			t.Errorf("%s: got %s, want it to contain %s", ct, body, want[ct])
+		}
+		delete(want, ct)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing parts %v", want)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Our own issue responses get an ETag too, so a client asking again with
+// If-None-Match gets a 304 instead of the same listing over again. They're
+// weak since the same listing can go out gzipped or not.
+
+// issueMaxAge is how long anyone who isn't logged in can reuse an issue
+// response without asking again. Logged in people's are personalized, so
+// they're private and always checked.
+const issueMaxAge = time.Minute
+
+// cacheable gives what h writes an ETag and Cache-Control, answering with a
+// 304 if the request already has it. Streamed issues go straight through
+// since holding them back is what streaming them is meant to avoid.
+func cacheable(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wantsNDJSON(r) {
+			h(w, r)
+			return
+		}
+		bw := &bufferedWriter{ResponseWriter: w}
+		h(bw, r)
+		if bw.status == 0 {
+			bw.status = http.StatusOK
+		}
+		if bw.status != http.StatusOK {
+			w.WriteHeader(bw.status)
+			w.Write(bw.buf.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(bw.buf.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Add("Vary", "Cookie")
+		if _, _, ok := findUser(r); ok {
+			w.Header().Set("Cache-Control", "private, no-cache")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(issueMaxAge/time.Second)))
+		}
+
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(bw.status)
+		w.Write(bw.buf.Bytes())
+	}
+}
+
+// etagMatch reports whether an If-None-Match header has etag in it, comparing
+// them weakly.
+func etagMatch(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds a response back instead of sending it.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheable(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+
+	body := `{"data": []}`
+	h := cacheable(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/api/v1/issues", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != body || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("got %d %q with ETag %q, want the body with a weak ETag", w.Code, w.Body, etag)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("got Cache-Control %q for someone logged out", cc)
+	}
+
+	r := httptest.NewRequest("GET", "/api/v1/issues", nil)
+	r.Header.Set("If-None-Match", `"other", `+strings.TrimPrefix(etag, "W/"))
+	w = httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("got %d %q, want a 304 for the ETag they have", w.Code, w.Body)
+	}
+
+	body = `{"data": [1]}`
+	w = httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("got %d with ETag %q, want the new body once it changes", w.Code, w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	h(w, withCookie("GET", "/api/v1/issues", sessionCookie(t, store, time.Now())))
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("got Cache-Control %q for someone logged in", cc)
+	}
+
+	// Streams aren't held back to work out an ETag
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/api/v1/issues?format=ndjson", nil))
+	if w.Header().Get("ETag") != "" || w.Body.String() != body {
+		t.Errorf("got %q with ETag %q, want the stream as it was", w.Body, w.Header().Get("ETag"))
+	}
+
+	// Errors aren't cached
+	h = cacheable(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusBadGateway, codeUpstream, "down", nil)
+	})
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/api/v1/issues", nil))
+	if w.Code != http.StatusBadGateway || w.Header().Get("ETag") != "" {
+		t.Errorf("got %d with ETag %q, want the error as it was", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The same server can list issues for fests other than Hacktoberfest, like
+// 24 Pull Requests or a sprint getting ready for Summer of Code, by giving
+// them as events in the tracking file. Each is served under
+// /events/{slug}/issues the same way /api/issues is, searching the event's
+// own repos and labels, or ours when it doesn't have any, and going by its
+// topics for which repos are Participating. Hacktoberfest itself is always the
+// hacktoberfest event.
+
+// hacktoberfestSlug is the event that's Hacktoberfest.
+const hacktoberfestSlug = "hacktoberfest"
+
+// Event is a fest we list issues for, running from Start to End, both days
+// in UTC.
+type Event struct {
+	Slug  string
+	Name  string
+	Start time.Time
+	End   time.Time
+
+	// Labels, Orgs and Projects are what to search for, like in Tracking.
+	// Empty means use the Tracking's.
+	Labels   map[string]bool
+	Orgs     map[string]bool
+	Projects map[string]bool
+
+	// Topics are what a repo needs one of for pull requests to it to count.
+	// Nil means the Hacktoberfest topic.
+	Topics []string
+}
+
+// eventFile is an Event as it's given in the tracking file, and how events
+// are listed, like
+//
+//	{
+//	  "slug": "24-pull-requests",
+//	  "name": "24 Pull Requests",
+//	  "start": "2017-12-01",
+//	  "end": "2017-12-24",
+//	  "labels": ["24 pull requests"],
+//	  "topics": ["24pullrequests"],
+//	  "orgs": ["devict"],
+//	  "projects": ["br0xen/boltbrowser"]
+//	}
+type eventFile struct {
+	Slug     string   `json:"slug"`
+	Name     string   `json:"name"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Labels   []string `json:"labels"`
+	Topics   []string `json:"topics"`
+	Orgs     []string `json:"orgs"`
+	Projects []string `json:"projects"`
+
+	// Active is only in listings, saying whether the event is on now
+	Active bool `json:"active"`
+}
+
+// reSlug matches what an event's slug can be.
+var reSlug = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
+// parseEvents checks the events from the tracking file and turns them into
+// Events.
+func parseEvents(files []eventFile) ([]Event, error) {
+	var events []Event
+	seen := map[string]bool{hacktoberfestSlug: true}
+	for _, f := range files {
+		if !reSlug.MatchString(f.Slug) {
+			return nil, errors.Errorf("event slug %q should be lowercase letters and numbers split up by dashes", f.Slug)
+		}
+		if seen[f.Slug] {
+			return nil, errors.Errorf("there's already an event called %q", f.Slug)
+		}
+		seen[f.Slug] = true
+
+		e := Event{
+			Slug:     f.Slug,
+			Name:     f.Name,
+			Labels:   set(f.Labels),
+			Orgs:     set(f.Orgs),
+			Projects: set(f.Projects),
+			Topics:   f.Topics,
+		}
+		if e.Name == "" {
+			e.Name = e.Slug
+		}
+		var err error
+		if e.Start, err = time.Parse(dateFormat, f.Start); err != nil {
+			return nil, errors.Errorf("event %q start %q should be a day like 2017-12-01", f.Slug, f.Start)
+		}
+		if e.End, err = time.Parse(dateFormat, f.End); err != nil {
+			return nil, errors.Errorf("event %q end %q should be a day like 2017-12-24", f.Slug, f.End)
+		}
+		if e.End.Before(e.Start) {
+			return nil, errors.Errorf("event %q ends before it starts", f.Slug)
+		}
+		for p := range e.Projects {
+			if strings.Count(p, "/") != 1 {
+				return nil, errors.Errorf("event %q project %q should look like owner/name", f.Slug, p)
+			}
+		}
+		if e.Topics == nil {
+			// Nil would mean the Hacktoberfest topic
+			e.Topics = []string{}
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// hacktoberfestEvent is Hacktoberfest in year, searching everything we track.
+func hacktoberfestEvent(year int) Event {
+	return Event{
+		Slug:  hacktoberfestSlug,
+		Name:  "Hacktoberfest",
+		Start: time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(year, time.October, 31, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// events are Hacktoberfest at now and then everything in t.Events.
+func events(t Tracking, now time.Time) []Event {
+	return append([]Event{hacktoberfestEvent(now.Year())}, t.Events...)
+}
+
+// findEvent gives the event with slug at now.
+func findEvent(slug string, now time.Time) (Event, bool) {
+	for _, e := range events(tracking(), now) {
+		if e.Slug == slug {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+// active reports whether e is on at now, anywhere in the world.
+func (e Event) active(now time.Time) bool {
+	start := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, time.FixedZone("", 14*60*60))
+	end := time.Date(e.End.Year(), e.End.Month(), e.End.Day()+1, 0, 0, 0, 0, time.FixedZone("", -12*60*60))
+	return !now.Before(start) && now.Before(end)
+}
+
+// client narrows c down to e's repos, if it has its own. Those are all on
+// GitHub so c's other sources are left out.
+func (e Event) client(c *Client) *Client {
+	if len(e.Orgs) == 0 && len(e.Projects) == 0 {
+		return c
+	}
+	c.Orgs, c.Projects = e.Orgs, e.Projects
+	c.Others = nil
+	return c
+}
+
+// params are the searchParams for e's issues with c, which e.client made.
+func (e Event) params(c *Client) searchParams {
+	p := defaultParams(c.Scope())
+	if len(e.Labels) > 0 {
+		p.labels = sortedKeys(e.Labels)
+	}
+	p.topics = e.Topics
+	return p
+}
+
+// file is e as it's listed at now.
+func (e Event) file(now time.Time) eventFile {
+	return eventFile{
+		Slug:     e.Slug,
+		Name:     e.Name,
+		Start:    e.Start.Format(dateFormat),
+		End:      e.End.Format(dateFormat),
+		Labels:   sortedKeys(e.Labels),
+		Topics:   e.Topics,
+		Orgs:     sortedKeys(e.Orgs),
+		Projects: sortedKeys(e.Projects),
+		Active:   e.active(now),
+	}
+}
+
+// listEvents gives every event we list issues for.
+func listEvents(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	files := []eventFile{}
+	for _, e := range events(tracking(), now) {
+		files = append(files, e.file(now))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(files); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// eventIssues lists the issues for the event given as /events/{slug}, taking
+// the same query parameters as /api/issues.
+func eventIssues(w http.ResponseWriter, r *http.Request) {
+	e, ok := findEvent(r.URL.Query().Get(":slug"), time.Now())
+	if !ok {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "there's no event "+strconv.Quote(r.URL.Query().Get(":slug")), nil)
+		return
+	}
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+
+	c = e.client(c)
+	listIssues(w, r, c, e.params(c))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEvents(t *testing.T) {
+	events, err := parseEvents([]eventFile{{
+		Slug:     "24-pull-requests",
+		Name:     "24 Pull Requests",
+		Start:    "2017-12-01",
+		End:      "2017-12-24",
+		Labels:   []string{"24 pull requests"},
+		Topics:   []string{"24pullrequests"},
+		Projects: []string{"br0xen/boltbrowser"},
+	}, {
+		Slug:  "gsoc-prep",
+		Start: "2018-03-01",
+		End:   "2018-03-01",
+	}})
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	want := []Event{{
+		Slug:     "24-pull-requests",
+		Name:     "24 Pull Requests",
+		Start:    time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2017, 12, 24, 0, 0, 0, 0, time.UTC),
+		Labels:   map[string]bool{"24 pull requests": true},
+		Orgs:     map[string]bool{},
+		Projects: map[string]bool{"br0xen/boltbrowser": true},
+		Topics:   []string{"24pullrequests"},
+	}, {
+		Slug:     "gsoc-prep",
+		Name:     "gsoc-prep",
+		Start:    time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+		Labels:   map[string]bool{},
+		Orgs:     map[string]bool{},
+		Projects: map[string]bool{},
+		Topics:   []string{},
+	}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got %+v", events)
+		t.Errorf("want %+v", want)
+	}
+}
+
+func TestParseEventsInvalid(t *testing.T) {
+	ok := eventFile{Slug: "fest", Start: "2017-12-01", End: "2017-12-24"}
+	tests := map[string][]eventFile{
+		"bad slug":      {{Slug: "Big Fest", Start: ok.Start, End: ok.End}},
+		"hacktoberfest": {{Slug: "hacktoberfest", Start: ok.Start, End: ok.End}},
+		"duplicate":     {ok, ok},
+		"no start":      {{Slug: "fest", End: ok.End}},
+		"backwards":     {{Slug: "fest", Start: ok.End, End: ok.Start}},
+		"bad project":   {{Slug: "fest", Start: ok.Start, End: ok.End, Projects: []string{"boltbrowser"}}},
+	}
+	for name, files := range tests {
+		if _, err := parseEvents(files); err == nil {
+			t.Errorf("%s: error should not be nil, but it was", name)
+		}
+	}
+}
+
+func TestEventActive(t *testing.T) {
+	e := hacktoberfestEvent(2017)
+	tests := map[time.Time]bool{
+		time.Date(2017, 9, 30, 9, 59, 0, 0, time.UTC):  false,
+		time.Date(2017, 9, 30, 10, 0, 0, 0, time.UTC):  true,
+		time.Date(2017, 10, 14, 0, 0, 0, 0, time.UTC):  true,
+		time.Date(2017, 11, 1, 11, 59, 0, 0, time.UTC): true,
+		time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC):  false,
+	}
+	for now, want := range tests {
+		if got := e.active(now); got != want {
+			t.Errorf("active at %v = %v, want %v", now, got, want)
+		}
+	}
+}
+
+func TestEventParams(t *testing.T) {
+	c := &Client{Orgs: map[string]bool{"devict": true}, Others: []IssueSource{fakeSource{}}}
+	c = hacktoberfestEvent(2017).client(c)
+	if p := hacktoberfestEvent(2017).params(c); !reflect.DeepEqual(p, defaultParams("org:devict")) {
+		t.Errorf("Hacktoberfest should search like always, got %+v", p)
+	}
+	if len(c.Others) != 1 {
+		t.Errorf("Hacktoberfest should keep the other sources")
+	}
+
+	e := Event{
+		Labels:   map[string]bool{"sprint": true},
+		Projects: map[string]bool{"br0xen/boltbrowser": true},
+		Topics:   []string{"sprint"},
+	}
+	c = e.client(c)
+	p := e.params(c)
+	if p.scope != "repo:br0xen/boltbrowser" || !reflect.DeepEqual(p.labels, []string{"sprint"}) || !reflect.DeepEqual(p.topics, []string{"sprint"}) {
+		t.Errorf("got %+v", p)
+	}
+	if c.Others != nil {
+		t.Errorf("an event with its own repos should only search GitHub")
+	}
+	if p.key() == defaultParams(p.scope).key() {
+		t.Errorf("events going by different topics should be cached apart")
+	}
+}
+
+func TestSearchIssuesTopics(t *testing.T) {
+	srcs := []IssueSource{fakeSource{
+		{URL: "https://github.com/a/b/issues/1", Repo: Repo{Owner: "a", Name: "b", Topics: []string{"hacktoberfest"}}, Participating: true},
+		{URL: "https://github.com/a/c/issues/1", Repo: Repo{Owner: "a", Name: "c", Topics: []string{"Sprint"}}},
+	}}
+	p := searchParams{labels: []string{"sprint"}, topics: []string{"sprint"}}
+
+	issues, err := fetchIssues(context.Background(), srcs, p)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	for _, i := range issues {
+		if want := i.Repo.Name == "c"; i.Participating != want {
+			t.Errorf("%s: got participating %v, want %v", i.URL, i.Participating, want)
+		}
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	old := tracking()
+	defer setTracking(old)
+	tr := old
+	tr.Events = []Event{{Slug: "fest", Name: "Fest", Start: time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2017, 12, 24, 0, 0, 0, 0, time.UTC), Topics: []string{}}}
+	setTracking(tr)
+
+	w := httptest.NewRecorder()
+	listEvents(w, httptest.NewRequest("GET", "/events", nil))
+	var got []eventFile
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(got) != 2 || got[0].Slug != "hacktoberfest" || got[1].Slug != "fest" || got[1].Start != "2017-12-01" || got[1].Active {
+		t.Errorf("got %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	eventIssues(w, httptest.NewRequest("GET", "/events/nope/issues?:slug=nope", nil))
+	if w.Code != 404 || !strings.Contains(w.Body.String(), codeNotFound) {
+		t.Errorf("got %d %s for an event that doesn't exist, want 404", w.Code, w.Body)
+	}
+}
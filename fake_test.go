@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitHub is a GitHub that answers from the responses recorded in
+// testdata/github. A search for a label is search/<label>.json, with dashes
+// for spaces, and its later pages are <label>.2.json and so on, each linking
+// to the next. A repo's languages are languages/<owner>/<name>.json. Anything
+// it has no fixture for is a 404.
+type fakeGitHub struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	// fail answers searches for a label, or requests for a path like
+	// /repos/devict/site/languages, with that status instead
+	fail map[string]int
+
+	// requests counts what's been asked for, as "search <label> <page>" or
+	// "languages <owner>/<name>"
+	requests map[string]int
+}
+
+var reLabelQualifier = regexp.MustCompile(`label:"([^"]+)"`)
+
+// newFakeGitHub starts a fakeGitHub. Close it once the test is done.
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{fail: make(map[string]int), requests: make(map[string]int)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search/issues":
+			f.search(t, w, r)
+		case strings.HasPrefix(r.URL.Path, "/repos/") && strings.HasSuffix(r.URL.Path, "/languages"):
+			repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/repos/"), "/languages")
+			f.answer(w, "languages "+repo, r.URL.Path, filepath.Join("languages", repo+".json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return f
+}
+
+// search answers a search from its fixture, linking to the next page if
+// there's one recorded.
+func (f *fakeGitHub) search(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	m := reLabelQualifier.FindStringSubmatch(q.Get("q"))
+	if m == nil {
+		t.Errorf("search %q isn't for a label", q.Get("q"))
+		http.Error(w, "no label", http.StatusUnprocessableEntity)
+		return
+	}
+	label := m[1]
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	name := strings.Replace(label, " ", "-", -1)
+	fixture := func(page int) string {
+		if page == 1 {
+			return filepath.Join("search", name+".json")
+		}
+		return filepath.Join("search", name+"."+strconv.Itoa(page)+".json")
+	}
+
+	if _, err := ioutil.ReadFile(filepath.Join("testdata", "github", fixture(page+1))); err == nil {
+		q.Set("page", strconv.Itoa(page+1))
+		next := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}
+		w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+	}
+	f.answer(w, "search "+label+" "+strconv.Itoa(page), label, fixture(page))
+}
+
+// answer counts the request as what and writes fixture, unless key is meant
+// to fail.
+func (f *fakeGitHub) answer(w http.ResponseWriter, what, key, fixture string) {
+	f.mu.Lock()
+	f.requests[what]++
+	status := f.fail[key]
+	f.mu.Unlock()
+
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "github", fixture))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// failWith makes requests for key, a label or a path, answer with status.
+func (f *fakeGitHub) failWith(key string, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail[key] = status
+}
+
+// count is how many times what has been asked for.
+func (f *fakeGitHub) count(what string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[what]
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// feedEntries is how many of the newest issues go in the feed.
+const feedEntries = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated time.Time   `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  time.Time      `xml:"published"`
+	Updated    time.Time      `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// issueFeed is an Atom feed of the newest tracked issues. Feed readers can't
+// log in so it's fetched with the server's PAT, or anonymously without one.
+// It takes the same filters and quotas as issues, so /issues.atom?lang=Go is
+// a feed of just Go issues.
+func issueFeed(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c := sharedClient()
+	p := defaultParams(c.Scope())
+	q, err := parseQuota(r, p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issues, err := loadIssues(r.Context(), c, p, false)
+	if !servable(err) {
+		logError(r.Context(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	issues = f.apply(issues)
+	issueOrder{by: "created", desc: true}.sort(issues)
+	issues = q.apply(issues)
+	if len(issues) > feedEntries {
+		issues = issues[:feedEntries]
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(buildFeed(requestURL(r), issues)); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// buildFeed makes the feed found at self out of issues, which should be
+// newest first.
+func buildFeed(self string, issues []Issue) atomFeed {
+	feed := atomFeed{
+		Title:   "Hacktoberfest issues",
+		ID:      self,
+		Links:   []atomLink{{Href: self, Rel: "self"}},
+		Author:  atomPerson{Name: "devICT"},
+		Entries: []atomEntry{},
+	}
+
+	for _, i := range issues {
+		updated := i.Updated
+		if updated.IsZero() {
+			updated = i.Date
+		}
+		if updated.After(feed.Updated) {
+			feed.Updated = updated
+		}
+
+		repo := i.Repo.Owner + "/" + i.Repo.Name
+		e := atomEntry{
+			Title:     repo + ": " + i.Title,
+			ID:        i.URL,
+			Link:      atomLink{Href: i.URL, Rel: "alternate"},
+			Published: i.Date,
+			Updated:   updated,
+			Author:    atomPerson{Name: repo, URI: repoPage(i.URL)},
+			Summary:   i.Body,
+		}
+		for _, l := range i.Languages {
+			e.Categories = append(e.Categories, atomCategory{Term: l})
+		}
+		for _, l := range sortedKeys(labelSet(i.Labels)) {
+			e.Categories = append(e.Categories, atomCategory{Term: l})
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+
+	// A feed has to say when it was updated even if it's empty
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+
+	return feed
+}
+
+// repoPage guesses the web page of the repo an issue is in from the issue's
+// own page, which on every forge we search is under the repo's.
+func repoPage(issueURL string) string {
+	i := strings.Index(issueURL, "/issues/")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(issueURL[:i], "/-")
+}
+
+// labelSet is the names of labels.
+func labelSet(labels map[string]string) map[string]bool {
+	m := make(map[string]bool)
+	for name := range labels {
+		m[name] = true
+	}
+	return m
+}
+
+// requestURL rebuilds the absolute URL r was made to, trusting
+// X-Forwarded-Proto from the proxy we run behind.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildFeed(t *testing.T) {
+	created := time.Date(2017, 10, 2, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2017, 10, 5, 0, 0, 0, 0, time.UTC)
+	issues := []Issue{{
+		Title:     "Fix <it>",
+		URL:       "https://github.com/devict/hacktoberfest/issues/1",
+		Repo:      Repo{Owner: "devict", Name: "hacktoberfest"},
+		Date:      created,
+		Updated:   updated,
+		Languages: []string{"Go"},
+		Labels:    map[string]string{"bug": "ee0701"},
+		Body:      "It's broken",
+	}}
+
+	feed := buildFeed("http://example.com/issues.atom", issues)
+	if !feed.Updated.Equal(updated) {
+		t.Errorf("got updated %v, want %v", feed.Updated, updated)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.Title != "devict/hacktoberfest: Fix <it>" || e.ID != issues[0].URL || !e.Published.Equal(created) {
+		t.Errorf("got entry %+v", e)
+	}
+	if len(e.Categories) != 2 || e.Categories[0].Term != "Go" || e.Categories[1].Term != "bug" {
+		t.Errorf("got categories %v, want Go and bug", e.Categories)
+	}
+
+	out, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<title>devict/hacktoberfest: Fix &lt;it&gt;</title>`,
+		`<updated>2017-10-05T00:00:00Z</updated>`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("feed should contain %s, got %s", want, out)
+		}
+	}
+}
+
+func TestBuildFeedEmpty(t *testing.T) {
+	feed := buildFeed("http://example.com/issues.atom", nil)
+	if feed.Updated.IsZero() || len(feed.Entries) != 0 {
+		t.Errorf("empty feed should have an update time and no entries, got %+v", feed)
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/issues.atom?lang=Go", nil)
+	if got, want := requestURL(r), "http://example.com/issues.atom?lang=Go"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if got, want := requestURL(r), "https://example.com/issues.atom?lang=Go"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRepoPage(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/devict/hacktoberfest/issues/1": "https://github.com/devict/hacktoberfest",
+		"https://gitlab.com/wichita/site/-/issues/7":       "https://gitlab.com/wichita/site",
+		"https://example.com/somewhere":                    "",
+	}
+	for in, want := range tests {
+		if got := repoPage(in); got != want {
+			t.Errorf("repoPage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// issueFilter narrows down a list of issues after they've been fetched. The
+// zero value keeps everything.
+type issueFilter struct {
+	// langs are lower cased language names. Issues match if their repo has any
+	// of them in its top languages, or all of them if allLangs is set.
+	langs    []string
+	allLangs bool
+
+	// naturalLangs are lower cased spoken languages. Issues match if their
+	// repo is in any of them, see aggregator.Repo.NaturalLanguages.
+	naturalLangs []string
+
+	// topics keep only issues in repos with any of them, lower cased
+	topics []string
+
+	// difficulties are the tiers to keep, see difficulty. Empty means any.
+	difficulties map[string]bool
+
+	// kinds are the kinds of work to keep, see aggregator.Kind. Empty means
+	// any.
+	kinds map[string]bool
+
+	// archived keeps issues in archived repos, which nobody can contribute to
+	archived bool
+
+	// participating keeps only issues in repos taking part in Hacktoberfest
+	participating bool
+
+	// unassigned keeps only issues nobody is assigned on GitHub
+	unassigned bool
+
+	// mentored keeps only issues with a mentor available
+	mentored bool
+
+	// collapse keeps only the first issue of each set of copies, see
+	// aggregator.MarkDuplicates
+	collapse bool
+
+	// words are lower cased words that must all be in an issue's title or
+	// the start of its body
+	words []string
+
+	// excludedRepos are owners and owner/names to leave out, lower cased
+	excludedRepos map[string]bool
+
+	// excludedWords are lower cased words that leave an issue out if they're
+	// in its title, body, labels or languages
+	excludedWords []string
+
+	// matched are lower cased search labels. Issues match if any of them
+	// found the issue, see Issue.MatchedLabels
+	matched map[string]bool
+}
+
+// parseFilter builds an issueFilter from query parameters:
+//
+//	lang          comma separated languages, matched case insensitively
+//	lang_match    "any" (the default) or "all" of the languages must match
+//	natural_lang  comma separated spoken languages, matched case insensitively
+//	topic         comma separated repo topics, matched case insensitively
+//	difficulty    comma separated tiers: easy, medium or hard
+//	kind          comma separated kinds: docs, bug, feature, tests or translation
+//	archived      true to keep issues in archived repos
+//	participating true for only issues in repos taking part in Hacktoberfest
+//	unassigned    true for only issues nobody has claimed
+//	mentored      true for only issues with a mentor available
+//	duplicates    "show" (the default) or "collapse" to keep one of each set of copies
+//	q             words to look for in titles and bodies, all of which must match
+//	exclude_repo  comma separated owners or owner/names to leave out
+//	exclude       comma separated words to leave out issues mentioning
+//	matched       comma separated labels, one of whose searches found the issue
+func parseFilter(r *http.Request) (issueFilter, error) {
+	var f issueFilter
+	q := r.URL.Query()
+
+	for _, l := range strings.Split(q.Get("lang"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			f.langs = append(f.langs, strings.ToLower(l))
+		}
+	}
+
+	switch m := q.Get("lang_match"); m {
+	case "", "any":
+	case "all":
+		f.allLangs = true
+	default:
+		return issueFilter{}, fmt.Errorf("lang_match %q should be any or all", m)
+	}
+
+	for _, l := range strings.Split(q.Get("natural_lang"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			f.naturalLangs = append(f.naturalLangs, strings.ToLower(l))
+		}
+	}
+	for _, t := range strings.Split(q.Get("topic"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			f.topics = append(f.topics, strings.ToLower(t))
+		}
+	}
+
+	if v := q.Get("difficulty"); v != "" {
+		f.difficulties = set(strings.Split(strings.ToLower(v), ","))
+		for d := range f.difficulties {
+			if d != aggregator.Easy && d != aggregator.Medium && d != aggregator.Hard {
+				return issueFilter{}, fmt.Errorf("difficulty %q should be easy, medium or hard", d)
+			}
+		}
+	}
+
+	if v := q.Get("kind"); v != "" {
+		f.kinds = set(strings.Split(strings.ToLower(v), ","))
+		for k := range f.kinds {
+			if !aggregator.IsKind(k) {
+				return issueFilter{}, fmt.Errorf("kind %q should be docs, bug, feature, tests or translation", k)
+			}
+		}
+	}
+
+	archived, err := boolParam(r, "archived")
+	if err != nil {
+		return issueFilter{}, err
+	}
+	f.archived = archived
+
+	participating, err := boolParam(r, "participating")
+	if err != nil {
+		return issueFilter{}, err
+	}
+	f.participating = participating
+
+	unassigned, err := boolParam(r, "unassigned")
+	if err != nil {
+		return issueFilter{}, err
+	}
+	f.unassigned = unassigned
+
+	mentored, err := boolParam(r, "mentored")
+	if err != nil {
+		return issueFilter{}, err
+	}
+	f.mentored = mentored
+
+	switch d := q.Get("duplicates"); d {
+	case "", "show":
+	case "collapse":
+		f.collapse = true
+	default:
+		return issueFilter{}, fmt.Errorf("duplicates %q should be show or collapse", d)
+	}
+
+	f.words = strings.Fields(strings.ToLower(q.Get("q")))
+
+	if v := q.Get("exclude_repo"); v != "" {
+		f.excludedRepos = set(strings.Split(strings.ToLower(v), ","))
+		for r := range f.excludedRepos {
+			if strings.Count(r, "/") > 1 {
+				return issueFilter{}, fmt.Errorf("exclude_repo %q should look like owner or owner/name", r)
+			}
+		}
+	}
+	f.excludedWords = sortedKeys(set(strings.Split(strings.ToLower(q.Get("exclude")), ",")))
+
+	if v := q.Get("matched"); v != "" {
+		f.matched = set(strings.Split(strings.ToLower(v), ","))
+	}
+
+	return f, nil
+}
+
+// boolParam reads the query parameter name as true or false, false if it
+// isn't there.
+func boolParam(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s %q should be true or false", name, v)
+	}
+	return b, nil
+}
+
+// keep reports whether i passes the filter.
+func (f issueFilter) keep(i Issue) bool {
+	if len(f.langs) > 0 {
+		have := make(map[string]bool)
+		for _, l := range i.Languages {
+			have[strings.ToLower(l)] = true
+		}
+
+		var matched int
+		for _, l := range f.langs {
+			if have[l] {
+				matched++
+			}
+		}
+
+		if matched == 0 || (f.allLangs && matched < len(f.langs)) {
+			return false
+		}
+	}
+
+	if len(f.naturalLangs) > 0 && !anyFold(i.NaturalLanguages, f.naturalLangs) {
+		return false
+	}
+
+	if len(f.topics) > 0 && !i.Repo.HasTopic(f.topics) {
+		return false
+	}
+
+	if len(f.difficulties) > 0 && !f.difficulties[i.Difficulty] {
+		return false
+	}
+
+	if len(f.kinds) > 0 && !f.kinds[i.Kind] {
+		return false
+	}
+
+	if i.Repo.Archived && !f.archived {
+		return false
+	}
+
+	if f.participating && !i.Participating {
+		return false
+	}
+
+	if f.unassigned && i.Assigned {
+		return false
+	}
+
+	if f.mentored && !i.MentorAvailable {
+		return false
+	}
+
+	if len(f.words) > 0 {
+		text := strings.ToLower(i.Title + "\n" + i.Body)
+		for _, w := range f.words {
+			if !strings.Contains(text, w) {
+				return false
+			}
+		}
+	}
+
+	if f.excludedRepos[strings.ToLower(i.Repo.Owner)] || f.excludedRepos[strings.ToLower(i.Repo.ID())] {
+		return false
+	}
+
+	if len(f.excludedWords) > 0 {
+		parts := []string{i.Title, i.Body}
+		parts = append(parts, i.Languages...)
+		for l := range i.Labels {
+			parts = append(parts, l)
+		}
+		text := strings.ToLower(strings.Join(parts, "\n"))
+		for _, w := range f.excludedWords {
+			if strings.Contains(text, w) {
+				return false
+			}
+		}
+	}
+
+	if len(f.matched) > 0 {
+		var found bool
+		for _, l := range i.MatchedLabels {
+			if f.matched[strings.ToLower(l)] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply gives the issues that pass the filter. Collapsing copies keeps
+// whichever of them passes first, which still says how many others there are.
+func (f issueFilter) apply(in []Issue) []Issue {
+	out := []Issue{}
+	seen := make(map[string]bool)
+	for _, i := range in {
+		if !f.keep(i) {
+			continue
+		}
+		if f.collapse && i.Duplicates > 0 {
+			original := i.DuplicateOf
+			if original == "" {
+				original = i.URL
+			}
+			if seen[original] {
+				continue
+			}
+			seen[original] = true
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+// anyFold reports whether any of have is one of want, ignoring case.
+func anyFold(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(h, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestFilterLanguages(t *testing.T) {
+	goRust := Issue{Title: "a", Languages: []string{"Go", "Rust"}}
+	goOnly := Issue{Title: "b", Languages: []string{"Go"}}
+	js := Issue{Title: "c", Languages: []string{"JavaScript"}}
+	none := Issue{Title: "d"}
+	data := []Issue{goRust, goOnly, js, none}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"lang=go", []string{"a", "b"}},
+		{"lang=Go,RUST", []string{"a", "b"}},
+		{"lang=Go,Rust&lang_match=all", []string{"a"}},
+		{"lang=rust,javascript&lang_match=any", []string{"a", "c"}},
+		{"lang=Haskell", []string{}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFilterDifficulty(t *testing.T) {
+	data := []Issue{
+		{Title: "a", Difficulty: aggregator.Easy},
+		{Title: "b", Difficulty: aggregator.Medium},
+		{Title: "c", Difficulty: aggregator.Hard},
+		{Title: "d"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"difficulty=easy", []string{"a"}},
+		{"difficulty=Easy,medium", []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFilterKind(t *testing.T) {
+	data := []Issue{
+		{Title: "a", Kind: aggregator.Docs},
+		{Title: "b", Kind: aggregator.Bug},
+		{Title: "c"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"kind=docs", []string{"a"}},
+		{"kind=Docs,bug", []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFilterNaturalLanguages(t *testing.T) {
+	data := []Issue{
+		{Title: "a", Repo: Repo{Topics: []string{"translations", "spanish"}}, NaturalLanguages: []string{"Spanish"}},
+		{Title: "b", Repo: Repo{Topics: []string{"i18n"}}, NaturalLanguages: []string{"French", "German"}},
+		{Title: "c"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"natural_lang=spanish", []string{"a"}},
+		{"natural_lang=SPANISH,+german", []string{"a", "b"}},
+		{"topic=Translations", []string{"a"}},
+		{"topic=i18n,translations&natural_lang=german", []string{"b"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFilterMatched(t *testing.T) {
+	data := []Issue{
+		{Title: "a", MatchedLabels: []string{"hacktoberfest"}},
+		{Title: "b", MatchedLabels: []string{"hacktoberfest", "help wanted"}},
+		{Title: "c"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"matched=Hacktoberfest", []string{"a", "b"}},
+		{"matched=help+wanted,good+first+issue", []string{"b"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, q := range []string{"lang=go&lang_match=some", "difficulty=trivial", "kind=chores", "duplicates=hide"} {
+		r := httptest.NewRequest("GET", "/api/issues?"+q, nil)
+		if _, err := parseFilter(r); err == nil {
+			t.Errorf("%q: error should not be nil, but it was", q)
+		}
+	}
+}
+
+func TestFilterArchived(t *testing.T) {
+	data := []Issue{
+		{Title: "a", Repo: Repo{Name: "live"}},
+		{Title: "b", Repo: Repo{Name: "read-only", Archived: true}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a"}},
+		{"archived=false", []string{"a"}},
+		{"archived=true", []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	if _, err := parseFilter(httptest.NewRequest("GET", "/api/issues?archived=maybe", nil)); err == nil {
+		t.Errorf("archived=maybe should be an error")
+	}
+}
+
+func TestFilterParticipating(t *testing.T) {
+	in := Issue{Title: "a", Participating: true}
+	out := Issue{Title: "b"}
+
+	f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?participating=true", nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if !f.keep(in) || f.keep(out) {
+		t.Errorf("should only keep issues in participating repos")
+	}
+}
+
+func TestFilterUnassigned(t *testing.T) {
+	free := Issue{Title: "a"}
+	taken := Issue{Title: "b", Assigned: true}
+
+	f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?unassigned=true", nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if !f.keep(free) || f.keep(taken) {
+		t.Errorf("should only keep issues nobody is assigned")
+	}
+
+	f, _ = parseFilter(httptest.NewRequest("GET", "/api/issues", nil))
+	if !f.keep(taken) {
+		t.Errorf("assigned issues should be kept unless asked not to")
+	}
+}
+
+func TestFilterMentored(t *testing.T) {
+	mentored := Issue{Title: "a", MentorAvailable: true}
+	alone := Issue{Title: "b"}
+
+	f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?mentored=true", nil))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if !f.keep(mentored) || f.keep(alone) {
+		t.Errorf("should only keep issues with a mentor available")
+	}
+}
+
+func TestFilterWords(t *testing.T) {
+	data := []Issue{
+		{Title: "Add a Dockerfile", Body: "So people can run it without Go"},
+		{Title: "Fix the docs", Body: "The docker instructions are wrong"},
+		{Title: "Add dark mode"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"q=docker", []string{"Add a Dockerfile", "Fix the docs"}},
+		{"q=DOCKER+go", []string{"Add a Dockerfile"}},
+		{"q=add", []string{"Add a Dockerfile", "Add dark mode"}},
+		{"q=kubernetes", []string{}},
+		{"q=+", []string{"Add a Dockerfile", "Fix the docs", "Add dark mode"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFilterExclude(t *testing.T) {
+	data := []Issue{
+		{Title: "a", Repo: Repo{Owner: "devict", Name: "hacktoberfest"}, Languages: []string{"Go"}},
+		{Title: "b", Repo: Repo{Owner: "devict", Name: "site"}, Languages: []string{"PHP"}},
+		{Title: "c", Repo: Repo{Owner: "MakeICT", Name: "hub"}, Body: "Update the WordPress theme"},
+		{Title: "d", Repo: Repo{Owner: "MakeICT", Name: "members"}, Labels: map[string]string{"wordpress": "ffffff"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"exclude_repo=devict/site", []string{"a", "c", "d"}},
+		{"exclude_repo=makeict", []string{"a", "b"}},
+		{"exclude_repo=devict/site,MakeICT/hub", []string{"a", "d"}},
+		{"exclude=wordpress,php", []string{"a"}},
+		{"exclude=,", []string{"a", "b", "c", "d"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	if _, err := parseFilter(httptest.NewRequest("GET", "/api/issues?exclude_repo=a/b/c", nil)); err == nil {
+		t.Errorf("exclude_repo=a/b/c should be an error")
+	}
+}
+
+func TestFilterDuplicates(t *testing.T) {
+	data := []Issue{
+		{Title: "a", URL: "a", DuplicateOf: "b", Duplicates: 2},
+		{Title: "b", URL: "b", Duplicates: 2},
+		{Title: "c", URL: "c"},
+		{Title: "d", URL: "d", DuplicateOf: "b", Duplicates: 2},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"duplicates=show", []string{"a", "b", "c", "d"}},
+		{"duplicates=collapse", []string{"a", "c"}},
+	}
+
+	for _, test := range tests {
+		f, err := parseFilter(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range f.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
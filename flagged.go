@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Repos with more than config.CopiedTitles open issues with the same title are
+// flagged for an admin to look at. Repos that open dozens of identical issues
+// are usually farming pull requests rather than asking for help.
+
+// flaggedRepo is a repo with more than config.CopiedTitles issues called
+// Title.
+type flaggedRepo struct {
+	Repo   string `json:"repo"`
+	Title  string `json:"title"`
+	Issues int    `json:"issues"`
+}
+
+// flagRepos finds the repos in issues with more than max open issues sharing
+// a title, ignoring case and surrounding space. They're sorted by how many
+// issues they have, most first. Repos that have been excluded already don't
+// show up since their issues never make it this far.
+func flagRepos(issues []Issue, max int) []flaggedRepo {
+	type key struct{ repo, title string }
+	counts := make(map[key]int)
+	titles := make(map[key]string)
+	for _, i := range issues {
+		k := key{i.Repo.ID(), strings.ToLower(strings.TrimSpace(i.Title))}
+		counts[k]++
+		if _, ok := titles[k]; !ok {
+			titles[k] = i.Title
+		}
+	}
+
+	flagged := []flaggedRepo{}
+	for k, n := range counts {
+		if n > max {
+			flagged = append(flagged, flaggedRepo{Repo: k.repo, Title: titles[k], Issues: n})
+		}
+	}
+	sort.Slice(flagged, func(i, j int) bool {
+		if flagged[i].Issues != flagged[j].Issues {
+			return flagged[i].Issues > flagged[j].Issues
+		}
+		return flagged[i].Repo < flagged[j].Repo
+	})
+	return flagged
+}
+
+// adminFlagged lists the repos in the standard listing that look like spam,
+// see flagRepos, so an admin can decide whether to exclude them.
+func adminFlagged(w http.ResponseWriter, r *http.Request) {
+	c, _ := issueClient(r)
+	issues, err := loadIssues(r.Context(), c, defaultParams(c.Scope()), false)
+	if !servable(err) {
+		upstreamError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(flagRepos(issues, config.CopiedTitles)); err != nil {
+		logError(r.Context(), err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlagRepos(t *testing.T) {
+	spam := Repo{Owner: "someone", Name: "spam"}
+	fine := Repo{Owner: "devict", Name: "hacktoberfest"}
+
+	var issues []Issue
+	for i := 0; i < 4; i++ {
+		issues = append(issues, Issue{Title: "Add your name to README", Repo: spam})
+	}
+	issues = append(issues, Issue{Title: " add your name to readme", Repo: spam})
+	for i := 0; i < 3; i++ {
+		issues = append(issues, Issue{Title: "Fix typo", Repo: fine})
+	}
+	issues = append(issues, Issue{Title: "Fix typo", Repo: spam})
+
+	want := []flaggedRepo{{Repo: "someone/spam", Title: "Add your name to README", Issues: 5}}
+	if got := flagRepos(issues, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := flagRepos(issues, 5); len(got) != 0 {
+		t.Errorf("nothing has more than 5 of the same title, got %+v", got)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// flight is a call to fetchIssues that is in progress or has just finished.
+type flight struct {
+	wg     sync.WaitGroup
+	issues []Issue
+	err    error
+}
+
+// flightGroup coalesces concurrent calls that share a key so only the first
+// one does the work and everybody else waits for and shares its result.
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[string]*flight
+}
+
+var flights = &flightGroup{}
+
+// size is how many calls are in flight.
+func (g *flightGroup) size() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.m)
+}
+
+// do runs fn for key unless a call for key is already in flight, in which
+// case it waits for that call and returns its result instead.
+func (g *flightGroup) do(key string, fn func() ([]Issue, error)) ([]Issue, error) {
+	f, started := g.join(key)
+	if !started {
+		f.wg.Wait()
+		return f.issues, f.err
+	}
+	g.run(key, f, fn)
+	return f.issues, f.err
+}
+
+// start runs fn for key in the background unless a call for key is already
+// in flight. It reports whether it started one.
+func (g *flightGroup) start(key string, fn func() ([]Issue, error)) bool {
+	f, started := g.join(key)
+	if started {
+		go g.run(key, f, fn)
+	}
+	return started
+}
+
+// join gives the flight for key, and whether it's a new one the caller has to
+// run.
+func (g *flightGroup) join(key string) (*flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.m == nil {
+		g.m = make(map[string]*flight)
+	}
+	if f, ok := g.m[key]; ok {
+		return f, false
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	g.m[key] = f
+	return f, true
+}
+
+// run makes the call for f, which join started for key. A panic in fn is
+// logged and becomes its error, since calls start makes have nothing above
+// them to recover, and the waiters still get an answer.
+func (g *flightGroup) run(key string, f *flight, fn func() ([]Issue, error)) {
+	defer func() {
+		if v := recover(); v != nil {
+			f.issues, f.err = nil, errors.Errorf("fetching issues panicked: %v", v)
+			logError(context.Background(), f.err, "key", key, "stack", string(debug.Stack()))
+		}
+
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		f.wg.Done()
+	}()
+
+	f.issues, f.err = fn()
+}
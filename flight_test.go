@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadIssuesCoalesces(t *testing.T) {
+	var searches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/issues":
+			atomic.AddInt32(&searches, 1)
+
+			// Be slow enough that every caller piles up behind the first
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"items": [{
+				"title": "Fix it",
+				"html_url": "https://github.com/devict/hacktoberfest/issues/1",
+				"repository_url": "https://api.github.com/repos/devict/hacktoberfest"
+			}]}`))
+		case "/repos/devict/hacktoberfest/languages":
+			w.Write([]byte(`{"Go": 100}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	p := defaultParams(c.Scope())
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			issues, err := loadIssues(context.Background(), c, p, true)
+			if err != nil {
+				t.Errorf("%d: error should be nil, got %v", i, err)
+			} else if len(issues) != 1 {
+				t.Errorf("%d: got %d issues, want 1", i, len(issues))
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// One search per label, no matter how many callers
+	if got := atomic.LoadInt32(&searches); got != int32(len(p.labels)) {
+		t.Errorf("GitHub was searched %d times, want %d", got, len(p.labels))
+	}
+}
+
+func TestFlightGroupKeys(t *testing.T) {
+	var g flightGroup
+	var calls int32
+	release := make(chan struct{})
+	fn := func() ([]Issue, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []Issue{{Title: "Fix it"}}, nil
+	}
+
+	// Two callers for each of two keys, all at once
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "a", "b", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if issues, err := g.do(key, fn); err != nil || len(issues) != 1 {
+				t.Errorf("%s: got %v, %v", key, issues, err)
+			}
+		}(key)
+	}
+
+	// Give everyone time to join a flight before letting them land
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("got %d calls, want one per key", got)
+	}
+
+	// Once a flight has landed the next call for its key starts a new one
+	g.do("a", fn)
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("got %d calls, want 3", got)
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup
+	if _, err := g.do("a", func() ([]Issue, error) { panic("oops") }); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("got error %v, want the panic", err)
+	}
+	if n := g.size(); n != 0 {
+		t.Fatalf("got %d calls in flight after a panic, want 0", n)
+	}
+
+	// In the background there's nobody to recover but us, and whoever's
+	// waiting still has to hear
+	release := make(chan struct{})
+	g.start("b", func() ([]Issue, error) {
+		<-release
+		panic("oops")
+	})
+	f, started := g.join("b")
+	if started {
+		t.Fatal("start should have put b in flight")
+	}
+	close(release)
+	f.wg.Wait()
+	if err := f.err; err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("got error %v waiting on a background call that panicked, want the panic", err)
+	}
+
+	issues, err := g.do("a", func() ([]Issue, error) { return []Issue{{Title: "Fix it"}}, nil })
+	if err != nil || len(issues) != 1 {
+		t.Errorf("got %v, %v, want the next call to run", issues, err)
+	}
+}
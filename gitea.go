@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// Gitea finds issues in a list of repos on a Gitea instance, like Codeberg.
+// Its API is close enough to GitHub's REST API that a Client pointed at it
+// can make the requests, and fetch languages, for us.
+type Gitea struct {
+	api *aggregator.GitHub
+
+	// Projects are the owner/name of each repo to search
+	Projects []string
+}
+
+// newGitea makes a Gitea for the instance at base, like https://codeberg.org.
+func newGitea(base, token string, projects []string) *Gitea {
+	return &Gitea{
+		api: &aggregator.GitHub{
+			BaseURL:  strings.TrimSuffix(base, "/") + "/api/v1",
+			HTTP:     metered{upstream},
+			Token:    token,
+			MaxPages: defaultMaxPages,
+
+			// Its own cache since an owner/name here isn't the same repo as on
+			// GitHub
+			Languages: newLanguages(defaultLanguageTTL),
+
+			Retries:      3,
+			RetryWait:    500 * time.Millisecond,
+			MaxRetryWait: 30 * time.Second,
+			CallTimeout:  config.CallTimeout,
+			Breaker:      newBreaker(base),
+			Observer:     observer{},
+		},
+		Projects: projects,
+	}
+}
+
+// SearchIssues finds open issues with label in each of our repos. q.Scope is
+// GitHub search syntax so it doesn't apply here, but the dates do.
+func (g *Gitea) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	for _, project := range g.Projects {
+		if err := g.searchRepo(ctx, project, label, q, ch); err != nil {
+			return errors.Wrapf(err, "could not search gitea repo %s", project)
+		}
+	}
+	return nil
+}
+
+func (g *Gitea) searchRepo(ctx context.Context, project, label string, q aggregator.Query, ch chan<- Issue) error {
+	parts := strings.SplitN(project, "/", 2)
+	if len(parts) != 2 {
+		return errors.Errorf("repo %q should look like owner/name", project)
+	}
+	repo := Repo{Owner: parts[0], Name: parts[1]}
+
+	vals := url.Values{}
+	vals.Add("state", "open")
+	vals.Add("type", "issues")
+	vals.Add("labels", label)
+	vals.Add("limit", "50")
+	if !q.Updated.IsZero() {
+		vals.Add("since", q.Updated.Format(time.RFC3339))
+	}
+
+	// Only ask for languages once we know there are issues to show them on
+	var languages []aggregator.Language
+	var haveLanguages, languagesUnknown bool
+
+	next := g.api.BaseURL + "/repos/" + url.PathEscape(repo.Owner) + "/" + url.PathEscape(repo.Name) + "/issues?" + vals.Encode()
+	for page := 1; next != "" && (g.api.MaxPages == 0 || page <= g.api.MaxPages); page++ {
+		var data []struct {
+			Number    int       `json:"number"`
+			Title     string    `json:"title"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+			UpdatedAt time.Time `json:"updated_at"`
+			HTMLURL   string    `json:"html_url"`
+			Comments  int       `json:"comments"`
+			Labels    Labels    `json:"labels"`
+			Assignees []struct {
+				Login string `json:"login"`
+			} `json:"assignees"`
+		}
+
+		var err error
+		next, err = g.api.GetURL(ctx, next, &data)
+		if err != nil {
+			return err
+		}
+
+		if len(data) > 0 && !haveLanguages {
+			languages, languagesUnknown, err = knownLanguages(ctx, repo, func() ([]aggregator.Language, error) {
+				return g.api.Languages.RepoLanguages(ctx, g.api, repo, q.MaxLangs)
+			})
+			if err != nil {
+				return err
+			}
+			haveLanguages = true
+		}
+
+		for _, item := range data {
+			// Depending on the version colors may or may not start with a #
+			for i := range item.Labels {
+				item.Labels[i].Color = strings.TrimPrefix(item.Labels[i].Color, "#")
+			}
+
+			issue := Issue{
+				Title:     item.Title,
+				Number:    item.Number,
+				State:     "open",
+				Date:      item.CreatedAt,
+				Updated:   item.UpdatedAt,
+				URL:       item.HTMLURL,
+				Repo:      repo,
+				Labels:    labelFilter(item.Labels),
+				Languages: aggregator.LanguageNames(languages),
+				Comments:  item.Comments,
+				Assigned:  len(item.Assignees) > 0,
+				Body:      aggregator.Excerpt(item.Body, aggregator.ExcerptLength),
+
+				LanguageShares: languages,
+				Difficulty:     aggregator.Difficulty(item.Labels),
+				Kind:           aggregator.Kind(item.Labels, item.Title),
+
+				LanguagesUnknown: languagesUnknown,
+
+				MentorAvailable: aggregator.Mentored(item.Labels),
+
+				// We don't know the topics of repos on Gitea, so this is always empty
+				NaturalLanguages: repo.NaturalLanguages(),
+			}
+
+			// Gitea can't search by when issues were opened so we have to
+			// leave out the old ones ourselves
+			if !q.InRange(issue) {
+				continue
+			}
+
+			select {
+
+			// Stop early because another worker failed or the caller gave up
+			case <-ctx.Done():
+				return ctx.Err()
+
+			// Send our issue on ch if we can
+			case ch <- issue:
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestGiteaSearchIssues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token sekret" {
+			t.Errorf("got authorization %q, want token sekret", got)
+		}
+
+		switch r.RequestURI {
+		case "/api/v1/repos/wichita/site/issues?labels=hacktoberfest&limit=50&state=open&type=issues":
+			w.Write([]byte(`[{
+				"number": 3,
+				"title": "Fix it",
+				"body": "It's broken",
+				"created_at": "2017-10-02T00:00:00Z",
+				"html_url": "https://codeberg.org/wichita/site/issues/3",
+				"comments": 1,
+				"labels": [{"name": "hacktoberfest", "color": "ff8800"}, {"name": "bug", "color": "#ee0701"}],
+				"assignees": [{"login": "someone"}]
+			}]`))
+		case "/api/v1/repos/wichita/site/languages":
+			w.Write([]byte(`{"Go": 100, "HTML": 10}`))
+		default:
+			t.Errorf("unexpected request for %s", r.RequestURI)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := newGitea(srv.URL, "sekret", []string{"wichita/site"})
+	g.api.Retries = 0
+
+	ch := make(chan Issue, 10)
+	if err := g.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{MaxLangs: 0}, ch); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	close(ch)
+
+	var got []Issue
+	for i := range ch {
+		got = append(got, i)
+	}
+	want := []Issue{{
+		Title:          "Fix it",
+		Number:         3,
+		State:          "open",
+		Date:           time.Date(2017, 10, 2, 0, 0, 0, 0, time.UTC),
+		URL:            "https://codeberg.org/wichita/site/issues/3",
+		Repo:           Repo{Owner: "wichita", Name: "site"},
+		Labels:         map[string]string{"bug": "ee0701"},
+		Languages:      []string{"Go", "HTML"},
+		LanguageShares: []aggregator.Language{{Name: "Go", Percent: 90.9}, {Name: "HTML", Percent: 9.1}},
+		Comments:       1,
+		Assigned:       true,
+		Body:           "It's broken",
+		Kind:           aggregator.Bug,
+
+		NaturalLanguages: []string{},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGiteaBadProject(t *testing.T) {
+	g := newGitea("http://example.com", "", []string{"nope"})
+	if err := g.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{}, make(chan Issue)); err == nil {
+		t.Errorf("error should not be nil, but it was")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// GitHubClient sends requests to GitHub. An *http.Client is one, and tests use
+// one that answers from fixtures instead, see fakeGitHub.
+type GitHubClient = aggregator.Doer
+
+// Client makes requests to the GitHub API for issues and repo details, with
+// the aggregator's GitHub doing the work: retries, ETags, the rate limit
+// budget, GraphQL and the language pool are all there. Each search it makes
+// is limited to the Orgs and Projects it holds.
+type Client struct {
+	aggregator.GitHub
+
+	// Shared is set when Token is the server's own, being used for someone
+	// who isn't logged in. Searches made with it always go through the cache.
+	Shared bool
+
+	// Others are searched alongside GitHub, see Sources
+	Others []IssueSource
+}
+
+// githubAPI gives the REST and GraphQL endpoints of the GitHub at web. Only
+// github.com has its API on a separate host.
+func githubAPI(web string) (rest, graphql string) {
+	if web == "https://github.com" {
+		return "https://api.github.com", "https://api.github.com/graphql"
+	}
+	return web + "/api/v3", web + "/api/graphql"
+}
+
+// defaultMaxPages is how many pages of search results we read per label. The
+// search API won't give out more than 1000 results, which is 10 pages.
+const defaultMaxPages = 10
+
+// newClient makes a Client for config.GitHubURL covering our tracked orgs and
+// projects.
+func newClient(token string) *Client {
+	t := tracking()
+	rest, graphql := githubAPI(config.GitHubURL)
+	return &Client{
+		GitHub: aggregator.GitHub{
+			BaseURL:    rest,
+			GraphQLURL: graphql,
+			HTTP:       metered{upstream},
+			Token:      token,
+			Orgs:       t.Orgs,
+			Projects:   t.Projects,
+			Hidden:     t.Labels,
+			MaxPages:   defaultMaxPages,
+			Languages:  languages,
+			Responses:  responses,
+			Observer:   observer{},
+
+			Retries:      3,
+			RetryWait:    500 * time.Millisecond,
+			MaxRetryWait: 30 * time.Second,
+			CallTimeout:  config.CallTimeout,
+			Breaker:      githubBreaker,
+			Budget:       budgets,
+		},
+		Others: others,
+	}
+}
+
+// credentials identifies the tokens c searches with: "" for the server's own,
+// which everyone who isn't logged in shares, or a hash of whoever's they are.
+func (c *Client) credentials() string {
+	h := sha256.New()
+	mine := c.Token != config.Token
+	io.WriteString(h, c.Token)
+	for _, src := range c.Others {
+		if g, ok := src.(*GitLab); ok && g.Bearer {
+			mine = true
+			io.WriteString(h, " gitlab:"+g.Token)
+		}
+	}
+	if !mine {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sources is everywhere a search with the client looks: GitHub itself, then
+// any Others.
+func (c *Client) Sources() []IssueSource {
+	return append([]IssueSource{c}, c.Others...)
+}
+
+// defaultMaxResponses is how many GitHub responses we keep ETags for.
+const defaultMaxResponses = 1000
+
+// responses is shared by every GitHub client.
+var responses = aggregator.NewResponseCache(defaultMaxResponses)
+
+// budgets is shared by every GitHub client.
+var budgets = aggregator.NewBudget()
+
+// defaultLanguageTTL is how long we keep a repo's languages before asking
+// GitHub again. They don't change much.
+const defaultLanguageTTL = 24 * time.Hour
+
+// languages is shared by every worker in every request so we only ask about
+// each repo once in a while. setConfig has it keep them in the sharedStore
+// when there is one.
+var languages = newLanguages(defaultLanguageTTL)
+
+// newLanguages makes an aggregator.Languages that tells our logs and traces
+// what it's doing.
+func newLanguages(ttl time.Duration) *aggregator.Languages {
+	l := aggregator.NewLanguages(ttl)
+	l.Observer = observer{}
+	return l
+}
+
+// languageStore is the sharedStore as languages keeps repos in it.
+type languageStore struct {
+	s sharedStore
+}
+
+func (l languageStore) Get(key string) ([]byte, bool, error) {
+	return l.s.get(key)
+}
+
+func (l languageStore) Set(key string, value []byte, ttl time.Duration) error {
+	return l.s.set(key, value, ttl)
+}
+
+func (l languageStore) Del(keys ...string) error {
+	return l.s.del(keys...)
+}
+
+// metered is a GitHubClient counting and timing each request hc makes, for
+// our metrics, traces and debug logs, and noting the rate limit left.
+type metered struct {
+	hc GitHubClient
+}
+
+func (m metered) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&githubCalls, 1)
+	defer atomic.AddInt64(&githubCalls, -1)
+
+	start := time.Now()
+	resp, err := m.hc.Do(req)
+	apiDuration.since(labels("host", req.URL.Host), start)
+	if err != nil {
+		apiRequests.add(labels("host", req.URL.Host, "code", "error"), 1)
+		return nil, err
+	}
+	apiRequests.add(labels("host", req.URL.Host, "code", strconv.Itoa(resp.StatusCode)), 1)
+	spanFrom(req.Context()).set("http.status_code", strconv.Itoa(resp.StatusCode))
+	logDebug(req.Context(), "upstream request",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"status", resp.StatusCode,
+		"duration", time.Since(start),
+	)
+	recordRateLimit(resp.Header)
+	return resp, nil
+}
+
+// recordRateLimit keeps track of how much rate limit GitHub says we have left.
+func recordRateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	rateLimitRemaining.set(labels("resource", aggregator.RateResource(h)), float64(remaining))
+}
+
+// observer passes on what the aggregator is up to to our traces, logs and
+// metrics.
+type observer struct{}
+
+func (observer) StartSpan(ctx context.Context, name string, remote bool) (context.Context, aggregator.Span) {
+	kind := spanInternal
+	if remote {
+		kind = spanClient
+	}
+	return startSpan(ctx, name, kind)
+}
+
+func (observer) Log(ctx context.Context, level aggregator.Level, msg string, kv ...interface{}) {
+	logAt(ctx, int(level), msg, kv)
+}
+
+func (observer) Searched(label string, took time.Duration) {
+	fetchDuration.observe(labels("label", label), took.Seconds())
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// testClient is a Client for srv that tracks a single org and project, hiding
+// the labels we search for like newClient does. It has no token so searches
+// use the REST API.
+func testClient(srv *httptest.Server) *Client {
+	return &Client{GitHub: aggregator.GitHub{
+		BaseURL:   srv.URL,
+		HTTP:      srv.Client(),
+		Orgs:      map[string]bool{"devict": true},
+		Projects:  map[string]bool{"imacrayon/eventsinwichita": true},
+		Hidden:    tracking().Labels,
+		Languages: newLanguages(time.Hour),
+
+		Retries:      2,
+		RetryWait:    time.Millisecond,
+		MaxRetryWait: 2 * time.Second,
+	}}
+}
+
+func TestClientScope(t *testing.T) {
+	c := &Client{GitHub: aggregator.GitHub{
+		Orgs:     map[string]bool{"devict": true, "MakeICT": true},
+		Projects: map[string]bool{"br0xen/boltbrowser": true},
+	}}
+
+	want := "org:MakeICT org:devict repo:br0xen/boltbrowser"
+	if got := c.Scope(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSearchIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		issues  int
+		err     string
+	}{
+		{
+			name: "ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search/issues" {
+					w.Write([]byte(`{"items": [{
+						"title": "Fix it",
+						"html_url": "https://github.com/devict/hacktoberfest/issues/1",
+						"repository_url": "https://api.github.com/repos/devict/hacktoberfest",
+						"labels": [{"name": "hacktoberfest"}, {"name": "bug", "color": "ff0000"}]
+					}]}`))
+					return
+				}
+				w.Write([]byte(`{"Go": 100}`))
+			},
+			issues: 1,
+		},
+		{
+			name: "bad status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			err: "status was 502",
+		},
+		{
+			name: "rate limited",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", "1506816000")
+				w.WriteHeader(http.StatusForbidden)
+			},
+			err: "rate limit exceeded",
+		},
+		{
+			name: "bad json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"items": `))
+			},
+			err: "could not decode json",
+		},
+		{
+			name: "languages fail",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search/issues" {
+					w.Write([]byte(`{"items": [{"repository_url": "https://api.github.com/repos/devict/hacktoberfest"}]}`))
+					return
+				}
+				w.WriteHeader(http.StatusNotFound)
+			},
+			issues: 1,
+		},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(test.handler)
+		c := testClient(srv)
+
+		// Big enough that SearchIssues never blocks on us
+		ch := make(chan Issue, 10)
+		err := c.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{Scope: c.Scope(), MaxLangs: defaultMaxLangs}, ch)
+		close(ch)
+		srv.Close()
+
+		if test.err == "" && err != nil {
+			t.Errorf("%s: error should be nil, got %v", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error should contain %q, got %v", test.name, test.err, err)
+		}
+		if len(ch) != test.issues {
+			t.Errorf("%s: got %d issues, want %d", test.name, len(ch), test.issues)
+		}
+	}
+}
+
+func TestSearchIssuesRequest(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{"items": []}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	err := c.SearchIssues(context.Background(), "help wanted", aggregator.Query{Scope: c.Scope()}, make(chan Issue))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	wantQ := `is:open type:issue label:"help wanted" org:devict repo:imacrayon/eventsinwichita`
+	if q := got.URL.Query().Get("q"); q != wantQ {
+		t.Errorf("got query %q, want %q", q, wantQ)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "" {
+		t.Errorf("got Authorization %q without a token", auth)
+	}
+}
+
+func TestRepoLanguagesRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/devict/hacktoberfest/languages" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"Go": 100, "CSS": 10}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	got, err := c.RepoLanguages(context.Background(), Repo{Owner: "devict", Name: "hacktoberfest"})
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	want := map[string]int{"Go": 100, "CSS": 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSearchIssuesPages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/issues" {
+			w.Write([]byte(`{"Go": 100}`))
+			return
+		}
+
+		// Three pages, each with one issue and a link to the next
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		if page != "3" {
+			next := map[string]string{"1": "2", "2": "3"}[page]
+			w.Header().Set("Link", `<`+srv.URL+`/search/issues?page=`+next+`>; rel="next"`)
+		}
+		w.Write([]byte(`{"items": [{
+			"html_url": "https://github.com/devict/hacktoberfest/issues/` + page + `",
+			"repository_url": "https://api.github.com/repos/devict/hacktoberfest"
+		}]}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		max  int
+		want int
+	}{
+		{0, 3},
+		{2, 2},
+		{10, 3},
+	}
+
+	for _, test := range tests {
+		c := testClient(srv)
+		c.MaxPages = test.max
+
+		ch := make(chan Issue, 10)
+		if err := c.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{Scope: c.Scope()}, ch); err != nil {
+			t.Errorf("max %d: error should be nil, got %v", test.max, err)
+		}
+		if len(ch) != test.want {
+			t.Errorf("max %d: got %d issues, want %d", test.max, len(ch), test.want)
+		}
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		header   map[string]string
+		requests int
+		err      string
+	}{
+		{"ok", []int{200}, nil, 1, ""},
+		{"recovers", []int{502, 503, 200}, nil, 3, ""},
+		{"gives up", []int{502, 502, 502, 200}, nil, 3, "status was 502"},
+		{"not found", []int{404, 200}, nil, 1, "status was 404"},
+		{"retry after", []int{403, 200}, map[string]string{"Retry-After": "1"}, 2, ""},
+		{"retry after too long", []int{403, 200}, map[string]string{"Retry-After": "600"}, 1, "secondary rate limit"},
+		{"reset too far off", []int{403, 200}, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "4102444800"}, 1, "rate limit exceeded"},
+	}
+
+	for _, test := range tests {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := test.statuses[requests]
+			requests++
+			if status != 200 {
+				for k, v := range test.header {
+					w.Header().Set(k, v)
+				}
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}))
+		c := testClient(srv)
+
+		var v struct{}
+		err := c.Get(context.Background(), "/", nil, &v)
+		srv.Close()
+
+		if test.err == "" && err != nil {
+			t.Errorf("%s: error should be nil, got %v", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error should contain %q, got %v", test.name, test.err, err)
+		}
+		if requests != test.requests {
+			t.Errorf("%s: got %d requests, want %d", test.name, requests, test.requests)
+		}
+	}
+}
+
+func TestClientRetriesPost(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	var v struct{}
+	if err := c.GraphQL(context.Background(), "{ viewer { login } }", nil, &v); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] == "" {
+		t.Errorf("the retry should send the same body, got %q", bodies)
+	}
+}
+
+func TestGitHubAPI(t *testing.T) {
+	tests := []struct {
+		web, rest, graphql string
+	}{
+		{"https://github.com", "https://api.github.com", "https://api.github.com/graphql"},
+		{"https://github.example.com", "https://github.example.com/api/v3", "https://github.example.com/api/graphql"},
+	}
+
+	for _, test := range tests {
+		rest, graphql := githubAPI(test.web)
+		if rest != test.rest || graphql != test.graphql {
+			t.Errorf("%s: got %s and %s, want %s and %s", test.web, rest, graphql, test.rest, test.graphql)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// rateLimits is what getRateLimit says about someone's GitHub rate limits.
+type rateLimits struct {
+	// Shared is true when their searches use the server's credentials, whose
+	// limits everyone shares, so their listings only come from the cache
+	Shared bool `json:"shared"`
+
+	Limits []aggregator.RateLimit `json:"limits"`
+}
+
+// getRateLimit shows whoever is logged in how much of each GitHub rate limit
+// their searches have left, so they can tell why listings are stale or
+// missing issues. GitHub doesn't count asking against any of them.
+func getRateLimit(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+
+	limits, err := c.RateLimits(r.Context())
+	if err != nil {
+		upstreamError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rateLimits{Shared: c.Shared, Limits: limits}); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// searchRateLimit is what we last heard of the rate limit c searches with,
+// for a listing's meta, or nil if we haven't.
+func searchRateLimit(c *Client) *aggregator.RateLimit {
+	l, ok := c.SearchRateLimit()
+	if !ok {
+		return nil
+	}
+	return &l
+}
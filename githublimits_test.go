@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestSearchRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Resource", "search")
+		w.Header().Set("X-RateLimit-Remaining", "3")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		w.Write([]byte(`{"items": []}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	c.Budget = aggregator.NewBudget()
+
+	if l := searchRateLimit(c); l != nil {
+		t.Errorf("got %+v before hearing from GitHub, want nil", l)
+	}
+
+	var data interface{}
+	if err := c.Get(context.Background(), "/search/issues", nil, &data); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if l := searchRateLimit(c); l == nil || l.Resource != "search" || l.Remaining != 3 || !l.Low {
+		t.Errorf("got %+v, want the search limit running low", l)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/markbates/goth"
+	"github.com/pkg/errors"
+)
+
+// GitLab finds issues in a list of projects on a GitLab instance. GitLab
+// won't search across projects the way GitHub does so we ask each project in
+// turn.
+type GitLab struct {
+	// api makes the requests for us, retrying and following pages the same
+	// way we do for GitHub
+	api *aggregator.GitHub
+
+	// Token is sent as a PRIVATE-TOKEN if it's set, or as a bearer token if
+	// Bearer is, for the OAuth tokens people log in with
+	Token  string
+	Bearer bool
+
+	// Projects are the namespace/project paths to search
+	Projects []string
+}
+
+// newGitLab makes a GitLab for the instance at base, like https://gitlab.com.
+func newGitLab(base, token string, projects []string) *GitLab {
+	return &GitLab{
+		api: &aggregator.GitHub{
+			BaseURL:  strings.TrimSuffix(base, "/") + "/api/v4",
+			HTTP:     metered{upstream},
+			MaxPages: defaultMaxPages,
+
+			Retries:      3,
+			RetryWait:    500 * time.Millisecond,
+			MaxRetryWait: 30 * time.Second,
+			CallTimeout:  config.CallTimeout,
+			Breaker:      newBreaker(base),
+			Observer:     observer{},
+		},
+		Token:    token,
+		Projects: projects,
+	}
+}
+
+// get is GitHub.GetURL with GitLab's kind of token.
+func (g *GitLab) get(ctx context.Context, u string, v interface{}) (string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not build request")
+	}
+	if g.Bearer {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	} else if g.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.Token)
+	}
+
+	h, err := g.api.Do(ctx, req, v)
+	if err != nil {
+		return "", err
+	}
+	return aggregator.NextLink(h.Get("Link")), nil
+}
+
+// SearchIssues finds open issues with label in each of our projects. q.Scope
+// is GitHub search syntax so it doesn't apply here, but the dates do.
+func (g *GitLab) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	for _, project := range g.Projects {
+		if err := g.searchProject(ctx, project, label, q, ch); err != nil {
+			return errors.Wrapf(err, "could not search gitlab project %s", project)
+		}
+	}
+	return nil
+}
+
+func (g *GitLab) searchProject(ctx context.Context, project, label string, q aggregator.Query, ch chan<- Issue) error {
+	vals := url.Values{}
+	vals.Add("state", "opened")
+	vals.Add("labels", label)
+	vals.Add("with_labels_details", "true")
+	vals.Add("per_page", "100")
+	if !q.Created.IsZero() {
+		vals.Add("created_after", q.Created.Format(time.RFC3339))
+	}
+	if !q.Updated.IsZero() {
+		vals.Add("updated_after", q.Updated.Format(time.RFC3339))
+	}
+
+	repo := Repo{Name: project}
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		repo = Repo{Owner: project[:i], Name: project[i+1:]}
+	}
+
+	// Only ask for languages once we know there are issues to show them on
+	var languages []aggregator.Language
+	var haveLanguages, languagesUnknown bool
+
+	next := g.api.BaseURL + "/projects/" + url.PathEscape(project) + "/issues?" + vals.Encode()
+	for page := 1; next != "" && (g.api.MaxPages == 0 || page <= g.api.MaxPages); page++ {
+		var data []struct {
+			IID         int       `json:"iid"`
+			Title       string    `json:"title"`
+			Description string    `json:"description"`
+			CreatedAt   time.Time `json:"created_at"`
+			UpdatedAt   time.Time `json:"updated_at"`
+			WebURL      string    `json:"web_url"`
+			Notes       int       `json:"user_notes_count"`
+			Labels      Labels    `json:"labels"`
+			Assignees   []struct {
+				Username string `json:"username"`
+			} `json:"assignees"`
+		}
+
+		var err error
+		next, err = g.get(ctx, next, &data)
+		if err != nil {
+			return err
+		}
+
+		if len(data) > 0 && !haveLanguages {
+			languages, languagesUnknown, err = knownLanguages(ctx, repo, func() ([]aggregator.Language, error) {
+				return g.projectLanguages(ctx, project, q.MaxLangs)
+			})
+			if err != nil {
+				return err
+			}
+			haveLanguages = true
+		}
+
+		for _, item := range data {
+			// GitLab colors come as #RRGGBB where GitHub leaves off the #
+			for i := range item.Labels {
+				item.Labels[i].Color = strings.TrimPrefix(item.Labels[i].Color, "#")
+			}
+
+			issue := Issue{
+				Title:     item.Title,
+				Number:    item.IID,
+				State:     "open",
+				Date:      item.CreatedAt,
+				Updated:   item.UpdatedAt,
+				URL:       item.WebURL,
+				Repo:      repo,
+				Labels:    labelFilter(item.Labels),
+				Languages: aggregator.LanguageNames(languages),
+				Comments:  item.Notes,
+				Assigned:  len(item.Assignees) > 0,
+				Body:      aggregator.Excerpt(item.Description, aggregator.ExcerptLength),
+
+				LanguageShares: languages,
+				Difficulty:     aggregator.Difficulty(item.Labels),
+				Kind:           aggregator.Kind(item.Labels, item.Title),
+
+				LanguagesUnknown: languagesUnknown,
+
+				MentorAvailable: aggregator.Mentored(item.Labels),
+
+				// We don't know the topics of projects on GitLab, so this is always empty
+				NaturalLanguages: repo.NaturalLanguages(),
+			}
+
+			select {
+
+			// Stop early because another worker failed or the caller gave up
+			case <-ctx.Done():
+				return ctx.Err()
+
+			// Send our issue on ch if we can
+			case ch <- issue:
+			}
+		}
+	}
+
+	return nil
+}
+
+// projectLanguages gives the top max languages of project, or all of them if
+// max is zero. GitLab gives them as percentages rather than bytes.
+func (g *GitLab) projectLanguages(ctx context.Context, project string, max int) ([]aggregator.Language, error) {
+	var data map[string]float64
+	if _, err := g.get(ctx, g.api.BaseURL+"/projects/"+url.PathEscape(project)+"/languages", &data); err != nil {
+		return nil, err
+	}
+
+	// TopLanguages wants whole numbers so keep a couple of decimal places'
+	// worth
+	shares := make(map[string]int)
+	for lang, pct := range data {
+		shares[lang] = int(pct * 100)
+	}
+	return aggregator.TopLanguages(max, shares), nil
+}
+
+// withGitLabToken gives srcs with the GitLab ones on config.GitLabURL
+// searching with someone's OAuth token instead of ours. srcs are left as
+// they are, since they're shared.
+func withGitLabToken(srcs []IssueSource, token string) []IssueSource {
+	if token == "" {
+		return srcs
+	}
+	out := make([]IssueSource, len(srcs))
+	for n, src := range srcs {
+		out[n] = src
+		if g, ok := src.(*GitLab); ok && g.api.BaseURL == config.GitLabURL+"/api/v4" {
+			mine := *g
+			mine.Token, mine.Bearer = token, true
+			out[n] = &mine
+		}
+	}
+	return out
+}
+
+// gitlabLogin is logging in with the OAuth application on c.GitLabURL. Its
+// user IDs are negated so they can't be mistaken for GitHub's in users.
+func gitlabLogin(c Config) *loginProvider {
+	return &loginProvider{
+		name:         "gitlab",
+		title:        "GitLab",
+		clientID:     c.GitLabClientID,
+		clientSecret: c.GitLabClientSecret,
+		authURL:      c.GitLabURL + "/oauth/authorize",
+		tokenURL:     c.GitLabURL + "/oauth/token",
+		redirectURL:  c.SiteURL + "/auth/gitlab/callback",
+		scopes:       []string{"read_user", "read_api"},
+		granted: func(ctx context.Context, t tokenResponse) ([]string, error) {
+			return splitScopes(t.Scope), nil
+		},
+		user: func(ctx context.Context, token string) (goth.User, error) {
+			return gitlabUser(ctx, c, token)
+		},
+		revoke: func(ctx context.Context, token string) error {
+			return gitlabRevoke(ctx, c, token)
+		},
+	}
+}
+
+// gitlabUser gives who token belongs to on c.GitLabURL.
+func gitlabUser(ctx context.Context, c Config, token string) (goth.User, error) {
+	req, err := http.NewRequest("GET", c.GitLabURL+"/api/v4/user", nil)
+	if err != nil {
+		return goth.User{}, errors.Wrap(err, "could not make user request")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+	resp, err := upstream.Do(req.WithContext(ctx))
+	if err != nil {
+		return goth.User{}, errors.Wrap(err, "could not fetch gitlab user")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return goth.User{}, errors.Errorf("fetching gitlab user got status %d", resp.StatusCode)
+	}
+
+	var data struct {
+		ID        int    `json:"id"`
+		Username  string `json:"username"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return goth.User{}, errors.Wrap(err, "could not decode gitlab user")
+	}
+	if data.ID <= 0 {
+		return goth.User{}, errors.New("gitlab user has no id")
+	}
+	return goth.User{
+		UserID:    strconv.Itoa(-data.ID),
+		NickName:  data.Username,
+		Name:      data.Name,
+		Email:     data.Email,
+		AvatarURL: data.AvatarURL,
+		Provider:  "gitlab",
+	}, nil
+}
+
+// gitlabRevoke asks c.GitLabURL to stop accepting token.
+func gitlabRevoke(ctx context.Context, c Config, token string) error {
+	if token == "" {
+		return nil
+	}
+	form := url.Values{
+		"client_id":     {c.GitLabClientID},
+		"client_secret": {c.GitLabClientSecret},
+		"token":         {token},
+	}
+	req, err := http.NewRequest("POST", c.GitLabURL+"/oauth/revoke", strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "could not make revoke request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+	resp, err := upstream.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "could not revoke gitlab token")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("revoking gitlab token got status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestGitLabSearchIssues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "sekret" {
+			t.Errorf("got token %q, want sekret", r.Header.Get("PRIVATE-TOKEN"))
+		}
+
+		switch r.RequestURI {
+		case "/api/v4/projects/wichita%2Fsite/issues?labels=hacktoberfest&per_page=100&state=opened&with_labels_details=true":
+			w.Header().Set("Link", `<http://`+r.Host+`/api/v4/projects/wichita%2Fsite/issues?page=2>; rel="next"`)
+			w.Write([]byte(`[{
+				"iid": 7,
+				"title": "Fix it",
+				"description": "It's broken",
+				"created_at": "2017-10-02T00:00:00Z",
+				"web_url": "https://gitlab.com/wichita/site/issues/7",
+				"user_notes_count": 2,
+				"labels": [{"name": "hacktoberfest", "color": "#ff8800"}, {"name": "Good First Issue", "color": "#00ff00"}],
+				"assignees": []
+			}]`))
+		case "/api/v4/projects/wichita%2Fsite/issues?page=2":
+			w.Write([]byte(`[]`))
+		case "/api/v4/projects/wichita%2Fsite/languages":
+			w.Write([]byte(`{"Go": 60.5, "HTML": 39.5}`))
+		default:
+			t.Errorf("unexpected request for %s", r.RequestURI)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := newGitLab(srv.URL+"/", "sekret", []string{"wichita/site"})
+	g.api.Retries = 0
+
+	ch := make(chan Issue, 10)
+	if err := g.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{MaxLangs: 1}, ch); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	close(ch)
+
+	var got []Issue
+	for i := range ch {
+		got = append(got, i)
+	}
+	want := []Issue{{
+		Title:          "Fix it",
+		Number:         7,
+		State:          "open",
+		Date:           time.Date(2017, 10, 2, 0, 0, 0, 0, time.UTC),
+		URL:            "https://gitlab.com/wichita/site/issues/7",
+		Repo:           Repo{Owner: "wichita", Name: "site"},
+		Labels:         map[string]string{"Good First Issue": "00ff00"},
+		Languages:      []string{"Go"},
+		LanguageShares: []aggregator.Language{{Name: "Go", Percent: 60.5}},
+		Comments:       2,
+		Body:           "It's broken",
+		Difficulty:     aggregator.Easy,
+
+		NaturalLanguages: []string{},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGitLabSearchIssuesFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	g := newGitLab(srv.URL, "", []string{"wichita/gone"})
+	err := g.SearchIssues(context.Background(), "hacktoberfest", aggregator.Query{}, make(chan Issue))
+	if err == nil {
+		t.Errorf("error should not be nil, but it was")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// repoGroup is a repo and its issues, for listings grouped by repo. What we
+// know about the repo is said once here rather than on every issue.
+type repoGroup struct {
+	Repo      Repo           `json:"repo"`
+	Languages []string       `json:"languages"`
+	Issues    []groupedIssue `json:"issues"`
+}
+
+// groupedIssue is an Issue without the repo details its group already has.
+type groupedIssue struct {
+	Issue
+
+	// These hide the Issue's own fields so they're left out
+	Repo      *Repo    `json:"repo,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+// groupParam reads the group query parameter, which can only be repo for now.
+func groupParam(r *http.Request) (bool, error) {
+	switch v := r.URL.Query().Get("group"); v {
+	case "":
+		return false, nil
+	case "repo":
+		return true, nil
+	default:
+		return false, fmt.Errorf("group %q should be repo", v)
+	}
+}
+
+// groupByRepo puts issues into a group for each repo, in the order each repo
+// first comes up, keeping the order of the issues within them.
+func groupByRepo(issues []Issue) []repoGroup {
+	groups := []repoGroup{}
+	index := make(map[string]int)
+	for _, i := range issues {
+		n, ok := index[i.Repo.ID()]
+		if !ok {
+			n = len(groups)
+			index[i.Repo.ID()] = n
+			groups = append(groups, repoGroup{Repo: i.Repo, Languages: i.Languages})
+		}
+		groups[n].Issues = append(groups[n].Issues, groupedIssue{Issue: i})
+	}
+	return groups
+}
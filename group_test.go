@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroupByRepo(t *testing.T) {
+	site := Repo{Owner: "devict", Name: "site", Stars: 3}
+	hub := Repo{Owner: "MakeICT", Name: "hub"}
+	issues := []Issue{
+		{Title: "a", Repo: site, Languages: []string{"Go"}},
+		{Title: "b", Repo: hub},
+		{Title: "c", Repo: site, Languages: []string{"Go"}},
+	}
+
+	groups := groupByRepo(issues)
+	if len(groups) != 2 || groups[0].Repo.Name != "site" || groups[1].Repo.Name != "hub" {
+		t.Fatalf("repos should be grouped in the order they first come up, got %+v", groups)
+	}
+	if len(groups[0].Issues) != 2 || groups[0].Issues[1].Title != "c" {
+		t.Errorf("got %+v, want a then c", groups[0].Issues)
+	}
+	if groups[0].Repo.Stars != 3 || len(groups[0].Languages) != 1 {
+		t.Errorf("repo details should be on the group, got %+v", groups[0])
+	}
+
+	data, err := json.Marshal(groups)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if n := strings.Count(string(data), `"owner"`); n != 2 {
+		t.Errorf("each repo should only be described once, got %d repos in %s", n, data)
+	}
+	if !strings.Contains(string(data), `"title":"a"`) {
+		t.Errorf("issues should still have their own fields, got %s", data)
+	}
+}
+
+func TestGroupParam(t *testing.T) {
+	if group, err := groupParam(httptest.NewRequest("GET", "/api/issues?group=repo", nil)); err != nil || !group {
+		t.Errorf("group=repo should group, got %v, %v", group, err)
+	}
+	if _, err := groupParam(httptest.NewRequest("GET", "/api/issues?group=label", nil)); err == nil {
+		t.Errorf("group=label should be an error")
+	}
+}
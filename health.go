@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// readyTimeout is how long /readyz waits on GitHub and the database before
+// calling us not ready.
+const readyTimeout = 5 * time.Second
+
+// warmed is set to 1 once the background refresh has filled the cache.
+var warmed int32
+
+// healthz answers as long as the process is up and serving.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// readyz reports whether we can serve issues properly: GitHub answers, the
+// background refresh has filled the cache if there is one, and the database
+// is up if we have one. Each check is reported as "ok" or what went wrong,
+// with a 503 if any of them failed.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	checks := map[string]error{
+		"github": githubReachable(ctx),
+	}
+	if app != nil || config.Token != "" {
+		checks["cache"] = nil
+		if atomic.LoadInt32(&warmed) == 0 {
+			checks["cache"] = errors.New("issues have not been fetched yet")
+		}
+	}
+	if db != nil {
+		checks["db"] = errors.Wrap(db.PingContext(ctx), "could not reach db")
+	}
+
+	status := http.StatusOK
+	data := make(map[string]string)
+	for name, err := range checks {
+		data[name] = "ok"
+		if err != nil {
+			data[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// githubReachable asks GitHub for our rate limit, which doesn't count against
+// it. It doesn't retry since whoever's asking will ask again soon enough.
+func githubReachable(ctx context.Context) error {
+	c := serverClient()
+	c.Retries = 0
+
+	var data struct{}
+	return c.Get(ctx, "/rate_limit", nil, &data)
+}
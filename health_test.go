@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	up := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/rate_limit" {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+		}
+		if !up {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	oldURL, oldToken := config.GitHubURL, config.Token
+	config.GitHubURL, config.Token = srv.URL, "abc123"
+	defer func() {
+		config.GitHubURL, config.Token = oldURL, oldToken
+		atomic.StoreInt32(&warmed, 0)
+	}()
+
+	tests := []struct {
+		up     bool
+		warmed int32
+		status int
+		want   map[string]string
+	}{
+		{true, 1, http.StatusOK, map[string]string{"github": "ok", "cache": "ok"}},
+		{true, 0, http.StatusServiceUnavailable, map[string]string{"github": "ok", "cache": "issues have not been fetched yet"}},
+		{false, 1, http.StatusServiceUnavailable, map[string]string{"github": "status was 502, not 200", "cache": "ok"}},
+	}
+	for _, test := range tests {
+		up = test.up
+		atomic.StoreInt32(&warmed, test.warmed)
+
+		w := httptest.NewRecorder()
+		readyz(w, httptest.NewRequest("GET", "/readyz", nil))
+
+		var got map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != test.status || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("up %v warmed %v: got %d %v, want %d %v", test.up, test.warmed, w.Code, got, test.status, test.want)
+		}
+	}
+}
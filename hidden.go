@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// People who are logged in can hide issues they aren't interested in so they
+// stop turning up in their listings. Hidden issues are a row each in hidden,
+// like bookmarks, and ?include_hidden=true brings them back.
+
+// getHidden lists the URLs of the issues someone has hidden.
+func getHidden(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	hidden, err := userURLs("hidden", u.UserID)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	urls := []string{}
+	for url := range hidden {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(urls); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// hideIssue hides an issue from whoever is logged in. Hiding one twice is
+// fine.
+func hideIssue(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	url, err := parseIssueURL(r)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO hidden (user_id, url, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, url) DO NOTHING`,
+		u.UserID,
+		url,
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unhideIssue puts a hidden issue back in whoever is logged in's listings.
+func unhideIssue(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	url, err := parseIssueURL(r)
+	if err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM hidden WHERE user_id = $1 AND url = $2", u.UserID, url); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Every full refresh of the listing records a snapshot of how many issues
+// are open, claimed and assigned, and how many opened and closed since the
+// refresh before, as a row in issue_snapshots. /api/v1/stats/history gives
+// them back for an October so we can chart how quickly issues get picked up.
+
+// Snapshot is the listing at one refresh.
+type Snapshot struct {
+	Taken    time.Time `json:"taken"`
+	Open     int       `json:"open"`
+	Claimed  int       `json:"claimed"`
+	Assigned int       `json:"assigned"`
+
+	// Opened and Closed are how many issues joined and left the listing
+	// since the snapshot before
+	Opened int `json:"opened"`
+	Closed int `json:"closed"`
+}
+
+// takeSnapshot works out the Snapshot of issues at now, with what changed
+// since the listing before.
+func takeSnapshot(issues []Issue, d issueDiff, claims map[string]claim, now time.Time) Snapshot {
+	s := Snapshot{Taken: now, Open: len(issues), Opened: len(d.Opened), Closed: len(d.Closed)}
+	for _, i := range issues {
+		if _, ok := claims[i.URL]; ok {
+			s.Claimed++
+		}
+		if i.Assigned {
+			s.Assigned++
+		}
+	}
+	return s
+}
+
+// recordSnapshot saves the snapshot of a full refresh. last is the listing
+// before, or nil if this is the first since we started, when there's nothing
+// to say opened or closed.
+func recordSnapshot(issues, last []Issue, now time.Time) error {
+	var d issueDiff
+	if last != nil {
+		d = diffIssues(last, issues)
+	}
+	claims, err := activeClaims(now)
+	if err != nil {
+		return err
+	}
+	s := takeSnapshot(issues, d, claims, now)
+
+	_, err = db.Exec(
+		"INSERT INTO issue_snapshots (taken_at, open, claimed, assigned, opened, closed) VALUES ($1, $2, $3, $4, $5, $6)",
+		s.Taken,
+		s.Open,
+		s.Claimed,
+		s.Assigned,
+		s.Opened,
+		s.Closed,
+	)
+	return errors.Wrap(err, "could not save snapshot")
+}
+
+// snapshotPeriods are how far apart the snapshots history gives back can be,
+// as Postgres date_trunc fields.
+var snapshotPeriods = map[string]bool{"hour": true, "day": true}
+
+// statsHistory gives the snapshots taken this October, or in the October of
+// the year query parameter. There's one every refresh, so they're thinned
+// out to the last of each hour, or of each day with every=day. Opened and
+// Closed are added up over what's left out so nothing goes missing.
+func statsHistory(w http.ResponseWriter, r *http.Request) {
+	if db == nil {
+		writeError(w, r, http.StatusBadRequest, codeDisabled, "history needs a database", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	year := time.Now().Year()
+	if v := q.Get("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 2014 {
+			invalidParameter(w, r, errors.New("year "+strconv.Quote(v)+" should be a year since Hacktoberfest began"))
+			return
+		}
+	}
+	every := "hour"
+	if v := q.Get("every"); v != "" {
+		if !snapshotPeriods[v] {
+			invalidParameter(w, r, errors.New("every "+strconv.Quote(v)+" should be hour or day"))
+			return
+		}
+		every = v
+	}
+	start, end := october(year)
+
+	// every is one of snapshotPeriods so it's safe to put in the query
+	rows, err := db.Query(
+		`SELECT taken_at, open, claimed, assigned, opened, closed FROM (
+			SELECT taken_at, open, claimed, assigned,
+				SUM(opened) OVER p AS opened, SUM(closed) OVER p AS closed,
+				ROW_NUMBER() OVER (PARTITION BY date_trunc('`+every+`', taken_at) ORDER BY taken_at DESC) AS n
+			FROM issue_snapshots
+			WHERE taken_at >= $1 AND taken_at < $2
+			WINDOW p AS (PARTITION BY date_trunc('`+every+`', taken_at))
+		) s WHERE n = 1 ORDER BY taken_at`,
+		start,
+		end,
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var s Snapshot
+		if err := rows.Scan(&s.Taken, &s.Open, &s.Claimed, &s.Assigned, &s.Opened, &s.Closed); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	writeEnvelope(w, r, apiEnvelope{Data: snapshots, Meta: apiMeta{Total: len(snapshots)}})
+}
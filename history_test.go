@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	now := time.Now()
+	old := []Issue{{URL: "a"}, {URL: "b"}}
+	issues := []Issue{{URL: "b", Assigned: true}, {URL: "c"}, {URL: "d"}}
+	claims := map[string]claim{"c": {By: "octocat"}, "gone": {By: "octocat"}}
+
+	got := takeSnapshot(issues, diffIssues(old, issues), claims, now)
+	want := Snapshot{Taken: now, Open: 3, Claimed: 1, Assigned: 1, Opened: 2, Closed: 1}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := takeSnapshot(issues, issueDiff{}, nil, now); got.Opened != 0 || got.Closed != 0 {
+		t.Errorf("got %+v, want nothing opened or closed without a listing before", got)
+	}
+}
+
+func TestStatsHistoryWithoutDatabase(t *testing.T) {
+	w := httptest.NewRecorder()
+	statsHistory(w, httptest.NewRequest("GET", "/api/v1/stats/history", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want 400 without a database", w.Code)
+	}
+}
@@ -4,276 +4,481 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"sync"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/devict/hacktoberfest/aggregator"
 	"github.com/pkg/errors"
 )
 
-// labels to fetch issues for
-var labels = map[string]bool{"hacktoberfest": true, "help wanted": true}
-
 // Issue is a requested change against one of our tracked GitHub repos.
-type Issue struct {
-	Title     string
-	Date      time.Time
-	URL       string
-	Repo      Repo
-	Labels    map[string]string
-	Languages []string
-}
+type Issue = aggregator.Issue
 
 // Labels are labels on a tracked issue.
-type Labels []struct {
-	Name  string `json:"name"`
-	Color string `json:"color"`
-}
+type Labels = aggregator.Labels
 
 func issues(w http.ResponseWriter, r *http.Request) {
-	u, _, ok := findUser(r)
+	c, ok := issueClient(r)
 	if !ok {
-		http.Error(w, "you are not logged in", http.StatusUnauthorized)
+		notLoggedIn(w, r)
 		return
 	}
 
-	issues, err := fetchIssues(r.Context(), u.AccessToken)
-	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	listIssues(w, r, c, defaultParams(c.Scope()))
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(issues); err != nil {
-		log.Println(err)
+// issueClient gives the Client to fetch issues for r with. With a GitHub App
+// everyone shares its tokens. Otherwise people who are logged in use their
+// own, searching GitHub with their GitHub token and GitLab with their GitLab
+// one. Someone with only a GitLab token shares the server's PAT for GitHub
+// if there is one, like everyone else does. Shared clients only get what's in
+// the cache.
+func issueClient(r *http.Request) (*Client, bool) {
+	if app != nil {
+		return sharedClient(), true
 	}
+	if tokens := userTokens(r); tokens != nil && (tokens["github"] != "" || config.Token == "") {
+		c := newClient(tokens["github"])
+		c.Others = withGitLabToken(c.Others, tokens["gitlab"])
+		return c, true
+	}
+	if config.Token == "" {
+		return nil, false
+	}
+	return sharedClient(), true
 }
 
-// fetchIssues makes concurrent requests to the search api to get issues with
-// particular labels. Their API won't let us search for something label:A OR
-// label:B only label:A AND label:B so we have to make multiple requests.
-func fetchIssues(ctx context.Context, token string) ([]Issue, error) {
-
-	// main chan where workers send their results
-	ch := make(chan Issue)
-
-	// errors is where workers will report failure. It has to have sufficient
-	// buffer space to prevent deadlocks because we only receive from it once
-	errors := make(chan error, len(labels))
-
-	// cCtx is a new context derived from our own. We use it to signal workers to
-	// stop early in the case of an error.
-	cCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	var wg sync.WaitGroup
-	wg.Add(len(labels))
-	for l := range labels {
-		go func(l string) {
-			if err := issueSearch(cCtx, l, token, ch); err != nil {
-				errors <- err
-			}
-			wg.Done()
-		}(l)
-	}
-
-	// When all searches are done close the channel so we stop trying to read it
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
-
-	var issues []Issue
-	for {
-		select {
-
-		// One of the workers failed so cancel the others and pass the error up
-		case err := <-errors:
-			cancel()
-			return nil, err
-
-		// Read from ch. If it was closed then we know we're done reading so dedupe
-		// our results and send them up. If it was open just append the value.
-		case i, open := <-ch:
-			if !open {
-				return dedupe(issues), nil
-			}
-			issues = append(issues, i)
-		}
+// serverClient is a Client using our own credentials: the GitHub App if there
+// is one, otherwise the PAT.
+func serverClient() *Client {
+	c := newClient(config.Token)
+	if app != nil {
+		c.Tokens = app
 	}
+	return c
 }
 
-// dedupe returns only the unique values from the issues provided. It uses the
-// URL field for identity.
-func dedupe(in []Issue) []Issue {
-	uniq := []Issue{}
-	seen := make(map[string]int)
-	for _, i := range in {
-		if seen[i.URL] == 0 {
-			uniq = append(uniq, i)
-		}
-		seen[i.URL]++
-	}
-	return uniq
+// sharedClient is serverClient on behalf of anyone.
+func sharedClient() *Client {
+	c := serverClient()
+	c.Shared = true
+	return c
 }
 
-// issueSearch makes a single request to the github search api. Issues are fed
-// into ch as they are found. An error is returned if we could not complete the
-// request or GitHub responds with anything but a 200. A ctx is provided so we
-// know if we need to quit early.
-func issueSearch(ctx context.Context, label, token string, ch chan<- Issue) error {
-	ctx.Done()
+// repoIssues lists issues for a single tracked repo given as /{owner}/{repo}.
+// Untracked repos are a 404 so we can't be used to search just anything.
+func repoIssues(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
 
-	req, err := http.NewRequest("GET", "https://api.github.com/search/issues", nil)
-	if err != nil {
-		return errors.Wrap(err, "could not build request")
+	repo := Repo{
+		Owner: r.URL.Query().Get(":owner"),
+		Name:  r.URL.Query().Get(":repo"),
 	}
+	if !tracked(repo) {
+		writeError(w, r, http.StatusNotFound, codeNotFound, repo.ID()+" isn't tracked", nil)
+		return
+	}
+
+	// The repo is on GitHub so there's no need to look anywhere else
+	c.Others = nil
+	listIssues(w, r, c, defaultParams(repoScope(repo)))
+}
 
-	// Tell the request to use our context so we can cancel it in-flight if needed
-	req = req.WithContext(ctx)
+// repoScope is the search qualifier covering just the one repo.
+func repoScope(r Repo) string {
+	return "repo:" + r.Owner + "/" + r.Name
+}
 
-	q := fmt.Sprintf(`is:open type:issue label:"%s"`, label)
-	for k := range orgs {
-		q += " org:" + k
+// listIssues writes out the issues searching for defaults finds, shaped by the
+// request's query parameters. Requests to /api/v1 get them in an apiEnvelope.
+func listIssues(w http.ResponseWriter, r *http.Request, c *Client, defaults searchParams) {
+	r = preferred(r)
+	p, err := parseParams(r, defaults)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
 	}
-	for k := range projects {
-		q += " repo:" + k
+
+	f, err := parseFilter(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
 	}
 
-	vals := req.URL.Query()
-	vals.Add("q", q)
-	vals.Add("sort", "updated")
-	vals.Add("order", "asc")
-	vals.Add("per_page", "100")
-	req.URL.RawQuery = vals.Encode()
+	o, err := parseOrder(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
 
-	// Use their access token so it counts against their rate limit
-	if token != "" {
-		req.Header.Add("Authorization", "token "+token)
+	pg, err := parsePage(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	q, err := parseQuota(r, p)
 	if err != nil {
-		return errors.Wrap(err, "could not execute request")
+		invalidParameter(w, r, err)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return errors.Wrapf(err, "status was %d, not 200", resp.StatusCode)
+	includeHidden, err := boolParam(r, "include_hidden")
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
 	}
 
-	var data struct {
-		Items []struct {
-			Title     string    `json:"title"`
-			CreatedAt time.Time `json:"created_at"`
-			HTMLURL   string    `json:"html_url"`
-			RepoURL   string    `json:"repository_url"`
-			Labels    `json:"labels"`
-		} `json:"items"`
+	group, err := groupParam(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return errors.Wrap(err, "could not decode json")
+	if group && (pg.paged || r.URL.Query().Get("format") == "csv") {
+		invalidParameter(w, r, errors.New("group can't be used with pages or csv"))
+		return
+	}
+
+	if wantsNDJSON(r) {
+		// Streamed issues go out as they're found so there's nothing to sort,
+		// group or split into pages
+		if o.by != "" || pg.paged || group {
+			invalidParameter(w, r, errors.New("sort, group and pages can't be used when streaming"))
+			return
+		}
+		streamNDJSON(w, r, c, p, f, q, loadMarks(r, includeHidden))
+		return
 	}
 
-	for _, item := range data.Items {
+	// Unless asked to be strict we make do with what the searches that worked
+	// found and say which didn't
+	issues, err := loadIssues(r.Context(), c, p, refreshParam(r))
+	if !servable(err) || (err != nil && strictParam(r)) {
+		upstreamError(w, r, err)
+		return
+	}
+	warn := incomplete(err)
+	setStale(w, err)
 
-		lf := newLanguageFetcher()
-		languages, err := lf.repoLanguages(ctx, item.RepoURL, token)
+	issues = f.apply(issues)
+	o.sort(issues)
+	if u, _, ok := findUser(r); ok {
+		issues = personalize(r.Context(), u, issues, includeHidden)
+	}
+	issues = q.apply(issues)
+	if db != nil {
+		claims, err := activeClaims(time.Now())
 		if err != nil {
-			return err
+			logError(r.Context(), err)
 		}
+		markClaimed(issues, claims)
+		matchMentors(r, issues)
+	}
+	markClosed(issues, closures.urls())
 
-		// filter out hacktoberfest labels
-		issueLabels := labelFilter(item.Labels)
-
-		issue := Issue{
-			Title:     item.Title,
-			Date:      item.CreatedAt,
-			URL:       item.HTMLURL,
-			Labels:    issueLabels,
-			Languages: languages,
+	if r.URL.Query().Get("format") == "csv" {
+		if pg.paged {
+			issues = pg.envelope(issues).Issues
+		}
+		setWarnings(w, warn)
+		if err := writeCSV(w, issues); err != nil {
+			logError(r.Context(), err)
 		}
+		return
+	}
 
-		issue.Repo, err = repoFromURL(item.RepoURL)
-		if err != nil {
-			return errors.Wrapf(err, "could not identify repo from %s", item.RepoURL)
+	if apiV1(r) {
+		_, stale := err.(*staleError)
+		env := apiEnvelope{Data: issues, Meta: apiMeta{Total: len(issues), Stale: stale, RateLimit: searchRateLimit(c), Warnings: languageWarnings(issues)}, Errors: warn.Warnings()}
+		switch {
+		case pg.paged:
+			page := pg.envelope(issues)
+			env.Data = page.Issues
+			env.Meta.Page, env.Meta.PerPage, env.Meta.Pages = page.Page, page.PerPage, page.Pages
+		case group:
+			env.Data = groupByRepo(issues)
 		}
+		writeEnvelope(w, r, env)
+		return
+	}
 
-		select {
+	w.Header().Set("Content-Type", "application/json")
 
-		// Stop early because another worker failed
-		case <-ctx.Done():
-			return nil
+	// The unversioned routes give back the bare list without paging, like
+	// they always have, or the groups, so warnings have to go in headers
+	var data interface{} = issues
+	switch {
+	case pg.paged:
+		env := pg.envelope(issues)
+		env.Warnings = warn.Warnings()
+		data = env
+	case group:
+		data = groupByRepo(issues)
+		setWarnings(w, warn)
+	default:
+		setWarnings(w, warn)
+	}
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// defaultMaxLangs is how many of a repo's top languages we list unless
+// config.MaxLanguages or max_langs says otherwise.
+const defaultMaxLangs = 3
+
+// searchParams is everything that changes what fetchIssues gives back.
+type searchParams struct {
+	// scope is the search qualifiers limiting which repos are searched
+	scope string
+
+	// labels are searched for one at a time. Issues with any of them are found.
+	labels []string
+
+	// maxLangs is how many languages to list per repo. Zero means all of them.
+	maxLangs int
+
+	// topics, if set, are what a repo needs one of to be Participating, for
+	// events other than Hacktoberfest
+	topics []string
 
-		// Send our issue on ch if we can
-		case ch <- issue:
+	// created and updated, unless they're zero, limit the search to issues
+	// opened or last changed on or after that day
+	created time.Time
+	updated time.Time
+
+	// credentials are whose tokens the searches are made with, from
+	// Client.credentials, so what one person's tokens found, or failed to,
+	// is only ever shared with themselves
+	credentials string
+}
+
+// dateFormat is how search qualifiers give days.
+const dateFormat = "2006-01-02"
+
+// query is the aggregator.Query making the searches p is for.
+func (p searchParams) query() aggregator.Query {
+	return aggregator.Query{
+		Scope:    p.scope,
+		Labels:   p.labels,
+		MaxLangs: p.maxLangs,
+		Topics:   p.topics,
+		Created:  p.created,
+		Updated:  p.updated,
+	}
+}
+
+// covers reports whether the search qualifiers in p.scope include repo.
+func (p searchParams) covers(r Repo) bool {
+	for _, q := range strings.Fields(p.scope) {
+		if strings.EqualFold(q, "org:"+r.Owner) || strings.EqualFold(q, "repo:"+r.Owner+"/"+r.Name) {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-type languageFetcher struct {
-	fetchedRepos map[string][]string
+// key identifies the set of searches fetchIssues will make for p so identical
+// calls can share a result.
+func (p searchParams) key() string {
+	var keys []string
+	for _, l := range p.labels {
+		keys = append(keys, "label:"+l)
+	}
+	sort.Strings(keys)
+	key := fmt.Sprintf("%s %s langs:%d", strings.Join(keys, " "), p.scope, p.maxLangs)
+	if p.topics != nil {
+		key += " topics:" + strings.Join(p.topics, ",")
+	}
+	if !p.created.IsZero() {
+		key += " created:" + p.created.Format(dateFormat)
+	}
+	if !p.updated.IsZero() {
+		key += " updated:" + p.updated.Format(dateFormat)
+	}
+	if p.credentials != "" {
+		key += " as:" + p.credentials
+	}
+	return key
 }
 
-func newLanguageFetcher() *languageFetcher {
-	return &languageFetcher{
-		fetchedRepos: make(map[string][]string),
+// defaultParams searches scope for every tracked label.
+func defaultParams(scope string) searchParams {
+	return searchParams{
+		scope:    scope,
+		labels:   tracking().labelList(),
+		maxLangs: config.MaxLanguages,
 	}
 }
 
-func (lf *languageFetcher) repoLanguages(ctx context.Context, repoURL, token string) ([]string, error) {
-	// Return cached languages if already fetched from repo.
-	if langs := lf.fetchedRepos[repoURL]; langs != nil {
-		return langs, nil
+// parseParams builds the searchParams from p, usually defaultParams, and the
+// max_langs, labels, max_age and since query parameters.
+func parseParams(r *http.Request, p searchParams) (searchParams, error) {
+
+	maxLangs, err := maxLangsParam(r)
+	if err != nil {
+		return searchParams{}, err
 	}
+	p.maxLangs = maxLangs
 
-	// If not cached, get languages from repo.
-	req, err := http.NewRequest("GET", fmt.Sprintf(repoURL+"/languages"), nil)
+	labels, err := labelsParam(r)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not build request")
+		return searchParams{}, err
+	}
+	if labels != nil {
+		p.labels = labels
+	}
+
+	if p.created, err = maxAgeParam(r, time.Now()); err != nil {
+		return searchParams{}, err
+	}
+	if p.updated, err = sinceParam(r); err != nil {
+		return searchParams{}, err
+	}
+
+	return p, nil
+}
+
+// maxAgeParam reads the max_age query parameter, like 30d, 2w or 12h, as the
+// day an issue has to have been opened on or after to be that new at now.
+// It's zero if there isn't one. We only go by days so searches made through
+// the day can share results.
+func maxAgeParam(r *http.Request, now time.Time) (time.Time, error) {
+	v := r.URL.Query().Get("max_age")
+	if v == "" {
+		return time.Time{}, nil
 	}
 
-	// Tell the request to use our context so we can cancel it in-flight if needed
-	req = req.WithContext(ctx)
+	age, err := parseAge(v)
+	if err != nil || age <= 0 {
+		return time.Time{}, fmt.Errorf("max_age %q should be a length of time like 30d, 2w or 12h", v)
+	}
+	t := now.UTC().Add(-age)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseAge is time.ParseDuration that also takes days and weeks, like 30d or
+// 2w, on their own.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
 
-	// Use their access token so it counts against their rate limit
-	if token != "" {
-		req.Header.Add("Authorization", "token "+token)
+// sinceParam reads the since query parameter, a day like 2017-10-01, as the
+// day an issue has to have been updated on or after. It's zero if there
+// isn't one.
+func sinceParam(r *http.Request) (time.Time, error) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		return time.Time{}, nil
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	t, err := time.Parse(dateFormat, v)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not execute request")
+		return time.Time{}, fmt.Errorf("since %q should be a day like 2017-10-01", v)
+	}
+	return t, nil
+}
+
+// maxLangsParam reads the max_langs query parameter, falling back to
+// config.MaxLanguages when there isn't one.
+func maxLangsParam(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("max_langs")
+	if v == "" {
+		return config.MaxLanguages, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("max_langs %q is not a number or is negative", v)
+	}
+	return n, nil
+}
+
+// maxLabels is the most labels one request can ask for, since each is its own
+// set of searches.
+const maxLabels = 5
+
+// labelsParam reads the comma separated labels query parameter. It's nil if
+// there isn't one, meaning search the tracked labels.
+func labelsParam(r *http.Request) ([]string, error) {
+	v := r.URL.Query().Get("labels")
+	if v == "" {
+		return nil, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, errors.Wrapf(err, "status was %d, not 200", resp.StatusCode)
+	labels := sortedKeys(set(strings.Split(v, ",")))
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("labels %q has no labels in it", v)
 	}
-	data := make(map[string]int)
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, errors.Wrap(err, "could not decode json")
+	if len(labels) > maxLabels {
+		return nil, fmt.Errorf("at most %d labels can be searched at once", maxLabels)
+	}
+
+	// Labels go inside quotes in the search so one with a quote in it could
+	// add qualifiers of its own, like searching outside our scope
+	for _, l := range labels {
+		if strings.Contains(l, `"`) {
+			return nil, fmt.Errorf("label %q can't contain a quote", l)
+		}
+	}
+
+	return labels, nil
+}
+
+// IssueSource is somewhere we can find issues. Client is the one for GitHub.
+type IssueSource = aggregator.Source
+
+// issueSearch searches srcs the way config says to: for no longer than
+// config.FetchTimeout, config.SearchConcurrency at a time going by
+// config.LabelPriority, and leaving out excluded repos.
+func issueSearch(srcs []IssueSource) *aggregator.Search {
+	return &aggregator.Search{
+		Sources:     srcs,
+		Timeout:     config.FetchTimeout,
+		Concurrency: config.SearchConcurrency,
+		Priority:    config.LabelPriority,
+		Exclude:     tracking().excludes,
+		Name:        sourceName,
+		Observer:    observer{},
 	}
+}
 
-	// Get top three languages
-	langs := top(3, data)
+// fetchIssues collects every issue streamIssues finds for p, merging the
+// copies different searches found. If some searches didn't finish we still
+// give back what the others found, along with the *incompleteError. How it
+// went is kept in searchRuns.
+func fetchIssues(ctx context.Context, srcs []IssueSource, p searchParams) ([]Issue, error) {
+	issues, err := issueSearch(srcs).Fetch(ctx, p.query())
+	if ctx.Err() == nil {
+		searchRuns.record(p, issues, err, time.Now())
+	}
+	return issues, err
+}
 
-	// Cache repo languages.
-	lf.fetchedRepos[repoURL] = langs
-	return langs, nil
+// streamIssues passes each issue searching srcs for p finds to found as soon
+// as it's found, see aggregator.Search.Stream.
+func streamIssues(ctx context.Context, srcs []IssueSource, p searchParams, found func(Issue) error) error {
+	return issueSearch(srcs).Stream(ctx, p.query(), found)
 }
 
 // labelFilter filters to show only labels that are
 // not related to hacktoberfest.
 func labelFilter(lbs Labels) map[string]string {
-	issueLabels := make(map[string]string)
-	for _, label := range lbs {
-		if !labels[label.Name] {
-			issueLabels[label.Name] = label.Color
-		}
-	}
-	return issueLabels
+	return aggregator.FilterLabels(lbs, tracking().Labels)
 }
@@ -1,8 +1,18 @@
 package main
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
 )
 
 func TestLabelFilter(t *testing.T) {
@@ -21,3 +31,561 @@ func TestLabelFilter(t *testing.T) {
 		t.Errorf("want %v", want)
 	}
 }
+
+func TestStreamIssuesDedupes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/issues":
+			// Every label search finds the same issue
+			w.Write([]byte(`{"items": [{
+				"title": "Fix it",
+				"html_url": "https://github.com/devict/hacktoberfest/issues/1",
+				"repository_url": "https://api.github.com/repos/devict/hacktoberfest"
+			}]}`))
+		default:
+			w.Write([]byte(`{"Go": 100}`))
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	var got []Issue
+	p := defaultParams(c.Scope())
+	err := streamIssues(context.Background(), c.Sources(), p, func(i Issue) error {
+		got = append(got, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got %d issues, want 1: %+v", len(got), got)
+	}
+}
+
+// settled waits a little while for the number of goroutines to drop back to
+// want, reporting whether it did.
+func settled(want int) bool {
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= want {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestStreamIssuesCancelWhileProducing(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search/issues" {
+			w.Write([]byte(`{"items": [
+				{"html_url": "https://github.com/devict/hacktoberfest/issues/1", "repository_url": "https://api.github.com/repos/devict/hacktoberfest"},
+				{"html_url": "https://github.com/devict/hacktoberfest/issues/2", "repository_url": "https://api.github.com/repos/devict/hacktoberfest"},
+				{"html_url": "https://github.com/devict/hacktoberfest/issues/3", "repository_url": "https://api.github.com/repos/devict/hacktoberfest"}
+			]}`))
+			return
+		}
+		w.Write([]byte(`{"Go": 100}`))
+	}))
+	c := testClient(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got int
+	err := streamIssues(ctx, c.Sources(), defaultParams(c.Scope()), func(i Issue) error {
+		got++
+		cancel()
+		return nil
+	})
+
+	if err != context.Canceled {
+		t.Errorf("error should be %v, got %v", context.Canceled, err)
+	}
+	if got != 1 {
+		t.Errorf("got %d issues after cancelling, want 1", got)
+	}
+
+	srv.Close()
+	srv.Client().Transport.(*http.Transport).CloseIdleConnections()
+	if !settled(before) {
+		t.Errorf("goroutines leaked: %d before, %d after", before, runtime.NumGoroutine())
+	}
+}
+
+func TestStreamIssuesCancelInFlight(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Give up from the caller's side while GitHub is still thinking
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	c := testClient(srv)
+
+	err := streamIssues(ctx, c.Sources(), defaultParams(c.Scope()), func(i Issue) error {
+		t.Errorf("should not have found %+v", i)
+		return nil
+	})
+
+	if err != context.Canceled {
+		t.Errorf("error should be %v, got %v", context.Canceled, err)
+	}
+
+	srv.Close()
+	srv.Client().Transport.(*http.Transport).CloseIdleConnections()
+	if !settled(before) {
+		t.Errorf("goroutines leaked: %d before, %d after", before, runtime.NumGoroutine())
+	}
+}
+
+func TestLabelsParam(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+		err   bool
+	}{
+		{"", nil, false},
+		{"labels=bug", []string{"bug"}, false},
+		{"labels=good+first+issue,+bug,bug", []string{"bug", "good first issue"}, false},
+		{"labels=,", nil, true},
+		{"labels=a,b,c,d,e,f", nil, true},
+		{`labels=x"+org:golang+label:"y`, nil, true},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/issues?"+test.query, nil)
+		got, err := labelsParam(r)
+		if test.err != (err != nil) {
+			t.Errorf("%q: got error %v, want error %v", test.query, err, test.err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestDateParams(t *testing.T) {
+	now := time.Date(2017, 10, 31, 15, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2017, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		query   string
+		created time.Time
+		updated time.Time
+		err     bool
+	}{
+		{"", time.Time{}, time.Time{}, false},
+		{"max_age=30d", day(1), time.Time{}, false},
+		{"max_age=2w", day(17), time.Time{}, false},
+		{"max_age=12h", day(31), time.Time{}, false},
+		{"since=2017-10-20", time.Time{}, day(20), false},
+		{"max_age=soon", time.Time{}, time.Time{}, true},
+		{"max_age=-3d", time.Time{}, time.Time{}, true},
+		{"since=yesterday", time.Time{}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/issues?"+test.query, nil)
+		created, err := maxAgeParam(r, now)
+		updated, err2 := sinceParam(r)
+		if err == nil {
+			err = err2
+		}
+		if test.err != (err != nil) {
+			t.Errorf("%q: got error %v, want error %v", test.query, err, test.err)
+			continue
+		}
+		if !created.Equal(test.created) || !updated.Equal(test.updated) {
+			t.Errorf("%q: got %v and %v, want %v and %v", test.query, created, updated, test.created, test.updated)
+		}
+	}
+}
+
+func TestSearchQueryDates(t *testing.T) {
+	p := searchParams{
+		scope:   "org:devict",
+		created: time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC),
+		updated: time.Date(2017, 10, 20, 0, 0, 0, 0, time.UTC),
+	}
+
+	want := `is:open type:issue label:"hacktoberfest" org:devict created:>=2017-10-01 updated:>=2017-10-20`
+	if got := aggregator.SearchQuery("hacktoberfest", p.scope, p.created, p.updated); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if p.key() == (searchParams{scope: "org:devict"}).key() {
+		t.Errorf("searches with dates shouldn't share results with ones without")
+	}
+
+	if p.query().InRange(Issue{Date: time.Date(2017, 9, 30, 0, 0, 0, 0, time.UTC), Updated: p.updated}) {
+		t.Errorf("issue opened before the day shouldn't be in range")
+	}
+}
+
+// fakeSource finds the same issues for every label.
+type fakeSource []Issue
+
+func (f fakeSource) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	for _, i := range f {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ch <- i:
+		}
+	}
+	return nil
+}
+
+func TestStreamIssuesSources(t *testing.T) {
+	srcs := []IssueSource{
+		fakeSource{{URL: "https://github.com/a/b/issues/1"}},
+		fakeSource{{URL: "https://gitlab.com/a/b/issues/1"}, {URL: "https://github.com/a/b/issues/1"}},
+	}
+	p := searchParams{labels: []string{"hacktoberfest", "help wanted"}}
+
+	issues, err := fetchIssues(context.Background(), srcs, p)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("got %d issues, want one from each source: %+v", len(issues), issues)
+	}
+}
+
+// labelSource finds a different copy of the same issues for each label.
+type labelSource map[string][]Issue
+
+func (f labelSource) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	return fakeSource(f[label]).SearchIssues(ctx, label, q, ch)
+}
+
+func TestFetchIssuesMerges(t *testing.T) {
+	day := time.Date(2017, 10, 2, 0, 0, 0, 0, time.UTC)
+	srcs := []IssueSource{labelSource{
+		"hacktoberfest": {
+			{URL: "https://github.com/a/b/issues/1", Date: day, Updated: day},
+			{URL: "https://github.com/a/b/issues/3", Date: day.Add(time.Hour)},
+		},
+		"help wanted": {
+			{URL: "https://github.com/a/b/issues/2", Date: day},
+			{URL: "https://github.com/a/b/issues/1", Date: day, Updated: day.Add(time.Hour), Languages: []string{"Go"}},
+		},
+	}}
+	p := searchParams{labels: []string{"hacktoberfest", "help wanted"}}
+
+	// Whichever search finishes first, it comes out the same
+	for n := 0; n < 20; n++ {
+		issues, err := fetchIssues(context.Background(), srcs, p)
+		if err != nil {
+			t.Fatalf("error should be nil, got %v", err)
+		}
+		var urls []string
+		for _, i := range issues {
+			urls = append(urls, i.URL)
+		}
+		want := []string{"https://github.com/a/b/issues/3", "https://github.com/a/b/issues/1", "https://github.com/a/b/issues/2"}
+		if !reflect.DeepEqual(urls, want) {
+			t.Fatalf("got %v, want newest first then by URL", urls)
+		}
+		if i := issues[1]; !reflect.DeepEqual(i.MatchedLabels, []string{"hacktoberfest", "help wanted"}) || len(i.Languages) != 1 {
+			t.Fatalf("got %+v, want the later copy matching both labels", i)
+		}
+		if i := issues[0]; !reflect.DeepEqual(i.MatchedLabels, []string{"hacktoberfest"}) {
+			t.Fatalf("got %v, want only the label that found it", i.MatchedLabels)
+		}
+	}
+}
+
+// orderSource notes which labels it's asked to search for, and how many
+// searches are running at once at most.
+type orderSource struct {
+	mu      sync.Mutex
+	labels  []string
+	running int
+	most    int
+}
+
+func (f *orderSource) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	f.mu.Lock()
+	f.labels = append(f.labels, label)
+	f.running++
+	if f.running > f.most {
+		f.most = f.running
+	}
+	f.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	f.running--
+	f.mu.Unlock()
+	return nil
+}
+
+func TestSearchIssuesPriority(t *testing.T) {
+	old := config
+	defer func() { config = old }()
+	config.SearchConcurrency = 1
+	config.LabelPriority = []string{"Help Wanted", "hacktoberfest"}
+
+	src := &orderSource{}
+	p := searchParams{labels: []string{"bug", "hacktoberfest", "help wanted"}}
+	if _, err := fetchIssues(context.Background(), []IssueSource{src}, p); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if want := []string{"help wanted", "hacktoberfest", "bug"}; !reflect.DeepEqual(src.labels, want) {
+		t.Errorf("searched %v, want %v", src.labels, want)
+	}
+
+	config.SearchConcurrency = 2
+	src = &orderSource{}
+	p.labels = []string{"a", "b", "c", "d", "e"}
+	if _, err := fetchIssues(context.Background(), []IssueSource{src}, p); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if len(src.labels) != 5 || src.most > 2 {
+		t.Errorf("searched %v with %d at once, want all of them 2 at a time at most", src.labels, src.most)
+	}
+}
+
+// slowSource finds nothing for "slow" until it's given up on.
+type slowSource []Issue
+
+func (f slowSource) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	if label == "slow" {
+		<-ctx.Done()
+		return errors.Wrap(ctx.Err(), "could not search")
+	}
+	return fakeSource(f).SearchIssues(ctx, label, q, ch)
+}
+
+func TestStreamIssuesTimeout(t *testing.T) {
+	old := config.FetchTimeout
+	config.FetchTimeout = 20 * time.Millisecond
+	defer func() { config.FetchTimeout = old }()
+
+	srcs := []IssueSource{slowSource{{URL: "https://github.com/a/b/issues/1"}}}
+	p := searchParams{labels: []string{"hacktoberfest", "slow"}}
+
+	issues, err := fetchIssues(context.Background(), srcs, p)
+	incomplete, ok := err.(*incompleteError)
+	if !ok {
+		t.Fatalf("error should be an *incompleteError, got %v", err)
+	}
+	if len(incomplete.Failed) != 1 || incomplete.Failed[0].Label != "slow" {
+		t.Errorf("only the slow search should have failed, got %+v", incomplete.Failed)
+	}
+	if len(issues) != 1 {
+		t.Errorf("issues from the other search should still come back, got %+v", issues)
+	}
+}
+
+func TestClientCallTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := testClient(srv)
+	c.Retries = 0
+	c.CallTimeout = 10 * time.Millisecond
+
+	var data struct{}
+	err := c.Get(context.Background(), "/slow", nil, &data)
+	if te, ok := errors.Cause(err).(interface{ Timeout() bool }); !ok || !te.Timeout() {
+		t.Errorf("error should be a timeout, got %v", err)
+	}
+}
+
+// brokenSource fails to search for "broken".
+type brokenSource []Issue
+
+func (f brokenSource) SearchIssues(ctx context.Context, label string, q aggregator.Query, ch chan<- Issue) error {
+	if label == "broken" {
+		return errors.New("status was 502, not 200")
+	}
+	return fakeSource(f).SearchIssues(ctx, label, q, ch)
+}
+
+func TestStreamIssuesPartial(t *testing.T) {
+	srcs := []IssueSource{brokenSource{{URL: "https://github.com/a/b/issues/1"}}}
+
+	p := searchParams{labels: []string{"hacktoberfest", "broken"}}
+	issues, err := fetchIssues(context.Background(), srcs, p)
+	warn := incomplete(err)
+	if warn == nil {
+		t.Fatalf("error should be an *incompleteError, got %v", err)
+	}
+	want := []searchWarning{{Source: "main.brokenSource", Label: "broken", Error: "status was 502, not 200"}}
+	if got := warn.Warnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings %+v, want %+v", got, want)
+	}
+	if len(issues) != 1 {
+		t.Errorf("issues from the other search should still come back, got %+v", issues)
+	}
+
+	// When nothing worked there's nothing to show
+	p = searchParams{labels: []string{"broken"}}
+	if _, err := fetchIssues(context.Background(), srcs, p); err == nil || incomplete(err) != nil {
+		t.Errorf("every search failing should be an error, got %v", err)
+	}
+}
+
+// byURL indexes issues by their URL.
+func byURL(issues []Issue) map[string]Issue {
+	m := make(map[string]Issue)
+	for _, i := range issues {
+		m[i.URL] = i
+	}
+	return m
+}
+
+func TestFetchIssuesFromFixtures(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+	c := testClient(gh.Server)
+
+	issues, err := fetchIssues(context.Background(), c.Sources(), defaultParams(c.Scope()))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	found := byURL(issues)
+	if len(issues) != 4 || len(found) != 4 {
+		t.Fatalf("got %d issues, want the 4 different ones in the fixtures once each", len(issues))
+	}
+
+	tests := []struct {
+		url       string
+		labels    map[string]string
+		languages []string
+		assigned  bool
+	}{
+		{"https://github.com/devict/hacktoberfest/issues/1", map[string]string{"enhancement": "a2eeef"}, []string{"Go", "HTML", "JavaScript"}, false},
+		{"https://github.com/devict/hacktoberfest/issues/3", map[string]string{"css": "e99695"}, []string{"Go", "HTML", "JavaScript"}, false},
+		{"https://github.com/imacrayon/eventsinwichita/issues/7", map[string]string{"documentation": "0075ca"}, []string{"PHP", "Vue", "JavaScript"}, true},
+		{"https://github.com/devict/site/issues/12", map[string]string{}, []string{"HTML", "CSS"}, false},
+	}
+	for _, test := range tests {
+		i, ok := found[test.url]
+		if !ok {
+			t.Errorf("%s wasn't found", test.url)
+			continue
+		}
+		if !reflect.DeepEqual(i.Labels, test.labels) {
+			t.Errorf("%s: got labels %v, want %v", test.url, i.Labels, test.labels)
+		}
+		if !reflect.DeepEqual(i.Languages, test.languages) {
+			t.Errorf("%s: got languages %v, want %v", test.url, i.Languages, test.languages)
+		}
+		if i.Assigned != test.assigned {
+			t.Errorf("%s: got assigned %t, want %t", test.url, i.Assigned, test.assigned)
+		}
+	}
+
+	if n := gh.count("search hacktoberfest 2"); n != 1 {
+		t.Errorf("second page of hacktoberfest was read %d times, want 1", n)
+	}
+
+	// Two searches can ask about the same repo at once, but after that its
+	// languages should be remembered
+	repos := []string{"devict/hacktoberfest", "devict/site", "imacrayon/eventsinwichita"}
+	asked := make(map[string]int)
+	for _, r := range repos {
+		asked[r] = gh.count("languages " + r)
+		if asked[r] < 1 || asked[r] > 2 {
+			t.Errorf("languages of %s were asked for %d times, want once or twice", r, asked[r])
+		}
+	}
+	if _, err := fetchIssues(context.Background(), c.Sources(), defaultParams(c.Scope())); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	for _, r := range repos {
+		if n := gh.count("languages " + r); n != asked[r] {
+			t.Errorf("languages of %s were asked for again", r)
+		}
+	}
+}
+
+func TestFetchIssuesFromFixturesMaxPages(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+	c := testClient(gh.Server)
+	c.MaxPages = 1
+
+	issues, err := fetchIssues(context.Background(), c.Sources(), defaultParams(c.Scope()))
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if _, ok := byURL(issues)["https://github.com/devict/hacktoberfest/issues/3"]; ok || len(issues) != 3 {
+		t.Errorf("got %d issues, want the 3 on first pages", len(issues))
+	}
+	if n := gh.count("search hacktoberfest 2"); n != 0 {
+		t.Errorf("second page was read %d times, want it left alone", n)
+	}
+}
+
+func TestFetchIssuesFromFixturesSearchFails(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+	c := testClient(gh.Server)
+	gh.failWith("good first issue", http.StatusBadGateway)
+
+	issues, err := fetchIssues(context.Background(), c.Sources(), defaultParams(c.Scope()))
+	e := incomplete(err)
+	if e == nil {
+		t.Fatalf("got error %v, want an *incompleteError", err)
+	}
+	if len(e.Failed) != 1 || e.Failed[0].Label != "good first issue" {
+		t.Errorf("got %+v, want only good first issue to fail", e.Failed)
+	}
+
+	// Issue 1 is found by the hacktoberfest search too, only issue 12 is lost
+	found := byURL(issues)
+	if _, ok := found["https://github.com/devict/site/issues/12"]; ok || len(found) != 3 {
+		t.Errorf("got %d issues, want the 3 the other searches found", len(found))
+	}
+	if n := gh.count("search good first issue 1"); n != 1+c.Retries {
+		t.Errorf("failing search was tried %d times, want %d", n, 1+c.Retries)
+	}
+}
+
+func TestFetchIssuesFromFixturesLanguagesFail(t *testing.T) {
+	gh := newFakeGitHub(t)
+	defer gh.Close()
+	c := testClient(gh.Server)
+	gh.failWith("/repos/devict/site/languages", http.StatusNotFound)
+
+	issues, err := fetchIssues(context.Background(), c.Sources(), defaultParams(c.Scope()))
+	if err != nil {
+		t.Fatalf("got error %v, want devict/site's issues without languages", err)
+	}
+	if len(issues) != 4 {
+		t.Errorf("got %d issues, want all 4", len(issues))
+	}
+	for _, i := range issues {
+		site := i.Repo.ID() == "devict/site"
+		if i.LanguagesUnknown != site || (site && len(i.Languages) != 0) {
+			t.Errorf("%s has languages %v, unknown %v", i.URL, i.Languages, i.LanguagesUnknown)
+		}
+	}
+	if w := languageWarnings(issues); len(w) != 1 || !strings.Contains(w[0], "devict/site") {
+		t.Errorf("got warnings %v, want one naming devict/site", w)
+	}
+}
+
+func TestRepoIssuesUntracked(t *testing.T) {
+	oldToken := config.Token
+	config.Token = "abc123"
+	defer func() { config.Token = oldToken }()
+
+	w := httptest.NewRecorder()
+	repoIssues(w, httptest.NewRequest("GET", "/api/v1/issues/someone/else?:owner=someone&:repo=else", nil))
+	if w.Code != 404 || !strings.Contains(w.Body.String(), codeNotFound) {
+		t.Errorf("got %d %s for a repo we don't track, want 404", w.Code, w.Body)
+	}
+}
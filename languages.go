@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// LanguageCount is how many open issues are in repos that use a language.
+type LanguageCount struct {
+	Language string `json:"language"`
+	Count    int    `json:"count"`
+}
+
+func issueLanguages(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+
+	issues, err := loadIssues(r.Context(), c, defaultParams(c.Scope()), refreshParam(r))
+	if !servable(err) {
+		upstreamError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(countLanguages(issues)); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// countLanguages tallies issues by the top languages of their repo. An issue
+// counts once for each of its languages. Results are sorted by count, most
+// first, then by name.
+func countLanguages(issues []Issue) []LanguageCount {
+	counts := make(map[string]int)
+	for _, i := range issues {
+		for _, l := range i.Languages {
+			counts[l]++
+		}
+	}
+
+	langs := []LanguageCount{}
+	for l, c := range counts {
+		langs = append(langs, LanguageCount{Language: l, Count: c})
+	}
+
+	sort.Slice(langs, func(i, j int) bool {
+		if langs[i].Count != langs[j].Count {
+			return langs[i].Count > langs[j].Count
+		}
+		return langs[i].Language < langs[j].Language
+	})
+
+	return langs
+}
+
+// knownLanguages is what get gives for repo, or none if it fails, since
+// going without languages is better than going without the issues. unknown
+// says it failed, and err is only ctx's if it's done.
+func knownLanguages(ctx context.Context, repo Repo, get func() ([]aggregator.Language, error)) (langs []aggregator.Language, unknown bool, err error) {
+	langs, err = get()
+	if err == nil {
+		return langs, false, nil
+	}
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+	logWarn(ctx, "could not get languages", "repo", repo.ID(), "err", err)
+	return []aggregator.Language{}, true, nil
+}
+
+// maxNamedRepos is the most repos languageWarnings names.
+const maxNamedRepos = 5
+
+// languageWarnings says which repos in issues we couldn't get the languages
+// of, if there are any, so whoever asked knows their issues might have some.
+func languageWarnings(issues []Issue) []string {
+	unknown := make(map[string]bool)
+	for _, i := range issues {
+		if i.LanguagesUnknown {
+			unknown[i.Repo.ID()] = true
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	repos := sortedKeys(unknown)
+	named := strings.Join(repos, ", ")
+	if len(repos) > maxNamedRepos {
+		named = fmt.Sprintf("%s and %d more", strings.Join(repos[:maxNamedRepos], ", "), len(repos)-maxNamedRepos)
+	}
+	return []string{"couldn't get the languages of " + named + ", so their issues don't have any"}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+func TestCountLanguages(t *testing.T) {
+	data := []Issue{
+		{Languages: []string{"Go", "JavaScript"}},
+		{Languages: []string{"Rust"}},
+		{Languages: []string{"Go"}},
+		{Languages: []string{"Rust", "C"}},
+		{},
+	}
+
+	want := []LanguageCount{
+		{"Go", 2},
+		{"Rust", 2},
+		{"C", 1},
+		{"JavaScript", 1},
+	}
+	got := countLanguages(data)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("countLanguages(%v) failed", data)
+		t.Errorf("got %v", got)
+		t.Errorf("want %v", want)
+	}
+}
+
+func TestKnownLanguages(t *testing.T) {
+	repo := Repo{Owner: "devict", Name: "hacktoberfest"}
+	failing := func() ([]aggregator.Language, error) { return nil, errors.New("boom") }
+
+	langs, unknown, err := knownLanguages(context.Background(), repo, failing)
+	if err != nil || !unknown || langs == nil || len(langs) != 0 {
+		t.Errorf("got %v, %v, %v, want no languages, unknown and no error", langs, unknown, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := knownLanguages(ctx, repo, failing); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+
+	langs, unknown, err = knownLanguages(context.Background(), repo, func() ([]aggregator.Language, error) {
+		return []aggregator.Language{{Name: "Go", Percent: 100}}, nil
+	})
+	if err != nil || unknown || len(langs) != 1 {
+		t.Errorf("got %v, %v, %v, want Go", langs, unknown, err)
+	}
+}
+
+func TestLanguageWarnings(t *testing.T) {
+	if w := languageWarnings([]Issue{{Repo: Repo{Owner: "devict", Name: "a"}}}); w != nil {
+		t.Errorf("got %v, want no warnings", w)
+	}
+
+	var issues []Issue
+	for _, name := range []string{"g", "f", "e", "d", "c", "b", "a", "a"} {
+		issues = append(issues, Issue{Repo: Repo{Owner: "devict", Name: name}, LanguagesUnknown: true})
+	}
+	want := []string{"couldn't get the languages of devict/a, devict/b, devict/c, devict/d, devict/e and 2 more, so their issues don't have any"}
+	if got := languageWarnings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
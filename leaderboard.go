@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The leaderboard counts pull requests people who've logged in here opened
+// for issues we listed. We hear about them through the webhook, so they have
+// to be in tracked repos, and only count if they mention an issue we've saved.
+// Each is a row in contributions per issue it mentions. Only people who've
+// chosen to share their info are shown.
+
+// leaderboardSize is the most people the leaderboard shows.
+const leaderboardSize = 50
+
+// pullRequestActions are the pull request event actions worth recording.
+// Opened and reopened ones might be new, edited ones might mention more
+// issues, and closed ones might have been merged.
+var pullRequestActions = map[string]bool{
+	"opened":   true,
+	"reopened": true,
+	"edited":   true,
+	"closed":   true,
+}
+
+// restPullRequest is a pull request the way webhooks describe it.
+type restPullRequest struct {
+	HTMLURL   string     `json:"html_url"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+	User      struct {
+		ID    int    `json:"id"`
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// reIssueRef matches the ways a pull request can mention an issue: #12,
+// owner/name#12, or a link to it on GitHub.
+var reIssueRef = issueRefPattern(config.GitHubURL)
+
+// issueRefPattern is reIssueRef for the GitHub at web.
+func issueRefPattern(web string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w/#])(?:([\w.-]+)/([\w.-]+))?#(\d+)\b|` + regexp.QuoteMeta(web) + `/([\w.-]+)/([\w.-]+)/issues/(\d+)\b`)
+}
+
+// referencedIssues gives the URLs of the issues body mentions, taking ones
+// without an owner and name to be in repo.
+func referencedIssues(body string, repo Repo) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, m := range reIssueRef.FindAllStringSubmatch(body, -1) {
+		owner, name, number := m[1], m[2], m[3]
+		if number == "" {
+			owner, name, number = m[4], m[5], m[6]
+		} else if owner == "" {
+			owner, name = repo.Owner, repo.Name
+		}
+
+		u := config.GitHubURL + "/" + owner + "/" + name + "/issues/" + number
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// recordPullRequest notes pr, in repo, against each issue we've saved that it
+// mentions, if whoever opened it has logged in here.
+func recordPullRequest(ctx context.Context, pr restPullRequest, repo Repo) error {
+	merged := pr.MergedAt != nil
+	for _, issue := range referencedIssues(pr.Body, repo) {
+		_, err := db.Exec(
+			`INSERT INTO contributions (pr_url, issue_url, user_id, opened_at, merged)
+			SELECT $1, i.url, u.id, $4, $5 FROM issues i, users u WHERE i.url = $2 AND u.id = $3
+			ON CONFLICT (pr_url, issue_url) DO UPDATE SET merged = $5`,
+			pr.HTMLURL,
+			issue,
+			pr.User.ID,
+			pr.CreatedAt,
+			merged,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not save contribution")
+		}
+	}
+
+	// Mentions taken out since don't come off, but it's still the same pull
+	// request so it's merged for all of them
+	_, err := db.Exec("UPDATE contributions SET merged = $2 WHERE pr_url = $1", pr.HTMLURL, merged)
+	return errors.Wrap(err, "could not update contributions")
+}
+
+// Contributor is someone on the leaderboard.
+type Contributor struct {
+	Username string
+	Avatar   string
+
+	// PRs is how many pull requests they've opened for our issues, and Merged
+	// how many of them were merged
+	PRs    int
+	Merged int
+}
+
+// october is when Hacktoberfest runs in year, anywhere on Earth.
+func october(year int) (start, end time.Time) {
+	start = time.Date(year, time.October, 1, 0, 0, 0, 0, time.FixedZone("", 14*60*60))
+	end = time.Date(year, time.November, 1, 0, 0, 0, 0, time.FixedZone("", -12*60*60))
+	return start, end
+}
+
+// leaderboard lists who's opened the most pull requests for issues we listed
+// this October, or in the October of the year query parameter.
+func leaderboard(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 2014 {
+			invalidParameter(w, r, errors.New("year "+strconv.Quote(v)+" should be a year since Hacktoberfest began"))
+			return
+		}
+	}
+	start, end := october(year)
+
+	rows, err := db.Query(
+		`SELECT u.username, u.avatar, COUNT(DISTINCT c.pr_url), COUNT(DISTINCT c.pr_url) FILTER (WHERE c.merged)
+		FROM contributions c JOIN users u ON u.id = c.user_id
+		WHERE u.share_info AND c.opened_at >= $1 AND c.opened_at < $2
+		GROUP BY u.id, u.username, u.avatar
+		ORDER BY 3 DESC, 4 DESC, u.username
+		LIMIT $3`,
+		start,
+		end,
+		leaderboardSize,
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	board := []Contributor{}
+	for rows.Next() {
+		var c Contributor
+		if err := rows.Scan(&c.Username, &c.Avatar, &c.PRs, &c.Merged); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		board = append(board, c)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(board); err != nil {
+		logError(r.Context(), err)
+	}
+}
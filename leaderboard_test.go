@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReferencedIssues(t *testing.T) {
+	repo := Repo{Owner: "devict", Name: "hacktoberfest"}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"Fixes #12", []string{config.GitHubURL + "/devict/hacktoberfest/issues/12"}},
+		{"Closes devict/site#3 and #4, see #4 too", []string{config.GitHubURL + "/devict/site/issues/3", config.GitHubURL + "/devict/hacktoberfest/issues/4"}},
+		{"For " + config.GitHubURL + "/MakeICT/members/issues/7.", []string{config.GitHubURL + "/MakeICT/members/issues/7"}},
+		{"See https://gitlab.com/devict/site/issues/3 and color #fff", nil},
+		{"Nothing to see here", nil},
+	}
+
+	for _, test := range tests {
+		got := referencedIssues(test.body, repo)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.body, got, test.want)
+		}
+	}
+}
+
+func TestOctober(t *testing.T) {
+	start, end := october(2026)
+
+	// It's October somewhere from 10am on the 30th of September UTC until noon
+	// on the 1st of November
+	if want := time.Date(2026, time.September, 30, 10, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("got start %v, want %v", start, want)
+	}
+	if want := time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("got end %v, want %v", end, want)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// listingPerPage is how many issues the home page shows at once.
+const listingPerPage = 25
+
+// issueListing is the page of issues the home page shows, with what it needs
+// for its filter controls and links to the pages either side. It lets people
+// find issues without any JavaScript.
+type issueListing struct {
+	Issues []Issue
+
+	// Error is why there aren't any issues to show, if there's a reason.
+	// Incomplete is set when some searches failed so issues could be missing.
+	Error      string
+	Incomplete bool
+
+	// LoggedIn is whether whoever's looking has logged in, and GitLab whether
+	// they could with GitLab too
+	LoggedIn bool
+	GitLab   bool
+
+	// Lang, Label and Query are what the listing is filtered by, and
+	// Languages and Labels what it could be
+	Lang      string
+	Label     string
+	Query     string
+	Languages []LanguageCount
+	Labels    []string
+
+	Page, Pages, Total int
+	Prev, Next         string
+}
+
+// buildListing lists the page of issues r asks for with the lang, matched, q
+// and page query parameters, newest first. Picking a label only filters the
+// standard listing by which searches found each issue, so it doesn't take a
+// search of its own. Anything wrong is put in the
+// listing's Error for the page to show, rather than failing the whole page.
+func buildListing(r *http.Request) issueListing {
+	q := r.URL.Query()
+	l := issueListing{
+		Lang:   q.Get("lang"),
+		Label:  q.Get("matched"),
+		Query:  q.Get("q"),
+		Labels: tracking().labelList(),
+		Page:   1,
+	}
+	_, _, l.LoggedIn = findUser(r)
+	_, l.GitLab = loginProviders["gitlab"]
+
+	c, ok := issueClient(r)
+	if !ok {
+		l.Error = "Sign in with GitHub to see issues you could work on."
+		return l
+	}
+
+	p, err := parseParams(r, defaultParams(c.Scope()))
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+	f, err := parseFilter(r)
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+	pg, err := parsePage(r)
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+	pg.perPage = listingPerPage
+	quota, err := parseQuota(r, p)
+	if err != nil {
+		l.Error = err.Error()
+		return l
+	}
+
+	issues, err := loadIssues(r.Context(), c, p, false)
+	if !servable(err) {
+		logError(r.Context(), err)
+		l.Error = "We couldn't get issues from GitHub just now. Try again in a minute."
+		return l
+	}
+	l.Incomplete = incomplete(err) != nil
+
+	// Languages are counted before filtering by them so the choices don't
+	// narrow down to the one picked
+	l.Languages = countLanguages(issues)
+	issues = f.apply(issues)
+	issueOrder{by: "created", desc: true}.sort(issues)
+	if u, _, ok := findUser(r); ok {
+		issues = personalize(r.Context(), u, issues, false)
+	}
+	issues = quota.apply(issues)
+
+	env := pg.envelope(issues)
+	l.Issues, l.Page, l.Pages, l.Total = env.Issues, env.Page, env.Pages, env.Total
+	if l.Page > 1 {
+		l.Prev = listingURL(r, l.Page-1)
+	}
+	if l.Page < l.Pages {
+		l.Next = listingURL(r, l.Page+1)
+	}
+	return l
+}
+
+// listingURL links to page of the listing r is for.
+func listingURL(r *http.Request, page int) string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	return "/?" + q.Encode() + "#issues"
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListingURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?lang=go&page=2&q=docs", nil)
+	if got, want := listingURL(r, 3), "/?lang=go&page=3&q=docs#issues"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHomeListing(t *testing.T) {
+	// Without a login or a token of our own there's nothing to search with,
+	// but the page should still render and say how to get some
+	w := httptest.NewRecorder()
+	home(w, httptest.NewRequest("GET", "/?lang=Go", nil))
+
+	if w.Code != 200 {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`id="issues"`, "Sign in with GitHub to see issues", `href="/auth/github"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page should contain %q", want)
+		}
+	}
+}
+
+func TestHomeTemplate(t *testing.T) {
+	data := struct {
+		Orgs     map[string]bool
+		Projects map[string]bool
+		Listing  issueListing
+	}{
+		Listing: issueListing{
+			Issues: []Issue{{
+				Title:     "Fix <it>",
+				URL:       "https://github.com/devict/hacktoberfest/issues/1",
+				Repo:      Repo{Owner: "devict", Name: "hacktoberfest"},
+				Languages: []string{"Go"},
+				Labels:    map[string]string{"good first issue": "7057ff"},
+				Claimed:   true,
+				ClaimedBy: "mattstratton",
+
+				MatchedLabels: []string{"hacktoberfest"},
+			}},
+			Lang:      "Go",
+			Languages: []LanguageCount{{Language: "Go", Count: 1}},
+			Labels:    []string{"hacktoberfest"},
+			Page:      2,
+			Pages:     3,
+			Prev:      "/?page=1#issues",
+			Next:      "/?page=3#issues",
+		},
+	}
+
+	w := httptest.NewRecorder()
+	v.HTML(w, 200, "home", data)
+	body := w.Body.String()
+	for _, want := range []string{
+		`<a href="https://github.com/devict/hacktoberfest/issues/1" target="_blank">Fix &lt;it&gt;</a>`,
+		`<option value="Go" selected>Go (1)</option>`,
+		"good first issue",
+		`<span class="badge badge-info">hacktoberfest</span>`,
+		`name="matched"`,
+		"claimed by mattstratton",
+		"Page 2 of 3",
+		`href="/?page=3#issues"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page should contain %q", want)
+		}
+	}
+}
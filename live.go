@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// issueDiff is what changed between two listings of the same issues.
+// Opened issues are new to the listing and closed ones have left it, most
+// likely because they were closed but possibly because they lost their label.
+type issueDiff struct {
+	Opened []Issue `json:"opened"`
+	Closed []Issue `json:"closed"`
+}
+
+func (d issueDiff) empty() bool {
+	return len(d.Opened) == 0 && len(d.Closed) == 0
+}
+
+// diffIssues compares an old listing to a new one, going by URL.
+func diffIssues(old, new []Issue) issueDiff {
+	d := issueDiff{Opened: []Issue{}, Closed: []Issue{}}
+
+	was := make(map[string]bool)
+	for _, i := range old {
+		was[i.URL] = true
+	}
+	is := make(map[string]bool)
+	for _, i := range new {
+		is[i.URL] = true
+		if !was[i.URL] {
+			d.Opened = append(d.Opened, i)
+		}
+	}
+	for _, i := range old {
+		if !is[i.URL] {
+			d.Closed = append(d.Closed, i)
+		}
+	}
+
+	return d
+}
+
+// liveHub hands out issueDiffs to everyone subscribed. It is safe for
+// concurrent use.
+type liveHub struct {
+	mu     sync.Mutex
+	subs   map[chan issueDiff]bool
+	closed bool
+}
+
+var live = newLiveHub()
+
+func newLiveHub() *liveHub {
+	return &liveHub{subs: make(map[chan issueDiff]bool)}
+}
+
+// subscribe gives a channel that diffs will be sent on. It's closed when the
+// hub is.
+func (h *liveHub) subscribe() chan issueDiff {
+	ch := make(chan issueDiff, 8)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subs[ch] = true
+	return ch
+}
+
+func (h *liveHub) unsubscribe(ch chan issueDiff) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[ch] {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// close closes every subscriber's channel, and those of anyone who subscribes
+// later, because we're shutting down.
+func (h *liveHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		close(ch)
+	}
+	h.subs = make(map[chan issueDiff]bool)
+	h.closed = true
+}
+
+// publish sends d to every subscriber. One that's fallen too far behind misses
+// it rather than holding up everyone else.
+func (h *liveHub) publish(d issueDiff) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- d:
+		default:
+			logWarn(context.Background(), "live subscriber is behind, dropping an update")
+		}
+	}
+}
+
+// liveIssues upgrades to a WebSocket and sends an issueDiff as JSON every time
+// the background refresh sees the listing change. Without a PAT there's no
+// background refresh, so nothing is ever sent.
+func liveIssues(w http.ResponseWriter, r *http.Request) {
+	// Browsers send cookies with WebSocket requests from any site, so make
+	// sure it's our own page asking
+	if !sameOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		logError(r.Context(), err)
+		return
+	}
+	defer ws.Close()
+
+	diffs := live.subscribe()
+	defer live.unsubscribe(diffs)
+
+	closed := make(chan struct{})
+	go func() {
+		ws.readUntilClose()
+		close(closed)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case d, ok := <-diffs:
+			// We're shutting down so say goodbye properly
+			if !ok {
+				ws.writeFrame(wsClose, nil)
+				return
+			}
+
+			data, err := json.Marshal(d)
+			if err != nil {
+				logError(r.Context(), err)
+				continue
+			}
+			if err := ws.writeText(data); err != nil {
+				logError(r.Context(), err)
+				return
+			}
+		}
+	}
+}
+
+// sameOrigin reports whether r came from a page on our own host. Requests
+// without an Origin aren't from a browser so there's no one to protect.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiffIssues(t *testing.T) {
+	a, b, c := Issue{URL: "a"}, Issue{URL: "b"}, Issue{URL: "c"}
+
+	d := diffIssues([]Issue{a, b}, []Issue{b, c})
+	if !reflect.DeepEqual(d.Opened, []Issue{c}) {
+		t.Errorf("got opened %v, want c", d.Opened)
+	}
+	if !reflect.DeepEqual(d.Closed, []Issue{a}) {
+		t.Errorf("got closed %v, want a", d.Closed)
+	}
+
+	if d := diffIssues([]Issue{a}, []Issue{a}); !d.empty() {
+		t.Errorf("the same listing should be no change, got %v", d)
+	}
+}
+
+func TestLiveHub(t *testing.T) {
+	h := newLiveHub()
+	ch := h.subscribe()
+
+	d := issueDiff{Opened: []Issue{{URL: "a"}}}
+	h.publish(d)
+	if got := <-ch; !reflect.DeepEqual(got, d) {
+		t.Errorf("got %v, want %v", got, d)
+	}
+
+	// A subscriber that never reads shouldn't block publishing
+	for i := 0; i < 20; i++ {
+		h.publish(d)
+	}
+
+	h.unsubscribe(ch)
+	if len(h.subs) != 0 {
+		t.Errorf("got %d subscribers, want 0", len(h.subs))
+	}
+}
+
+func TestLiveHubClose(t *testing.T) {
+	h := newLiveHub()
+	before := h.subscribe()
+	h.close()
+	after := h.subscribe()
+
+	for _, ch := range []chan issueDiff{before, after} {
+		if _, ok := <-ch; ok {
+			t.Errorf("subscribers should be closed")
+		}
+		h.unsubscribe(ch)
+	}
+	h.publish(issueDiff{})
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://example.com", true},
+		{"https://evil.example", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/ws", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if got := sameOrigin(r); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.origin, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gcontext "github.com/gorilla/context"
+)
+
+// Log lines are structured: a level, a message, and key/value pairs that
+// include the request ID and who was logged in when there's a request behind
+// them. They're logfmt, or JSON with LOG_FORMAT=json, and LOG_LEVEL (debug,
+// info, warn or error) says how much to write.
+
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// logLevel and logJSON are config.LogLevel and config.LogFormat, set by
+// setConfig. Until then it's info level as text.
+var (
+	logLevel = levelInfo
+	logJSON  bool
+
+	logMu  sync.Mutex
+	logOut io.Writer = os.Stderr
+)
+
+// parseLevel reads a level name, defaulting to info.
+func parseLevel(s string) int {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return i
+		}
+	}
+	return levelInfo
+}
+
+// validLevel reports whether s is one of the level names.
+func validLevel(s string) bool {
+	for _, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func logDebug(ctx context.Context, msg string, kv ...interface{}) {
+	logAt(ctx, levelDebug, msg, kv)
+}
+
+func logInfo(ctx context.Context, msg string, kv ...interface{}) {
+	logAt(ctx, levelInfo, msg, kv)
+}
+
+func logWarn(ctx context.Context, msg string, kv ...interface{}) {
+	logAt(ctx, levelWarn, msg, kv)
+}
+
+// logError logs err as the message. Our errors already read like one.
+func logError(ctx context.Context, err error, kv ...interface{}) {
+	logAt(ctx, levelError, err.Error(), kv)
+}
+
+func logAt(ctx context.Context, level int, msg string, kv []interface{}) {
+	if level < logLevel {
+		return
+	}
+
+	fields := []interface{}{
+		"time", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		"level", levelNames[level],
+		"msg", msg,
+	}
+	if info := requestFrom(ctx); info != nil {
+		fields = append(fields, "request_id", info.id)
+		if info.user != "" {
+			fields = append(fields, "user", info.user)
+		}
+	}
+	fields = append(fields, kv...)
+
+	var b bytes.Buffer
+	if logJSON {
+		writeJSONFields(&b, fields)
+	} else {
+		writeLogfmt(&b, fields)
+	}
+	b.WriteByte('\n')
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	b.WriteTo(logOut)
+}
+
+// writeLogfmt writes fields as key=value, quoting values that need it.
+func writeLogfmt(b *bytes.Buffer, fields []interface{}) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		v := fmt.Sprint(fields[i+1])
+		if v == "" || strings.ContainsAny(v, " =\"\\\n\t") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(b, "%v=%s", fields[i], v)
+	}
+}
+
+// writeJSONFields writes fields as a JSON object in the order given. Numbers
+// and booleans stay as they are and everything else becomes a string.
+func writeJSONFields(b *bytes.Buffer, fields []interface{}) {
+	b.WriteByte('{')
+	for i := 0; i+1 < len(fields); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(fmt.Sprint(fields[i]))
+		b.Write(k)
+		b.WriteByte(':')
+
+		var v []byte
+		switch fields[i+1].(type) {
+		case int, int64, float64, bool:
+			v, _ = json.Marshal(fields[i+1])
+		default:
+			v, _ = json.Marshal(fmt.Sprint(fields[i+1]))
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+}
+
+// requestInfo is what we know about the request a log line is for.
+type requestInfo struct {
+	id   string
+	user string
+}
+
+type requestKey struct{}
+
+func requestFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestKey{}).(*requestInfo)
+	return info
+}
+
+// reRequestID is what we'll accept as an X-Request-ID from a proxy in front
+// of us. Anything else gets a new one.
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// logRequests gives every request an ID, sent back as X-Request-ID, and logs
+// each one once it's been served.
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &requestInfo{id: r.Header.Get("X-Request-ID")}
+		if !reRequestID.MatchString(info.id) {
+			info.id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", info.id)
+		ctx := context.WithValue(r.Context(), requestKey{}, info)
+		r = r.WithContext(ctx)
+
+		// Sessions are kept by request until they're cleared, and nothing
+		// else clears them
+		defer gcontext.Clear(r)
+		if u, _, ok := findUser(r); ok {
+			info.user = u.NickName
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r)
+
+		logInfo(ctx, "served request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"duration", time.Since(start),
+		)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteLogfmt(t *testing.T) {
+	var b bytes.Buffer
+	writeLogfmt(&b, []interface{}{"msg", "could not fetch", "status", 502, "empty", "", "label", "good-first-issue"})
+	want := `msg="could not fetch" status=502 empty="" label=good-first-issue`
+	if got := b.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWriteJSONFields(t *testing.T) {
+	var b bytes.Buffer
+	writeJSONFields(&b, []interface{}{"msg", `say "hi"`, "status", 502, "ok", false})
+	want := `{"msg":"say \"hi\"","status":502,"ok":false}`
+	if got := b.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var out bytes.Buffer
+	logOut = &out
+	defer func() { logOut = os.Stderr }()
+
+	var id string
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = requestFrom(r.Context()).id
+		logWarn(r.Context(), "something happened")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		header string
+		same   bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"not a valid id", false},
+	}
+	for _, test := range tests {
+		out.Reset()
+		req := httptest.NewRequest("GET", "/api/issues", nil)
+		if test.header != "" {
+			req.Header.Set("X-Request-ID", test.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if got := w.Header().Get("X-Request-ID"); got != id || id == "" {
+			t.Errorf("%q: sent back %q, handler saw %q", test.header, got, id)
+		}
+		if (id == test.header) != test.same {
+			t.Errorf("%q: got id %q", test.header, id)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%q: got %d log lines, want 2", test.header, len(lines))
+		}
+		if !strings.Contains(lines[0], "level=warn") || !strings.Contains(lines[0], "request_id="+id) {
+			t.Errorf("%q: handler's line should carry the request id, got %s", test.header, lines[0])
+		}
+		if !strings.Contains(lines[1], "method=GET path=/api/issues status=418") {
+			t.Errorf("%q: got request line %s", test.header, lines[1])
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	var out bytes.Buffer
+	logOut = &out
+	defer func() { logOut = os.Stderr }()
+
+	logDebug(context.Background(), "hidden")
+	if out.Len() != 0 {
+		t.Errorf("debug shouldn't be logged at info, got %s", out.String())
+	}
+}
@@ -1,127 +1,299 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
+	"expvar"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
+	"net/http/pprof"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/pat"
 	_ "github.com/lib/pq"
-	"github.com/markbates/goth/gothic"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
 
-// Any project under one of these organizations counts
-var orgs = map[string]bool{
-	"devict":         true,
-	"MakeICT":        true,
-	"openwichita":    true,
-	"StartupWichita": true,
-	"Wichitalks":     true,
-	"Ennovar":        true,
-}
+var v = newRender(config.Dev)
 
-// These specific projects also count
-var projects = map[string]bool{
-	"imacrayon/eventsinwichita": true,
-	"br0xen/boltbrowser":        true,
-	"benblankley/fort-rpg":      true,
-	"chrisl8/ArloBot":           true,
+// newRender makes the renderer for templates, reloading them for every
+// request in dev.
+func newRender(dev bool) *render.Render {
+	return render.New(render.Options{
+		Layout:        "layout",
+		IsDevelopment: dev,
+	})
 }
 
-var v = render.New(render.Options{
-	Layout:        "layout",
-	IsDevelopment: dev(),
-})
-
 var db *sql.DB
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	run, args := "web", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		run, args = args[0], args[1:]
+	}
+
+	// Printing issues only needs GitHub, not the db, and has flags of its own
+	if run == "issues" {
+		c, err := loadConfig(nil, os.Getenv)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		setConfig(c)
+		runIssuesCommand(args)
+	}
+
+	c, err := loadConfig(args, os.Getenv)
+	switch {
+	case err == flag.ErrHelp:
+		os.Exit(0)
+	case err == errUsage:
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	setConfig(c)
+
 	if err := setupDB(); err != nil {
-		log.Println("could not set up db", err)
+		logError(context.Background(), errors.Wrap(err, "could not set up db"))
+		os.Exit(1)
+	}
+	if err := loadLoggedOut(); err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not load logged out sessions"))
 		os.Exit(1)
 	}
 
-	run := "web"
-	if len(os.Args) > 1 {
-		run = os.Args[1]
+	t, err := buildTracking()
+	if err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not load tracking"))
+		os.Exit(1)
+	}
+	setTracking(t)
+
+	if notifications, err = loadNotifications(); err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not load notifications"))
+		os.Exit(1)
 	}
 
 	if run == "check" {
 		if err := check(); err != nil {
-			log.Fatal(err)
+			logError(context.Background(), err)
+			os.Exit(1)
 		}
 		return
 	}
 
 	// default: web
 
+	go reloadOnHangup()
+
+	if app, err = loadApp(); err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not load GitHub App"))
+		os.Exit(1)
+	}
+
+	// Background work runs until we're told to shut down
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// Send traces to an OpenTelemetry collector if there is one
+	if config.OTelEndpoint != "" {
+		spans = newSpanExporter(config.OTelEndpoint, config.OTelServiceName)
+		workers.Add(1)
+		go func() {
+			spans.run(ctx, 5*time.Second)
+			workers.Done()
+		}()
+	}
+
+	// Keep the issue listing warm with the server's token so users don't wait
+	// on GitHub, starting before we take any requests. Without one every user
+	// fetches issues with their own token.
+	if app != nil || config.Token != "" {
+		ready := make(chan struct{})
+		workers.Add(1)
+		go func() {
+			refreshIssues(ctx, refreshEvery, ready)
+			workers.Done()
+		}()
+		waitWarm(ctx, ready, config.WarmTimeout)
+	} else {
+		logWarn(context.Background(), "neither PAT nor a GitHub App is set, issues will not be refreshed in the background")
+	}
+
+	// Email digests use the same listing so they need it kept warm too
+	if digestsEnabled() && (app != nil || config.Token != "") {
+		workers.Add(1)
+		go func() {
+			sendDigests(ctx, digestInterval)
+			workers.Done()
+		}()
+	}
+
 	r := pat.New()
 
 	// Register auth handlers. pat requires all routes be registered most
 	// specific first so the shorter routes have to be added last
-	r.Get("/auth/{provider}/callback", authCallback)
-	r.Get("/auth/{provider}", gothic.BeginAuthHandler)
+	r.Get("/auth/{provider}/callback", timed("/auth/{provider}/callback", authCallback))
+	r.Get("/auth/{provider}", timed("/auth/{provider}", beginAuth))
+
+	r.Get("/api/issues/stream", timed("/api/issues/stream", limited(issueStream)))
+	r.Get("/api/issues/languages", timed("/api/issues/languages", limited(cacheable(issueLanguages))))
+	r.Get("/api/v1/issues/recently-closed", timed("/api/v1/issues/recently-closed", cacheable(recentlyClosed)))
+	r.Get("/api/v1/issues/{owner}/{repo}", timed("/api/v1/issues/{owner}/{repo}", limited(cacheable(repoIssues))))
+	r.Get("/api/v1/issues", timed("/api/v1/issues", limited(cacheable(issues))))
+	r.Get("/api/v1/repos/{owner}/{repo}", timed("/api/v1/repos/{owner}/{repo}", limited(cacheable(repoDetails))))
+	r.Get("/api/v1/mentors", timed("/api/v1/mentors", listMentors))
+	r.Get("/api/v1/stats/history", timed("/api/v1/stats/history", statsHistory))
+	r.Get("/api/v1/stats", timed("/api/v1/stats", limited(cacheable(stats))))
+	r.Get("/api/issues/{owner}/{repo}", timed("/api/issues/{owner}/{repo}", limited(cacheable(deprecated(repoIssues)))))
+	r.Get("/api/issues", timed("/api/issues", limited(cacheable(deprecated(issues)))))
+	r.Post("/api/issues/claim", timed("/api/issues/claim", requireUser(claimIssue)))
+	r.Delete("/api/issues/claim", timed("/api/issues/claim", requireUser(releaseClaim)))
+	r.Get("/api/prs", timed("/api/prs", requireUser(prs)))
+	r.Get("/api/share", timed("/api/share", requireUser(getShare)))
+	r.Put("/api/share", timed("/api/share", requireUser(updateShare)))
+	r.Get("/api/me/bookmarks", timed("/api/me/bookmarks", requireUser(getBookmarks)))
+	r.Post("/api/me/bookmarks", timed("/api/me/bookmarks", requireUser(addBookmark)))
+	r.Delete("/api/me/bookmarks", timed("/api/me/bookmarks", requireUser(removeBookmark)))
+	r.Get("/api/me/hidden", timed("/api/me/hidden", requireUser(getHidden)))
+	r.Post("/api/me/hidden", timed("/api/me/hidden", requireUser(hideIssue)))
+	r.Delete("/api/me/hidden", timed("/api/me/hidden", requireUser(unhideIssue)))
+	r.Get("/api/me/preferences", timed("/api/me/preferences", requireUser(getPreferences)))
+	r.Put("/api/me/preferences", timed("/api/me/preferences", requireUser(updatePreferences)))
+	r.Get("/api/me/progress", timed("/api/me/progress", requireUser(getProgress)))
+	r.Get("/api/me/feed", timed("/api/me/feed", requireUser(getActivity)))
+	r.Get("/api/me/tokens", timed("/api/me/tokens", requireUser(getKeys)))
+	r.Post("/api/me/tokens", timed("/api/me/tokens", requireUser(createKey)))
+	r.Delete("/api/me/tokens/{id}", timed("/api/me/tokens/{id}", requireUser(revokeKey)))
+	r.Get("/api/me/mentor", timed("/api/me/mentor", requireUser(getMentor)))
+	r.Put("/api/me/mentor", timed("/api/me/mentor", requireUser(updateMentor)))
+	r.Delete("/api/me/mentor", timed("/api/me/mentor", requireUser(removeMentor)))
+	r.Get("/api/me/ratelimit", timed("/api/me/ratelimit", requireUser(getRateLimit)))
+	r.Get("/api/leaderboard", timed("/api/leaderboard", leaderboard))
+	r.Post("/api/repos/submit", timed("/api/repos/submit", requireUser(submitRepo)))
+	r.Get("/api/me/digest", timed("/api/me/digest", requireUser(getDigest)))
+	r.Put("/api/me/digest", timed("/api/me/digest", requireUser(updateDigest)))
+	r.Get("/digest/unsubscribe", timed("/digest/unsubscribe", unsubscribeDigest))
+	r.Post("/digest/unsubscribe", timed("/digest/unsubscribe", unsubscribeDigest))
+	r.Post("/api/webhooks/github", timed("/api/webhooks/github", githubWebhook))
 
-	r.Get("/api/issues", issues)
-	r.Get("/api/prs", prs)
-	r.Get("/api/share", getShare)
-	r.Put("/api/share", updateShare)
+	r.Get("/api/admin/export", timed("/api/admin/export", requireAdmin(adminExport)))
+	r.Post("/api/admin/import", timed("/api/admin/import", requireAdmin(adminImport)))
+	r.Get("/api/admin/tracking", timed("/api/admin/tracking", requireAdmin(adminTracking)))
+	r.Get("/api/admin/submissions", timed("/api/admin/submissions", requireAdmin(adminSubmissions)))
+	r.Post("/api/admin/submissions/{owner}/{repo}/approve", timed("/api/admin/submissions/{owner}/{repo}/approve", requireAdmin(approveSubmission)))
+	r.Post("/api/admin/submissions/{owner}/{repo}/reject", timed("/api/admin/submissions/{owner}/{repo}/reject", requireAdmin(rejectSubmission)))
+	r.Post("/api/admin/orgs", timed("/api/admin/orgs", requireAdmin(addOrg)))
+	r.Delete("/api/admin/orgs/{name}", timed("/api/admin/orgs/{name}", requireAdmin(removeOrg)))
+	r.Post("/api/admin/repos", timed("/api/admin/repos", requireAdmin(addRepo)))
+	r.Delete("/api/admin/repos/{owner}/{repo}", timed("/api/admin/repos/{owner}/{repo}", requireAdmin(removeRepo)))
+	r.Get("/api/admin/audit", timed("/api/admin/audit", requireAdmin(adminAudit)))
+	r.Get("/api/admin/diagnostics", timed("/api/admin/diagnostics", requireAdmin(adminDiagnostics)))
+	r.Get("/api/admin/excluded/flagged", timed("/api/admin/excluded/flagged", requireAdmin(adminFlagged)))
+	r.Post("/api/admin/excluded", timed("/api/admin/excluded", requireAdmin(addExcluded)))
+	r.Delete("/api/admin/excluded/{owner}/{repo}", timed("/api/admin/excluded/{owner}/{repo}", requireAdmin(removeExcluded)))
+	r.Delete("/api/admin/excluded/{owner}", timed("/api/admin/excluded/{owner}", requireAdmin(removeExcluded)))
 
-	r.Get("/profile", profile)
+	r.Get("/openapi.json", timed("/openapi.json", serveSpec))
+	r.Get("/docs", timed("/docs", apiDocs))
+	r.Get("/issues.atom", timed("/issues.atom", issueFeed))
+	r.Get("/events.ics", timed("/events.ics", eventsCalendar))
+	r.Get("/events/{slug}/issues", timed("/events/{slug}/issues", limited(cacheable(eventIssues))))
+	r.Get("/events", timed("/events", listEvents))
+	r.Get("/metrics", timed("/metrics", serveMetrics))
+	r.Get("/debug/pprof/cmdline", timed("/debug/pprof/cmdline", debugOnly(http.HandlerFunc(pprof.Cmdline))))
+	r.Get("/debug/pprof/profile", timed("/debug/pprof/profile", debugOnly(http.HandlerFunc(pprof.Profile))))
+	r.Get("/debug/pprof/symbol", timed("/debug/pprof/symbol", debugOnly(http.HandlerFunc(pprof.Symbol))))
+	r.Post("/debug/pprof/symbol", timed("/debug/pprof/symbol", debugOnly(http.HandlerFunc(pprof.Symbol))))
+	r.Get("/debug/pprof/trace", timed("/debug/pprof/trace", debugOnly(http.HandlerFunc(pprof.Trace))))
+	r.Get("/debug/pprof/", timed("/debug/pprof/", debugOnly(http.HandlerFunc(pprof.Index))))
+	r.Get("/debug/vars", timed("/debug/vars", debugOnly(expvar.Handler())))
+	r.Get("/healthz", timed("/healthz", healthz))
+	r.Get("/readyz", timed("/readyz", readyz))
+	r.Get("/ws", timed("/ws", requireUser(liveIssues)))
+	r.Get("/profile", timed("/profile", profile))
 
 	// Serve static files
 	r.PathPrefix("/public/").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("public"))))
 
-	r.Get("/", home)
+	r.Post("/logout", timed("/logout", logout))
+	r.Get("/", timed("/", home))
+
+	addr := config.Addr
+	srv := &http.Server{Addr: addr, Handler: chain(r, logRequests, recoverPanics, compress, cors, apiKeys, sameSiteCookies, renewSessions)}
+
+	// Shutdown doesn't wait for WebSockets since they've been hijacked, so
+	// tell them to close
+	srv.RegisterOnShutdown(live.close)
+
+	errs := make(chan error, 1)
+	if config.TLSHost != "" {
+		// Serve HTTPS with a certificate from the ACME certificate authority,
+		// leaving plain HTTP to answer its challenges and redirect
+		certs := newCertManager(config.TLSHost, config.TLSCacheDir, config.ACMEDirectory, config.ACMEEmail)
+		plain := &http.Server{Addr: addr, Handler: challenges(certs, config.TLSHost, config.TLSAddr)}
+		srv.RegisterOnShutdown(func() { plain.Close() })
+		go func() {
+			logInfo(ctx, "redirecting to HTTPS", "addr", addr)
+			if err := plain.ListenAndServe(); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}()
 
-	addr := ":8080"
-	if p := os.Getenv("PORT"); p != "" {
-		addr = ":" + p
+		srv.Addr = config.TLSAddr
+		srv.TLSConfig = &tls.Config{GetCertificate: certs.GetCertificate}
+		go func() {
+			logInfo(ctx, "server running", "addr", srv.Addr, "host", config.TLSHost)
+			errs <- srv.ListenAndServeTLS("", "")
+		}()
+	} else {
+		go func() {
+			logInfo(ctx, "server running", "addr", addr)
+			errs <- srv.ListenAndServe()
+		}()
 	}
 
-	fmt.Println("Server running on", addr)
-	log.Fatal(http.ListenAndServe(addr, logger(r)))
-}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errs:
+		logError(ctx, err)
+		os.Exit(1)
+	case s := <-stop:
+		logInfo(ctx, "shutting down", "signal", s)
+	}
 
-func logger(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		log.Printf("Serving %s", r.URL.String())
-		h.ServeHTTP(w, r)
-		log.Printf("Done serving %s [%v]", r.URL.String(), time.Since(start))
-	})
+	shutdown(srv, stopWorkers, &workers, config.ShutdownTimeout)
 }
 
 func home(w http.ResponseWriter, r *http.Request) {
+	t := tracking()
 	data := struct {
 		Orgs     map[string]bool
 		Projects map[string]bool
+		Listing  issueListing
 	}{
-		Orgs:     orgs,
-		Projects: projects,
+		Orgs:     t.Orgs,
+		Projects: t.Projects,
+		Listing:  buildListing(r),
 	}
 	v.HTML(w, http.StatusOK, "home", data)
 }
 
-func dev() bool {
-	d, err := strconv.ParseBool(os.Getenv("DEV"))
-	if err != nil {
-		return false
-	}
-
-	return d
-}
-
 func setupDB() error {
 	var err error
-	db, err = sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	db, err = sql.Open("postgres", config.DatabaseURL)
 	if err != nil {
 		return errors.Wrap(err, "could not open db")
 	}
@@ -131,7 +303,7 @@ func setupDB() error {
 		if err = db.Ping(); err == nil {
 			break
 		}
-		log.Printf("Waiting for db to come up, attempt %d of 20: %v", attempts, err)
+		logInfo(context.Background(), "waiting for db to come up", "attempt", attempts, "of", 20, "err", err)
 		time.Sleep(time.Duration(attempts) * time.Second)
 	}
 	if err != nil {
@@ -154,5 +326,232 @@ func setupDB() error {
 		return errors.Wrap(err, "could not make user table")
 	}
 
+	q = `CREATE TABLE IF NOT EXISTS tracked (
+		kind varchar(16),
+		name varchar(255),
+		active boolean,
+		updated_by varchar(255),
+		updated_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(kind, name)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make tracked table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS issues (
+		url varchar(1024),
+		owner varchar(255),
+		repo varchar(255),
+		data jsonb,
+		first_seen TIMESTAMP WITH TIME ZONE,
+		last_seen TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make issues table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS issue_searches (
+		key text,
+		urls text[],
+		fetched_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(key)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make issue_searches table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS bookmarks (
+		user_id integer,
+		url varchar(1024),
+		created_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id, url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make bookmarks table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS hidden (
+		user_id integer,
+		url varchar(1024),
+		created_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id, url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make hidden table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS claims (
+		url varchar(1024),
+		user_id integer,
+		username varchar(255),
+		claimed_at TIMESTAMP WITH TIME ZONE,
+		expires_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make claims table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS preferences (
+		user_id integer,
+		data jsonb,
+		updated_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make preferences table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS contributions (
+		pr_url varchar(1024),
+		issue_url varchar(1024),
+		user_id integer,
+		opened_at TIMESTAMP WITH TIME ZONE,
+		merged boolean default false,
+		PRIMARY KEY(pr_url, issue_url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make contributions table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS digests (
+		user_id integer,
+		email varchar(255),
+		frequency varchar(16),
+		token varchar(64) UNIQUE,
+		last_sent TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make digests table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS submissions (
+		repo varchar(255),
+		submitted_by varchar(255),
+		status varchar(16),
+		submitted_at TIMESTAMP WITH TIME ZONE,
+		decided_by varchar(255),
+		decided_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(repo)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make submissions table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS logged_out_sessions (
+		id varchar(64),
+		expires_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make logged out sessions table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS issue_snapshots (
+		taken_at TIMESTAMP WITH TIME ZONE,
+		open integer,
+		claimed integer,
+		assigned integer,
+		opened integer,
+		closed integer,
+		PRIMARY KEY(taken_at)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make issue snapshots table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS closed_issues (
+		url varchar(1024),
+		data jsonb,
+		closed_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(url)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make closed issues table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS activity (
+		user_id integer,
+		kind varchar(16),
+		url varchar(1024),
+		data jsonb,
+		happened_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id, kind, url, happened_at)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make activity table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS activity_visits (
+		user_id integer,
+		visited_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make activity visits table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS api_keys (
+		id varchar(16),
+		user_id integer,
+		username varchar(255),
+		provider varchar(16),
+		name varchar(255),
+		scope varchar(16),
+		rate_limit integer,
+		hash varchar(64) UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE,
+		last_used TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make API keys table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS mentors (
+		user_id integer,
+		username varchar(255),
+		languages jsonb,
+		note text,
+		updated_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(user_id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make mentors table")
+	}
+
+	q = `CREATE TABLE IF NOT EXISTS audit_log (
+		id serial,
+		actor varchar(255),
+		action varchar(64),
+		target varchar(1024),
+		at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY(id)
+	)`
+	_, err = db.Exec(q)
+	if err != nil {
+		return errors.Wrap(err, "could not make audit log table")
+	}
+
 	return nil
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/markbates/goth"
+	"github.com/pkg/errors"
+)
+
+// Bookmarked and hidden issues belong to whoever is logged in. Each is a table
+// of URLs keyed by the user's GitHub ID, and they're applied to their listings
+// by personalize.
+
+// issueURLRequest is the body of bookmarking or hiding an issue, or undoing
+// that.
+type issueURLRequest struct {
+	URL string `json:"url"`
+}
+
+// parseIssueURL decodes an issueURLRequest and checks it names an issue.
+func parseIssueURL(r *http.Request) (string, error) {
+	var req issueURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", errors.Wrap(err, "invalid request")
+	}
+	return checkIssueURL(req.URL)
+}
+
+// checkIssueURL tidies up raw and checks it could be a link to an issue.
+func checkIssueURL(raw string) (string, error) {
+	u := strings.TrimSpace(raw)
+	if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		return "", errors.Errorf("url %q is not a link to an issue", raw)
+	}
+	if len(u) > 1024 {
+		return "", errors.New("url is too long")
+	}
+	return u, nil
+}
+
+// userURLs gives the URLs user has in table, bookmarks or hidden.
+func userURLs(table, user string) (map[string]bool, error) {
+	rows, err := db.Query("SELECT url FROM "+table+" WHERE user_id = $1", user)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not query %s", table)
+	}
+	defer rows.Close()
+
+	urls := make(map[string]bool)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, errors.Wrapf(err, "could not scan %s", table)
+		}
+		urls[u] = true
+	}
+	return urls, errors.Wrapf(rows.Err(), "could not iterate over %s", table)
+}
+
+// personalize marks the issues u has bookmarked and, unless includeHidden,
+// leaves out the ones they've hidden. issues must be a copy, not what's in the
+// cache. If we can't tell we'd rather show too much than nothing.
+func personalize(ctx context.Context, u goth.User, issues []Issue, includeHidden bool) []Issue {
+	if db == nil {
+		return issues
+	}
+
+	bookmarks, err := userURLs("bookmarks", u.UserID)
+	if err != nil {
+		logError(ctx, err)
+	}
+	markBookmarked(issues, bookmarks)
+
+	if includeHidden {
+		return issues
+	}
+	hidden, err := userURLs("hidden", u.UserID)
+	if err != nil {
+		logError(ctx, err)
+	}
+	return withoutHidden(issues, hidden)
+}
+
+// markBookmarked sets Bookmarked on the issues in urls.
+func markBookmarked(issues []Issue, urls map[string]bool) {
+	for n := range issues {
+		issues[n].Bookmarked = urls[issues[n].URL]
+	}
+}
+
+// withoutHidden leaves out the issues in hidden.
+func withoutHidden(issues []Issue, hidden map[string]bool) []Issue {
+	if len(hidden) == 0 {
+		return issues
+	}
+	out := []Issue{}
+	for _, i := range issues {
+		if !hidden[i.URL] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
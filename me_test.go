@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIssueURL(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+		ok   bool
+	}{
+		{`{"url": "https://github.com/devict/hacktoberfest/issues/1"}`, "https://github.com/devict/hacktoberfest/issues/1", true},
+		{`{"url": " https://gitlab.com/group/project/-/issues/2 "}`, "https://gitlab.com/group/project/-/issues/2", true},
+		{`{"url": ""}`, "", false},
+		{`{"url": "javascript:alert(1)"}`, "", false},
+		{`{"url": "https://github.com/` + strings.Repeat("a", 1024) + `"}`, "", false},
+		{`"https://github.com/devict/hacktoberfest/issues/1"`, "", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/me/bookmarks", strings.NewReader(test.body))
+		got, err := parseIssueURL(r)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got error %v, want ok %v", test.body, err, test.ok)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %q, want %q", test.body, got, test.want)
+		}
+	}
+}
+
+func TestMarkBookmarked(t *testing.T) {
+	issues := []Issue{{URL: "a"}, {URL: "b", Bookmarked: true}, {URL: "c"}}
+	markBookmarked(issues, map[string]bool{"a": true})
+
+	for n, want := range []bool{true, false, false} {
+		if issues[n].Bookmarked != want {
+			t.Errorf("%q: got %v, want %v", issues[n].URL, issues[n].Bookmarked, want)
+		}
+	}
+}
+
+func TestWithoutHidden(t *testing.T) {
+	issues := []Issue{{URL: "a"}, {URL: "b"}, {URL: "c"}}
+
+	got := withoutHidden(issues, map[string]bool{"b": true, "d": true})
+	want := []Issue{{URL: "a"}, {URL: "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := withoutHidden(issues, nil); len(got) != 3 {
+		t.Errorf("nothing hidden: got %d issues, want 3", len(got))
+	}
+}
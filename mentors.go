@@ -0,0 +1,241 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// People who know their way around a language can offer to help newcomers
+// with it by putting themselves in the mentors directory, with the languages
+// they'll help with. It's listed at /api/v1/mentors, and issues a newcomer is
+// likely to pick up, easy ones and ones with a mentor available, name a few
+// mentors who know one of their repo's languages. Mentors are a row each in
+// mentors, which is only there for people who opted in.
+
+// maxMentorLanguages is the most languages one mentor can offer help with.
+const maxMentorLanguages = 10
+
+// maxMentorNote is the most characters a mentor's note can have.
+const maxMentorNote = 280
+
+// maxMentorMatches is the most mentors an issue names.
+const maxMentorMatches = 3
+
+// mentor is someone in the mentors directory.
+type mentor struct {
+	Username  string    `json:"username"`
+	Languages []string  `json:"languages"`
+	Note      string    `json:"note"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// mentorRequest is the body of joining the mentors directory, or changing
+// what you offer.
+type mentorRequest struct {
+	Languages []string `json:"languages"`
+	Note      string   `json:"note"`
+}
+
+// check tidies up m and makes sure it's something we can list.
+func (m *mentorRequest) check() error {
+	var langs []string
+	for _, l := range m.Languages {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	m.Languages = sortedKeys(set(langs))
+	m.Note = strings.TrimSpace(m.Note)
+
+	if len(m.Languages) == 0 || len(m.Languages) > maxMentorLanguages {
+		return fmt.Errorf("mentors should offer help with between 1 and %d languages", maxMentorLanguages)
+	}
+	for _, l := range m.Languages {
+		if strings.Contains(l, ",") {
+			return fmt.Errorf("language %q can't have commas in it", l)
+		}
+	}
+	if len([]rune(m.Note)) > maxMentorNote {
+		return fmt.Errorf("note can be at most %d characters", maxMentorNote)
+	}
+	return nil
+}
+
+// helpsWith reports whether m offers to help with any of langs.
+func (m mentor) helpsWith(langs []string) bool {
+	return anyFold(m.Languages, langs)
+}
+
+// loadMentors gives everyone in the mentors directory, by username.
+func loadMentors() ([]mentor, error) {
+	rows, err := db.Query("SELECT username, languages, note, updated_at FROM mentors ORDER BY lower(username)")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query mentors")
+	}
+	defer rows.Close()
+
+	mentors := []mentor{}
+	for rows.Next() {
+		var m mentor
+		var langs []byte
+		if err := rows.Scan(&m.Username, &langs, &m.Note, &m.UpdatedAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan mentor")
+		}
+		if err := json.Unmarshal(langs, &m.Languages); err != nil {
+			return nil, errors.Wrap(err, "could not decode mentor languages")
+		}
+		mentors = append(mentors, m)
+	}
+	return mentors, errors.Wrap(rows.Err(), "could not iterate over mentors")
+}
+
+// markMentors sets Mentors on the issues that are easy or have a mentor
+// available to the first few mentors who help with one of their languages,
+// leaving out whoever is asking. issues must be a copy, not what's in the
+// cache.
+func markMentors(issues []Issue, mentors []mentor, asking string) {
+	for n := range issues {
+		i := &issues[n]
+		i.Mentors = nil
+		if i.Difficulty != aggregator.Easy && !i.MentorAvailable {
+			continue
+		}
+		for _, m := range mentors {
+			if len(i.Mentors) == maxMentorMatches {
+				break
+			}
+			if m.helpsWith(i.Languages) && !strings.EqualFold(m.Username, asking) {
+				i.Mentors = append(i.Mentors, m.Username)
+			}
+		}
+	}
+}
+
+// matchMentors is markMentors for whoever r is from, logging rather than
+// failing if we can't load the directory.
+func matchMentors(r *http.Request, issues []Issue) {
+	mentors, err := loadMentors()
+	if err != nil {
+		logError(r.Context(), err)
+		return
+	}
+	var asking string
+	if u, _, ok := findUser(r); ok {
+		asking = u.NickName
+	}
+	markMentors(issues, mentors, asking)
+}
+
+// listMentors gives the mentors directory, only those who help with one of
+// the languages in ?lang= if it's given.
+func listMentors(w http.ResponseWriter, r *http.Request) {
+	if db == nil {
+		writeError(w, r, http.StatusBadRequest, codeDisabled, "the mentors directory needs a database", nil)
+		return
+	}
+
+	mentors, err := loadMentors()
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	var langs []string
+	for _, l := range strings.Split(r.URL.Query().Get("lang"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	if len(langs) > 0 {
+		matched := []mentor{}
+		for _, m := range mentors {
+			if m.helpsWith(langs) {
+				matched = append(matched, m)
+			}
+		}
+		mentors = matched
+	}
+
+	writeEnvelope(w, r, apiEnvelope{Data: mentors, Meta: apiMeta{Total: len(mentors)}})
+}
+
+// getMentor gives what whoever is logged in offers in the mentors directory,
+// a 404 if they aren't in it.
+func getMentor(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	m := mentor{Username: u.NickName}
+	var langs []byte
+	err := db.QueryRow("SELECT languages, note, updated_at FROM mentors WHERE user_id = $1", u.UserID).Scan(&langs, &m.Note, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "you aren't in the mentors directory", nil)
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(langs, &m.Languages)
+	}
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// updateMentor puts whoever is logged in in the mentors directory, or
+// changes what they offer if they're already in it.
+func updateMentor(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var req mentorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+	if err := req.check(); err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	langs, err := json.Marshal(req.Languages)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	_, err = db.Exec(
+		`INSERT INTO mentors (user_id, username, languages, note, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET username = $2, languages = $3, note = $4, updated_at = $5`,
+		u.UserID,
+		u.NickName,
+		langs,
+		req.Note,
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeMentor takes whoever is logged in out of the mentors directory.
+func removeMentor(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	if _, err := db.Exec("DELETE FROM mentors WHERE user_id = $1", u.UserID); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
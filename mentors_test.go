@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMentorRequestCheck(t *testing.T) {
+	m := mentorRequest{Languages: []string{" Go", "Rust", "Go", ""}, Note: "  Happy to pair  "}
+	if err := m.check(); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+	if want := []string{"Go", "Rust"}; !reflect.DeepEqual(m.Languages, want) {
+		t.Errorf("got languages %q, want %q", m.Languages, want)
+	}
+	if m.Note != "Happy to pair" {
+		t.Errorf("got note %q, want it trimmed", m.Note)
+	}
+
+	for _, bad := range []mentorRequest{
+		{},
+		{Languages: []string{"C, C++"}},
+		{Languages: []string{"Go"}, Note: strings.Repeat("a", maxMentorNote+1)},
+		{Languages: strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")},
+	} {
+		if err := bad.check(); err == nil {
+			t.Errorf("%+v: error should not be nil, but it was", bad)
+		}
+	}
+}
+
+func TestMarkMentors(t *testing.T) {
+	mentors := []mentor{
+		{Username: "alice", Languages: []string{"Go", "Python"}},
+		{Username: "bob", Languages: []string{"go"}},
+		{Username: "carol", Languages: []string{"Go"}},
+		{Username: "dave", Languages: []string{"Go"}},
+		{Username: "erin", Languages: []string{"Rust"}},
+	}
+	issues := []Issue{
+		{URL: "easy", Difficulty: "easy", Languages: []string{"Go"}},
+		{URL: "mentored", MentorAvailable: true, Languages: []string{"Python", "Rust"}},
+		{URL: "hard", Difficulty: "hard", Languages: []string{"Go"}},
+		{URL: "nobody", Difficulty: "easy", Languages: []string{"COBOL"}},
+	}
+	markMentors(issues, mentors, "Bob")
+
+	want := map[string][]string{
+		"easy":     {"alice", "carol", "dave"},
+		"mentored": {"alice", "erin"},
+		"hard":     nil,
+		"nobody":   nil,
+	}
+	for _, i := range issues {
+		if !reflect.DeepEqual(i.Mentors, want[i.URL]) {
+			t.Errorf("%s: got mentors %q, want %q", i.URL, i.Mentors, want[i.URL])
+		}
+	}
+}
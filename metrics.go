@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gcontext "github.com/gorilla/context"
+	"github.com/pkg/errors"
+)
+
+// We write the Prometheus text format ourselves rather than pull in the client
+// library. It's only counters, gauges and histograms and there aren't many of
+// them.
+
+var (
+	apiRequests = newMetric("hacktoberfest_api_requests_total", "counter",
+		"Requests made to GitHub and the other forges we search, by host and status code.")
+	apiDuration = newHistogram("hacktoberfest_api_request_duration_seconds",
+		"How long requests to GitHub and the other forges took, by host.", durationBuckets)
+	rateLimitRemaining = newMetric("hacktoberfest_github_rate_limit_remaining", "gauge",
+		"Requests left in the rate limit as of the last response, by rate limit resource.")
+	cacheLookups = newMetric("hacktoberfest_issue_cache_lookups_total", "counter",
+		"Issue cache lookups, by whether they were a hit or a miss.")
+	fetchDuration = newHistogram("hacktoberfest_issue_fetch_duration_seconds",
+		"How long searching one source for one label took, by label.", durationBuckets)
+	breakerOpen = newMetric("hacktoberfest_breaker_open", "gauge",
+		"Whether we've stopped calling a host after repeated failures, by host.")
+	handlerDuration = newHistogram("hacktoberfest_http_request_duration_seconds",
+		"How long we took to answer requests for issues, by route and status code.", durationBuckets)
+)
+
+// durationBuckets are upper bounds in seconds, from a quick cached answer to
+// a search that paged through everything.
+var durationBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// registry is every metric in the order they're written out.
+var registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// metric is a counter, gauge or histogram and all of its series. It is safe
+// for concurrent use.
+type metric struct {
+	name, kind, help string
+
+	// buckets are the histogram's bucket bounds, in increasing order
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one set of label values. A histogram's counts are cumulative,
+// counts[i] being the observations no bigger than buckets[i].
+type series struct {
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newMetric(name, kind, help string) *metric {
+	m := &metric{name: name, kind: kind, help: help, series: make(map[string]*series)}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.metrics = append(registry.metrics, m)
+	return m
+}
+
+func newHistogram(name, help string, buckets []float64) *metric {
+	m := newMetric(name, "histogram", help)
+	m.buckets = buckets
+	return m
+}
+
+// labels formats name/value pairs for a series, like labels("host", "api.github.com").
+func labels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (m *metric) get(l string) *series {
+	s, ok := m.series[l]
+	if !ok {
+		s = &series{counts: make([]uint64, len(m.buckets))}
+		m.series[l] = s
+	}
+	return s
+}
+
+// add adds v to a counter.
+func (m *metric) add(l string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(l).value += v
+}
+
+// set sets a gauge to v.
+func (m *metric) set(l string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(l).value = v
+}
+
+// observe records v in a histogram.
+func (m *metric) observe(l string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.get(l)
+	for i, b := range m.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// since observes how long it's been since start, in seconds.
+func (m *metric) since(l string, start time.Time) {
+	m.observe(l, time.Since(start).Seconds())
+}
+
+// write writes m in the text format, series sorted by labels so the output is
+// stable.
+func (m *metric) write(w *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for l := range m.series {
+		keys = append(keys, l)
+	}
+	sort.Strings(keys)
+
+	for _, l := range keys {
+		s := m.series[l]
+		if m.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, braces(l), formatFloat(s.value))
+			continue
+		}
+
+		for i, b := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, braces(join(l, labels("le", formatFloat(b)))), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, braces(join(l, labels("le", "+Inf"))), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, braces(l), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, braces(l), s.count)
+	}
+}
+
+func braces(l string) string {
+	if l == "" {
+		return ""
+	}
+	return "{" + l + "}"
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// serveMetrics is the /metrics endpoint for Prometheus to scrape.
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	registry.mu.Lock()
+	metrics := registry.metrics
+	registry.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, m := range metrics {
+		m.write(&b)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b.WriteTo(w)
+}
+
+// timed records how long h takes to answer in handlerDuration under route,
+// and traces it.
+func timed(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, s := startSpan(remoteParent(r.Context(), r.Header.Get("traceparent")), r.Method+" "+route, spanServer)
+
+		r = r.WithContext(ctx)
+		defer gcontext.Clear(r)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+
+		handlerDuration.since(labels("route", route, "code", strconv.Itoa(sw.status)), start)
+		s.set("http.url", r.URL.String())
+		s.set("http.status_code", strconv.Itoa(sw.status))
+		var err error
+		if sw.status >= 500 {
+			err = errors.New(http.StatusText(sw.status))
+		}
+		s.finish(err)
+	}
+}
+
+// statusWriter remembers the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+
+	// wrote is set once anything's been sent
+	wrote bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack passes through so WebSockets still work.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response can't be hijacked")
+	}
+	w.status = http.StatusSwitchingProtocols
+	w.wrote = true
+	return hj.Hijack()
+}
+
+// Flush passes through so streamed responses still stream.
+func (w *statusWriter) Flush() {
+	w.wrote = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricWrite(t *testing.T) {
+	c := &metric{name: "things_total", kind: "counter", help: "Things.", series: make(map[string]*series)}
+	c.add(labels("kind", "b"), 1)
+	c.add(labels("kind", "a"), 2)
+	c.add(labels("kind", "a"), 0.5)
+
+	h := &metric{name: "wait_seconds", kind: "histogram", help: "Waits.", buckets: []float64{.1, 1}, series: make(map[string]*series)}
+	h.observe("", .05)
+	h.observe("", .5)
+	h.observe("", 5)
+
+	var b bytes.Buffer
+	c.write(&b)
+	h.write(&b)
+
+	want := `# HELP things_total Things.
+# TYPE things_total counter
+things_total{kind="a"} 2.5
+things_total{kind="b"} 1
+# HELP wait_seconds Waits.
+# TYPE wait_seconds histogram
+wait_seconds_bucket{le="0.1"} 1
+wait_seconds_bucket{le="1"} 2
+wait_seconds_bucket{le="+Inf"} 3
+wait_seconds_sum 5.55
+wait_seconds_count 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		pairs []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"host", "api.github.com"}, `host="api.github.com"`},
+		{[]string{"a", "1", "b", "2"}, `a="1",b="2"`},
+		{[]string{"label", "say \"hi\"\\\n"}, `label="say \"hi\"\\\n"`},
+	}
+	for _, test := range tests {
+		if got := labels(test.pairs...); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.pairs, got, test.want)
+		}
+	}
+}
+
+func TestTimed(t *testing.T) {
+	// The metrics are shared by every test, and every run with -count, so
+	// it's the change that counts
+	l := labels("route", "/test/timed", "code", "418")
+	count := func() uint64 {
+		handlerDuration.mu.Lock()
+		defer handlerDuration.mu.Unlock()
+		if s, ok := handlerDuration.series[l]; ok {
+			return s.count
+		}
+		return 0
+	}
+	before := count()
+
+	h := timed("/test/timed", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusTeapot)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/test/timed", nil))
+
+	rec := httptest.NewRecorder()
+	serveMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	want := fmt.Sprintf(`hacktoberfest_http_request_duration_seconds_count{route="/test/timed",code="418"} %d`, before+1)
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics should include %s, got\n%s", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/markbates/goth"
+)
+
+// Everything we serve goes through the same middleware, chained together in
+// main: logging, then recovering from panics, then compression, CORS and
+// sessions. Routes add their own on top, like timed for metrics and
+// requireUser or requireAdmin instead of checking who's logged in themselves.
+
+// middleware wraps a handler in something every request it serves goes
+// through.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps h in ms, the first outermost, so requests go through them in
+// order.
+func chain(h http.Handler, ms ...middleware) http.Handler {
+	for n := len(ms) - 1; n >= 0; n-- {
+		h = ms[n](h)
+	}
+	return h
+}
+
+// recoverPanics turns a panic in h into a 500, logged with the request's
+// details and where it happened, instead of the connection just closing. If
+// h had already started answering, the response is cut off since it can't be
+// finished.
+func recoverPanics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			logError(r.Context(), fmt.Errorf("panic serving request: %v", v),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"stack", string(debug.Stack()),
+			)
+			if sw.wrote {
+				panic(http.ErrAbortHandler)
+			}
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "something went wrong", nil)
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
+
+// userKey is the context key of whoever requireUser or requireAdmin let in.
+type userKey struct{}
+
+// requireUser serves h only to people who are logged in, who h can get with
+// currentUser.
+func requireUser(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := findUser(r)
+		if !ok {
+			notLoggedIn(w, r)
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	}
+}
+
+// requireAdmin is requireUser for people on the admins list.
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, ok := findAdmin(r)
+		if !ok {
+			notAdmin(w, r)
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	}
+}
+
+// currentUser is who requireUser or requireAdmin let r through for.
+func currentUser(r *http.Request) goth.User {
+	u, _ := r.Context().Value(userKey{}).(goth.User)
+	return u
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") }), mark("a"), mark("b"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := strings.Join(order, ","); got != "a,b,handler" {
+		t.Errorf("went through %s, want a,b,handler", got)
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	var out bytes.Buffer
+	logOut = &out
+	defer func() { logOut = os.Stderr }()
+
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var issues []Issue
+		_ = issues[3]
+	}), logRequests, recoverPanics)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/issues", nil))
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), codeInternal) {
+		t.Errorf("got %d %s, want a 500", w.Code, w.Body)
+	}
+	id := w.Header().Get("X-Request-ID")
+	for _, want := range []string{"panic serving request", "index out of range", "path=/api/v1/issues", "request_id=" + id, "middleware_test.go", "status=500"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("logs should contain %s, got %s", want, out.String())
+		}
+	}
+}
+
+func TestRecoverPanicsStarted(t *testing.T) {
+	var out bytes.Buffer
+	logOut = &out
+	defer func() { logOut = os.Stderr }()
+
+	h := recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[{"))
+		panic("oops")
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("got panic %v, want the response cut off", v)
+		}
+		if !strings.Contains(out.String(), "oops") {
+			t.Errorf("the panic should still be logged, got %s", out.String())
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRequireUser(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	oldAdmins := admins
+	defer func() { admins = oldAdmins }()
+	admins = map[string]bool{}
+
+	var got string
+	h := func(w http.ResponseWriter, r *http.Request) { got = currentUser(r).NickName }
+
+	w := httptest.NewRecorder()
+	requireUser(h)(w, httptest.NewRequest("GET", "/api/me/bookmarks", nil))
+	if w.Code != http.StatusUnauthorized || got != "" {
+		t.Errorf("got status %d for someone logged out, want 401", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	requireUser(h)(w, withCookie("GET", "/api/me/bookmarks", sessionCookie(t, store, time.Now())))
+	if w.Code != http.StatusOK || got != "octocat" {
+		t.Errorf("got status %d and user %q, want octocat let through", w.Code, got)
+	}
+
+	got = ""
+	w = httptest.NewRecorder()
+	requireAdmin(h)(w, withCookie("GET", "/api/admin/tracking", sessionCookie(t, store, time.Now())))
+	if w.Code != http.StatusForbidden || got != "" {
+		t.Errorf("got status %d for someone who isn't an admin, want 403", w.Code)
+	}
+
+	admins["octocat"] = true
+	w = httptest.NewRecorder()
+	requireAdmin(h)(w, withCookie("GET", "/api/admin/tracking", sessionCookie(t, store, time.Now())))
+	if w.Code != http.StatusOK || got != "octocat" {
+		t.Errorf("got status %d and user %q, want the admin let through", w.Code, got)
+	}
+}
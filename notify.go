@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Communities can have new issues posted to their Slack or Discord as the
+// background refresh finds them. Each place to post is a notification in the
+// JSON file named by NOTIFICATIONS_FILE, like:
+//
+//	[{"url": "https://hooks.slack.com/services/...", "channel": "#help-wanted", "languages": ["Go"]},
+//	 {"url": "https://discord.com/api/webhooks/...", "labels": ["good first issue"]}]
+
+// notification is somewhere to post new issues: an incoming webhook on Slack
+// or Discord, and which issues it wants.
+type notification struct {
+	URL string `json:"url"`
+
+	// Kind is slack or discord, going by URL if it isn't given
+	Kind string `json:"kind"`
+
+	// Channel overrides the one a Slack webhook posts to. Discord webhooks
+	// only ever post to their own.
+	Channel string `json:"channel"`
+
+	// Languages and Labels narrow down which issues are posted, to those in
+	// any of the languages and with any of the labels. Empty means any.
+	Languages []string `json:"languages"`
+	Labels    []string `json:"labels"`
+}
+
+// notifications are where new issues are posted, from NOTIFICATIONS_FILE.
+var notifications []notification
+
+// notifyMax is the most issues one message lists, so they stay readable and
+// under Discord's limit on length.
+const notifyMax = 10
+
+// notifyClient posts notifications.
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// loadNotifications reads the notifications in config.NotificationsFile, if
+// it's set, and checks we know where and how to post them.
+func loadNotifications() ([]notification, error) {
+	path := config.NotificationsFile
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open notifications file")
+	}
+	defer f.Close()
+
+	var ns []notification
+	if err := json.NewDecoder(f).Decode(&ns); err != nil {
+		return nil, errors.Wrap(err, "could not decode notifications file")
+	}
+
+	for i := range ns {
+		if err := ns[i].check(); err != nil {
+			return nil, errors.Wrapf(err, "notification %d", i+1)
+		}
+	}
+	return ns, nil
+}
+
+// check makes sure n has a URL we can post to, filling in Kind if needed.
+func (n *notification) check() error {
+	u, err := url.Parse(n.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("url %q should be an https webhook URL", n.URL)
+	}
+
+	if n.Kind == "" {
+		switch {
+		case u.Host == "hooks.slack.com":
+			n.Kind = "slack"
+		case (u.Host == "discord.com" || u.Host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+			n.Kind = "discord"
+		}
+	}
+	if n.Kind != "slack" && n.Kind != "discord" {
+		return errors.Errorf("can't tell whether %s is Slack or Discord, set kind", u.Host)
+	}
+	return nil
+}
+
+// wants reports whether n should be told about i.
+func (n notification) wants(i Issue) bool {
+	if len(n.Languages) > 0 {
+		var f issueFilter
+		for _, l := range n.Languages {
+			f.langs = append(f.langs, strings.ToLower(l))
+		}
+		if !f.keep(i) {
+			return false
+		}
+	}
+
+	if len(n.Labels) == 0 {
+		return true
+	}
+	for have := range i.Labels {
+		for _, want := range n.Labels {
+			if strings.EqualFold(have, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// message is what to post to n about issues.
+func (n notification) message(issues []Issue) interface{} {
+	var b bytes.Buffer
+	if len(issues) == 1 {
+		b.WriteString("A new issue is looking for help:\n")
+	} else {
+		fmt.Fprintf(&b, "%d new issues are looking for help:\n", len(issues))
+	}
+
+	for j, i := range issues {
+		if j == notifyMax {
+			fmt.Fprintf(&b, "…and %d more\n", len(issues)-notifyMax)
+			break
+		}
+
+		repo := i.Repo.Owner + "/" + i.Repo.Name
+		if n.Kind == "slack" {
+			fmt.Fprintf(&b, "• <%s|%s> in %s", i.URL, slackEscape(i.Title), repo)
+		} else {
+			fmt.Fprintf(&b, "• [%s](<%s>) in %s", discordEscape(i.Title), i.URL, repo)
+		}
+		if len(i.Languages) > 0 {
+			fmt.Fprintf(&b, " (%s)", i.Languages[0])
+		}
+		b.WriteString("\n")
+	}
+
+	if n.Kind == "slack" {
+		msg := map[string]string{"text": b.String()}
+		if n.Channel != "" {
+			msg["channel"] = n.Channel
+		}
+		return msg
+	}
+	return map[string]string{"content": b.String()}
+}
+
+// slackEscape escapes the characters Slack treats as markup in text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// discordEscape escapes the characters that would end a Discord link's text
+// or turn it into markdown.
+func discordEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`).Replace(s)
+}
+
+// post sends n's message about issues.
+func (n notification) post(ctx context.Context, issues []Issue) error {
+	body, err := json.Marshal(n.message(issues))
+	if err != nil {
+		return errors.Wrap(err, "could not encode notification")
+	}
+
+	req, err := http.NewRequest("POST", n.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifyClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "could not post notification")
+	}
+	defer resp.Body.Close()
+
+	// Slack says 200 and Discord 204
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("could not post notification, status was %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notify posts the newly opened issues to every notification that wants any
+// of them. One failing doesn't stop the others.
+func notify(ctx context.Context, ns []notification, opened []Issue) {
+	for _, n := range ns {
+		var wanted []Issue
+		for _, i := range opened {
+			if n.wants(i) {
+				wanted = append(wanted, i)
+			}
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+
+		if err := n.post(ctx, wanted); err != nil {
+			u, _ := url.Parse(n.URL)
+			logError(ctx, err, "kind", n.Kind, "host", u.Host)
+		}
+	}
+}
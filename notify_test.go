@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotificationCheck(t *testing.T) {
+	tests := []struct {
+		n    notification
+		kind string
+		ok   bool
+	}{
+		{notification{URL: "https://hooks.slack.com/services/T0/B0/x"}, "slack", true},
+		{notification{URL: "https://discord.com/api/webhooks/1/x"}, "discord", true},
+		{notification{URL: "https://chat.example.com/hook", Kind: "slack"}, "slack", true},
+		{notification{URL: "https://chat.example.com/hook"}, "", false},
+		{notification{URL: "http://hooks.slack.com/services/T0/B0/x"}, "", false},
+		{notification{URL: "https://discord.com/channels/1", Kind: "teams"}, "", false},
+	}
+
+	for _, test := range tests {
+		n := test.n
+		err := n.check()
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got error %v, want ok %v", test.n.URL, err, test.ok)
+			continue
+		}
+		if test.ok && n.Kind != test.kind {
+			t.Errorf("%q: got kind %q, want %q", test.n.URL, n.Kind, test.kind)
+		}
+	}
+}
+
+func TestNotificationWants(t *testing.T) {
+	i := Issue{Languages: []string{"Go", "JavaScript"}, Labels: map[string]string{"Good First Issue": "7057ff"}}
+
+	tests := []struct {
+		n    notification
+		want bool
+	}{
+		{notification{}, true},
+		{notification{Languages: []string{"go"}}, true},
+		{notification{Languages: []string{"Python"}}, false},
+		{notification{Labels: []string{"good first issue"}}, true},
+		{notification{Languages: []string{"Go"}, Labels: []string{"bug"}}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.n.wants(i); got != test.want {
+			t.Errorf("%+v: got %v, want %v", test.n, got, test.want)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var got []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		got = append(got, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ns := []notification{
+		{URL: srv.URL + "/slack", Kind: "slack", Channel: "#go", Languages: []string{"Go"}},
+		{URL: srv.URL + "/discord", Kind: "discord"},
+		{URL: srv.URL + "/nobody", Kind: "slack", Languages: []string{"COBOL"}},
+	}
+	opened := []Issue{
+		{Title: "Fix <it>", URL: "https://github.com/devict/hacktoberfest/issues/1", Repo: Repo{Owner: "devict", Name: "hacktoberfest"}, Languages: []string{"Go"}},
+		{Title: "Add [docs]", URL: "https://github.com/devict/site/issues/2", Repo: Repo{Owner: "devict", Name: "site"}},
+	}
+	notify(context.Background(), ns, opened)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d messages, want 2", len(got))
+	}
+	if got[0]["channel"] != "#go" || !strings.Contains(got[0]["text"], "<https://github.com/devict/hacktoberfest/issues/1|Fix &lt;it&gt;> in devict/hacktoberfest (Go)") {
+		t.Errorf("got slack message %v", got[0])
+	}
+	if strings.Contains(got[0]["text"], "docs") {
+		t.Errorf("slack message should only have Go issues, got %v", got[0])
+	}
+	if c := got[1]["content"]; !strings.Contains(c, "2 new issues") || !strings.Contains(c, `[Add \[docs\]](<https://github.com/devict/site/issues/2>) in devict/site`) {
+		t.Errorf("got discord message %v", got[1])
+	}
+}
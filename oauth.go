@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/markbates/goth"
+	"github.com/pkg/errors"
+)
+
+// Logging in is OAuth's web flow, with GitHub or GitLab. beginAuth sends
+// people to the provider with a random state, and with PKCE a code challenge,
+// keeping both in a short lived login cookie. completeAuth only takes the code
+// they're sent back with if the state matches the cookie's, so nobody can log
+// someone in as them by getting them to follow a callback link. With PKCE the
+// code is also no use without the verifier in the cookie.
+
+// loginProvider is somewhere people can log in.
+type loginProvider struct {
+	// name is the provider in /auth/{provider} and what its tokens are kept
+	// under, and title is what people know it as
+	name  string
+	title string
+
+	clientID     string
+	clientSecret string
+	authURL      string
+	tokenURL     string
+	redirectURL  string
+
+	// scopes are what we ask for, which need to be granted to log in
+	scopes []string
+
+	// granted gives the scopes t was granted
+	granted func(ctx context.Context, t tokenResponse) ([]string, error)
+
+	// user gives who token belongs to, with Provider set to name
+	user func(ctx context.Context, token string) (goth.User, error)
+
+	// revoke stops token working
+	revoke func(ctx context.Context, token string) error
+}
+
+// loginProviders are the loginProviders by name. setupAuth fills them in.
+var loginProviders = map[string]*loginProvider{}
+
+// loginProviderOf is the loginProvider r is for, from its URL.
+func loginProviderOf(r *http.Request) (*loginProvider, bool) {
+	p, ok := loginProviders[r.URL.Query().Get(":provider")]
+	return p, ok
+}
+
+// loginName is the cookie a login in progress is kept in.
+const loginName = "login"
+
+// loginMaxAge is how long someone has to log in with the provider before
+// they have to start again.
+const loginMaxAge = 10 * time.Minute
+
+// randomToken gives n random bytes made URL safe.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge is the S256 PKCE challenge for verifier.
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// beginAuth sends people to log in with the provider in the URL.
+func beginAuth(w http.ResponseWriter, r *http.Request) {
+	p, ok := loginProviderOf(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		logError(r.Context(), errors.Wrap(err, "could not make login state"))
+		http.Error(w, "could not start login", http.StatusInternalServerError)
+		return
+	}
+	q := url.Values{
+		"client_id":     {p.clientID},
+		"redirect_uri":  {p.redirectURL},
+		"response_type": {"code"},
+		"state":         {state},
+	}
+	if len(p.scopes) > 0 {
+		q.Set("scope", strings.Join(p.scopes, " "))
+	}
+
+	s, _ := sess.Get(r, loginName)
+	s.Values = map[interface{}]interface{}{"state": state}
+	if config.PKCE {
+		verifier, err := randomToken(32)
+		if err != nil {
+			logError(r.Context(), errors.Wrap(err, "could not make code verifier"))
+			http.Error(w, "could not start login", http.StatusInternalServerError)
+			return
+		}
+		q.Set("code_challenge", codeChallenge(verifier))
+		q.Set("code_challenge_method", "S256")
+		s.Values["verifier"] = verifier
+	}
+
+	s.Options.MaxAge = int(loginMaxAge / time.Second)
+	if err := s.Save(r, w); err != nil {
+		logError(r.Context(), errors.Wrap(err, "could not save login state"))
+		http.Error(w, "could not start login", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, p.authURL+"?"+q.Encode(), http.StatusTemporaryRedirect)
+}
+
+// errLoginState is a callback whose state isn't the one we sent them to
+// the provider with, or that we didn't send them at all.
+var errLoginState = errors.New("login state mismatch, please try logging in again")
+
+// completeAuth checks the callback r is for the login its cookie started,
+// swaps the code for a token and gives back who it belongs to, with the token
+// as their AccessToken. The login cookie is cleared either way so the
+// callback can't be used twice.
+func completeAuth(w http.ResponseWriter, r *http.Request) (goth.User, error) {
+	p, ok := loginProviderOf(r)
+	if !ok {
+		return goth.User{}, errors.Errorf("can't log in with %q", r.URL.Query().Get(":provider"))
+	}
+
+	s, _ := sess.Get(r, loginName)
+	state, _ := s.Values["state"].(string)
+	verifier, _ := s.Values["verifier"].(string)
+	s.Values = make(map[interface{}]interface{})
+	s.Options.MaxAge = -1
+	if err := s.Save(r, w); err != nil {
+		logError(r.Context(), errors.Wrap(err, "could not clear login state"))
+	}
+
+	q := r.URL.Query()
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+		return goth.User{}, errLoginState
+	}
+	if e := q.Get("error"); e == "access_denied" {
+		return goth.User{}, errLoginDeclined
+	} else if e != "" {
+		return goth.User{}, errors.Errorf("%s said %s: %s", p.title, e, q.Get("error_description"))
+	}
+
+	t, err := exchangeCode(r.Context(), p, q.Get("code"), verifier)
+	if err != nil {
+		return goth.User{}, err
+	}
+
+	granted, err := p.granted(r.Context(), t)
+	if err != nil {
+		return goth.User{}, err
+	}
+	if missing := missingScopes(p.scopes, granted); len(missing) > 0 {
+		// We won't keep it, so nobody should be able to use it
+		if err := p.revoke(r.Context(), t.AccessToken); err != nil {
+			logError(r.Context(), err)
+		}
+		return goth.User{}, &scopeError{provider: p.title, missing: missing}
+	}
+
+	u, err := p.user(r.Context(), t.AccessToken)
+	if err != nil {
+		return goth.User{}, err
+	}
+	u.AccessToken = t.AccessToken
+	return u, nil
+}
+
+// errLoginDeclined is someone choosing not to let us log them in on the
+// provider's authorize page.
+var errLoginDeclined = errors.New("you didn't authorize us")
+
+// scopeError is a token without every scope we asked for, like when someone
+// edits them out of the authorize URL.
+type scopeError struct {
+	provider string
+	missing  []string
+}
+
+func (e *scopeError) Error() string {
+	return e.provider + " didn't grant the " + strings.Join(e.missing, ", ") + " scope we need"
+}
+
+// grantedScopes gives the scopes token has, from the X-OAuth-Scopes header
+// GitHub sends back with anything asked for with it.
+func grantedScopes(ctx context.Context, c Config, token string) ([]string, error) {
+	api, _ := githubAPI(c.GitHubURL)
+	req, err := http.NewRequest("GET", api+"/user", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make scopes request")
+	}
+	req.Header.Set("Authorization", "token "+token)
+
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+	resp, err := upstream.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not check token scopes")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("checking token scopes got status %d", resp.StatusCode)
+	}
+	return splitScopes(resp.Header.Get("X-OAuth-Scopes")), nil
+}
+
+// splitScopes splits a list of scopes separated by commas or spaces.
+func splitScopes(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// broaderScopes are the scopes that include each of the ones we ask for.
+var broaderScopes = map[string]string{
+	"user:email":  "user",
+	"public_repo": "repo",
+	"read_user":   "api",
+	"read_api":    "api",
+}
+
+// missingScopes gives which of want aren't in granted, either themselves or
+// through a broader scope.
+func missingScopes(want, granted []string) []string {
+	have := set(granted)
+	var missing []string
+	for _, w := range want {
+		if !have[w] && !have[broaderScopes[w]] {
+			missing = append(missing, w)
+		}
+	}
+	return missing
+}
+
+// tokenResponse is what a provider gives for a code.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+
+	// Scope is what was granted, if the provider says. GitHub doesn't
+	// always, so we ask it.
+	Scope string `json:"scope"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode swaps code for an access token from p, sending verifier too
+// if there is one. goth can't send it, so we don't use its Authorize.
+func exchangeCode(ctx context.Context, p *loginProvider, code, verifier string) (tokenResponse, error) {
+	if code == "" {
+		return tokenResponse{}, errors.Errorf("%s didn't send a code", p.title)
+	}
+
+	form := url.Values{
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {p.redirectURL},
+	}
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.CallTimeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, errors.Wrap(err, "could not make token request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := upstream.Do(req.WithContext(ctx))
+	if err != nil {
+		return tokenResponse{}, errors.Wrap(err, "could not exchange code")
+	}
+	defer resp.Body.Close()
+
+	var t tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return tokenResponse{}, errors.Wrapf(err, "could not decode token response with status %d", resp.StatusCode)
+	}
+	if t.Error != "" {
+		return tokenResponse{}, errors.Errorf("could not exchange code: %s: %s", t.Error, t.ErrorDescription)
+	}
+	if t.AccessToken == "" {
+		return tokenResponse{}, errors.Errorf("no access token in response with status %d", resp.StatusCode)
+	}
+	return t, nil
+}
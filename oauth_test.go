@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markbates/goth"
+)
+
+// grantScopes and gitlabScopes are the scopes fakeOAuth says its GitHub and
+// GitLab tokens have.
+var grantScopes, gitlabScopes = "user:email", "read_user read_api"
+
+// fakeOAuth is GitHub's and GitLab's side of logging in. Each gives out a
+// token for the code "good", as long as the verifier matches challenge.
+func fakeOAuth(t *testing.T, challenge *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			r.ParseForm()
+			if r.Form.Get("client_secret") != "secret" {
+				t.Errorf("token request had client secret %q", r.Form.Get("client_secret"))
+			}
+			if r.Form.Get("code") != "good" {
+				json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+				return
+			}
+			if got := codeChallenge(r.Form.Get("code_verifier")); got != *challenge {
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "verifier mismatch"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "token", "token_type": "bearer"})
+		case "/api/v3/applications/id/token":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v3/user":
+			// The goth we have sends the token in the query string
+			if r.Header.Get("Authorization") != "token token" && r.URL.Query().Get("access_token") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-OAuth-Scopes", grantScopes)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "login": "octocat", "email": "octocat@example.com"})
+		case "/oauth/token":
+			r.ParseForm()
+			if r.Form.Get("client_id") != "gl-id" || r.Form.Get("grant_type") != "authorization_code" {
+				t.Errorf("got GitLab token request %v", r.Form)
+			}
+			if r.Form.Get("code") != "good" || codeChallenge(r.Form.Get("code_verifier")) != *challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "gl-token", "token_type": "Bearer", "scope": gitlabScopes})
+		case "/oauth/revoke":
+			r.ParseForm()
+			revokedGitLab = r.Form.Get("token")
+		case "/api/v4/user":
+			if r.Header.Get("Authorization") != "Bearer gl-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "username": "tanuki", "name": "Tanuki"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// revokedGitLab is the last GitLab token fakeOAuth was asked to revoke.
+var revokedGitLab string
+
+// useOAuth points logging in at srv, with PKCE, until the func it gives back
+// puts it all back how it was.
+func useOAuth(srv *httptest.Server) func() {
+	restore := keepSessions()
+	c := config
+	c.GitHubURL = srv.URL
+	c.ClientID, c.ClientSecret = "id", "secret"
+	c.GitLabURL, c.GitLabClientID, c.GitLabClientSecret = srv.URL, "gl-id", "secret"
+	c.SiteURL = "https://hacktoberfest.example.com"
+	c.SessionSecret = "banana"
+	c.PKCE = true
+	config = c
+	setupAuth(c)
+	return func() { restore(); setupAuth(config) }
+}
+
+// callback is GitHub sending someone back with code and state, carrying the
+// login cookie if there is one.
+func callback(code, state string, login *http.Cookie) *http.Request {
+	return providerCallback("github", code, state, login)
+}
+
+// providerCallback is callback from provider.
+func providerCallback(provider, code, state string, login *http.Cookie) *http.Request {
+	q := url.Values{":provider": {provider}, "code": {code}, "state": {state}}
+	r := httptest.NewRequest("GET", "/auth/"+provider+"/callback?"+q.Encode(), nil)
+	if login != nil {
+		r.AddCookie(login)
+	}
+	return r
+}
+
+// startLogin begins logging in with provider, giving the state it was sent
+// off with and its login cookie.
+func startLogin(t *testing.T, provider string) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	beginAuth(w, httptest.NewRequest("GET", "/auth/"+provider+"?:provider="+provider, nil))
+	to, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return to.Query().Get("state"), w.Result().Cookies()[0]
+}
+
+func TestLogin(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+
+	// A state in the query string, like an attacker's, is ignored
+	w := httptest.NewRecorder()
+	sameSiteCookies(http.HandlerFunc(beginAuth)).ServeHTTP(w, httptest.NewRequest("GET", "/auth/github?:provider=github&state=chosen", nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("got status %d, want a redirect to GitHub", w.Code)
+	}
+	to, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := to.Query().Get("state")
+	challenge = to.Query().Get("code_challenge")
+	if !strings.HasPrefix(to.String(), srv.URL+"/login/oauth/authorize") || state == "" || state == "chosen" {
+		t.Errorf("got redirect to %s, want GitHub with a random state", to)
+	}
+	if challenge == "" || to.Query().Get("code_challenge_method") != "S256" {
+		t.Errorf("redirect %s should have an S256 code challenge", to)
+	}
+
+	if set := w.Header().Get("Set-Cookie"); !strings.Contains(set, "SameSite=Lax") || !strings.Contains(set, "Secure") {
+		t.Errorf("login cookie %q should be Secure and SameSite=Lax", set)
+	}
+	login := w.Result().Cookies()[0]
+
+	for _, c := range []struct {
+		name  string
+		r     *http.Request
+		valid bool
+	}{
+		{"no login cookie", callback("good", state, nil), false},
+		{"someone else's state", callback("good", "chosen", login), false},
+		{"wrong code", callback("stolen", state, login), true},
+	} {
+		_, err := completeAuth(httptest.NewRecorder(), c.r)
+		if c.valid && (err == nil || err == errLoginState) {
+			t.Errorf("%s: got error %v, want GitHub to refuse", c.name, err)
+		}
+		if !c.valid && err != errLoginState {
+			t.Errorf("%s: got error %v, want errLoginState", c.name, err)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	u, err := completeAuth(w, callback("good", state, login))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.NickName != "octocat" || u.AccessToken != "token" {
+		t.Errorf("got %+v, want octocat with their token", u)
+	}
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("login cookie should be cleared after the callback, got %v", cleared)
+	}
+}
+
+func TestLoginWithoutPKCE(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+	config.PKCE = false
+
+	w := httptest.NewRecorder()
+	beginAuth(w, httptest.NewRequest("GET", "/auth/github?:provider=github", nil))
+	to, _ := url.Parse(w.Header().Get("Location"))
+	if to.Query().Get("code_challenge") != "" {
+		t.Errorf("redirect %s shouldn't have a code challenge without PKCE", to)
+	}
+
+	// The fake wants the challenge of the verifier, which is none
+	challenge = codeChallenge("")
+	if _, err := completeAuth(httptest.NewRecorder(), callback("good", to.Query().Get("state"), w.Result().Cookies()[0])); err != nil {
+		t.Errorf("got error %v logging in without PKCE", err)
+	}
+}
+
+func TestLoginScopes(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+	config.PKCE = false
+	challenge = codeChallenge("")
+
+	oldComments, oldScopes := config.ClaimComments, grantScopes
+	defer func() { config.ClaimComments, grantScopes = oldComments, oldScopes }()
+
+	login := func() error {
+		setupAuth(config)
+		w := httptest.NewRecorder()
+		beginAuth(w, httptest.NewRequest("GET", "/auth/github?:provider=github", nil))
+		to, _ := url.Parse(w.Header().Get("Location"))
+		_, err := completeAuth(httptest.NewRecorder(), callback("good", to.Query().Get("state"), w.Result().Cookies()[0]))
+		return err
+	}
+
+	// Plain logins don't need anything
+	config.ClaimComments, grantScopes = false, ""
+	if err := login(); err != nil {
+		t.Errorf("got error %v logging in without any scopes", err)
+	}
+
+	config.ClaimComments = true
+	if err, ok := login().(*scopeError); !ok || len(err.missing) != 1 || err.missing[0] != "public_repo" {
+		t.Errorf("got error %v, want public_repo missing", err)
+	}
+	grantScopes = "repo, user"
+	if err := login(); err != nil {
+		t.Errorf("got error %v, but repo includes public_repo", err)
+	}
+}
+
+func TestLoginDeclined(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+
+	w := httptest.NewRecorder()
+	beginAuth(w, httptest.NewRequest("GET", "/auth/github?:provider=github", nil))
+	to, _ := url.Parse(w.Header().Get("Location"))
+
+	q := url.Values{":provider": {"github"}, "error": {"access_denied"}, "state": {to.Query().Get("state")}}
+	r := httptest.NewRequest("GET", "/auth/github/callback?"+q.Encode(), nil)
+	r.AddCookie(w.Result().Cookies()[0])
+	w = httptest.NewRecorder()
+	authCallback(w, r)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "authorize us on GitHub") {
+		t.Errorf("got %d %q, want a page saying they declined", w.Code, w.Body.String())
+	}
+}
+
+func TestGitLabLogin(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+	config.PKCE = false
+	challenge = codeChallenge("")
+
+	w := httptest.NewRecorder()
+	beginAuth(w, httptest.NewRequest("GET", "/auth/gitlab?:provider=gitlab", nil))
+	to, _ := url.Parse(w.Header().Get("Location"))
+	if !strings.HasPrefix(to.String(), srv.URL+"/oauth/authorize") || to.Query().Get("scope") != "read_user read_api" {
+		t.Errorf("got redirect to %s, want GitLab asking for read_user and read_api", to)
+	}
+
+	u, err := completeAuth(httptest.NewRecorder(), providerCallback("gitlab", "good", to.Query().Get("state"), w.Result().Cookies()[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.UserID != "-7" || u.NickName != "tanuki" || u.Provider != "gitlab" || u.AccessToken != "gl-token" {
+		t.Errorf("got %+v, want tanuki from GitLab with a negated ID", u)
+	}
+
+	// A token without what we asked for is given back
+	old := gitlabScopes
+	defer func() { gitlabScopes = old }()
+	gitlabScopes, revokedGitLab = "read_user", ""
+	state, login := startLogin(t, "gitlab")
+	if _, err := completeAuth(httptest.NewRecorder(), providerCallback("gitlab", "good", state, login)); err == nil || revokedGitLab != "gl-token" {
+		t.Errorf("got error %v with %q revoked, want read_api missing and the token revoked", err, revokedGitLab)
+	}
+
+	// Without its OAuth application there's no logging in with GitLab
+	c := config
+	c.GitLabClientID = ""
+	setupAuth(c)
+	w = httptest.NewRecorder()
+	beginAuth(w, httptest.NewRequest("GET", "/auth/gitlab?:provider=gitlab", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want 404 without a GitLab application", w.Code)
+	}
+}
+
+func TestConnectGitHub(t *testing.T) {
+	var challenge string
+	srv := fakeOAuth(t, &challenge)
+	defer srv.Close()
+	defer useOAuth(srv)()
+	config.PKCE = false
+	challenge = codeChallenge("")
+
+	oldOthers := others
+	defer func() { others = oldOthers }()
+	ours := newGitLab(srv.URL, "ours", []string{"wichita/site"})
+	others = []IssueSource{ours}
+
+	// Someone logged in with GitLab
+	r := httptest.NewRequest("GET", "/", nil)
+	s, _ := sess.Get(r, sessionName)
+	if err := startSession(s, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	gitlabber := goth.User{UserID: "-7", NickName: "tanuki", Provider: "gitlab"}
+	s.Values["user"] = gitlabber
+	s.Values[tokenName("gitlab")], _ = sealToken(tokenOwner(gitlabber, "gitlab"), "gl-token")
+	w := httptest.NewRecorder()
+	if err := s.Save(r, w); err != nil {
+		t.Fatal(err)
+	}
+	session := w.Result().Cookies()[0]
+
+	if u, _, ok := findUser(withCookie("GET", "/", session)); !ok || u.AccessToken != "" {
+		t.Errorf("got %+v, want someone with no GitHub token", u)
+	}
+	w = httptest.NewRecorder()
+	requireUser(getProgress)(w, withCookie("GET", "/progress", session))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %d for progress, want 403 without GitHub", w.Code)
+	}
+
+	// connects GitHub, staying who they are
+	state, login := startLogin(t, "github")
+	r = callback("good", state, login)
+	r.AddCookie(session)
+	w = httptest.NewRecorder()
+	authCallback(w, r)
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("got status %d connecting GitHub: %s", w.Code, w.Body)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionName {
+			session = c
+		}
+	}
+
+	r = withCookie("GET", "/", session)
+	u, _, tokens, ok := sessionUser(r)
+	if !ok || u.NickName != "tanuki" || tokens["github"] != "token" || tokens["gitlab"] != "gl-token" {
+		t.Errorf("got %+v with tokens %v, want tanuki with both tokens", u, tokens)
+	}
+
+	// Searches go to each provider with its own token
+	c, ok := issueClient(r)
+	if !ok || c.Token != "token" || c.Shared {
+		t.Fatalf("got client %+v, want their own GitHub token", c)
+	}
+	if g := c.Others[0].(*GitLab); g.Token != "gl-token" || !g.Bearer {
+		t.Errorf("got GitLab token %q, want theirs as a bearer token", g.Token)
+	}
+	if ours.Token != "ours" || ours.Bearer {
+		t.Errorf("the shared GitLab source shouldn't change")
+	}
+}
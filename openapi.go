@@ -0,0 +1,538 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// The API is described by an OpenAPI document served at /openapi.json, with
+// Swagger UI to try it out at /docs. Schemas are built from the Go types
+// handlers actually encode, so they can't drift from what's sent. The same
+// document is kept in client/openapi.json for generating the Go client.
+
+// operation is one thing the API can do, described well enough to build its
+// part of the document. Path parameters come from the {names} in path.
+type operation struct {
+	method, path string
+
+	// id is what clients call the operation
+	id, summary, tag string
+
+	query []specParam
+
+	// body is what the request body is decoded into, and result what's sent
+	// back with status on success. Either can be nil for none.
+	body   interface{}
+	status int
+	result interface{}
+}
+
+// specParam is a query parameter, where kind is string, integer or boolean.
+type specParam struct {
+	name, kind, description string
+}
+
+// issueList is what /api/v1/issues gives back: an apiEnvelope with issues in
+// it.
+type issueList struct {
+	Data   []Issue         `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// closedList is what /api/v1/issues/recently-closed gives back: an
+// apiEnvelope with ClosedIssues in it.
+type closedList struct {
+	Data   []ClosedIssue   `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// statsEnvelope is what /api/v1/stats gives back: an apiEnvelope with
+// issueStats in it.
+type statsEnvelope struct {
+	Data   issueStats      `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// repoEnvelope is what /api/v1/repos/{owner}/{repo} gives back: an
+// apiEnvelope with a repoDetail in it.
+type repoEnvelope struct {
+	Data   repoDetail      `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// mentorsEnvelope is what /api/v1/mentors gives back: an apiEnvelope with
+// the mentors in it.
+type mentorsEnvelope struct {
+	Data   []mentor        `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// historyEnvelope is what /api/v1/stats/history gives back: an apiEnvelope
+// with Snapshots in it.
+type historyEnvelope struct {
+	Data   []Snapshot      `json:"data"`
+	Meta   apiMeta         `json:"meta"`
+	Errors []searchWarning `json:"errors"`
+}
+
+// These are the bodies handlers decode, which they do into structs of their
+// own.
+type (
+	orgRequest struct {
+		Org string `json:"org"`
+	}
+	repoRequest struct {
+		Repo string `json:"repo"`
+	}
+	excludeRequest struct {
+		Name string `json:"name"`
+	}
+)
+
+// issueParams are the query parameters listing issues takes.
+var issueParams = []specParam{
+	{"labels", "string", "Comma separated labels to search for instead of the tracked ones"},
+	{"lang", "string", "Comma separated languages the repo should use"},
+	{"lang_match", "string", "any (the default) or all of the languages must match"},
+	{"natural_lang", "string", "Comma separated spoken languages the repo's topics should name"},
+	{"topic", "string", "Comma separated topics the repo should have one of"},
+	{"difficulty", "string", "Comma separated tiers: easy, medium or hard"},
+	{"kind", "string", "Comma separated kinds of work: docs, bug, feature, tests or translation"},
+	{"archived", "boolean", "Keep issues in archived repos"},
+	{"participating", "boolean", "Only issues in repos taking part in Hacktoberfest"},
+	{"unassigned", "boolean", "Only issues nobody is assigned"},
+	{"mentored", "boolean", "Only issues with a mentor available"},
+	{"duplicates", "string", "show (the default) or collapse to keep one of each set of copies across repos"},
+	{"q", "string", "Words that must all be in the title or body"},
+	{"exclude_repo", "string", "Comma separated owners or owner/names to leave out"},
+	{"exclude", "string", "Comma separated words to leave out issues mentioning"},
+	{"matched", "string", "Comma separated labels, one of whose searches must have found the issue"},
+	{"include_hidden", "boolean", "Keep issues you've hidden"},
+	{"max_langs", "integer", "How many of each repo's languages to list, MAX_LANGUAGES (3) unless given, 0 for all"},
+	{"max_age", "string", "Only issues opened this recently, like 30d or 2w"},
+	{"since", "string", "Only issues updated on or after this day, like 2017-10-20"},
+	{"sort", "string", "created, updated, comments, reactions, activity, stars, score or repo"},
+	{"order", "string", "asc or desc"},
+	{"page", "integer", "Which page to give back"},
+	{"per_page", "integer", "How many issues a page has"},
+	{"max_per_repo", "integer", "The most issues from one repo, MAX_PER_REPO (25) unless given if more than one is searched, 0 for no limit"},
+	{"max_per_org", "integer", "The most issues from one owner, 0 (the default) for no limit"},
+	{"refresh", "boolean", "Skip the cache"},
+	{"strict", "boolean", "Fail if any search does, rather than give back what the rest found"},
+}
+
+// statsParams are the query parameters stats takes: the filters from
+// issueParams, lang through matched, and refresh and strict.
+var statsParams = append(append([]specParam{}, issueParams[1:16]...),
+	specParam{"refresh", "boolean", "Skip the cache"},
+	specParam{"strict", "boolean", "Fail if any search does, rather than give back what the rest found"},
+)
+
+// diagnosticsParams are the query parameters diagnostics takes: the ones from
+// issueParams that change what's searched for, labels and max_langs through
+// since.
+var diagnosticsParams = append([]specParam{issueParams[0]}, issueParams[18:21]...)
+
+// apiOperations are everything the document describes.
+var apiOperations = []operation{
+	{method: "GET", path: "/api/v1/issues", id: "listIssues", tag: "issues",
+		summary: "List open issues in tracked repos",
+		query:   issueParams, status: http.StatusOK, result: issueList{}},
+	{method: "GET", path: "/api/v1/issues/{owner}/{repo}", id: "listRepoIssues", tag: "issues",
+		summary: "List open issues in one tracked repo",
+		query:   issueParams, status: http.StatusOK, result: issueList{}},
+	{method: "GET", path: "/api/v1/issues/recently-closed", id: "listRecentlyClosed", tag: "issues",
+		summary: "List issues we were listing that have closed lately, newest first",
+		query: []specParam{
+			{"days", "integer", "How many days back to go, 7 unless given and at most 31"},
+		},
+		status: http.StatusOK, result: closedList{}},
+
+	{method: "GET", path: "/api/v1/repos/{owner}/{repo}", id: "getRepo", tag: "issues",
+		summary: "Get what we know about a tracked repo and its open issues",
+		status:  http.StatusOK, result: repoEnvelope{}},
+
+	{method: "GET", path: "/api/v1/stats", id: "getStats", tag: "issues",
+		summary: "Count the listed issues by language, repo, label and day opened",
+		query:   statsParams, status: http.StatusOK, result: statsEnvelope{}},
+
+	{method: "GET", path: "/api/v1/stats/history", id: "getStatsHistory", tag: "issues",
+		summary: "List snapshots of the listing through an October",
+		query: []specParam{
+			{"year", "integer", "Which October, this year's unless given"},
+			{"every", "string", "hour (the default) or day, how far apart snapshots are"},
+		},
+		status: http.StatusOK, result: historyEnvelope{}},
+
+	{method: "GET", path: "/api/v1/mentors", id: "listMentors", tag: "mentors",
+		summary: "List the people offering to help newcomers, by username",
+		query: []specParam{
+			{"lang", "string", "Comma separated languages they should help with one of"},
+		},
+		status: http.StatusOK, result: mentorsEnvelope{}},
+	{method: "GET", path: "/api/me/mentor", id: "getMentor", tag: "mentors",
+		summary: "Get what you offer in the mentors directory",
+		status:  http.StatusOK, result: mentor{}},
+	{method: "PUT", path: "/api/me/mentor", id: "updateMentor", tag: "mentors",
+		summary: "Join the mentors directory, or change what you offer",
+		body:    mentorRequest{}, status: http.StatusNoContent},
+	{method: "DELETE", path: "/api/me/mentor", id: "removeMentor", tag: "mentors",
+		summary: "Leave the mentors directory",
+		status:  http.StatusNoContent},
+
+	{method: "GET", path: "/auth/{provider}", id: "login", tag: "auth",
+		summary: "Log in, by being sent to the provider (only github) and back",
+		status:  http.StatusTemporaryRedirect},
+	{method: "GET", path: "/auth/{provider}/callback", id: "loginCallback", tag: "auth",
+		summary: "Finish logging in, where the provider sends people back to",
+		status:  http.StatusTemporaryRedirect},
+
+	{method: "GET", path: "/api/me/preferences", id: "getPreferences", tag: "preferences",
+		summary: "Get your preferences",
+		status:  http.StatusOK, result: preferences{}},
+	{method: "PUT", path: "/api/me/preferences", id: "updatePreferences", tag: "preferences",
+		summary: "Replace your preferences",
+		body:    preferences{}, status: http.StatusNoContent},
+	{method: "GET", path: "/api/me/feed", id: "getFeed", tag: "preferences",
+		summary: "Get what's changed since you last looked, and mark it seen",
+		status:  http.StatusOK, result: activityFeed{}},
+	{method: "GET", path: "/api/me/tokens", id: "listKeys", tag: "preferences",
+		summary: "List your API keys",
+		status:  http.StatusOK, result: []apiKey{}},
+	{method: "POST", path: "/api/me/tokens", id: "createKey", tag: "preferences",
+		summary: "Make an API key, which is only given back this once",
+		body:    keyRequest{}, status: http.StatusCreated, result: apiKey{}},
+	{method: "DELETE", path: "/api/me/tokens/{id}", id: "revokeKey", tag: "preferences",
+		summary: "Revoke one of your API keys",
+		status:  http.StatusNoContent},
+	{method: "GET", path: "/api/me/ratelimit", id: "getRateLimit", tag: "preferences",
+		summary: "Get how much of each GitHub rate limit your searches have left",
+		status:  http.StatusOK, result: rateLimits{}},
+
+	{method: "POST", path: "/api/repos/submit", id: "submitRepo", tag: "submissions",
+		summary: "Ask for a repo you can push to to be tracked",
+		body:    repoRequest{}, status: http.StatusAccepted},
+
+	{method: "GET", path: "/api/admin/export", id: "exportArchive", tag: "admin",
+		summary: "Export tracked changes, preferences, bookmarks and claims",
+		status:  http.StatusOK, result: archive{}},
+	{method: "POST", path: "/api/admin/import", id: "importArchive", tag: "admin",
+		summary: "Import an export, keeping whichever of each thing is newest",
+		body:    archive{}, status: http.StatusNoContent},
+	{method: "GET", path: "/api/admin/tracking", id: "getTracking", tag: "admin",
+		summary: "Get what's being tracked",
+		status:  http.StatusOK, result: trackingView{}},
+	{method: "POST", path: "/api/admin/orgs", id: "addOrg", tag: "admin",
+		summary: "Track an org",
+		body:    orgRequest{}, status: http.StatusNoContent},
+	{method: "DELETE", path: "/api/admin/orgs/{name}", id: "removeOrg", tag: "admin",
+		summary: "Stop tracking an org",
+		status:  http.StatusNoContent},
+	{method: "POST", path: "/api/admin/repos", id: "addRepo", tag: "admin",
+		summary: "Track a repo",
+		body:    repoRequest{}, status: http.StatusNoContent},
+	{method: "DELETE", path: "/api/admin/repos/{owner}/{repo}", id: "removeRepo", tag: "admin",
+		summary: "Stop tracking a repo",
+		status:  http.StatusNoContent},
+	{method: "GET", path: "/api/admin/audit", id: "listAudit", tag: "admin",
+		summary: "List who changed what, newest first",
+		query: []specParam{
+			{"actor", "string", "Only what this username did"},
+			{"action", "string", "Only this action, like org.added, submission.approved or claim.made"},
+			{"before", "string", "Only what happened before this time, the at of the last entry seen to go further back"},
+		},
+		status: http.StatusOK, result: []auditEntry{}},
+	{method: "GET", path: "/api/admin/diagnostics", id: "getDiagnostics", tag: "admin",
+		summary: "Get the searches the standard listing makes and how they went last time",
+		query:   diagnosticsParams, status: http.StatusOK, result: searchDiagnostics{}},
+	{method: "GET", path: "/api/admin/excluded/flagged", id: "listFlagged", tag: "admin",
+		summary: "List repos that look like they're spamming issues",
+		status:  http.StatusOK, result: []flaggedRepo{}},
+	{method: "POST", path: "/api/admin/excluded", id: "addExcluded", tag: "admin",
+		summary: "Leave an owner, or owner/name, out of every listing",
+		body:    excludeRequest{}, status: http.StatusNoContent},
+	{method: "DELETE", path: "/api/admin/excluded/{owner}/{repo}", id: "removeExcludedRepo", tag: "admin",
+		summary: "Let an excluded repo back in",
+		status:  http.StatusNoContent},
+	{method: "DELETE", path: "/api/admin/excluded/{owner}", id: "removeExcludedOwner", tag: "admin",
+		summary: "Let an excluded owner back in",
+		status:  http.StatusNoContent},
+	{method: "GET", path: "/api/admin/submissions", id: "listSubmissions", tag: "admin",
+		summary: "List pending submissions",
+		status:  http.StatusOK, result: []Submission{}},
+	{method: "POST", path: "/api/admin/submissions/{owner}/{repo}/approve", id: "approveSubmission", tag: "admin",
+		summary: "Approve a submission, tracking its repo",
+		status:  http.StatusNoContent},
+	{method: "POST", path: "/api/admin/submissions/{owner}/{repo}/reject", id: "rejectSubmission", tag: "admin",
+		summary: "Reject a submission",
+		status:  http.StatusNoContent},
+}
+
+// specNames are the types that get a schema of their own in the document,
+// what it's called, and what it is.
+var specNames = map[reflect.Type][2]string{
+	reflect.TypeOf(Issue{}):                {"Issue", "An open issue in a tracked repo"},
+	reflect.TypeOf(ClosedIssue{}):          {"ClosedIssue", "An issue we were listing that has since closed"},
+	reflect.TypeOf(Repo{}):                 {"Repo", "A repo on GitHub, with what we know about it"},
+	reflect.TypeOf(aggregator.Language{}):  {"Language", "One of a repo's languages, with how much of its code is in it"},
+	reflect.TypeOf(repoDetail{}):           {"RepoDetail", "A tracked repo with its languages and open issues"},
+	reflect.TypeOf(issueList{}):            {"IssueList", "The envelope /api/v1/issues gives issues back in"},
+	reflect.TypeOf(apiMeta{}):              {"Meta", "What an envelope says about its data"},
+	reflect.TypeOf(searchWarning{}):        {"Warning", "A search that failed, so issues could be missing"},
+	reflect.TypeOf(aggregator.RateLimit{}): {"RateLimit", "How much of one of GitHub's rate limits is left"},
+	reflect.TypeOf(rateLimits{}):           {"RateLimits", "How much of each GitHub rate limit someone's searches have left"},
+	reflect.TypeOf(apiError{}):             {"Error", "What went wrong, with a code to branch on"},
+	reflect.TypeOf(activityFeed{}):         {"Feed", "What's changed for someone since they last looked"},
+	reflect.TypeOf(activityEvent{}):        {"FeedEvent", "An issue being opened, having its claim released or closing"},
+	reflect.TypeOf(apiKey{}):               {"APIKey", "A key scripts can use the API with as whoever made it"},
+	reflect.TypeOf(keyRequest{}):           {"KeyRequest", "An API key to make"},
+	reflect.TypeOf(mentor{}):               {"Mentor", "Someone offering to help newcomers with some languages"},
+	reflect.TypeOf(mentorRequest{}):        {"MentorRequest", "What someone offers in the mentors directory"},
+	reflect.TypeOf(preferences{}):          {"Preferences", "What someone wants listings to show them by default"},
+	reflect.TypeOf(archive{}):              {"Archive", "What only lives in the database, for moving it or restoring it"},
+	reflect.TypeOf(trackingView{}):         {"Tracking", "The orgs, repos and labels being tracked, and the owners and repos left out"},
+	reflect.TypeOf(auditEntry{}):           {"AuditEntry", "Something someone did, from the audit log"},
+	reflect.TypeOf(searchDiagnostics{}):    {"SearchDiagnostics", "The searches for each label and how they went last time"},
+	reflect.TypeOf(labelDiagnostics{}):     {"LabelDiagnostics", "The search for one label"},
+	reflect.TypeOf(flaggedRepo{}):          {"FlaggedRepo", "A repo with a lot of issues sharing a title"},
+	reflect.TypeOf(Submission{}):           {"Submission", "A repo someone asked us to track"},
+	reflect.TypeOf(orgRequest{}):           {"OrgRequest", "An org to track"},
+	reflect.TypeOf(repoRequest{}):          {"RepoRequest", "A repo, as owner/name"},
+	reflect.TypeOf(excludeRequest{}):       {"ExcludeRequest", "An owner, or owner/name, to leave out of listings"},
+}
+
+// schemas builds JSON schemas from Go types, going by how encoding/json
+// would encode them.
+type schemas map[string]interface{}
+
+// of is the schema for t, or a reference to it if it's in specNames.
+func (s schemas) of(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.of(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		named, ok := specNames[t]
+		if !ok {
+			return s.object(t)
+		}
+		name := named[0]
+		if _, done := s[name]; !done {
+			// Claim the name first in case the type refers to itself
+			s[name] = nil
+			o := s.object(t)
+			o["description"] = named[1]
+			s[name] = o
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	// Anything else, like an interface, could be any JSON at all
+	return map[string]interface{}{}
+}
+
+// object is the schema for the struct t, with a property for each field
+// encoding/json would encode. Fields without omitempty are required.
+func (s schemas) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := map[string]bool{}
+	s.fields(t, props, required)
+
+	o := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = sortedKeys(required)
+	}
+	return o
+}
+
+// fields adds t's fields to props, and the required ones to required, unless
+// props already has one by that name.
+func (s schemas) fields(t reflect.Type, props map[string]interface{}, required map[string]bool) {
+	// Embedded structs without a name of their own have their fields
+	// promoted, but fields of ours win, so they come last
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f.Type)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := props[name]; ok {
+			continue
+		}
+		props[name] = s.of(f.Type)
+		if !strings.Contains(opts, ",omitempty") {
+			required[name] = true
+		}
+	}
+
+	for _, e := range embedded {
+		s.fields(e, props, required)
+	}
+}
+
+// rePathParam finds the {names} of path parameters.
+var rePathParam = regexp.MustCompile(`{([a-z]+)}`)
+
+// document is the OpenAPI document for ops.
+func document(ops []operation) map[string]interface{} {
+	s := schemas{}
+	errorResponse := map[string]interface{}{
+		"description": "What went wrong",
+		"content":     jsonContent(s.of(reflect.TypeOf(apiError{}))),
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range ops {
+		var params []interface{}
+		for _, m := range rePathParam.FindAllStringSubmatch(op.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, p := range op.query {
+			params = append(params, map[string]interface{}{
+				"name": p.name, "in": "query", "description": p.description,
+				"schema": map[string]interface{}{"type": p.kind},
+			})
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(op.status)}
+		if op.result != nil {
+			success["content"] = jsonContent(s.of(reflect.TypeOf(op.result)))
+		}
+		o := map[string]interface{}{
+			"operationId": op.id,
+			"summary":     op.summary,
+			"tags":        []string{op.tag},
+			"responses": map[string]interface{}{
+				strconv.Itoa(op.status): success,
+				"default":               errorResponse,
+			},
+		}
+		if params != nil {
+			o["parameters"] = params
+		}
+		if op.body != nil {
+			o["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(s.of(reflect.TypeOf(op.body))),
+			}
+		}
+
+		p, _ := paths[op.path].(map[string]interface{})
+		if p == nil {
+			p = map[string]interface{}{}
+			paths[op.path] = p
+		}
+		p[strings.ToLower(op.method)] = o
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Wichita Hacktoberfest",
+			"description": "Find issues to work on in Wichita's open source projects. Logging in is by a session cookie, from /auth/github, or an API key from /api/me/tokens sent as a bearer token.",
+			"version":     "1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": map[string]interface{}(s)},
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// specJSON is the document for apiOperations, encoded once.
+var specJSON = struct {
+	once sync.Once
+	data []byte
+}{}
+
+// openAPISpec is the document for apiOperations as indented JSON.
+func openAPISpec() []byte {
+	specJSON.once.Do(func() {
+		data, err := json.MarshalIndent(document(apiOperations), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		specJSON.data = append(data, '\n')
+	})
+	return specJSON.data
+}
+
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(openAPISpec())
+}
+
+// swaggerUI loads Swagger UI pointed at /openapi.json.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Wichita Hacktoberfest API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+func apiDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
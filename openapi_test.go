@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIFile(t *testing.T) {
+	// The client is generated from this copy, so it has to keep up
+	const path = "client/openapi.json"
+	if os.Getenv("UPDATE_OPENAPI") != "" {
+		if err := ioutil.WriteFile(path, openAPISpec(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, openAPISpec()) {
+		t.Errorf("%s is out of date, update it with UPDATE_OPENAPI=1 go test -run TestOpenAPIFile then go generate ./client", path)
+	}
+}
+
+func TestIssueListMatchesEnvelope(t *testing.T) {
+	fields := func(v interface{}) []string {
+		o := schemas{}.object(reflect.TypeOf(v))
+		var names []string
+		for name := range o["properties"].(map[string]interface{}) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got, want := fields(issueList{}), fields(apiEnvelope{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("issueList has %q, apiEnvelope has %q", got, want)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := schemas{}
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{"", `{"type":"string"}`},
+		{[]int{}, `{"items":{"type":"integer"},"type":"array"}`},
+		{map[string]string{}, `{"additionalProperties":{"type":"string"},"type":"object"}`},
+		{Repo{}, `{"$ref":"#/components/schemas/Repo"}`},
+		{groupedIssue{}, ``},
+	}
+
+	for _, test := range tests {
+		data, err := json.Marshal(s.of(reflect.TypeOf(test.v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.want != "" && string(data) != test.want {
+			t.Errorf("%T: got %s, want %s", test.v, data, test.want)
+		}
+	}
+
+	// Fields are promoted from embedded structs, unless they're hidden, and
+	// only ones without omitempty are required
+	o := s.object(reflect.TypeOf(groupedIssue{}))
+	props := o["properties"].(map[string]interface{})
+	if _, ok := props["title"]; !ok {
+		t.Errorf("title should be promoted from Issue, got %v", props)
+	}
+	if got := props["repo"]; !reflect.DeepEqual(got, map[string]interface{}{"$ref": "#/components/schemas/Repo"}) {
+		t.Errorf("repo should be the groupedIssue's own, got %v", got)
+	}
+	for _, name := range o["required"].([]string) {
+		if name == "repo" || name == "languages" {
+			t.Errorf("%s has omitempty so shouldn't be required", name)
+		}
+	}
+
+	if _, ok := s["Repo"]; !ok {
+		t.Errorf("Repo should have a schema of its own, got %v", s)
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	w := httptest.NewRecorder()
+	serveSpec(w, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("got openapi %q, want 3.0.3", doc.OpenAPI)
+	}
+	for _, op := range apiOperations {
+		if _, ok := doc.Paths[op.path][strings.ToLower(op.method)]; !ok {
+			t.Errorf("%s %s is missing", op.method, op.path)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// issueOrder is how to sort a list of issues. The zero value leaves them in
+// the order they were found.
+type issueOrder struct {
+	by   string // created, updated, comments, reactions, activity, stars, score or repo
+	desc bool
+}
+
+// parseOrder builds an issueOrder from query parameters:
+//
+//	sort  created, updated, comments, reactions, activity, stars, score or repo
+//	order asc or desc. Defaults to desc, except for repo which is asc.
+//
+// activity is when the issue was last commented on, or opened if nobody has
+// commented yet, so issues people are talking about come before ones that
+// have been left alone.
+func parseOrder(r *http.Request) (issueOrder, error) {
+	q := r.URL.Query()
+	o := issueOrder{by: q.Get("sort")}
+
+	switch o.by {
+	case "":
+		if q.Get("order") != "" {
+			return issueOrder{}, fmt.Errorf("order needs a sort to go with it")
+		}
+		return o, nil
+	case "created", "updated", "comments", "reactions", "activity", "stars", "score":
+		o.desc = true
+	case "repo":
+	default:
+		return issueOrder{}, fmt.Errorf("sort %q should be created, updated, comments, reactions, activity, stars, score or repo", o.by)
+	}
+
+	switch order := q.Get("order"); order {
+	case "":
+	case "asc":
+		o.desc = false
+	case "desc":
+		o.desc = true
+	default:
+		return issueOrder{}, fmt.Errorf("order %q should be asc or desc", order)
+	}
+
+	return o, nil
+}
+
+// sort puts issues in order. Ties are broken by URL so the same issues always
+// come out the same way, whichever search found them first.
+func (o issueOrder) sort(issues []Issue) {
+	if o.by == "" {
+		return
+	}
+
+	now := time.Now()
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if o.desc {
+			a, b = b, a
+		}
+
+		switch o.by {
+		case "created":
+			if !a.Date.Equal(b.Date) {
+				return a.Date.Before(b.Date)
+			}
+		case "updated":
+			if !a.Updated.Equal(b.Updated) {
+				return a.Updated.Before(b.Updated)
+			}
+		case "comments":
+			if a.Comments != b.Comments {
+				return a.Comments < b.Comments
+			}
+		case "reactions":
+			if a.Reactions != b.Reactions {
+				return a.Reactions < b.Reactions
+			}
+		case "activity":
+			if at, bt := lastActive(a), lastActive(b); !at.Equal(bt) {
+				return at.Before(bt)
+			}
+		case "stars":
+			if a.Repo.Stars != b.Repo.Stars {
+				return a.Repo.Stars < b.Repo.Stars
+			}
+		case "score":
+			if as, bs := score(a, now), score(b, now); as != bs {
+				return as < bs
+			}
+		case "repo":
+			ar := strings.ToLower(a.Repo.Owner + "/" + a.Repo.Name)
+			br := strings.ToLower(b.Repo.Owner + "/" + b.Repo.Name)
+			if ar != br {
+				return ar < br
+			}
+			if a.Number != b.Number {
+				return a.Number < b.Number
+			}
+		}
+
+		return a.URL < b.URL
+	})
+}
+
+// lastActive is when issue last had a comment, or when it was opened if it
+// hasn't had any we know of.
+func lastActive(issue Issue) time.Time {
+	if issue.LastComment != nil {
+		return *issue.LastComment
+	}
+	return issue.Date
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIssueOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 10, d, 0, 0, 0, 0, time.UTC) }
+	commented := day(8)
+	data := []Issue{
+		{URL: "a", Date: day(3), Updated: day(5), Comments: 1, Reactions: 2, Repo: Repo{Owner: "devict", Name: "site", Stars: 4}, Number: 2},
+		{URL: "b", Date: day(1), Updated: day(9), Comments: 7, LastComment: &commented, Repo: Repo{Owner: "MakeICT", Name: "hub", Stars: 9}, Number: 1},
+		{URL: "c", Date: day(2), Updated: day(2), Comments: 1, Reactions: 5, Repo: Repo{Owner: "devict", Name: "site", Stars: 4}, Number: 1},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"sort=created", []string{"a", "c", "b"}},
+		{"sort=created&order=asc", []string{"b", "c", "a"}},
+		{"sort=updated", []string{"b", "a", "c"}},
+		{"sort=comments", []string{"b", "c", "a"}},
+		{"sort=reactions", []string{"c", "a", "b"}},
+		{"sort=activity", []string{"b", "a", "c"}},
+		{"sort=activity&order=asc", []string{"c", "a", "b"}},
+		{"sort=stars", []string{"b", "c", "a"}},
+		{"sort=score", []string{"c", "a", "b"}},
+		{"sort=repo", []string{"c", "a", "b"}},
+		{"sort=repo&order=desc", []string{"b", "a", "c"}},
+	}
+
+	for _, test := range tests {
+		o, err := parseOrder(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		issues := append([]Issue(nil), data...)
+		o.sort(issues)
+
+		got := []string{}
+		for _, i := range issues {
+			got = append(got, i.URL)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseOrderInvalid(t *testing.T) {
+	for _, q := range []string{"sort=votes", "sort=created&order=up", "order=asc"} {
+		if _, err := parseOrder(httptest.NewRequest("GET", "/api/issues?"+q, nil)); err == nil {
+			t.Errorf("%q: error should not be nil, but it was", q)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultPerPage is how many issues are on a page when a page is asked for
+	// without saying how big
+	defaultPerPage = 50
+
+	// maxPerPage is the biggest page we'll give out
+	maxPerPage = 100
+)
+
+// issuePage is one page of an issue listing. Paging is only done when the
+// request asks for it, otherwise the whole list is sent as before.
+type issuePage struct {
+	paged   bool
+	page    int
+	perPage int
+}
+
+// parsePage builds an issuePage from the page and per_page query parameters.
+// Pages start at 1.
+func parsePage(r *http.Request) (issuePage, error) {
+	q := r.URL.Query()
+	pg := issuePage{page: 1, perPage: defaultPerPage}
+
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return issuePage{}, fmt.Errorf("page %q should be a number from 1 up", v)
+		}
+		pg.page = n
+		pg.paged = true
+	}
+
+	if v := q.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			return issuePage{}, fmt.Errorf("per_page %q should be a number from 1 to %d", v, maxPerPage)
+		}
+		pg.perPage = n
+		pg.paged = true
+	}
+
+	return pg, nil
+}
+
+// pageEnvelope is what a paged listing looks like. Total and Pages count
+// every issue that passed the filters, not just the ones on this page.
+type pageEnvelope struct {
+	Issues  []Issue `json:"issues"`
+	Page    int     `json:"page"`
+	PerPage int     `json:"per_page"`
+	Total   int     `json:"total"`
+	Pages   int     `json:"pages"`
+
+	// Warnings are the searches that failed, so issues could be missing
+	Warnings []searchWarning `json:"warnings"`
+}
+
+// envelope cuts this page out of issues. Asking for a page past the end gives
+// an empty one.
+func (pg issuePage) envelope(issues []Issue) pageEnvelope {
+	start := (pg.page - 1) * pg.perPage
+	if start > len(issues) {
+		start = len(issues)
+	}
+	end := start + pg.perPage
+	if end > len(issues) {
+		end = len(issues)
+	}
+
+	return pageEnvelope{
+		Issues:  issues[start:end],
+		Page:    pg.page,
+		PerPage: pg.perPage,
+		Total:   len(issues),
+		Pages:   (len(issues) + pg.perPage - 1) / pg.perPage,
+	}
+}
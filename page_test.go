@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIssuePage(t *testing.T) {
+	var data []Issue
+	for i := 1; i <= 5; i++ {
+		data = append(data, Issue{Number: i})
+	}
+
+	tests := []struct {
+		query string
+		paged bool
+		first int
+		count int
+		pages int
+	}{
+		{"", false, 1, 5, 1},
+		{"per_page=2", true, 1, 2, 3},
+		{"page=3&per_page=2", true, 5, 1, 3},
+		{"page=4&per_page=2", true, 0, 0, 3},
+	}
+
+	for _, test := range tests {
+		pg, err := parsePage(httptest.NewRequest("GET", "/api/issues?"+test.query, nil))
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+		if pg.paged != test.paged {
+			t.Errorf("%q: got paged %v, want %v", test.query, pg.paged, test.paged)
+		}
+
+		e := pg.envelope(data)
+		if len(e.Issues) != test.count {
+			t.Errorf("%q: got %d issues, want %d", test.query, len(e.Issues), test.count)
+		}
+		if test.count > 0 && e.Issues[0].Number != test.first {
+			t.Errorf("%q: page starts with %d, want %d", test.query, e.Issues[0].Number, test.first)
+		}
+		if e.Total != 5 || e.Pages != test.pages {
+			t.Errorf("%q: got total %d pages %d, want 5 and %d", test.query, e.Total, e.Pages, test.pages)
+		}
+	}
+
+	for _, q := range []string{"page=0", "page=x", "per_page=101", "per_page=0"} {
+		if _, err := parsePage(httptest.NewRequest("GET", "/api/issues?"+q, nil)); err == nil {
+			t.Errorf("%q: error should not be nil, but it was", q)
+		}
+	}
+}
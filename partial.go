@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// failedSearch is a search for one label in one source that didn't finish.
+type failedSearch = aggregator.SearchError
+
+// incompleteError is what streamIssues gives back when some searches failed
+// but the issues from the rest were all passed along.
+type incompleteError = aggregator.IncompleteError
+
+// incomplete is err if it's an *incompleteError, meaning there are still
+// issues to show, or nil if it's any other kind of error or none at all.
+func incomplete(err error) *incompleteError {
+	e, _ := err.(*incompleteError)
+	return e
+}
+
+// searchWarning is how a failed search is described to clients.
+type searchWarning = aggregator.Warning
+
+// setWarnings adds a Warning header for each search that failed, for
+// responses that have nowhere else to say so.
+func setWarnings(w http.ResponseWriter, e *incompleteError) {
+	for _, sw := range e.Warnings() {
+		w.Header().Add("Warning", `199 hacktoberfest `+strconv.Quote(fmt.Sprintf("could not search %s for %q", sw.Source, sw.Label)))
+	}
+}
+
+// strictParam reports whether the request asked with ?strict=true to fail
+// outright if any search did, rather than get what the others found.
+func strictParam(r *http.Request) bool {
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+	return strict
+}
+
+// sourceName is how we refer to src in logs and warnings.
+func sourceName(src IssueSource) string {
+	switch src.(type) {
+	case *Client:
+		return "github"
+	case *GitLab:
+		return "gitlab"
+	case *Gitea:
+		return "gitea"
+	}
+	return fmt.Sprintf("%T", src)
+}
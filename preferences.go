@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// People who are logged in can save the languages and labels they're after,
+// and orgs they'd rather not see. Their listings then go by them unless the
+// query says otherwise, and only their labels are searched for. Preferences
+// are a row each in preferences, as JSON.
+
+// preferences are what someone wants to see. Empty lists mean no preference.
+type preferences struct {
+	Languages    []string `json:"languages"`
+	Labels       []string `json:"labels"`
+	ExcludedOrgs []string `json:"excluded_orgs"`
+}
+
+// check tidies up p and makes sure it's something we can search for.
+func (p *preferences) check() error {
+	p.Languages = sortedKeys(set(p.Languages))
+	p.Labels = sortedKeys(set(p.Labels))
+	p.ExcludedOrgs = sortedKeys(set(p.ExcludedOrgs))
+
+	if len(p.Labels) > maxLabels {
+		return fmt.Errorf("at most %d labels can be searched at once", maxLabels)
+	}
+	for _, l := range p.Labels {
+		if strings.ContainsAny(l, `",`) {
+			return fmt.Errorf("label %q can't have quotes or commas in it", l)
+		}
+	}
+	for _, l := range p.Languages {
+		if strings.Contains(l, ",") {
+			return fmt.Errorf("language %q can't have commas in it", l)
+		}
+	}
+	for _, o := range p.ExcludedOrgs {
+		if strings.ContainsAny(o, "/,") {
+			return fmt.Errorf("excluded org %q should be just an owner", o)
+		}
+	}
+	return nil
+}
+
+// loadPreferences gives user's preferences, which are empty if they haven't
+// saved any.
+func loadPreferences(user string) (preferences, error) {
+	var data []byte
+	err := db.QueryRow("SELECT data FROM preferences WHERE user_id = $1", user).Scan(&data)
+	if err == sql.ErrNoRows {
+		return preferences{Languages: []string{}, Labels: []string{}, ExcludedOrgs: []string{}}, nil
+	}
+	if err != nil {
+		return preferences{}, errors.Wrap(err, "could not query preferences")
+	}
+
+	var p preferences
+	if err := json.Unmarshal(data, &p); err != nil {
+		return preferences{}, errors.Wrap(err, "could not decode preferences")
+	}
+	return p, nil
+}
+
+// withPreferences gives a copy of r with p filled in for whichever of the
+// lang, labels and exclude_repo query parameters it doesn't have.
+func withPreferences(r *http.Request, p preferences) *http.Request {
+	q := r.URL.Query()
+	changed := false
+	fill := func(name string, values []string) {
+		if _, ok := q[name]; !ok && len(values) > 0 {
+			q.Set(name, strings.Join(values, ","))
+			changed = true
+		}
+	}
+	fill("lang", p.Languages)
+	fill("labels", p.Labels)
+	fill("exclude_repo", p.ExcludedOrgs)
+	if !changed {
+		return r
+	}
+
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	r2 := *r
+	r2.URL = &u
+	return &r2
+}
+
+// preferred is r with the preferences of whoever's asking filled in, see
+// withPreferences. If we can't load them we carry on without.
+func preferred(r *http.Request) *http.Request {
+	u, _, ok := findUser(r)
+	if !ok || db == nil {
+		return r
+	}
+
+	p, err := loadPreferences(u.UserID)
+	if err != nil {
+		logError(r.Context(), err)
+		return r
+	}
+	return withPreferences(r, p)
+}
+
+// getPreferences gives the preferences of whoever is logged in.
+func getPreferences(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	p, err := loadPreferences(u.UserID)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// updatePreferences replaces the preferences of whoever is logged in.
+func updatePreferences(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+
+	var p preferences
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		invalidRequest(w, r, "invalid request")
+		return
+	}
+	if err := p.check(); err != nil {
+		invalidRequest(w, r, err.Error())
+		return
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO preferences (user_id, data, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET data = $2, updated_at = $3`,
+		u.UserID,
+		data,
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
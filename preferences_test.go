@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithPreferences(t *testing.T) {
+	p := preferences{
+		Languages:    []string{"Go", "Python"},
+		Labels:       []string{"good first issue"},
+		ExcludedOrgs: []string{"spammer"},
+	}
+
+	tests := []struct {
+		query string
+		want  map[string]string
+	}{
+		{"", map[string]string{"lang": "Go,Python", "labels": "good first issue", "exclude_repo": "spammer"}},
+		{"lang=Rust&sort=newest", map[string]string{"lang": "Rust", "labels": "good first issue", "exclude_repo": "spammer", "sort": "newest"}},
+		{"lang=&labels=hacktoberfest", map[string]string{"lang": "", "labels": "hacktoberfest", "exclude_repo": "spammer"}},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/api/issues?"+test.query, nil)
+		got := withPreferences(r, p).URL.Query()
+		for name, want := range test.want {
+			if got.Get(name) != want {
+				t.Errorf("%q: got %s %q, want %q", test.query, name, got.Get(name), want)
+			}
+		}
+		if r.URL.RawQuery != test.query {
+			t.Errorf("%q: original changed to %q", test.query, r.URL.RawQuery)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/api/issues?lang=Go", nil)
+	if withPreferences(r, preferences{}) != r {
+		t.Errorf("no preferences: got a copy, want the same request")
+	}
+}
+
+func TestPreferencesCheck(t *testing.T) {
+	p := preferences{Languages: []string{" Go", "Go", ""}, Labels: []string{"help wanted"}}
+	if err := p.check(); err != nil {
+		t.Fatal(err)
+	}
+	want := preferences{Languages: []string{"Go"}, Labels: []string{"help wanted"}, ExcludedOrgs: []string{}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+
+	invalid := []preferences{
+		{Labels: []string{`bad"label`}},
+		{Languages: []string{"Go,Rust"}},
+		{ExcludedOrgs: []string{"devict/hacktoberfest"}},
+		{Labels: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}},
+	}
+	for _, p := range invalid {
+		if err := p.check(); err == nil {
+			t.Errorf("%+v: got no error", p)
+		}
+	}
+}
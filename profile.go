@@ -1,35 +1,48 @@
 package main
 
 import (
-	"log"
 	"net/http"
+	"sort"
 
 	"github.com/markbates/goth"
 )
 
 func profile(w http.ResponseWriter, r *http.Request) {
-	u, n, ok := findUser(r)
+	u, n, tokens, ok := sessionUser(r)
 	if !ok {
 		http.Redirect(w, r, "/auth/github", http.StatusTemporaryRedirect)
 		return
 	}
 
-	s, _ := sess.Get(r, "session")
+	s, _ := sess.Get(r, sessionName)
 	s.Values["new"] = false
 	if err := s.Save(r, w); err != nil {
-		log.Println(err)
+		logError(r.Context(), err)
 	}
 
+	// Providers they could connect, so they can search there as themselves
+	type provider struct{ Name, Title string }
+	var connect []provider
+	for _, p := range loginProviders {
+		if tokens[p.name] == "" {
+			connect = append(connect, provider{p.name, p.title})
+		}
+	}
+	sort.Slice(connect, func(i, j int) bool { return connect[i].Name < connect[j].Name })
+
+	t := tracking()
 	info := struct {
 		Orgs     map[string]bool
 		Projects map[string]bool
 		User     goth.User
 		New      bool
+		Connect  []provider
 	}{
-		Orgs:     orgs,
-		Projects: projects,
+		Orgs:     t.Orgs,
+		Projects: t.Projects,
 		User:     u,
 		New:      n,
+		Connect:  connect,
 	}
 
 	v.HTML(w, http.StatusOK, "profile", info)
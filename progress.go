@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// progressGoal is how many accepted pull requests finish Hacktoberfest.
+const progressGoal = 4
+
+// acceptedLabel is what maintainers put on pull requests they accept for
+// Hacktoberfest without merging, or in repos that aren't taking part.
+const acceptedLabel = "hacktoberfest-accepted"
+
+// Progress is how far someone has got with Hacktoberfest this year.
+type Progress struct {
+	Year     int
+	Goal     int
+	Accepted int
+	PRs      []ProgressPR
+}
+
+// ProgressPR is one of someone's pull requests made in October. It counts if
+// it's in a repo taking part, or labelled accepted, and is Accepted once it's
+// merged or labelled accepted. Ones labelled spam or invalid never are.
+type ProgressPR struct {
+	Title string
+	URL   string
+	Date  time.Time
+	Repo  Repo
+
+	Merged        bool
+	Participating bool
+	Accepted      bool
+}
+
+// restPR is a pull request the way the REST search API describes it.
+type restPR struct {
+	Title       string    `json:"title"`
+	CreatedAt   time.Time `json:"created_at"`
+	HTMLURL     string    `json:"html_url"`
+	RepoURL     string    `json:"repository_url"`
+	Labels      `json:"labels"`
+	PullRequest struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// octoberQuery searches for username's pull requests made in October of
+// year, anywhere on Earth.
+func octoberQuery(username string, year int) string {
+	return fmt.Sprintf("author:%s type:pr created:%d-10-01T00:00:00+14:00..%d-10-31T23:59:59-12:00", username, year, year)
+}
+
+// octoberPRs finds the pull requests username made in October of year.
+func (c *Client) octoberPRs(ctx context.Context, username string, year int) ([]restPR, error) {
+	vals := url.Values{}
+	vals.Add("q", octoberQuery(username, year))
+	vals.Add("sort", "created")
+	vals.Add("order", "asc")
+	vals.Add("per_page", "100")
+
+	prs := []restPR{}
+	next := c.BaseURL + "/search/issues?" + vals.Encode()
+	for page := 1; next != "" && (c.MaxPages == 0 || page <= c.MaxPages); page++ {
+		var data struct {
+			Items []restPR `json:"items"`
+		}
+
+		var err error
+		if next, err = c.GetURL(ctx, next, &data); err != nil {
+			return nil, err
+		}
+		prs = append(prs, data.Items...)
+	}
+	return prs, nil
+}
+
+// progressOf works out how far username has got with their pull requests in
+// October of year, looking up whether the repos they're in are taking part.
+func (c *Client) progressOf(ctx context.Context, username string, year int) (Progress, error) {
+	found, err := c.octoberPRs(ctx, username, year)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	prs := make([]ProgressPR, len(found))
+	var unique []Repo
+	seen := make(map[string]bool)
+	for i, item := range found {
+		repo, err := aggregator.RepoFromURL(item.RepoURL)
+		if err != nil {
+			return Progress{}, errors.Wrapf(err, "could not identify repo from %s", item.RepoURL)
+		}
+		prs[i] = ProgressPR{Title: item.Title, URL: item.HTMLURL, Date: item.CreatedAt, Repo: repo}
+		if !seen[repo.ID()] {
+			seen[repo.ID()] = true
+			unique = append(unique, repo)
+		}
+	}
+
+	details, err := c.RepoDetails(ctx, unique)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	p := Progress{Year: year, Goal: progressGoal, PRs: prs}
+	for i, item := range found {
+		if d, ok := details[prs[i].Repo.ID()]; ok {
+			prs[i].Repo = d.Details
+		}
+		prs[i].Merged = item.PullRequest.MergedAt != nil
+		prs[i].Accepted, prs[i].Participating = accepted(prs[i].Repo, item.Labels, prs[i].Merged)
+		if prs[i].Accepted {
+			p.Accepted++
+		}
+	}
+	return p, nil
+}
+
+// accepted reports whether a pull request in repo with labels counts towards
+// Hacktoberfest, and whether it would once accepted.
+func accepted(repo Repo, labels Labels, merged bool) (ok, participating bool) {
+	labelled := false
+	for _, l := range labels {
+		switch strings.ToLower(l.Name) {
+		case acceptedLabel:
+			labelled = true
+		case "spam", "invalid":
+			return false, false
+		}
+	}
+
+	participating = labelled || repo.Participating()
+	return participating && (merged || labelled), participating
+}
+
+// getProgress shows the logged in user how far they've got with Hacktoberfest
+// this year, searching with their own token.
+func getProgress(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	if u.AccessToken == "" {
+		noGitHub(w, r)
+		return
+	}
+
+	c := newClient(u.AccessToken)
+	p, err := c.progressOf(r.Context(), u.NickName, time.Now().Year())
+	if err != nil {
+		upstreamError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logError(r.Context(), err)
+	}
+}
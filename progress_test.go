@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccepted(t *testing.T) {
+	participating := Repo{Owner: "devict", Name: "hacktoberfest", Topics: []string{"Hacktoberfest"}}
+	other := Repo{Owner: "devict", Name: "other"}
+	label := func(names ...string) Labels {
+		var lbs Labels
+		for _, n := range names {
+			lbs = append(lbs, struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			}{Name: n})
+		}
+		return lbs
+	}
+
+	tests := []struct {
+		name          string
+		repo          Repo
+		labels        Labels
+		merged        bool
+		ok            bool
+		participating bool
+	}{
+		{"merged in a participating repo", participating, nil, true, true, true},
+		{"open in a participating repo", participating, nil, false, false, true},
+		{"merged elsewhere", other, nil, true, false, false},
+		{"labelled elsewhere", other, label("hacktoberfest-accepted"), false, true, true},
+		{"marked spam", participating, label("spam"), true, false, false},
+		{"marked invalid", other, label("Invalid", "hacktoberfest-accepted"), true, false, false},
+	}
+
+	for _, test := range tests {
+		ok, participating := accepted(test.repo, test.labels, test.merged)
+		if ok != test.ok || participating != test.participating {
+			t.Errorf("%q: got %v, %v, want %v, %v", test.name, ok, participating, test.ok, test.participating)
+		}
+	}
+}
+
+func TestProgressOf(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/issues":
+			if q := r.URL.Query().Get("q"); q != octoberQuery("mattstratton", 2026) {
+				t.Errorf("got query %q", q)
+			}
+			w.Write([]byte(`{"items": [
+				{"title": "Add tests", "html_url": "https://github.com/devict/hacktoberfest/pull/1", "repository_url": "https://api.github.com/repos/devict/hacktoberfest", "pull_request": {"merged_at": "2026-10-03T00:00:00Z"}},
+				{"title": "Fix typo", "html_url": "https://github.com/devict/hacktoberfest/pull/2", "repository_url": "https://api.github.com/repos/devict/hacktoberfest", "pull_request": {"merged_at": null}},
+				{"title": "Docs", "html_url": "https://github.com/someone/else/pull/3", "repository_url": "https://api.github.com/repos/someone/else", "pull_request": {"merged_at": "2026-10-04T00:00:00Z"}}
+			]}`))
+		case "/graphql":
+			w.Write([]byte(`{"data": {
+				"r0": {"stargazerCount": 12, "repositoryTopics": {"nodes": [{"topic": {"name": "hacktoberfest"}}]}, "languages": {"edges": []}},
+				"r1": {"stargazerCount": 1, "repositoryTopics": {"nodes": []}, "languages": {"edges": []}}
+			}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := testClient(srv)
+	c.Token = "abc123"
+
+	p, err := c.progressOf(context.Background(), "mattstratton", 2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Goal != 4 || p.Accepted != 1 || len(p.PRs) != 3 {
+		t.Fatalf("got %d/%d accepted of %d PRs, want 1/4 of 3", p.Accepted, p.Goal, len(p.PRs))
+	}
+
+	want := []struct{ merged, participating, accepted bool }{
+		{true, true, true},
+		{false, true, false},
+		{true, false, false},
+	}
+	for i, w := range want {
+		pr := p.PRs[i]
+		if pr.Merged != w.merged || pr.Participating != w.participating || pr.Accepted != w.accepted {
+			t.Errorf("%q: got merged %v, participating %v, accepted %v, want %v, %v, %v", pr.Title, pr.Merged, pr.Participating, pr.Accepted, w.merged, w.participating, w.accepted)
+		}
+	}
+	if p.PRs[0].Repo.Stars != 12 || !strings.HasSuffix(p.PRs[0].URL, "/pull/1") {
+		t.Errorf("got %+v, want repo details and link", p.PRs[0])
+	}
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
+	"github.com/devict/hacktoberfest/aggregator"
 	"github.com/pkg/errors"
 )
 
@@ -15,33 +15,38 @@ import (
 type PR struct {
 	Title string
 	Date  time.Time
-	URL   string
+
+	// URL is the API URL of the pull request. HTMLURL is the one to send people
+	// to.
+	URL     string
+	HTMLURL string
+
 	Repo  Repo
 	Valid bool
 }
 
 func prs(w http.ResponseWriter, r *http.Request) {
-	u, _, ok := findUser(r)
-	if !ok {
-		http.Error(w, "you are not logged in", http.StatusUnauthorized)
+	u := currentUser(r)
+	if u.AccessToken == "" {
+		noGitHub(w, r)
 		return
 	}
 
 	prs, err := fetchPRs(u.NickName, u.AccessToken)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		upstreamError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(prs); err != nil {
-		log.Println(err)
+		logError(r.Context(), err)
 	}
 }
 
 func fetchPRs(username, token string) ([]PR, error) {
-	req, err := http.NewRequest("GET", "https://api.github.com/search/issues", nil)
+	api, _ := githubAPI(config.GitHubURL)
+	req, err := http.NewRequest("GET", api+"/search/issues", nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not build request")
 	}
@@ -59,7 +64,7 @@ func fetchPRs(username, token string) ([]PR, error) {
 		req.Header.Add("Authorization", "token "+token)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := upstream.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not execute request")
 	}
@@ -74,6 +79,7 @@ func fetchPRs(username, token string) ([]PR, error) {
 			Title     string    `json:"title"`
 			CreatedAt time.Time `json:"created_at"`
 			URL       string    `json:"url"`
+			HTMLURL   string    `json:"html_url"`
 			RepoURL   string    `json:"repository_url"`
 		} `json:"items"`
 	}
@@ -84,12 +90,13 @@ func fetchPRs(username, token string) ([]PR, error) {
 	prs := []PR{}
 	for _, item := range data.Items {
 		pr := PR{
-			Title: item.Title,
-			Date:  item.CreatedAt,
-			URL:   item.URL,
+			Title:   item.Title,
+			Date:    item.CreatedAt,
+			URL:     item.URL,
+			HTMLURL: item.HTMLURL,
 		}
 
-		pr.Repo, err = repoFromURL(item.RepoURL)
+		pr.Repo, err = aggregator.RepoFromURL(item.RepoURL)
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not identify repo from %s", item.RepoURL)
 		}
@@ -103,7 +110,7 @@ func fetchPRs(username, token string) ([]PR, error) {
 	}
 
 	for i, pr := range prs {
-		prs[i].Valid = orgs[pr.Repo.Owner] || projects[pr.Repo.Owner+"/"+pr.Repo.Name]
+		prs[i].Valid = tracked(pr.Repo)
 	}
 
 	return prs, nil
@@ -21,8 +21,9 @@ function loadIssues() {
   btn.append(" <i class='fa fa-spin fa-spinner'></i>");
   btn.addClass("disabled");
 
-  $.ajax({type: 'GET', url: '/api/issues'})
-    .then(function(data) {
+  $.ajax({type: 'GET', url: '/api/v1/issues?sort=score'})
+    .then(function(resp) {
+      var data = resp && resp.data;
       if (!data || !data.length) {
         return;
       }
@@ -30,17 +31,32 @@ function loadIssues() {
       var rows = '';
       data.forEach(function(issue) {
         var tags = "";
-        for (var tag in issue["Labels"]) {
-          tags += "<span class='badge badge-default is-issue-tag' style='background-color: #" + issue["Labels"][tag] + ";'>" + tag + "</span>";
+        for (var tag in issue["labels"]) {
+          tags += "<span class='badge badge-default is-issue-tag' style='background-color: #" + issue["labels"][tag] + ";'>" + tag + "</span>";
         }
         if (tags != "") {
           tags = "</br>" + tags;
         }
 
+        var repo = issue["repo"];
+        var about = "";
+        if (repo["stars"]) {
+          about += " <span class='text-muted'><i class='fa fa-star'></i> " + repo["stars"] + "</span>";
+        }
+        if (issue["participating"]) {
+          about += " <span class='badge badge-success'>participating</span>";
+        }
+        if (repo["archived"]) {
+          about += " <span class='badge badge-secondary'>archived</span>";
+        }
+        if (repo["description"]) {
+          about += "</br><small>" + $('<div>').text(repo["description"]).html() + "</small>";
+        }
+
         rows += "<tr>" +
-          "<td> <a href='" + issue["URL"] + "'>" + issue["Title"] + "</a>" + tags + "</td>" +
-          "<td>" + issue["Repo"]["Owner"] + "/" + issue["Repo"]["Name"] + "</td>" +
-          "<td>" + issue["Languages"].join(", ") + "</td>" +
+          "<td> <a href='" + issue["url"] + "'>" + issue["title"] + "</a>" + tags + "</td>" +
+          "<td>" + repo["owner"] + "/" + repo["name"] + about + "</td>" +
+          "<td>" + issue["languages"].join(", ") + "</td>" +
           "</tr>";
       })
       $("#issues").append(rows);
@@ -70,7 +86,7 @@ function checkPRs() {
 
         t.find('.title').text(p.Title);
         t.find('.date').text(Date(p.Date));
-        t.find('.repo').text(p.Repo.Owner + '/' + p.Repo.Name);
+        t.find('.repo').text(p.Repo.owner + '/' + p.Repo.name);
 
         results.prepend(t);
       });
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPerRepo is the most issues from one repo a listing of more than
+// one gives, unless MAX_PER_REPO says otherwise. A repo with hundreds of
+// labelled issues would otherwise fill the list by itself.
+const defaultMaxPerRepo = 25
+
+// issueQuota limits how many issues a listing has from any one repo, or any
+// one owner. Zero is no limit.
+type issueQuota struct {
+	perRepo int
+	perOrg  int
+}
+
+// parseQuota builds an issueQuota from the max_per_repo and max_per_org query
+// parameters. Listings searching more than one repo are limited to
+// config.MaxPerRepo from each unless max_per_repo says otherwise, and 0 is
+// no limit.
+func parseQuota(r *http.Request, p searchParams) (issueQuota, error) {
+	var q issueQuota
+	if !singleRepo(p.scope) {
+		q.perRepo = config.MaxPerRepo
+	}
+
+	for name, n := range map[string]*int{"max_per_repo": &q.perRepo, "max_per_org": &q.perOrg} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		max, err := strconv.Atoi(v)
+		if err != nil || max < 0 {
+			return issueQuota{}, fmt.Errorf("%s %q should be a number from 0 up", name, v)
+		}
+		*n = max
+	}
+	return q, nil
+}
+
+// singleRepo reports whether scope only covers one repo.
+func singleRepo(scope string) bool {
+	quals := strings.Fields(scope)
+	return len(quals) == 1 && strings.HasPrefix(quals[0], "repo:")
+}
+
+// keeper gives a func reporting whether each issue it's given in turn is
+// within the quota, going by the ones before it.
+func (q issueQuota) keeper() func(Issue) bool {
+	repos := make(map[string]int)
+	orgs := make(map[string]int)
+	return func(i Issue) bool {
+		repo, org := strings.ToLower(i.Repo.ID()), strings.ToLower(i.Repo.Owner)
+		if (q.perRepo > 0 && repos[repo] >= q.perRepo) || (q.perOrg > 0 && orgs[org] >= q.perOrg) {
+			return false
+		}
+		repos[repo]++
+		orgs[org]++
+		return true
+	}
+}
+
+// apply gives the issues within the quota, the first ones from each repo and
+// owner.
+func (q issueQuota) apply(in []Issue) []Issue {
+	if q.perRepo == 0 && q.perOrg == 0 {
+		return in
+	}
+	keep := q.keeper()
+	out := []Issue{}
+	for _, i := range in {
+		if keep(i) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	defer func(n int) { config.MaxPerRepo = n }(config.MaxPerRepo)
+	config.MaxPerRepo = 2
+
+	data := []Issue{
+		{Title: "a", Repo: Repo{Owner: "devict", Name: "busy"}},
+		{Title: "b", Repo: Repo{Owner: "devict", Name: "Busy"}},
+		{Title: "c", Repo: Repo{Owner: "devict", Name: "busy"}},
+		{Title: "d", Repo: Repo{Owner: "devict", Name: "quiet"}},
+		{Title: "e", Repo: Repo{Owner: "makeict", Name: "site"}},
+		{Title: "f", Repo: Repo{Owner: "devict", Name: "busy"}},
+	}
+
+	tests := []struct {
+		scope, query string
+		want         []string
+	}{
+		{"org:devict org:makeict", "", []string{"a", "b", "d", "e"}},
+		{"org:devict org:makeict", "max_per_repo=1", []string{"a", "d", "e"}},
+		{"org:devict org:makeict", "max_per_repo=0", []string{"a", "b", "c", "d", "e", "f"}},
+		{"org:devict org:makeict", "max_per_repo=0&max_per_org=2", []string{"a", "b", "e"}},
+		{"org:devict org:makeict", "max_per_org=3", []string{"a", "b", "d", "e"}},
+		{"repo:devict/busy", "", []string{"a", "b", "c", "d", "e", "f"}},
+		{"repo:devict/busy", "max_per_repo=1", []string{"a", "d", "e"}},
+	}
+
+	for _, test := range tests {
+		q, err := parseQuota(httptest.NewRequest("GET", "/api/issues?"+test.query, nil), searchParams{scope: test.scope})
+		if err != nil {
+			t.Errorf("%q: error should be nil, got %v", test.query, err)
+			continue
+		}
+
+		got := []string{}
+		for _, i := range q.apply(data) {
+			got = append(got, i.Title)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: got %v, want %v", test.scope, test.query, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"max_per_repo=-1", "max_per_org=some"} {
+		if _, err := parseQuota(httptest.NewRequest("GET", "/api/issues?"+bad, nil), searchParams{}); err == nil {
+			t.Errorf("%q: error should not be nil, but it was", bad)
+		}
+	}
+}
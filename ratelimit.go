@@ -0,0 +1,145 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Issue searches are the expensive thing we do, so each client gets a token
+// bucket of them: IssueBurst at once, refilling at IssueRate a minute. People
+// who are logged in are counted by session and everyone else by address, so
+// one aggressive client can't use up GitHub's rate limit or our CPU for the
+// rest.
+
+// issueLimiter limits the issue routes, or is nil if they aren't limited.
+// setConfig makes it.
+var issueLimiter *rateLimiter
+
+// rateLimiter is a token bucket for each client.
+type rateLimiter struct {
+	// rate is how many tokens a bucket gets back a second, up to burst
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// bucket is how many tokens a client had left when it last asked.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter makes a rateLimiter allowing perMinute requests a minute,
+// burst at once. A perMinute of zero is no limit, which is nil.
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// take takes a token from key's bucket at now. If there isn't one it gives
+// how long until there will be.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Buckets that have filled back up are the same as new ones, so there's
+	// no need to keep them
+	if now.Sub(l.swept) > time.Minute {
+		for k, b := range l.buckets {
+			if l.refill(b, now) >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens, b.last = l.refill(b, now), now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// refill is how many tokens b has at now.
+func (l *rateLimiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// limited answers requests to h with a 429 once whoever's making them has
+// used up their share of issueLimiter, or of their API key's own limit.
+func limited(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := issueLimiter
+		if k, ok := usedKey(r); ok {
+			l = keyLimiters.get(k)
+		}
+		if l == nil {
+			h(w, r)
+			return
+		}
+		ok, wait := l.take(clientKey(r), time.Now())
+		if !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			writeError(w, r, http.StatusTooManyRequests, codeThrottled, "you're making too many requests, slow down", map[string]interface{}{
+				"retry_after": secs,
+			})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// clientKey is who's making r: their API key if they used one, their session
+// if they're logged in, otherwise their address.
+func clientKey(r *http.Request) string {
+	if k, ok := usedKey(r); ok {
+		return "key:" + k.ID
+	}
+	if s, err := sess.Get(r, sessionName); err == nil && sessionValid(s, time.Now()) {
+		if id, _, ok := sessionIssued(s); ok {
+			return "session:" + id
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP is the address r came from. With TrustProxy it's the last one in
+// X-Forwarded-For, which the proxy in front of us added, since anything
+// before it could be made up.
+func clientIP(r *http.Request) string {
+	if config.TrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			parts := strings.Split(fwd, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
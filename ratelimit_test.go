@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(60, 2)
+	now := time.Now()
+
+	for n := 0; n < 2; n++ {
+		if ok, _ := l.take("a", now); !ok {
+			t.Fatalf("request %d should be within the burst", n+1)
+		}
+	}
+	ok, wait := l.take("a", now)
+	if ok || wait != time.Second {
+		t.Errorf("got %v waiting %v, want refused for a second", ok, wait)
+	}
+	if ok, _ := l.take("b", now); !ok {
+		t.Errorf("someone else should have their own bucket")
+	}
+
+	if ok, _ := l.take("a", now.Add(time.Second)); !ok {
+		t.Errorf("a token should be back after a second")
+	}
+
+	// Full buckets are swept once they're the same as new ones
+	l.take("c", now.Add(2*time.Minute))
+	if _, ok := l.buckets["b"]; ok {
+		t.Errorf("b's bucket should have been swept, got %v", l.buckets)
+	}
+
+	if newRateLimiter(0, 10) != nil {
+		t.Errorf("a rate of 0 should be no limit")
+	}
+}
+
+func TestLimited(t *testing.T) {
+	defer keepSessions()()
+	old := issueLimiter
+	defer func() { issueLimiter = old }()
+	issueLimiter = newRateLimiter(1, 1)
+
+	h := limited(func(w http.ResponseWriter, r *http.Request) {})
+	from := func(addr, fwd string) *http.Request {
+		r := httptest.NewRequest("GET", "/api/v1/issues", nil)
+		r.RemoteAddr = addr
+		if fwd != "" {
+			r.Header.Set("X-Forwarded-For", fwd)
+		}
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	h(w, from("10.0.0.1:1234", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d for the first request", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h(w, from("10.0.0.1:5678", ""))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("got status %d with Retry-After %q, want 429 for a minute", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// X-Forwarded-For is only believed behind a proxy, and only its last hop
+	w = httptest.NewRecorder()
+	h(w, from("10.0.0.1:1234", "10.0.0.2"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, X-Forwarded-For shouldn't be trusted by default", w.Code)
+	}
+	config.TrustProxy = true
+	w = httptest.NewRecorder()
+	h(w, from("10.0.0.9:1234", "10.0.0.1, 10.0.0.2"))
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want the proxy's client counted on its own", w.Code)
+	}
+
+	// Logged in people are counted by session rather than address
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	r := withCookie("GET", "/api/v1/issues", sessionCookie(t, store, time.Now()))
+	r.RemoteAddr = "10.0.0.1:1234"
+	w = httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want a session to have its own bucket", w.Code)
+	}
+}
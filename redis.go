@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sharedStore keeps values outside the process so every instance behind a
+// load balancer sees the same ones. Without one, set by REDIS_URL, each
+// instance keeps its own in memory.
+type sharedStore interface {
+	// get gives the value at key and whether there was one
+	get(key string) ([]byte, bool, error)
+
+	// set puts value at key for ttl, or for good if ttl isn't positive
+	set(key string, value []byte, ttl time.Duration) error
+
+	del(keys ...string) error
+
+	// keys gives every key starting with prefix
+	keys(prefix string) ([]string, error)
+}
+
+// shared is the sharedStore, or nil if there isn't one.
+var shared sharedStore
+
+// sharedPrefix starts every key we keep in the sharedStore so it can be
+// shared with other apps.
+const sharedPrefix = "hacktoberfest:"
+
+// This is just enough of the Redis protocol (RESP) for GET, SET, DEL and SCAN,
+// which is all sharedStore needs.
+
+// redisClient is a sharedStore in Redis. It keeps a few connections open and
+// is safe for concurrent use.
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+	tls      bool
+	timeout  time.Duration
+
+	idle chan *redisConn
+}
+
+// redisIdle is how many connections a redisClient keeps open between
+// commands.
+const redisIdle = 8
+
+// newRedis makes a redisClient for a URL like redis://:password@host:6379/0,
+// or rediss:// for TLS. It doesn't connect until it's used.
+func newRedis(rawurl string) (*redisClient, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse redis url")
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, errors.Errorf("redis url should start redis:// or rediss://, not %s://", u.Scheme)
+	}
+
+	c := &redisClient{
+		addr:    u.Host,
+		tls:     u.Scheme == "rediss",
+		timeout: 5 * time.Second,
+		idle:    make(chan *redisConn, redisIdle),
+	}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, errors.Errorf("redis database should be a number, not %q", db)
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error Redis replied with, like a command it didn't know.
+// The connection is still fine after one.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn is one connection to Redis.
+type redisConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// dial connects to Redis, logging in and picking the database.
+func (c *redisClient) dial() (*redisConn, error) {
+	var conn net.Conn
+	var err error
+	if c.tls {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: c.timeout}, "tcp", c.addr, nil)
+	} else {
+		conn, err = net.DialTimeout("tcp", c.addr, c.timeout)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to redis")
+	}
+
+	rc := &redisConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}
+	if c.password != "" {
+		if _, err := rc.do(c.timeout, "AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "could not log in to redis")
+		}
+	}
+	if c.db != 0 {
+		if _, err := rc.do(c.timeout, "SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "could not select redis database")
+		}
+	}
+	return rc, nil
+}
+
+// do sends a command on an idle connection, or a new one if there aren't
+// any, and gives back the reply.
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	var rc *redisConn
+	select {
+	case rc = <-c.idle:
+	default:
+		var err error
+		if rc, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := rc.do(c.timeout, args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// We can't tell where we are in the stream any more
+		rc.conn.Close()
+		return nil, errors.Wrapf(err, "redis %s failed", args[0])
+	}
+
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return reply, err
+}
+
+// do sends args as a command and reads the reply, giving up after timeout.
+func (rc *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	rc.conn.SetDeadline(time.Now().Add(timeout))
+
+	fmt.Fprintf(rc.rw, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(rc.rw, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := rc.rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(rc.rw.Reader)
+}
+
+// readReply reads one reply: a string, an int64, a []byte, a []interface{}
+// of replies, or nil. An error reply is a redisError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = readReply(r); err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+			}
+		}
+		return replies, nil
+	}
+	return nil, errors.Errorf("unexpected reply %q", line)
+}
+
+func (c *redisClient) get(key string) ([]byte, bool, error) {
+	reply, err := c.do("GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, errors.Errorf("redis GET gave %T", reply)
+	}
+	return b, true, nil
+}
+
+func (c *redisClient) set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+	_, err := c.do(args...)
+	return err
+}
+
+func (c *redisClient) del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+func (c *redisClient) keys(prefix string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", redisEscape(prefix)+"*", "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, errors.Errorf("redis SCAN gave %v", reply)
+		}
+		next, _ := parts[0].([]byte)
+		found, _ := parts[1].([]interface{})
+		for _, k := range found {
+			if b, ok := k.([]byte); ok {
+				keys = append(keys, string(b))
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+// redisEscape stops the glob characters in s meaning anything to MATCH.
+func redisEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return r.Replace(s)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// fakeRedis is just enough of a Redis server for redisClient, keeping
+// everything in memory.
+type fakeRedis struct {
+	net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		Listener: l,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// url is where to find f, logged in.
+func (f *fakeRedis) url() string {
+	if f.password != "" {
+		return "redis://:" + f.password + "@" + f.Addr().String() + "/1"
+	}
+	return "redis://" + f.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		cmd, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range cmd.([]interface{}) {
+			args = append(args, string(a.([]byte)))
+		}
+
+		name := strings.ToUpper(args[0])
+		switch {
+		case name == "AUTH":
+			if authed = args[1] == f.password; authed {
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		default:
+			fmt.Fprint(conn, f.do(name, args[1:]))
+		}
+	}
+}
+
+func (f *fakeRedis) do(name string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for k, e := range f.expires {
+		if time.Now().After(e) {
+			delete(f.values, k)
+			delete(f.expires, k)
+		}
+	}
+
+	switch name {
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.values[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.values[args[0]] = args[1]
+		delete(f.expires, args[0])
+		if len(args) == 4 && args[2] == "PX" {
+			ms, _ := strconv.Atoi(args[3])
+			f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args {
+			if _, ok := f.values[k]; ok {
+				n++
+			}
+			delete(f.values, k)
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		// Everything at once, which is allowed
+		var keys []string
+		for k := range f.values {
+			if ok, _ := path.Match(args[2], k); ok {
+				keys = append(keys, bulk(k))
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulk("0"), len(keys), strings.Join(keys, ""))
+	}
+	return "-ERR unknown command '" + name + "'\r\n"
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestRedis(t *testing.T) {
+	f := newFakeRedis(t, "hunter2")
+	defer f.Close()
+	c, err := newRedis(f.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := c.get("a"); ok || err != nil {
+		t.Errorf("missing key gave %v %v", ok, err)
+	}
+	if err := c.set("a", []byte("1\r\n2"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.set("a*b", []byte("3"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok, err := c.get("a"); string(b) != "1\r\n2" || !ok || err != nil {
+		t.Errorf("got %q %v %v, want what was set", b, ok, err)
+	}
+
+	keys, err := c.keys("a*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "a*b" {
+		t.Errorf("keys starting a* are %q, want only a*b", keys)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, _ := c.get("a*b"); ok {
+		t.Errorf("key should have expired")
+	}
+	if err := c.del("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.get("a"); ok {
+		t.Errorf("key should have been deleted")
+	}
+
+	if _, err := c.do("FLY"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("got error %v for an unknown command", err)
+	}
+	if _, _, err := c.get("a"); err != nil {
+		t.Errorf("connection should still work after an error reply, got %v", err)
+	}
+
+	wrong, _ := newRedis("redis://:hunter3@" + f.Addr().String())
+	if _, _, err := wrong.get("a"); err == nil {
+		t.Errorf("wrong password should be an error")
+	}
+}
+
+func TestNewRedis(t *testing.T) {
+	c, err := newRedis("rediss://:secret@cache.example.com/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.addr != "cache.example.com:6379" || c.password != "secret" || c.db != 2 || !c.tls {
+		t.Errorf("got %+v", c)
+	}
+
+	for _, u := range []string{"http://localhost", "redis://localhost/zero", "://"} {
+		if _, err := newRedis(u); err == nil {
+			t.Errorf("%q should be an error", u)
+		}
+	}
+}
+
+func TestIssueCacheShared(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+	store, _ := newRedis(f.url())
+
+	// Two instances
+	a, b := newIssueCache(time.Minute), newIssueCache(time.Minute)
+	a.shared, b.shared = store, store
+
+	p := searchParams{scope: "org:devict", labels: []string{"hacktoberfest"}, maxLangs: 3}
+	a.set(p, []Issue{{Title: "Fix it", Repo: Repo{Owner: "devict", Name: "site"}}})
+	e, ok := b.entry(p.key())
+	if !ok || len(e.issues) != 1 || e.issues[0].Title != "Fix it" {
+		t.Fatalf("other instance got %+v %v, want what was set", e, ok)
+	}
+	if e.params.key() != p.key() {
+		t.Errorf("params came back as %+v, want %+v", e.params, p)
+	}
+
+	b.update(func(p searchParams, issues []Issue) []Issue {
+		return append([]Issue{{Title: "New"}}, issues...)
+	})
+	if issues, _ := a.get(p.key()); len(issues) != 2 {
+		t.Errorf("update should be seen everywhere, got %v", issues)
+	}
+
+	failed := &incompleteError{Failed: []failedSearch{{Source: "github", Label: "hacktoberfest", Err: fmt.Errorf("502")}}}
+	a.setIncomplete(p, nil, failed)
+	if e, ok := b.entry(p.key()); !ok || e.incomplete == nil || e.incomplete.Error() != failed.Error() {
+		t.Errorf("got incomplete %v, want %v", e.incomplete, failed)
+	}
+
+	b.invalidate()
+	if _, ok := a.last(p.key()); ok {
+		t.Errorf("invalidated entry should be gone everywhere")
+	}
+}
+
+func TestLanguagesShared(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+	store, _ := newRedis(f.url())
+
+	a, b := newLanguages(time.Hour), newLanguages(time.Hour)
+	a.Store, b.Store = languageStore{store}, languageStore{store}
+	a.Prefix, b.Prefix = "languages:", "languages:"
+
+	site := Repo{Owner: "devict", Name: "site"}
+	a.Save(site.ID(), aggregator.RepoInfo{Bytes: map[string]int{"PHP": 10}, Details: site, Fetched: time.Now()})
+	site.Stars = 5
+	b.Remember(site)
+
+	if got, ok := a.Load(site.ID()); !ok || got.Bytes["PHP"] != 10 || got.Details.Stars != 5 {
+		t.Errorf("got %+v %v, want the languages and the remembered details", got, ok)
+	}
+
+	a.Invalidate(site)
+	if d := b.Details(Repo{Owner: "devict", Name: "site"}); d.Stars != 0 {
+		t.Errorf("invalidated repo should be forgotten everywhere, got %+v", d)
+	}
+}
+
+func TestLoggedOutShared(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+	store, _ := newRedis(f.url())
+
+	a := &sessionList{shared: store, ids: make(map[string]time.Time)}
+	b := &sessionList{shared: store, ids: make(map[string]time.Time)}
+
+	now := time.Now()
+	a.add("abc", now.Add(time.Hour))
+	if !b.has("abc", now) {
+		t.Errorf("logging out on one instance should log out on the others")
+	}
+	if b.has("abc", now.Add(2*time.Hour)) {
+		t.Errorf("logout should be forgotten once the session would have expired")
+	}
+	if b.has("def", now) {
+		t.Errorf("other sessions are still logged in")
+	}
+}
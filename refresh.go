@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRefreshInterval is how often the background refresher goes to
+// GitHub for the standard issue listing during October.
+const defaultRefreshInterval = 2 * time.Minute
+
+// defaultOffSeasonInterval is how often it goes the rest of the year, when
+// hardly anyone is looking. What it fetches is served until the next refresh,
+// so intervals longer than the cache TTL don't send requests to GitHub in
+// between.
+const defaultOffSeasonInterval = time.Hour
+
+// refreshIssues keeps the standard listing of every tracked repo in the cache
+// so requests for it never wait on GitHub. It uses the server's own
+// credentials so it doesn't depend on anyone being logged in. Whenever the
+// listing changes the difference goes out to live subscribers, and new issues
+// to notifications. Each full listing is recorded as a Snapshot too, and
+// issues that left it are checked for having closed.
+//
+// The first refresh is straight away, and ready is closed once it's done,
+// however it went, so startup can wait for a warm cache. After that every
+// says how long to wait until the next. It runs until ctx is done, finishing
+// any refresh it's in the middle of first.
+func refreshIssues(ctx context.Context, every func(time.Time) time.Duration, ready chan<- struct{}) {
+	var last []Issue
+	refresh := func() {
+		start := time.Now()
+		c := serverClient()
+		issues, err := loadIssues(ctx, c, defaultParams(c.Scope()), true)
+		if err != nil && incomplete(err) == nil {
+			logError(ctx, err)
+			return
+		}
+		atomic.StoreInt32(&warmed, 1)
+
+		// Issues missing because their search failed haven't closed, so wait
+		// for a full listing before telling anyone what changed
+		if err != nil {
+			logWarn(ctx, "refreshed incomplete issues", "err", err)
+			return
+		}
+		logInfo(ctx, "refreshed issues", "issues", len(issues), "duration", time.Since(start))
+		// Allow for the next refresh taking as long as it can
+		cache.setRefreshed(defaultParams(c.Scope()), issues, every(time.Now())+config.FetchTimeout)
+		if flagged := flagRepos(issues, config.CopiedTitles); len(flagged) > 0 {
+			logWarn(ctx, "repos flagged for review", "repos", len(flagged), "first", flagged[0].Repo)
+		}
+		if db != nil {
+			if err := recordSnapshot(issues, last, time.Now()); err != nil {
+				logError(ctx, err)
+			}
+		}
+
+		// The first listing is our starting point, not news
+		if last != nil {
+			if d := diffIssues(last, issues); !d.empty() {
+				live.publish(d)
+				if len(d.Opened) > 0 {
+					notify(ctx, notifications, d.Opened)
+				}
+				for _, i := range d.Opened {
+					recordReopened(ctx, i.URL)
+				}
+				checkClosed(ctx, c, d.Closed)
+			}
+		}
+		last = issues
+	}
+
+	refresh()
+	close(ready)
+	for {
+		t := time.NewTimer(every(time.Now()))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+			refresh()
+		}
+	}
+}
+
+// refreshEvery is how long after now the next background refresh is:
+// config.RefreshInterval in October and config.OffSeasonInterval otherwise.
+func refreshEvery(now time.Time) time.Duration {
+	if now.Month() == time.October {
+		return config.RefreshInterval
+	}
+	return config.OffSeasonInterval
+}
+
+// waitWarm waits up to timeout for ready to be closed, so we start serving
+// with the cache already full. If it takes longer we start anyway, and
+// requests wait on the refresh like they would any fetch.
+func waitWarm(ctx context.Context, ready <-chan struct{}, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	start := time.Now()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-ready:
+		logInfo(ctx, "warmed cache", "duration", time.Since(start))
+	case <-t.C:
+		logWarn(ctx, "cache isn't warm yet, serving anyway", "waited", timeout)
+	}
+}
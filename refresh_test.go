@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshEvery(t *testing.T) {
+	old := config
+	defer func() { config = old }()
+	config.RefreshInterval = 10 * time.Minute
+	config.OffSeasonInterval = time.Hour
+
+	tests := []struct {
+		now  time.Time
+		want time.Duration
+	}{
+		{time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC), 10 * time.Minute},
+		{time.Date(2017, 10, 31, 23, 0, 0, 0, time.UTC), 10 * time.Minute},
+		{time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC), time.Hour},
+		{time.Date(2018, 3, 14, 0, 0, 0, 0, time.UTC), time.Hour},
+	}
+	for _, test := range tests {
+		if got := refreshEvery(test.now); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.now, got, test.want)
+		}
+	}
+}
+
+func TestWaitWarm(t *testing.T) {
+	ready := make(chan struct{})
+	start := time.Now()
+	waitWarm(context.Background(), ready, 20*time.Millisecond)
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("waited %v, want the whole timeout when the cache isn't warm", waited)
+	}
+
+	close(ready)
+	start = time.Now()
+	waitWarm(context.Background(), ready, time.Minute)
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %v, want to stop as soon as the cache is warm", waited)
+	}
+}
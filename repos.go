@@ -1,26 +1,108 @@
 package main
 
 import (
-	"fmt"
-	"regexp"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
 )
 
 // Repo is a repository on Github. Owner can be either an organization or user.
-type Repo struct {
-	Owner string
-	Name  string
+type Repo = aggregator.Repo
+
+// tracked reports whether the repo counts for the event, either because its
+// owner is one of our orgs or because it is listed in projects, and hasn't
+// been excluded.
+func tracked(r Repo) bool {
+	t := tracking()
+	return (t.Orgs[r.Owner] || t.Projects[r.Owner+"/"+r.Name]) && !t.excludes(r)
 }
 
-var reRepo = regexp.MustCompile("https://api.github.com/repos/([^/]+)/([^/]+)")
+// repoDetail is what /api/v1/repos/{owner}/{repo} gives back: what we know
+// about a tracked repo and the open issues we're listing in it.
+type repoDetail struct {
+	Repo
+
+	// Languages are its top config.MaxLanguages languages, most code first,
+	// and LanguageShares the same with how much of its code is in each
+	Languages      []string              `json:"languages"`
+	LanguageShares []aggregator.Language `json:"language_shares"`
+
+	// Participating is true if it has opted in to Hacktoberfest, see
+	// Repo.Participating
+	Participating bool `json:"participating"`
+
+	Issues []Issue `json:"issues"`
+}
+
+// repoDetails gives the repoDetail of a tracked repo for a drill-down page.
+// Everything comes from what we already have, the standard listing and the
+// languages we've fetched, so it doesn't cost any GitHub calls unless the
+// listing needs searching again.
+func repoDetails(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+
+	repo := Repo{
+		Owner: r.URL.Query().Get(":owner"),
+		Name:  r.URL.Query().Get(":repo"),
+	}
+	if !tracked(repo) {
+		writeError(w, r, http.StatusNotFound, codeNotFound, repo.ID()+" isn't tracked", nil)
+		return
+	}
+
+	issues, err := loadIssues(r.Context(), c, defaultParams(c.Scope()), false)
+	if !servable(err) {
+		upstreamError(w, r, err)
+		return
+	}
+	setStale(w, err)
+
+	d := repoDetail{Repo: languages.Details(repo), Issues: []Issue{}}
+	for _, i := range issues {
+		if strings.EqualFold(i.Repo.ID(), repo.ID()) {
+			d.Issues = append(d.Issues, i)
+		}
+	}
+	if u, _, ok := findUser(r); ok {
+		d.Issues = personalize(r.Context(), u, d.Issues, false)
+	}
+	if db != nil {
+		claims, err := activeClaims(time.Now())
+		if err != nil {
+			logError(r.Context(), err)
+		}
+		markClaimed(d.Issues, claims)
+		matchMentors(r, d.Issues)
+	}
+	markClosed(d.Issues, closures.urls())
+	open := d.Issues[:0]
+	for _, i := range d.Issues {
+		if !i.Closed {
+			open = append(open, i)
+		}
+	}
+	d.Issues = open
 
-func repoFromURL(url string) (Repo, error) {
-	if !reRepo.MatchString(url) {
-		return Repo{}, fmt.Errorf("url %q did not match regexp", url)
+	// What languages has is best, but it can have forgotten the repo since
+	// the listing was searched and the issues know it too
+	d.LanguageShares = []aggregator.Language{}
+	if f, ok := languages.Load(repo.ID()); ok {
+		d.LanguageShares = aggregator.TopLanguages(config.MaxLanguages, f.Bytes)
+	} else if len(d.Issues) > 0 {
+		d.Repo = d.Issues[0].Repo
+		if d.Issues[0].LanguageShares != nil {
+			d.LanguageShares = d.Issues[0].LanguageShares
+		}
 	}
+	d.Languages = aggregator.LanguageNames(d.LanguageShares)
+	d.Participating = d.Repo.Participating()
 
-	matches := reRepo.FindStringSubmatch(url)
-	return Repo{
-		Name:  matches[2],
-		Owner: matches[1],
-	}, nil
+	_, stale := err.(*staleError)
+	writeEnvelope(w, r, apiEnvelope{Data: d, Meta: apiMeta{Total: len(d.Issues), Stale: stale, Warnings: languageWarnings(d.Issues)}, Errors: incomplete(err).Warnings()})
 }
@@ -1,26 +1,59 @@
 package main
 
-import "testing"
-
-func TestRepoFromURL(t *testing.T) {
-	tests := []struct {
-		url  string
-		repo Repo
-		ok   bool
-	}{
-		{"https://api.github.com/repos/spf13/hugo", Repo{Owner: "spf13", Name: "hugo"}, true},
-		{"https://api.github.com/repos/exercism/xgo", Repo{Owner: "exercism", Name: "xgo"}, true},
-		{"https://api.github.com/", Repo{}, false},
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestRepoDetails(t *testing.T) {
+	oldToken := config.Token
+	config.Token = "abc123"
+	defer func() { config.Token = oldToken }()
+	defer cache.invalidate()
+	defer func() { closures = &closedIssues{} }()
+
+	repo := Repo{Owner: "devict", Name: "hacktoberfest"}
+	details := Repo{Owner: "devict", Name: "hacktoberfest", Stars: 12, Topics: []string{"Hacktoberfest"}}
+	languages.Save(repo.ID(), aggregator.RepoInfo{Bytes: map[string]int{"Go": 75, "HTML": 25}, Details: details, Fetched: time.Now()})
+	defer languages.Invalidate(repo)
+
+	cache.set(defaultParams(newClient(config.Token).Scope()), []Issue{
+		{URL: "https://github.com/devict/hacktoberfest/issues/1", Repo: repo},
+		{URL: "https://github.com/devict/hacktoberfest/issues/2", Repo: repo},
+		{URL: "https://github.com/devict/other/issues/1", Repo: Repo{Owner: "devict", Name: "other"}},
+	})
+	closures.add(ClosedIssue{Issue: Issue{URL: "https://github.com/devict/hacktoberfest/issues/2", Closed: true}, ClosedAt: time.Now()})
+
+	w := httptest.NewRecorder()
+	repoDetails(w, httptest.NewRequest("GET", "/api/v1/repos/devict/hacktoberfest?:owner=devict&:repo=hacktoberfest", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	var got repoEnvelope
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Data.Repo, details) || !got.Data.Participating {
+		t.Errorf("got repo %+v, want the details we fetched and participating", got.Data)
+	}
+	want := []aggregator.Language{{Name: "Go", Percent: 75}, {Name: "HTML", Percent: 25}}
+	if !reflect.DeepEqual(got.Data.LanguageShares, want) || !reflect.DeepEqual(got.Data.Languages, []string{"Go", "HTML"}) {
+		t.Errorf("got languages %v %v, want %v", got.Data.Languages, got.Data.LanguageShares, want)
+	}
+	if len(got.Data.Issues) != 1 || got.Data.Issues[0].URL != "https://github.com/devict/hacktoberfest/issues/1" || got.Meta.Total != 1 {
+		t.Errorf("got issues %+v, want just the open one in the repo", got.Data.Issues)
 	}
 
-	for i, test := range tests {
-		got, err := repoFromURL(test.url)
-		if test.ok && err != nil {
-			t.Errorf("%d: error should be nil, got %v", i, err)
-		} else if !test.ok && err == nil {
-			t.Errorf("%d: error should not be nil, but it was", i)
-		} else if got != test.repo {
-			t.Errorf("%d: got != want:\n%+v\n%+v", i, got, test.repo)
-		}
+	w = httptest.NewRecorder()
+	repoDetails(w, httptest.NewRequest("GET", "/api/v1/repos/someone/else?:owner=someone&:repo=else", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got %d for a repo we don't track, want 404", w.Code)
 	}
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// freshHalfLife is how long it takes an issue nobody's touched to lose half
+// its freshness.
+const freshHalfLife = 14 * 24 * time.Hour
+
+// score guesses how worthwhile i is to pick up at now, from 0 to 1. Issues
+// that someone's been active on lately, that don't have a long discussion
+// to catch up on, and that are in popular repos taking part in Hacktoberfest
+// score highest. Someone already being assigned halves it, copies of the same
+// issue across repos count for less the more of them there are, and an
+// archived repo means nobody can work on it at all. We don't know how quickly
+// maintainers answer so that doesn't count yet.
+func score(i Issue, now time.Time) float64 {
+	if i.Repo.Archived {
+		return 0
+	}
+
+	fresh := math.Exp2(-float64(now.Sub(i.Updated)) / float64(freshHalfLife))
+	if fresh > 1 {
+		fresh = 1
+	}
+	quiet := 1 / (1 + float64(i.Comments)/5)
+	stars := math.Min(math.Log10(1+float64(i.Repo.Stars))/4, 1)
+	var participating float64
+	if i.Participating {
+		participating = 1
+	}
+
+	s := 0.4*fresh + 0.2*quiet + 0.2*stars + 0.2*participating
+	if i.Assigned {
+		s /= 2
+	}
+	s /= 1 + math.Log2(1+float64(i.Duplicates))
+	return s
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2017, 10, 31, 0, 0, 0, 0, time.UTC)
+	base := Issue{Updated: now.Add(-24 * time.Hour), Comments: 2, Repo: Repo{Stars: 50}}
+
+	better := func(name string, a, b Issue) {
+		if score(a, now) <= score(b, now) {
+			t.Errorf("%s: %v should score more than %v", name, score(a, now), score(b, now))
+		}
+	}
+
+	old := base
+	old.Updated = now.AddDate(0, -2, 0)
+	better("fresher", base, old)
+
+	busy := base
+	busy.Comments = 40
+	better("quieter", base, busy)
+
+	popular := base
+	popular.Repo.Stars = 5000
+	better("more stars", popular, base)
+
+	in := base
+	in.Participating = true
+	better("participating", in, base)
+
+	taken := base
+	taken.Assigned = true
+	better("unassigned", base, taken)
+
+	copied := base
+	copied.Duplicates = 30
+	better("not copied", base, copied)
+
+	archived := base
+	archived.Repo.Archived = true
+	if s := score(archived, now); s != 0 {
+		t.Errorf("archived repo should score 0, got %v", s)
+	}
+
+	for _, i := range []Issue{base, old, busy, popular, in, taken, copied} {
+		if s := score(i, now); s < 0 || s > 1 {
+			t.Errorf("score should be from 0 to 1, got %v for %+v", s, i)
+		}
+	}
+}
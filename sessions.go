@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
+)
+
+// Sessions live in a cookie, signed and encrypted with keys made from
+// SessionSecret. Each holds who's logged in, an ID, and when it was issued.
+// One stops working once it's SessionMaxAge old or its ID has logged out, and
+// one still in use is issued afresh once it's SessionRenewAfter old so people
+// who keep coming back stay logged in. Sessions made with any of the
+// OldSessionSecrets still work, and are issued afresh with the current one
+// the next time they're used.
+//
+// Since the session is all in the cookie, any instance sharing the secrets can
+// read it. Only which sessions have logged out needs sharing, see sessionList.
+
+// sessionName is the cookie sessions are kept in.
+const sessionName = "session"
+
+// sessionKeys gives the signing and encryption keys for each of secrets, in
+// the order sessions.NewCookieStore takes them. Secrets can be any length, so
+// the keys are hashes of them.
+func sessionKeys(secrets ...string) [][]byte {
+	var pairs [][]byte
+	for _, s := range secrets {
+		if s == "" {
+			continue
+		}
+		hash := sha256.Sum256([]byte("session signing key:" + s))
+		block := sha256.Sum256([]byte("session encryption key:" + s))
+		pairs = append(pairs, hash[:], block[:])
+	}
+	return pairs
+}
+
+// newSessionStore makes the cookie store for c. New sessions use the current
+// secret and old ones are still read.
+func newSessionStore(c Config) *sessions.CookieStore {
+	store := sessions.NewCookieStore(sessionKeys(append([]string{c.SessionSecret}, c.OldSessionSecrets...)...)...)
+	store.Options.HttpOnly = true
+	store.Options.Secure = strings.HasPrefix(c.SiteURL, "https://")
+	store.MaxAge(int(c.SessionMaxAge / time.Second))
+	return store
+}
+
+// startSession gives s a new ID, issued now. Doing it on every login means
+// an ID someone got hold of before then is no use to them.
+func startSession(s *sessions.Session, now time.Time) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return errors.Wrap(err, "could not make session id")
+	}
+	s.Values["id"] = hex.EncodeToString(b)
+	s.Values["issued"] = now.Unix()
+	return nil
+}
+
+// sessionIssued gives s's ID and when it was issued, if it's a session we
+// started.
+func sessionIssued(s *sessions.Session) (string, time.Time, bool) {
+	id, _ := s.Values["id"].(string)
+	issued, ok := s.Values["issued"].(int64)
+	if id == "" || !ok {
+		return "", time.Time{}, false
+	}
+	return id, time.Unix(issued, 0), true
+}
+
+// sessionValid reports whether s is one we started that hasn't expired or
+// logged out by now.
+func sessionValid(s *sessions.Session, now time.Time) bool {
+	id, issued, ok := sessionIssued(s)
+	if !ok || now.Sub(issued) > config.SessionMaxAge {
+		return false
+	}
+	return !loggedOut.has(id, now)
+}
+
+// renewSessions issues sessions afresh before h sees the request if they're
+// SessionRenewAfter old or made with an old secret. It reads the cookie
+// itself rather than through sess.Get so nothing is left in gorilla's
+// registry for requests pat never sees.
+func renewSessions(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store, ok := sess.(*sessions.CookieStore)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		s, err := store.New(r, sessionName)
+		if err == nil && !s.IsNew && sessionValid(s, now) {
+			_, issued, _ := sessionIssued(s)
+			if now.Sub(issued) > config.SessionRenewAfter || !currentKey(store, r) {
+				s.Values["issued"] = now.Unix()
+				if err := store.Save(r, w, s); err != nil {
+					logError(r.Context(), errors.Wrap(err, "could not renew session"))
+				}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// sameSite is what the cookies we set say for SameSite. It's None when other
+// origins can use the API with people's sessions, which cors guards instead.
+var sameSite = "Lax"
+
+// sameSiteCookies marks the cookies h sets SameSite=Lax, so browsers don't
+// send them along with requests other sites make, like a form posting to
+// /logout. Lax still sends them when GitHub sends people back after logging
+// in. gorilla/sessions can't set it itself.
+func sameSiteCookies(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&sameSiteWriter{ResponseWriter: w}, r)
+	})
+}
+
+// sameSiteWriter adds SameSite=Lax to the cookies set through it before the
+// headers are written, unless they already say.
+type sameSiteWriter struct {
+	http.ResponseWriter
+}
+
+func (w *sameSiteWriter) setSameSite() {
+	cookies := w.Header()["Set-Cookie"]
+	for n, c := range cookies {
+		if !strings.Contains(strings.ToLower(c), "samesite=") {
+			cookies[n] = c + "; SameSite=" + sameSite
+		}
+	}
+}
+
+func (w *sameSiteWriter) WriteHeader(status int) {
+	w.setSameSite()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *sameSiteWriter) Write(b []byte) (int, error) {
+	w.setSameSite()
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack passes through so WebSockets still work.
+func (w *sameSiteWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response can't be hijacked")
+	}
+	return hj.Hijack()
+}
+
+// Flush passes through so streamed responses still stream.
+func (w *sameSiteWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// currentKey reports whether r's session cookie was made with the current
+// secret.
+func currentKey(store *sessions.CookieStore, r *http.Request) bool {
+	c, err := r.Cookie(sessionName)
+	if err != nil || len(store.Codecs) == 0 {
+		return false
+	}
+	var values map[interface{}]interface{}
+	return store.Codecs[0].Decode(sessionName, c.Value, &values) == nil
+}
+
+// logout ends the session it's sent with, so the cookie it was in stops
+// working even if someone kept a copy, and revokes its tokens, then sends
+// them home.
+func logout(w http.ResponseWriter, r *http.Request) {
+	for name, token := range userTokens(r) {
+		if err := loginProviders[name].revoke(r.Context(), token); err != nil {
+			// The session still ends, so only someone who copied the token
+			// before now could use it
+			logError(r.Context(), err, "provider", name)
+		}
+	}
+	if id, until, ok := endSession(w, r); ok {
+		if err := saveLoggedOut(id, until); err != nil {
+			// They're still logged out here, just not after a restart
+			logError(r.Context(), err)
+		}
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// endSession adds r's session to loggedOut and tells the browser to forget
+// it. If it was one we started it gives back its ID and when it would have
+// expired.
+func endSession(w http.ResponseWriter, r *http.Request) (string, time.Time, bool) {
+	s, _ := sess.Get(r, sessionName)
+	id, issued, ok := sessionIssued(s)
+	until := issued.Add(config.SessionMaxAge)
+	if ok {
+		loggedOut.add(id, until)
+	}
+
+	s.Values = make(map[interface{}]interface{})
+	s.Options.MaxAge = -1
+	if err := s.Save(r, w); err != nil {
+		logError(r.Context(), err)
+	}
+	return id, until, ok
+}
+
+// sessionList is the IDs of sessions that have logged out, each kept until
+// it would have expired anyway. With a sharedStore they're kept there too,
+// so logging out on one instance logs out on all of them. It is safe for
+// concurrent use.
+type sessionList struct {
+	shared sharedStore
+
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+var loggedOut = &sessionList{ids: make(map[string]time.Time)}
+
+// sharedLogoutPrefix starts the keys of logged out sessions in the
+// sharedStore, and the ID follows it.
+const sharedLogoutPrefix = sharedPrefix + "logout:"
+
+func (l *sessionList) add(id string, until time.Time) {
+	l.mu.Lock()
+	l.ids[id] = until
+	l.mu.Unlock()
+
+	if keep := time.Until(until); l.shared != nil && keep > 0 {
+		if err := l.shared.set(sharedLogoutPrefix+id, []byte(until.UTC().Format(time.RFC3339)), keep); err != nil {
+			logError(context.Background(), errors.Wrap(err, "could not share logout"))
+		}
+	}
+}
+
+// has reports whether id is on the list at now, forgetting it if it's
+// expired. If the sharedStore can't be reached only the ones that logged out
+// here are on it.
+func (l *sessionList) has(id string, now time.Time) bool {
+	l.mu.Lock()
+	until, ok := l.ids[id]
+	if ok && now.After(until) {
+		delete(l.ids, id)
+		ok = false
+	}
+	l.mu.Unlock()
+	if ok || l.shared == nil {
+		return ok
+	}
+
+	b, ok, err := l.shared.get(sharedLogoutPrefix + id)
+	if err != nil {
+		logError(context.Background(), errors.Wrap(err, "could not check shared logouts"))
+		return false
+	}
+	if !ok {
+		return false
+	}
+	until, err = time.Parse(time.RFC3339, string(b))
+	return err != nil || !now.After(until)
+}
+
+// saveLoggedOut records that the session id logged out, so it stays that way
+// after a restart.
+func saveLoggedOut(id string, until time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO logged_out_sessions (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING",
+		id,
+		until,
+	)
+	return errors.Wrap(err, "could not save logout")
+}
+
+// loadLoggedOut fills in loggedOut from the database, clearing out sessions
+// that would have expired by now.
+func loadLoggedOut() error {
+	if _, err := db.Exec("DELETE FROM logged_out_sessions WHERE expires_at < $1", time.Now()); err != nil {
+		return errors.Wrap(err, "could not clear expired logouts")
+	}
+
+	rows, err := db.Query("SELECT id, expires_at FROM logged_out_sessions")
+	if err != nil {
+		return errors.Wrap(err, "could not query logouts")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var until time.Time
+		if err := rows.Scan(&id, &until); err != nil {
+			return errors.Wrap(err, "could not scan logout")
+		}
+		loggedOut.add(id, until)
+	}
+	return errors.Wrap(rows.Err(), "could not iterate over logouts")
+}
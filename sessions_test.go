@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/markbates/goth"
+)
+
+// keepSessions gives back a func that puts the session config back how it
+// was.
+func keepSessions() func() {
+	oldConfig, oldSess := config, sess
+	return func() { config, sess = oldConfig, oldSess }
+}
+
+// useSessions makes sessions work as c says.
+func useSessions(t *testing.T, c Config) *sessions.CookieStore {
+	config.SessionSecret, config.OldSessionSecrets = c.SessionSecret, c.OldSessionSecrets
+	store := newSessionStore(config)
+	sess = store
+	return store
+}
+
+// sessionCookie is the cookie of a session for octocat in store, issued at
+// issued.
+func sessionCookie(t *testing.T, store *sessions.CookieStore, issued time.Time) *http.Cookie {
+	return userCookie(t, store, issued, goth.User{NickName: "octocat", Provider: "github"})
+}
+
+// userCookie is the cookie of a session for u in store, issued at issued.
+func userCookie(t *testing.T, store *sessions.CookieStore, issued time.Time, u goth.User) *http.Cookie {
+	r := httptest.NewRequest("GET", "/", nil)
+	s, _ := store.New(r, sessionName)
+	if err := startSession(s, issued); err != nil {
+		t.Fatal(err)
+	}
+	s.Values["user"] = u
+
+	w := httptest.NewRecorder()
+	if err := store.Save(r, w, s); err != nil {
+		t.Fatal(err)
+	}
+	return w.Result().Cookies()[0]
+}
+
+// withCookie is a request for path carrying c.
+func withCookie(method, path string, c *http.Cookie) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	r.AddCookie(c)
+	return r
+}
+
+func TestSessionLogin(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+
+	c := sessionCookie(t, store, time.Now())
+	if !c.HttpOnly {
+		t.Errorf("session cookie should be HttpOnly")
+	}
+	if u, _, ok := findUser(withCookie("GET", "/", c)); !ok || u.NickName != "octocat" {
+		t.Errorf("got %+v, %t, want octocat logged in", u, ok)
+	}
+
+	// Someone else's secret can't read it
+	useSessions(t, Config{SessionSecret: "apple"})
+	if _, _, ok := findUser(withCookie("GET", "/", c)); ok {
+		t.Errorf("a session made with another secret should not log anyone in")
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+
+	c := sessionCookie(t, store, time.Now().Add(-config.SessionMaxAge-time.Minute))
+	if _, _, ok := findUser(withCookie("GET", "/", c)); ok {
+		t.Errorf("a session older than SessionMaxAge should not log anyone in")
+	}
+}
+
+func TestSessionRenewal(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	var saw bool
+	h := renewSessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { saw = true }))
+
+	// Fresh sessions are left alone
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, withCookie("GET", "/", sessionCookie(t, store, time.Now())))
+	if !saw || len(w.Result().Cookies()) != 0 {
+		t.Errorf("a fresh session should be passed straight through, got cookies %v", w.Result().Cookies())
+	}
+
+	// Older ones get issued again
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, withCookie("GET", "/", sessionCookie(t, store, time.Now().Add(-config.SessionRenewAfter-time.Minute))))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got cookies %v, want the session issued again", cookies)
+	}
+	s, err := store.New(withCookie("GET", "/", cookies[0]), sessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, issued, _ := sessionIssued(s); time.Since(issued) > time.Minute {
+		t.Errorf("renewed session was issued %v, want just now", issued)
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	defer keepSessions()()
+	old := sessionCookie(t, useSessions(t, Config{SessionSecret: "banana"}), time.Now())
+	store := useSessions(t, Config{SessionSecret: "apple", OldSessionSecrets: []string{"banana"}})
+
+	if _, _, ok := findUser(withCookie("GET", "/", old)); !ok {
+		t.Fatalf("a session made with an old secret should still work")
+	}
+
+	w := httptest.NewRecorder()
+	renewSessions(http.NotFoundHandler()).ServeHTTP(w, withCookie("GET", "/", old))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !currentKey(store, withCookie("GET", "/", cookies[0])) {
+		t.Errorf("got cookies %v, want the session issued again with the current secret", cookies)
+	}
+}
+
+func TestEndSession(t *testing.T) {
+	defer keepSessions()()
+	store := useSessions(t, Config{SessionSecret: "banana"})
+	c := sessionCookie(t, store, time.Now())
+
+	w := httptest.NewRecorder()
+	if _, until, ok := endSession(w, withCookie("POST", "/logout", c)); !ok || until.Before(time.Now()) {
+		t.Errorf("got %v, %t, want the session's expiry", until, ok)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("got cookies %v, want the session cookie deleted", cookies)
+	}
+
+	// A copy of the cookie is no use now
+	if _, _, ok := findUser(withCookie("GET", "/", c)); ok {
+		t.Errorf("a session should not log anyone in after logging out")
+	}
+}
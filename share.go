@@ -2,42 +2,32 @@ package main
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 )
 
 func getShare(w http.ResponseWriter, r *http.Request) {
-	u, _, ok := findUser(r)
-	if !ok {
-		http.Error(w, "you are not logged in", http.StatusUnauthorized)
-		return
-	}
+	u := currentUser(r)
 
 	var share bool
 	if err := db.QueryRow("SELECT share_info FROM users WHERE id = $1", u.UserID).Scan(&share); err != nil {
-		log.Println(err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		databaseError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(share); err != nil {
-		log.Println(err)
+		logError(r.Context(), err)
 	}
 }
 
 func updateShare(w http.ResponseWriter, r *http.Request) {
-	u, _, ok := findUser(r)
-	if !ok {
-		http.Error(w, "you are not logged in", http.StatusUnauthorized)
-		return
-	}
+	u := currentUser(r)
 
 	var share bool
 	if err := json.NewDecoder(r.Body).Decode(&share); err != nil {
-		log.Println(err)
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		logError(r.Context(), err)
+		invalidRequest(w, r, "invalid request")
 		return
 	}
 
@@ -48,8 +38,7 @@ func updateShare(w http.ResponseWriter, r *http.Request) {
 		u.UserID,
 	)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		databaseError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultShutdownTimeout is how long we wait for requests and background work
+// to finish when asked to stop, unless SHUTDOWN_TIMEOUT says otherwise. It's
+// a little under the 30 seconds most platforms give before killing us.
+const defaultShutdownTimeout = 25 * time.Second
+
+// shutdown stops srv taking new requests and waits for the ones it's serving,
+// then stops the background workers and waits for them to finish what they're
+// doing. Whatever isn't done within timeout is abandoned.
+func shutdown(srv *http.Server, stopWorkers context.CancelFunc, workers *sync.WaitGroup, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logWarn(ctx, "gave up waiting for requests to finish", "err", err)
+	}
+
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logInfo(ctx, "shut down cleanly")
+	case <-ctx.Done():
+		logWarn(ctx, "gave up waiting for background work to finish")
+	}
+}
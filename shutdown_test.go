@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("finished"))
+	})}
+	go srv.Serve(l)
+
+	// One request is in flight when we're told to stop
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	// And a worker that takes a moment to wrap up once it's stopped
+	ctx, stop := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	var finished bool
+	workers.Add(1)
+	go func() {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+		workers.Done()
+	}()
+
+	shutdown(srv, stop, &workers, time.Second)
+
+	if got := <-body; got != "finished" {
+		t.Errorf("in flight request should finish, got %q", got)
+	}
+	if !finished {
+		t.Errorf("shutdown should wait for workers")
+	}
+	if _, err := http.Get("http://" + l.Addr().String()); err == nil {
+		t.Errorf("new requests should be refused")
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// others are the sources searched alongside GitHub, configured when we start.
+var others = otherSources()
+
+// otherSources builds the IssueSources other than GitHub that config asks
+// for: GitLab for config.GitLabProjects and Gitea for config.GiteaProjects.
+func otherSources() []IssueSource {
+	var srcs []IssueSource
+	if len(config.GitLabProjects) > 0 {
+		srcs = append(srcs, newGitLab(config.GitLabURL, config.GitLabToken, config.GitLabProjects))
+	}
+	if len(config.GiteaProjects) > 0 {
+		srcs = append(srcs, newGitea(config.GiteaURL, config.GiteaToken, config.GiteaProjects))
+	}
+	return srcs
+}
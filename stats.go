@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// issueStats is what /api/v1/stats gives back: how many of the issues we're
+// listing there are, broken down a few ways. Each breakdown is sorted by
+// count, most first, then by name, except Opened which goes by day.
+type issueStats struct {
+	Total     int             `json:"total"`
+	Languages []LanguageCount `json:"languages"`
+	Repos     []RepoCount     `json:"repos"`
+	Labels    []LabelCount    `json:"labels"`
+
+	// Opened is how many were opened each day, in UTC
+	Opened []DayCount `json:"opened"`
+}
+
+// RepoCount is how many issues a repo, like devict/hacktoberfest, has.
+type RepoCount struct {
+	Repo  string `json:"repo"`
+	Count int    `json:"count"`
+}
+
+// LabelCount is how many issues have a label.
+type LabelCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// DayCount is how many issues were opened on a day, like 2017-10-02.
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// stats gives the issueStats of the standard listing, narrowed down by the
+// same filters as listing issues if any are given. The languages are counted
+// before filtering by them so the choices don't narrow down to the one
+// picked, like the home page does.
+func stats(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+	f, err := parseFilter(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	issues, err := loadIssues(r.Context(), c, defaultParams(c.Scope()), refreshParam(r))
+	if !servable(err) || (err != nil && strictParam(r)) {
+		upstreamError(w, r, err)
+		return
+	}
+	setStale(w, err)
+
+	s := countIssues(f.apply(issues))
+	anyLang := f
+	anyLang.langs = nil
+	s.Languages = countLanguages(anyLang.apply(issues))
+
+	_, stale := err.(*staleError)
+	writeEnvelope(w, r, apiEnvelope{Data: s, Meta: apiMeta{Total: s.Total, Stale: stale}, Errors: incomplete(err).Warnings()})
+}
+
+// countIssues works out the issueStats of issues.
+func countIssues(issues []Issue) issueStats {
+	repos := make(map[string]int)
+	labels := make(map[string]int)
+	days := make(map[string]int)
+	for _, i := range issues {
+		repos[i.Repo.Owner+"/"+i.Repo.Name]++
+		for l := range i.Labels {
+			labels[l]++
+		}
+		days[i.Date.UTC().Format("2006-01-02")]++
+	}
+
+	s := issueStats{
+		Total:     len(issues),
+		Languages: countLanguages(issues),
+		Repos:     []RepoCount{},
+		Labels:    []LabelCount{},
+		Opened:    []DayCount{},
+	}
+	for _, name := range byCount(repos) {
+		s.Repos = append(s.Repos, RepoCount{Repo: name, Count: repos[name]})
+	}
+	for _, name := range byCount(labels) {
+		s.Labels = append(s.Labels, LabelCount{Label: name, Count: labels[name]})
+	}
+	for _, day := range sortedKeys(present(days)) {
+		s.Opened = append(s.Opened, DayCount{Day: day, Count: days[day]})
+	}
+	return s
+}
+
+// byCount gives the keys of counts, most first, then by name.
+func byCount(counts map[string]int) []string {
+	keys := sortedKeys(present(counts))
+	sort.SliceStable(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+	return keys
+}
+
+// present is the set of keys in counts.
+func present(counts map[string]int) map[string]bool {
+	keys := make(map[string]bool, len(counts))
+	for k := range counts {
+		keys[k] = true
+	}
+	return keys
+}
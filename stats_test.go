@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCountIssues(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 10, d, 12, 0, 0, 0, time.UTC) }
+	data := []Issue{
+		{Repo: Repo{Owner: "devict", Name: "hacktoberfest"}, Labels: map[string]string{"hacktoberfest": "", "bug": ""}, Languages: []string{"Go"}, Date: day(2)},
+		{Repo: Repo{Owner: "devict", Name: "hacktoberfest"}, Labels: map[string]string{"hacktoberfest": ""}, Languages: []string{"Go"}, Date: day(2)},
+		{Repo: Repo{Owner: "wichita", Name: "site"}, Labels: map[string]string{"hacktoberfest": ""}, Languages: []string{"Ruby"}, Date: day(1)},
+	}
+
+	want := issueStats{
+		Total:     3,
+		Languages: []LanguageCount{{"Go", 2}, {"Ruby", 1}},
+		Repos:     []RepoCount{{"devict/hacktoberfest", 2}, {"wichita/site", 1}},
+		Labels:    []LabelCount{{"hacktoberfest", 3}, {"bug", 1}},
+		Opened:    []DayCount{{"2017-10-01", 1}, {"2017-10-02", 2}},
+	}
+	if got := countIssues(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+		t.Errorf("want %+v", want)
+	}
+
+	if got := countIssues(nil); got.Repos == nil || got.Labels == nil || got.Opened == nil {
+		t.Errorf("got %+v, want empty lists rather than null", got)
+	}
+}
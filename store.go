@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Fetched issues are kept in the database as well as the cache so we still
+// have something to show after a restart or while GitHub is down. Each issue
+// is a row in issues, with the languages of its repo, and issue_searches
+// records which issues each set of searches (by searchParams.key) last found.
+
+// saveIssues records issues as what the searches for key found just now.
+func saveIssues(key string, issues []Issue) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	urls := []string{}
+	for _, i := range issues {
+		data, err := json.Marshal(i)
+		if err != nil {
+			return errors.Wrap(err, "could not encode issue")
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO issues (url, owner, repo, data, first_seen, last_seen) VALUES ($1, $2, $3, $4, $5, $5)
+			ON CONFLICT (url) DO UPDATE SET data = $4, last_seen = $5`,
+			i.URL,
+			i.Repo.Owner,
+			i.Repo.Name,
+			data,
+			now,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not save issue")
+		}
+		urls = append(urls, i.URL)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO issue_searches (key, urls, fetched_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET urls = $2, fetched_at = $3`,
+		key,
+		pq.Array(urls),
+		now,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not save search")
+	}
+
+	return errors.Wrap(tx.Commit(), "could not commit issues")
+}
+
+// storedIssues gives the issues the searches for key last found and when that
+// was. ok is false if they've never been saved.
+func storedIssues(key string) (issues []Issue, fetched time.Time, ok bool, err error) {
+	err = db.QueryRow("SELECT fetched_at FROM issue_searches WHERE key = $1", key).Scan(&fetched)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrap(err, "could not query search")
+	}
+
+	rows, err := db.Query(
+		`SELECT i.data FROM issue_searches s JOIN issues i ON i.url = ANY(s.urls)
+		WHERE s.key = $1 ORDER BY array_position(s.urls, i.url)`,
+		key,
+	)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrap(err, "could not query issues")
+	}
+	defer rows.Close()
+
+	issues = []Issue{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, time.Time{}, false, errors.Wrap(err, "could not scan issue")
+		}
+
+		var i Issue
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, time.Time{}, false, errors.Wrap(err, "could not decode issue")
+		}
+		issues = append(issues, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, time.Time{}, false, errors.Wrap(err, "could not iterate over issues")
+	}
+
+	return issues, fetched, true, nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// issueMarks is everything listIssues marks issues with, loaded once so
+// streams can mark each issue as it's found: whoever's asking's bookmarks and
+// hidden issues, claims, mentors and which issues have closed.
+type issueMarks struct {
+	bookmarks map[string]bool
+	hidden    map[string]bool
+	claims    map[string]claim
+	mentors   []mentor
+	asking    string
+	closed    map[string]bool
+}
+
+// loadMarks gets the issueMarks for whoever r is from, leaving their hidden
+// issues in if includeHidden. Like personalize, if we can't tell we'd rather
+// show too much than nothing.
+func loadMarks(r *http.Request, includeHidden bool) issueMarks {
+	m := issueMarks{closed: closures.urls()}
+	if db == nil {
+		return m
+	}
+
+	var err error
+	if u, _, ok := findUser(r); ok {
+		m.asking = u.NickName
+		if m.bookmarks, err = userURLs("bookmarks", u.UserID); err != nil {
+			logError(r.Context(), err)
+		}
+		if !includeHidden {
+			if m.hidden, err = userURLs("hidden", u.UserID); err != nil {
+				logError(r.Context(), err)
+			}
+		}
+	}
+	if m.claims, err = activeClaims(time.Now()); err != nil {
+		logError(r.Context(), err)
+	}
+	if m.mentors, err = loadMentors(); err != nil {
+		logError(r.Context(), err)
+	}
+	return m
+}
+
+// mark gives i marked the way listIssues would, or false if it's hidden.
+func (m issueMarks) mark(i Issue) (Issue, bool) {
+	if m.hidden[i.URL] {
+		return i, false
+	}
+	one := []Issue{i}
+	markBookmarked(one, m.bookmarks)
+	markClaimed(one, m.claims)
+	markMentors(one, m.mentors, m.asking)
+	markClosed(one, m.closed)
+	return one[0], true
+}
+
+// sendIssues passes each issue for p that passes f to send as soon as it's
+// found, straight from the cache if it's fresh, marked with m. It gives back
+// how many were sent so callers know whether they've started their response.
+// Like loadIssues, an *incompleteError means some searches failed but the
+// others' issues were all sent.
+func sendIssues(r *http.Request, c *Client, p searchParams, f issueFilter, m issueMarks, send func(Issue) error) (int, error) {
+	p.credentials = c.credentials()
+	var sent int
+	found := func(i Issue) error {
+		if !f.keep(i) {
+			return nil
+		}
+		i, ok := m.mark(i)
+		if !ok {
+			return nil
+		}
+		sent++
+		return send(i)
+	}
+
+	// Cached issues don't need a search at all. Shared clients have to wait
+	// for a fetch through the cache like everyone else so we don't make one
+	// set of searches per visitor.
+	var issues []Issue
+	var warn *incompleteError
+	var cached bool
+	if c.Shared {
+		var err error
+		issues, err = loadIssues(r.Context(), c, p, false)
+		if !servable(err) {
+			return 0, err
+		}
+		warn = incomplete(err)
+		cached = true
+	} else if !refreshParam(r) {
+		var e cacheEntry
+		e, cached = cache.entry(p.key())
+		issues, warn = e.issues, e.incomplete
+	}
+
+	if cached {
+		for _, i := range issues {
+			if err := found(i); err != nil {
+				return sent, err
+			}
+		}
+		if warn != nil {
+			return sent, warn
+		}
+		return sent, nil
+	}
+
+	err := streamIssues(r.Context(), c.Sources(), p, found)
+	return sent, err
+}
+
+// wantsNDJSON reports whether r asks for issues as newline delimited JSON,
+// with ?format=ndjson or by accepting application/x-ndjson.
+func wantsNDJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// streamNDJSON writes issues for p that pass f, and are within q, as newline
+// delimited JSON marked with m, flushing each one as soon as it is found so
+// clients can start rendering before the whole search is done.
+func streamNDJSON(w http.ResponseWriter, r *http.Request, c *Client, p searchParams, f issueFilter, q issueQuota, m issueMarks) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	keep := q.keeper()
+	sent, err := sendIssues(r, c, p, f, m, func(i Issue) error {
+		if !keep(i) {
+			return nil
+		}
+		if err := enc.Encode(i); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	// There's nowhere to put warnings in the stream so all we can do is
+	// stop short in strict mode
+	if incomplete(err) != nil && !strictParam(r) {
+		return
+	}
+	// Once we've started streaming the status is already on its way so all we
+	// can do is stop early
+	switch {
+	case err != nil && sent == 0:
+		upstreamError(w, r, err)
+	case err != nil:
+		logError(r.Context(), err)
+	}
+}
+
+// issueStream sends the tracked issues as server-sent events. Each one is an
+// "issue" event with the issue as JSON. A "done" event follows the last of
+// them, or an "error" event if the search failed part way. If only some
+// label searches failed each gets a "warning" event before "done", or with
+// ?strict=true the stream ends in "error" instead. It takes the same
+// query parameters as issues, apart from sort and paging, and leaves out the
+// issues whoever's asking has hidden unless ?include_hidden=true.
+func issueStream(w http.ResponseWriter, r *http.Request) {
+	c, ok := issueClient(r)
+	if !ok {
+		notLoggedIn(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		internalError(w, r, errors.New("streaming is not supported"))
+		return
+	}
+
+	r = preferred(r)
+	p, err := parseParams(r, defaultParams(c.Scope()))
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	f, err := parseFilter(r)
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	includeHidden, err := boolParam(r, "include_hidden")
+	if err != nil {
+		invalidParameter(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	_, err = sendIssues(r, c, p, f, loadMarks(r, includeHidden), func(i Issue) error {
+		return writeEvent(w, flusher, "issue", i)
+	})
+	if warn := incomplete(err); warn != nil && !strictParam(r) {
+		for _, sw := range warn.Warnings() {
+			writeEvent(w, flusher, "warning", sw)
+		}
+		err = nil
+	}
+	if err != nil {
+		logError(r.Context(), err)
+		writeEvent(w, flusher, "error", "could not fetch issues")
+		return
+	}
+	writeEvent(w, flusher, "done", struct{}{})
+}
+
+// writeEvent sends v as JSON in a server-sent event called name.
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendIssuesFromCache(t *testing.T) {
+	p := searchParams{scope: "repo:devict/stream-test", labels: []string{"hacktoberfest"}}
+	cache.set(p, []Issue{
+		{Title: "a", Languages: []string{"Go"}},
+		{Title: "b", Languages: []string{"Rust"}},
+	})
+	defer cache.invalidate()
+
+	r := httptest.NewRequest("GET", "/api/issues/stream?lang=go", nil)
+	f, err := parseFilter(r)
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	// The client has nowhere to search so anything but the cache would fail
+	var got []string
+	sent, err := sendIssues(r, &Client{}, p, f, issueMarks{}, func(i Issue) error {
+		got = append(got, i.Title)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("error should be nil, got %v", err)
+	}
+	if sent != 1 || len(got) != 1 || got[0] != "a" {
+		t.Errorf("got %d sent %v, want just a", sent, got)
+	}
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	p := searchParams{scope: "repo:devict/stream-test", labels: []string{"hacktoberfest"}}
+	cache.set(p, []Issue{{Title: "a"}, {Title: "b"}})
+	defer cache.invalidate()
+
+	accept := httptest.NewRequest("GET", "/api/v1/issues", nil)
+	accept.Header.Set("Accept", "application/x-ndjson")
+	for _, r := range []*http.Request{httptest.NewRequest("GET", "/api/v1/issues?format=ndjson", nil), accept} {
+		if !wantsNDJSON(r) {
+			t.Errorf("%s with Accept %q should want NDJSON", r.URL, r.Header.Get("Accept"))
+		}
+	}
+	if wantsNDJSON(httptest.NewRequest("GET", "/api/v1/issues?format=csv", nil)) {
+		t.Error("csv shouldn't want NDJSON")
+	}
+
+	r := httptest.NewRequest("GET", "/api/v1/issues?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	streamNDJSON(w, r, &Client{}, p, issueFilter{}, issueQuota{}, issueMarks{})
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %q, want an issue a line", w.Body)
+	}
+	for n, want := range []string{"a", "b"} {
+		var i Issue
+		if err := json.Unmarshal([]byte(lines[n]), &i); err != nil || i.Title != want {
+			t.Errorf("line %d: got %q, %v, want %s", n, lines[n], err, want)
+		}
+	}
+	if !w.Flushed {
+		t.Error("issues should be flushed as they go")
+	}
+}
+
+func TestSendIssuesMarks(t *testing.T) {
+	p := searchParams{scope: "repo:devict/stream-test", labels: []string{"hacktoberfest"}}
+	cache.set(p, []Issue{{Title: "a", URL: "a"}, {Title: "b", URL: "b"}, {Title: "c", URL: "c"}})
+	defer cache.invalidate()
+
+	m := issueMarks{
+		bookmarks: map[string]bool{"a": true},
+		hidden:    map[string]bool{"b": true},
+		claims:    map[string]claim{"c": {By: "someone"}},
+		closed:    map[string]bool{"c": true},
+	}
+	r := httptest.NewRequest("GET", "/api/v1/issues?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	streamNDJSON(w, r, &Client{}, p, issueFilter{}, issueQuota{}, m)
+
+	var got []Issue
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var i Issue
+		if err := json.Unmarshal([]byte(line), &i); err != nil {
+			t.Fatalf("got %q, %v", line, err)
+		}
+		got = append(got, i)
+	}
+	if len(got) != 2 || got[0].Title != "a" || got[1].Title != "c" {
+		t.Fatalf("got %+v, want a and c without the hidden b", got)
+	}
+	if !got[0].Bookmarked || got[0].Closed || got[0].Claimed {
+		t.Errorf("got a %+v, want it bookmarked", got[0])
+	}
+	if got[1].Bookmarked || !got[1].Closed || got[1].ClaimedBy != "someone" {
+		t.Errorf("got c %+v, want it claimed and closed", got[1])
+	}
+
+	// Marking is only for this request, the cache stays as it was
+	if e, _ := cache.entry(p.key()); e.issues[0].Bookmarked || e.issues[2].Claimed {
+		t.Errorf("cache was marked: %+v", e.issues)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := writeEvent(w, w, "issue", Issue{Title: "Fix it"}); err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	want := "event: issue\ndata: {\"title\":\"Fix it\","
+	if got := w.Body.String(); len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("got %q, want it to start with %q", got, want)
+	}
+	if got := w.Body.String(); got[len(got)-2:] != "\n\n" {
+		t.Errorf("event should end with a blank line, got %q", got)
+	}
+	if !w.Flushed {
+		t.Errorf("event should be flushed")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+	"github.com/pkg/errors"
+)
+
+// Maintainers can ask for their repo to be tracked. Submissions are a row
+// each in submissions, pending until an admin approves or rejects them. Only
+// people who can push to a repo can submit it, and approving one adds it to
+// the tracked projects like an admin adding it themselves.
+
+// maxSubmission is the biggest body submitting a repo reads, far more than a
+// repo's name needs.
+const maxSubmission = 4 << 10
+
+// Submission is a repo someone asked us to track.
+type Submission struct {
+	Repo        string    `json:"repo"`
+	SubmittedBy string    `json:"submitted_by"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// canPush looks up repo, like owner/name, as whoever c's token belongs to and
+// reports whether they can push to it. It also gives back the repo's name the
+// way GitHub writes it.
+func (c *Client) canPush(ctx context.Context, repo string) (name string, ok bool, err error) {
+	var data struct {
+		FullName    string `json:"full_name"`
+		Permissions struct {
+			Admin    bool `json:"admin"`
+			Maintain bool `json:"maintain"`
+			Push     bool `json:"push"`
+		} `json:"permissions"`
+	}
+	if err := c.Get(ctx, "/repos/"+repo, nil, &data); err != nil {
+		return "", false, err
+	}
+
+	p := data.Permissions
+	return data.FullName, p.Admin || p.Maintain || p.Push, nil
+}
+
+// submitRepo records the repo in the body, like {"repo": "owner/name"}, as
+// submitted by whoever is logged in, as long as they can push to it.
+func submitRepo(w http.ResponseWriter, r *http.Request) {
+	u := currentUser(r)
+	if u.AccessToken == "" {
+		noGitHub(w, r)
+		return
+	}
+
+	var body struct {
+		Repo string `json:"repo"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubmission)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.Count(body.Repo, "/") != 1 {
+		invalidRequest(w, r, "invalid request, repo should look like owner/name")
+		return
+	}
+
+	c := newClient(u.AccessToken)
+	name, ok, err := c.canPush(r.Context(), strings.TrimSpace(body.Repo))
+	if se, isStatus := err.(*aggregator.StatusError); isStatus && se.Code == http.StatusNotFound {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "repo "+body.Repo+" doesn't exist or you can't see it", nil)
+		return
+	}
+	if err != nil {
+		upstreamError(w, r, err)
+		return
+	}
+	if !ok {
+		writeError(w, r, http.StatusForbidden, codeForbidden, "only people who can push to "+name+" can submit it", nil)
+		return
+	}
+	if tracking().Projects[name] {
+		writeError(w, r, http.StatusConflict, codeConflict, name+" is already tracked", nil)
+		return
+	}
+
+	// Submitting again after being rejected puts it back in line
+	_, err = db.Exec(
+		`INSERT INTO submissions (repo, submitted_by, status, submitted_at) VALUES ($1, $2, 'pending', $3)
+		ON CONFLICT (repo) DO UPDATE SET submitted_by = $2, status = 'pending', submitted_at = $3, decided_by = NULL, decided_at = NULL`,
+		name,
+		u.NickName,
+		time.Now(),
+	)
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// adminSubmissions lists the submissions waiting on an admin, oldest first.
+func adminSubmissions(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query("SELECT repo, submitted_by, submitted_at FROM submissions WHERE status = 'pending' ORDER BY submitted_at")
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	subs := []Submission{}
+	for rows.Next() {
+		var s Submission
+		if err := rows.Scan(&s.Repo, &s.SubmittedBy, &s.SubmittedAt); err != nil {
+			databaseError(w, r, err)
+			return
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		databaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(subs); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// decide marks the pending submission of repo with status, giving
+// sql.ErrNoRows if there isn't one.
+func decide(repo, status, by string) error {
+	res, err := db.Exec(
+		"UPDATE submissions SET status = $2, decided_by = $3, decided_at = $4 WHERE repo = $1 AND status = 'pending'",
+		repo,
+		status,
+		by,
+		time.Now(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not update submission")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "could not count updated submissions")
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// approveSubmission starts tracking a submitted repo.
+func approveSubmission(w http.ResponseWriter, r *http.Request) {
+	decideSubmission(w, r, "approved")
+}
+
+// rejectSubmission turns down a submitted repo.
+func rejectSubmission(w http.ResponseWriter, r *http.Request) {
+	decideSubmission(w, r, "rejected")
+}
+
+// decideSubmission is the shared part of approving and rejecting.
+func decideSubmission(w http.ResponseWriter, r *http.Request, status string) {
+	u := currentUser(r)
+
+	repo := r.URL.Query().Get(":owner") + "/" + r.URL.Query().Get(":repo")
+	err := decide(repo, status, u.NickName)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "no pending submission for "+repo, nil)
+		return
+	}
+	if err == nil && status == "approved" {
+		err = saveChange(trackedChange{Kind: "project", Name: repo, Active: true}, u.NickName)
+	}
+	if err != nil {
+		databaseError(w, r, err)
+		return
+	}
+	audit(r, "submission."+status, repo)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestCanPush(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/devict/hacktoberfest":
+			w.Write([]byte(`{"full_name": "devict/hacktoberfest", "permissions": {"admin": false, "maintain": false, "push": true}}`))
+		case "/repos/DEVICT/site":
+			w.Write([]byte(`{"full_name": "devict/site", "permissions": {"admin": false, "push": false, "pull": true}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		repo string
+		name string
+		ok   bool
+		code int
+	}{
+		{"devict/hacktoberfest", "devict/hacktoberfest", true, 0},
+		{"DEVICT/site", "devict/site", false, 0},
+		{"devict/secret", "", false, http.StatusNotFound},
+	}
+
+	c := testClient(srv)
+	for _, test := range tests {
+		name, ok, err := c.canPush(context.Background(), test.repo)
+		if test.code != 0 {
+			if se, isStatus := err.(*aggregator.StatusError); !isStatus || se.Code != test.code {
+				t.Errorf("%q: got error %v, want status %d", test.repo, err, test.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.repo, err)
+			continue
+		}
+		if name != test.name || ok != test.ok {
+			t.Errorf("%q: got %q, %v, want %q, %v", test.repo, name, ok, test.name, test.ok)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "time"
+
+// incompleteRetry is how soon we search again after a search that didn't
+// finish, rather than keep serving what we could get until the cache expires.
+const incompleteRetry = 30 * time.Second
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// People's GitHub access tokens are kept in their session cookie. The cookie
+// is already encrypted with SessionSecret, but with TokenKey the token is
+// encrypted again on its own with AES-GCM, so getting hold of the session
+// secret or a session isn't enough to act as them on GitHub. Each sealed
+// token is tied to the user it belongs to so it can't be moved to another
+// session. When they log out the token is revoked too.
+
+// tokenCipher seals and opens access tokens, or is nil if there's no
+// TokenKey and they're kept as they are.
+var tokenCipher cipher.AEAD
+
+// sealedPrefix starts sealed tokens so they can be told apart from ones kept
+// before there was a TokenKey.
+const sealedPrefix = "sealed:"
+
+// newTokenCipher makes the AES-GCM cipher for key, which is the base64 of
+// 16, 24 or 32 random bytes. No key is no cipher.
+func newTokenCipher(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "token key should be base64")
+	}
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "token key should be 16, 24 or 32 bytes")
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealToken encrypts userID's token with tokenCipher, if there is one.
+func sealToken(userID, token string) (string, error) {
+	if tokenCipher == nil || token == "" {
+		return token, nil
+	}
+	nonce := make([]byte, tokenCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "could not make nonce")
+	}
+	sealed := tokenCipher.Seal(nonce, nonce, []byte(token), []byte(userID))
+	return sealedPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openToken decrypts a token sealToken gave for userID. Tokens kept before
+// there was a TokenKey are given back as they are, but once there is one a
+// sealed token is the only kind we trust.
+func openToken(userID, token string) (string, error) {
+	if !strings.HasPrefix(token, sealedPrefix) {
+		if tokenCipher != nil && token != "" {
+			return "", errors.New("token isn't sealed")
+		}
+		return token, nil
+	}
+	if tokenCipher == nil {
+		return "", errors.New("token is sealed but there's no token key")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, sealedPrefix))
+	if err != nil || len(b) < tokenCipher.NonceSize() {
+		return "", errors.New("sealed token is malformed")
+	}
+	n := tokenCipher.NonceSize()
+	plain, err := tokenCipher.Open(nil, b[:n], b[n:], []byte(userID))
+	if err != nil {
+		return "", errors.Wrap(err, "could not open token")
+	}
+	return string(plain), nil
+}
+
+// revokeToken asks GitHub to stop accepting token, which our OAuth app gave
+// out, so a copy of it is no more use than the session it was in.
+func revokeToken(ctx context.Context, c Config, token string) error {
+	if token == "" || c.ClientID == "" {
+		return nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"access_token": token})
+	api, _ := githubAPI(c.GitHubURL)
+	req, err := http.NewRequest("DELETE", api+"/applications/"+c.ClientID+"/token", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not make revoke request")
+	}
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+	resp, err := upstream.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "could not revoke token")
+	}
+	resp.Body.Close()
+
+	// 404 is a token that's already gone
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return errors.Errorf("revoking token got status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// useTokenKey seals tokens with key until the func it gives back puts the
+// old cipher back.
+func useTokenKey(t *testing.T, key string) func() {
+	old := tokenCipher
+	c, err := newTokenCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenCipher = c
+	return func() { tokenCipher = old }
+}
+
+func TestSealToken(t *testing.T) {
+	defer useTokenKey(t, "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")()
+
+	sealed, err := sealToken("1", "gho_secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "gho_secret") {
+		t.Errorf("sealed token %q should be encrypted", sealed)
+	}
+	if again, _ := sealToken("1", "gho_secret"); again == sealed {
+		t.Errorf("sealing twice should use a new nonce")
+	}
+
+	if token, err := openToken("1", sealed); err != nil || token != "gho_secret" {
+		t.Errorf("got %q, %v, want the token back", token, err)
+	}
+	if _, err := openToken("2", sealed); err == nil {
+		t.Errorf("someone else's sealed token should not open")
+	}
+	if _, err := openToken("1", "gho_plain"); err == nil {
+		t.Errorf("an unsealed token should not be trusted once there's a key")
+	}
+
+	// Without a key tokens are kept as they are
+	tokenCipher = nil
+	if token, _ := sealToken("1", "gho_secret"); token != "gho_secret" {
+		t.Errorf("got %q, want the token as it is without a key", token)
+	}
+	if _, err := openToken("1", sealed); err == nil {
+		t.Errorf("a sealed token should not open without a key")
+	}
+}
+
+func TestNewTokenCipher(t *testing.T) {
+	for _, key := range []string{"not base64!", "c2hvcnQ="} {
+		if _, err := newTokenCipher(key); err == nil {
+			t.Errorf("key %q should be an error", key)
+		}
+	}
+	if c, err := newTokenCipher(""); c != nil || err != nil {
+		t.Errorf("no key should be no cipher, got %v %v", c, err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	var revoked string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/v3/applications/id/token" {
+			t.Errorf("got %s %s, want DELETE /applications/id/token", r.Method, r.URL.Path)
+		}
+		if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.AccessToken == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		revoked = body.AccessToken
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := defaultConfig()
+	c.GitHubURL, c.ClientID, c.ClientSecret = srv.URL, "id", "secret"
+	if err := revokeToken(context.Background(), c, "gho_secret"); err != nil || revoked != "gho_secret" {
+		t.Errorf("got %v revoking, with %q revoked", err, revoked)
+	}
+	if err := revokeToken(context.Background(), c, "gone"); err != nil {
+		t.Errorf("got %v revoking a token that's already gone", err)
+	}
+
+	c.ClientSecret = "wrong"
+	if err := revokeToken(context.Background(), c, "gho_secret"); err == nil {
+		t.Errorf("a refused revoke should be an error")
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// This is just enough tracing to see where a slow search spent its time:
+// spans carried in contexts and sent in batches to an OpenTelemetry collector
+// as OTLP JSON over HTTP. It's off unless OTEL_EXPORTER_OTLP_ENDPOINT is set.
+
+// spans is where finished spans go, nil when tracing is off.
+var spans *spanExporter
+
+// Span kinds, as OTLP numbers them.
+const (
+	spanInternal = 1
+	spanServer   = 2
+	spanClient   = 3
+)
+
+// span is one timed operation. Its methods can be called on a nil span, which
+// is what startSpan gives out when tracing is off, and do nothing. A span is
+// only meant to be used from the goroutine that started it.
+type span struct {
+	name     string
+	kind     int
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+}
+
+type spanKey struct{}
+
+// startSpan starts a span that's a child of the one in ctx, if there is one,
+// and gives back a context carrying it for the children of its own.
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *span) {
+	if spans == nil {
+		return ctx, nil
+	}
+
+	s := &span{name: name, kind: kind, start: time.Now(), attrs: make(map[string]string)}
+	if parent := spanFrom(ctx); parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// spanFrom is the span ctx carries, or nil.
+func spanFrom(ctx context.Context) *span {
+	s, _ := ctx.Value(spanKey{}).(*span)
+	return s
+}
+
+// linkSpan gives a background context carrying only the span in ctx, for work
+// that outlives the request it was started for but still belongs in its
+// trace.
+func linkSpan(ctx context.Context) context.Context {
+	if s := spanFrom(ctx); s != nil {
+		return context.WithValue(context.Background(), spanKey{}, s)
+	}
+	return context.Background()
+}
+
+// remoteParent continues the trace a caller started, going by its W3C
+// traceparent header, like 00-<trace id>-<span id>-01.
+func remoteParent(ctx context.Context, header string) context.Context {
+	if spans == nil {
+		return ctx
+	}
+
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var s span
+	if _, err := hex.Decode(s.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &s)
+}
+
+func (s *span) set(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// finish ends s, marking it failed if err isn't nil, and hands it off to be
+// exported.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	spans.add(s)
+}
+
+// Set and Finish are set and finish, so a span can be an aggregator.Span.
+func (s *span) Set(key, value string) {
+	s.set(key, value)
+}
+
+func (s *span) Finish(err error) {
+	s.finish(err)
+}
+
+// spanExporter sends finished spans to a collector in batches.
+type spanExporter struct {
+	url     string
+	service string
+	HTTP    *http.Client
+
+	ch chan *span
+}
+
+// maxBatch is the most spans we send at once.
+const maxBatch = 512
+
+// newSpanExporter exports to the collector at endpoint, like
+// http://localhost:4318.
+func newSpanExporter(endpoint, service string) *spanExporter {
+	return &spanExporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+		ch:      make(chan *span, 4*maxBatch),
+	}
+}
+
+// add queues s to be sent. If the collector can't keep up we drop spans rather
+// than slow down requests.
+func (e *spanExporter) add(s *span) {
+	select {
+	case e.ch <- s:
+	default:
+	}
+}
+
+// run sends whatever spans have finished every interval, or sooner if there
+// are enough of them. Once ctx is done it sends what's left and returns.
+func (e *spanExporter) run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var batch []*span
+	for {
+		select {
+		case s := <-e.ch:
+			if batch = append(batch, s); len(batch) < maxBatch {
+				continue
+			}
+		case <-t.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-ctx.Done():
+			for len(e.ch) > 0 && len(batch) < maxBatch {
+				batch = append(batch, <-e.ch)
+			}
+			if len(batch) > 0 {
+				if err := e.export(batch); err != nil {
+					logError(ctx, err)
+				}
+			}
+			return
+		}
+
+		if err := e.export(batch); err != nil {
+			logError(context.Background(), err)
+		}
+		batch = nil
+	}
+}
+
+// otlp types are the parts of OTLP's JSON encoding we use. IDs are hex and
+// times are nanoseconds given as strings.
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []otlpAttr `json:"attributes,omitempty"`
+	Status       struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func attr(key, value string) otlpAttr {
+	a := otlpAttr{Key: key}
+	a.Value.StringValue = value
+	return a
+}
+
+// export sends batch to the collector.
+func (e *spanExporter) export(batch []*span) error {
+	var scope otlpScopeSpans
+	scope.Scope.Name = "hacktoberfest"
+	for _, s := range batch {
+		o := otlpSpan{
+			TraceID: hex.EncodeToString(s.traceID[:]),
+			SpanID:  hex.EncodeToString(s.spanID[:]),
+			Name:    s.name,
+			Kind:    s.kind,
+			Start:   strconv.FormatInt(s.start.UnixNano(), 10),
+			End:     strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		keys := make([]string, 0, len(s.attrs))
+		for k := range s.attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			o.Attributes = append(o.Attributes, attr(k, s.attrs[k]))
+		}
+		if s.err != nil {
+			o.Status.Code = 2
+			o.Status.Message = s.err.Error()
+		}
+		scope.Spans = append(scope.Spans, o)
+	}
+
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpAttr{attr("service.name", e.service)}
+	rs.ScopeSpans = []otlpScopeSpans{scope}
+
+	body, err := json.Marshal(otlpTraces{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		return errors.Wrap(err, "could not encode spans")
+	}
+
+	resp, err := e.HTTP.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not export spans")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("could not export spans, status was %d", resp.StatusCode)
+	}
+	return nil
+}
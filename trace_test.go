@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	var got otlpTraces
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("spans sent to %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer collector.Close()
+
+	e := newSpanExporter(collector.URL, "test")
+	spans = e
+	defer func() { spans = nil }()
+
+	ctx := remoteParent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	ctx, parent := startSpan(ctx, "parent", spanServer)
+	_, child := startSpan(ctx, "child", spanClient)
+	child.set("label", "hacktoberfest")
+	child.finish(errors.New("stalled"))
+	parent.finish(nil)
+
+	if err := e.export([]*span{<-e.ch, <-e.ch}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	out := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(out) != 2 {
+		t.Fatalf("got %d spans, want 2", len(out))
+	}
+	c, p := out[0], out[1]
+
+	if p.TraceID != "0af7651916cd43dd8448eb211c80319c" || p.ParentSpanID != "b7ad6b7169203331" {
+		t.Errorf("parent should continue the remote trace, got %+v", p)
+	}
+	if c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID {
+		t.Errorf("child should be under parent, got %+v", c)
+	}
+	if c.Status.Code != 2 || c.Status.Message != "stalled" {
+		t.Errorf("child should have failed, got %+v", c.Status)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Key != "label" || c.Attributes[0].Value.StringValue != "hacktoberfest" {
+		t.Errorf("got attributes %+v", c.Attributes)
+	}
+}
+
+func TestSpansOff(t *testing.T) {
+	ctx, s := startSpan(context.Background(), "nothing", spanInternal)
+	s.set("ignored", "yes")
+	s.finish(nil)
+	if s != nil || spanFrom(ctx) != nil {
+		t.Errorf("there should be no span when tracing is off")
+	}
+}
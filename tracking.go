@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Tracking is what we look for on GitHub: any project under one of the Orgs
+// counts, as do the specific Projects (given as owner/name). Issues are listed
+// if they have any of the Labels. Anything Excluded, whether a whole owner or
+// an owner/name, is left out even if it would otherwise count. Events are the
+// fests other than Hacktoberfest we list issues for too, see Event.
+//
+// A Tracking is never modified once it's in use. Reloading swaps in a whole
+// new one so the maps can be read without locking.
+type Tracking struct {
+	Orgs     map[string]bool
+	Projects map[string]bool
+	Labels   map[string]bool
+	Excluded map[string]bool
+	Events   []Event
+}
+
+// defaultTracking is used for anything not set by the tracking file or the
+// environment.
+var defaultTracking = Tracking{
+	Orgs: map[string]bool{
+		"devict":         true,
+		"MakeICT":        true,
+		"openwichita":    true,
+		"StartupWichita": true,
+		"Wichitalks":     true,
+		"Ennovar":        true,
+	},
+	Projects: map[string]bool{
+		"imacrayon/eventsinwichita": true,
+		"br0xen/boltbrowser":        true,
+		"benblankley/fort-rpg":      true,
+		"chrisl8/ArloBot":           true,
+	},
+	Labels: map[string]bool{
+		"hacktoberfest":     true,
+		"help wanted":       true,
+		"good first issue":  true,
+		"beginner friendly": true,
+	},
+	Excluded: map[string]bool{},
+}
+
+var (
+	trackingMu sync.RWMutex
+	current    = defaultTracking
+)
+
+// tracking gives the Tracking currently in use.
+func tracking() Tracking {
+	trackingMu.RLock()
+	defer trackingMu.RUnlock()
+	return current
+}
+
+// setTracking swaps in t and drops any cached issues found with the old one.
+func setTracking(t Tracking) {
+	trackingMu.Lock()
+	current = t
+	trackingMu.Unlock()
+
+	cache.invalidate()
+}
+
+// excludes reports whether r, or its whole owner, is Excluded.
+func (t Tracking) excludes(r Repo) bool {
+	return t.Excluded[r.Owner] || t.Excluded[r.ID()]
+}
+
+// labelList is the labels sorted by name.
+func (t Tracking) labelList() []string {
+	return sortedKeys(t.Labels)
+}
+
+// sortedKeys gives the keys of m in order.
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadTracking builds a Tracking starting with the defaults, then the JSON
+// file config.TrackingFile if there is one, then the Orgs, Projects, Labels
+// and Excluded in config. Each list that is set replaces the one before it
+// entirely. The file looks like
+//
+//	{
+//	  "orgs": ["devict", "MakeICT"],
+//	  "projects": ["br0xen/boltbrowser"],
+//	  "labels": ["hacktoberfest"],
+//	  "excluded": ["devict/spam"],
+//	  "events": [{"slug": "24-pull-requests", "name": "24 Pull Requests", ...}]
+//	}
+//
+// See eventFile for what goes in an event.
+func loadTracking() (Tracking, error) {
+	t := defaultTracking
+
+	if path := config.TrackingFile; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return Tracking{}, errors.Wrap(err, "could not open tracking file")
+		}
+		defer f.Close()
+
+		var data struct {
+			Orgs     []string    `json:"orgs"`
+			Projects []string    `json:"projects"`
+			Labels   []string    `json:"labels"`
+			Excluded []string    `json:"excluded"`
+			Events   []eventFile `json:"events"`
+		}
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return Tracking{}, errors.Wrap(err, "could not decode tracking file")
+		}
+
+		if data.Orgs != nil {
+			t.Orgs = set(data.Orgs)
+		}
+		if data.Projects != nil {
+			t.Projects = set(data.Projects)
+		}
+		if data.Labels != nil {
+			t.Labels = set(data.Labels)
+		}
+		if data.Excluded != nil {
+			t.Excluded = set(data.Excluded)
+		}
+		if t.Events, err = parseEvents(data.Events); err != nil {
+			return Tracking{}, err
+		}
+	}
+
+	if config.Orgs != nil {
+		t.Orgs = set(config.Orgs)
+	}
+	if config.Projects != nil {
+		t.Projects = set(config.Projects)
+	}
+	if config.Labels != nil {
+		t.Labels = set(config.Labels)
+	}
+	if config.Excluded != nil {
+		t.Excluded = set(config.Excluded)
+	}
+
+	for p := range t.Projects {
+		if strings.Count(p, "/") != 1 {
+			return Tracking{}, errors.Errorf("project %q should look like owner/name", p)
+		}
+	}
+	for e := range t.Excluded {
+		if strings.Count(e, "/") > 1 {
+			return Tracking{}, errors.Errorf("excluded %q should look like owner or owner/name", e)
+		}
+	}
+	if len(t.Labels) == 0 {
+		return Tracking{}, errors.New("at least one label must be tracked")
+	}
+
+	return t, nil
+}
+
+// set makes a map of the non-empty values in list.
+func set(list []string) map[string]bool {
+	m := make(map[string]bool)
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" {
+			m[v] = true
+		}
+	}
+	return m
+}
+
+// reloadOnHangup rebuilds the Tracking every time the process gets a SIGHUP.
+// If the new one can't be built we keep using the old one.
+func reloadOnHangup() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		t, err := buildTracking()
+		if err != nil {
+			logWarn(context.Background(), "could not reload tracking, keeping the old one", "err", err)
+			continue
+		}
+		setTracking(t)
+		logInfo(context.Background(), "reloaded tracking", "orgs", len(t.Orgs), "projects", len(t.Projects), "labels", len(t.Labels))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLoadTracking(t *testing.T) {
+	f, err := ioutil.TempFile("", "tracking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"orgs": ["devict"], "labels": ["good first issue"]}`)
+	f.Close()
+
+	old := config
+	defer func() { config = old }()
+	config.TrackingFile = f.Name()
+	config.Labels = []string{"hacktoberfest", "bug"}
+
+	got, err := loadTracking()
+	if err != nil {
+		t.Fatalf("error should be nil, got %v", err)
+	}
+
+	want := Tracking{
+		Orgs:     map[string]bool{"devict": true},
+		Projects: defaultTracking.Projects,
+		Labels:   map[string]bool{"hacktoberfest": true, "bug": true},
+		Excluded: map[string]bool{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+		t.Errorf("want %+v", want)
+	}
+}
+
+func TestLoadTrackingInvalid(t *testing.T) {
+	old := config
+	defer func() { config = old }()
+	config.Projects = []string{"boltbrowser"}
+
+	if _, err := loadTracking(); err == nil {
+		t.Errorf("error should not be nil, but it was")
+	}
+}
+
+func TestTrackingExcludes(t *testing.T) {
+	tr := Tracking{Excluded: map[string]bool{"spammer": true, "devict/spam": true}}
+
+	tests := []struct {
+		repo Repo
+		want bool
+	}{
+		{Repo{Owner: "spammer", Name: "anything"}, true},
+		{Repo{Owner: "devict", Name: "spam"}, true},
+		{Repo{Owner: "devict", Name: "hacktoberfest"}, false},
+	}
+
+	for _, test := range tests {
+		if got := tr.excludes(test.repo); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.repo.ID(), got, test.want)
+		}
+	}
+}
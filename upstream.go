@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// upstream is the http.Client every call to GitHub and the other forges goes
+// through, sharing one pool of connections. Unlike http.DefaultClient it
+// gives up on a call that hangs, even one made without a deadline of its own,
+// instead of holding up whoever's waiting on it forever. setConfig builds it
+// from the config main loads.
+var upstream = newUpstream(config)
+
+// newUpstream makes the upstream client for c: everything's bounded by
+// c.CallTimeout, each host keeps c.UpstreamIdleConns connections open between
+// calls, and calls go through c.UpstreamProxy, or the proxy in HTTPS_PROXY and
+// HTTP_PROXY if it isn't set. validate has already checked the proxy's URL.
+func newUpstream(c Config) *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if u, err := url.Parse(c.UpstreamProxy); c.UpstreamProxy != "" && err == nil {
+		proxy = http.ProxyURL(u)
+	}
+
+	return &http.Client{
+		Timeout: c.CallTimeout,
+		Transport: &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          4 * c.UpstreamIdleConns,
+			MaxIdleConnsPerHost:   c.UpstreamIdleConns,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: c.CallTimeout,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// useUpstream makes hc the upstream client, closing the connections the one
+// before kept open, and remakes the other sources so they use it too.
+func useUpstream(hc *http.Client) {
+	if tr, ok := upstream.Transport.(*http.Transport); ok {
+		tr.CloseIdleConnections()
+	}
+	upstream = hc
+	others = otherSources()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewUpstream(t *testing.T) {
+	c := defaultConfig()
+	c.CallTimeout = 3 * time.Second
+	c.UpstreamIdleConns = 4
+	c.UpstreamProxy = "http://proxy.example.com:3128"
+
+	hc := newUpstream(c)
+	if hc.Timeout != c.CallTimeout {
+		t.Errorf("got timeout %v, want %v", hc.Timeout, c.CallTimeout)
+	}
+	tr := hc.Transport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != 4 || tr.ResponseHeaderTimeout != c.CallTimeout {
+		t.Errorf("got %d idle connections per host and header timeout %v", tr.MaxIdleConnsPerHost, tr.ResponseHeaderTimeout)
+	}
+	req := httptest.NewRequest("GET", "https://api.github.com/search/issues", nil)
+	if u, err := tr.Proxy(req); err != nil || u == nil || u.Host != "proxy.example.com:3128" {
+		t.Errorf("got proxy %v, %v, want UPSTREAM_PROXY", u, err)
+	}
+}
+
+func TestUpstreamTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := defaultConfig()
+	c.CallTimeout = 50 * time.Millisecond
+	hc := newUpstream(c)
+
+	start := time.Now()
+	if _, err := hc.Get(srv.URL); err == nil {
+		t.Errorf("error should not be nil for a call that hangs, but it was")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("took %v to give up, want about %v", d, c.CallTimeout)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+// minWebhookSecret is the fewest characters validate accepts in
+// config.WebhookSecret.
+const minWebhookSecret = 12
+
+// maxWebhookBody is the biggest webhook body we'll read. GitHub caps the
+// payloads it sends at 25MB, and anyone can post to us before we've checked
+// the signature, so there's no reason to buffer more.
+const maxWebhookBody = 25 << 20
+
+// issueActions are the issue event actions that can change which issues we
+// would list, or what we'd say about them.
+var issueActions = map[string]bool{
+	"opened":      true,
+	"edited":      true,
+	"closed":      true,
+	"reopened":    true,
+	"labeled":     true,
+	"unlabeled":   true,
+	"assigned":    true,
+	"unassigned":  true,
+	"deleted":     true,
+	"transferred": true,
+}
+
+func githubWebhook(w http.ResponseWriter, r *http.Request) {
+	if config.WebhookSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logError(r.Context(), err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(body, r.Header.Get("X-Hub-Signature-256"), config.WebhookSecret) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event struct {
+		Action      string               `json:"action"`
+		Issue       aggregator.RESTIssue `json:"issue"`
+		PullRequest restPullRequest      `json:"pull_request"`
+		Repository  struct {
+			Name  string `json:"name"`
+			Owner struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+			Stars       int      `json:"stargazers_count"`
+			Description string   `json:"description"`
+			Archived    bool     `json:"archived"`
+			Disabled    bool     `json:"disabled"`
+			Topics      []string `json:"topics"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		logError(r.Context(), err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	repo := Repo{
+		Owner:       event.Repository.Owner.Login,
+		Name:        event.Repository.Name,
+		Stars:       event.Repository.Stars,
+		Description: event.Repository.Description,
+		Archived:    event.Repository.Archived || event.Repository.Disabled,
+		Topics:      event.Repository.Topics,
+	}
+	if repo.Topics == nil {
+		repo.Topics = []string{}
+	}
+	if !tracked(repo) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Everything else, including the ping GitHub sends when the hook is
+	// created, is acknowledged and ignored
+	switch r.Header.Get("X-GitHub-Event") {
+	case "issues":
+		if issueActions[event.Action] {
+			logInfo(r.Context(), "updating cache for issue event", "action", event.Action, "repo", repo.Owner+"/"+repo.Name)
+			applyIssueEvent(r.Context(), serverClient(), event.Action, event.Issue, repo)
+		}
+
+	// Pull requests for issues we listed count towards the leaderboard
+	case "pull_request":
+		if pullRequestActions[event.Action] && db != nil {
+			if err := recordPullRequest(r.Context(), event.PullRequest, repo); err != nil {
+				logError(r.Context(), err, "pr", event.PullRequest.HTMLURL)
+			}
+		}
+
+	// A label being renamed or deleted changes every issue that has it, and a
+	// repo being renamed or moved changes all of its issues
+	case "label", "repository":
+		logInfo(r.Context(), "invalidating cache for event", "event", r.Header.Get("X-GitHub-Event"), "action", event.Action, "repo", repo.Owner+"/"+repo.Name)
+		cache.invalidate()
+		languages.Invalidate(repo)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyIssueEvent updates every cached listing for what action did to item
+// in repo, so the change shows up straight away without a fresh search. The
+// issue is dropped from every listing and put back in those it belongs in.
+// If we can't work out how it would look we fall back to dropping the cache.
+// Issues we were listing that close are recorded in closures.
+func applyIssueEvent(ctx context.Context, c *Client, action string, item aggregator.RESTIssue, repo Repo) {
+	gone := action == "deleted" || action == "transferred" || item.State != "open"
+
+	// Get the languages before taking the cache's lock. All of them, so each
+	// listing can take as many as it shows.
+	var langs []aggregator.Language
+	if !gone {
+		var err error
+		if langs, err = c.Languages.RepoLanguages(ctx, &c.GitHub, repo, 0); err != nil {
+			logWarn(ctx, "could not get languages, invalidating cache", "err", err)
+			cache.invalidate()
+			return
+		}
+		c.Languages.Remember(repo)
+	}
+
+	// The issue as a listing had it, if one did, to keep if it's closed
+	var listed *Issue
+	cache.update(func(p searchParams, issues []Issue) []Issue {
+		keep := !gone && p.covers(repo) && hasAnyLabel(item.Labels, p.labels)
+
+		var i Issue
+		if keep {
+			l := langs
+			if p.maxLangs > 0 && len(l) > p.maxLangs {
+				l = l[:p.maxLangs]
+			}
+			i = item.Issue(repo, l, c.Hidden)
+			i.MatchedLabels = sortedKeys(set(matchingLabels(item.Labels, p.labels)))
+			keep = p.query().InRange(i)
+		}
+
+		out := make([]Issue, 0, len(issues)+1)
+		var replaced bool
+		for _, old := range issues {
+			if old.URL != item.HTMLURL {
+				out = append(out, old)
+				continue
+			}
+			if listed == nil {
+				o := old
+				listed = &o
+			}
+			if keep {
+				out = append(out, i)
+				replaced = true
+			}
+		}
+		if keep && !replaced {
+			out = append(out, i)
+		}
+		return out
+	})
+
+	switch {
+	case action == "closed" && listed != nil:
+		at := time.Now()
+		if item.ClosedAt != nil {
+			at = *item.ClosedAt
+		}
+		recordClosed(ctx, *listed, at)
+	case action == "reopened":
+		recordReopened(ctx, item.HTMLURL)
+	}
+}
+
+// hasAnyLabel reports whether lbs include any of names.
+func hasAnyLabel(lbs Labels, names []string) bool {
+	return len(matchingLabels(lbs, names)) > 0
+}
+
+// matchingLabels are which of names lbs include, the way a search for each
+// of them would find it. GitHub doesn't care about case in label searches so
+// we don't either.
+func matchingLabels(lbs Labels, names []string) []string {
+	var matched []string
+	for _, n := range names {
+		for _, l := range lbs {
+			if strings.EqualFold(l.Name, n) {
+				matched = append(matched, n)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// validSignature checks the X-Hub-Signature-256 header GitHub sends, which is
+// the hex HMAC-SHA256 of the body prefixed with "sha256=".
+func validSignature(body []byte, header, secret string) bool {
+	if !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
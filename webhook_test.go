@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devict/hacktoberfest/aggregator"
+)
+
+func TestValidSignature(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+
+	// echo -n '{"action":"opened"}' | openssl dgst -sha256 -hmac banana
+	sig := "sha256=dcd0ae6c1c86be31c32eeeee567910a19574e342cb5cdbcc442ab6798db2e3eb"
+
+	tests := []struct {
+		header string
+		secret string
+		ok     bool
+	}{
+		{sig, "banana", true},
+		{sig, "apple", false},
+		{"sha1=" + sig[7:], "banana", false},
+		{"sha256=nothex", "banana", false},
+		{"", "banana", false},
+	}
+
+	for i, test := range tests {
+		if got := validSignature(body, test.header, test.secret); got != test.ok {
+			t.Errorf("%d: validSignature(%q, %q) = %v, want %v", i, test.header, test.secret, got, test.ok)
+		}
+	}
+}
+
+func TestGitHubWebhookTooBig(t *testing.T) {
+	old := config.WebhookSecret
+	config.WebhookSecret = "bananabanana"
+	defer func() { config.WebhookSecret = old }()
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(strings.Repeat("a", maxWebhookBody+1))
+	githubWebhook(w, httptest.NewRequest("POST", "/webhooks/github", body))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d for a body over the limit, want 400 before the signature's checked", w.Code)
+	}
+}
+
+func TestApplyIssueEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Go": 100, "HTML": 10}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	repo := Repo{Owner: "devict", Name: "hacktoberfest"}
+
+	everything := searchParams{scope: "org:devict", labels: []string{"hacktoberfest"}, maxLangs: 1}
+	elsewhere := searchParams{scope: "org:MakeICT", labels: []string{"hacktoberfest"}}
+	defer cache.invalidate()
+	defer func() { closures = &closedIssues{} }()
+
+	other := Issue{URL: "https://github.com/devict/hacktoberfest/issues/2"}
+	cache.set(everything, []Issue{{URL: "https://github.com/devict/hacktoberfest/issues/1", Title: "Old"}, other})
+	cache.set(elsewhere, []Issue{other})
+
+	item := aggregator.RESTIssue{
+		Title:   "New",
+		State:   "open",
+		HTMLURL: "https://github.com/devict/hacktoberfest/issues/1",
+		Labels:  Labels{{Name: "Hacktoberfest"}},
+	}
+
+	// Editing replaces the issue where it was
+	applyIssueEvent(context.Background(), c, "edited", item, repo)
+	got, _ := cache.get(everything.key())
+	if len(got) != 2 || got[0].Title != "New" || !reflect.DeepEqual(got[0].Languages, []string{"Go"}) {
+		t.Errorf("edited issue should be replaced, got %+v", got)
+	}
+	if got, _ := cache.get(everything.key()); !reflect.DeepEqual(got[0].MatchedLabels, []string{"hacktoberfest"}) {
+		t.Errorf("got matched labels %v, want the searched label however it's written", got[0].MatchedLabels)
+	}
+	if got, _ := cache.get(elsewhere.key()); len(got) != 1 {
+		t.Errorf("listings that don't cover the repo shouldn't change, got %+v", got)
+	}
+
+	// Closing takes it out
+	item.State = "closed"
+	applyIssueEvent(context.Background(), c, "closed", item, repo)
+	if got, _ := cache.get(everything.key()); len(got) != 1 || got[0].URL != other.URL {
+		t.Errorf("closed issue should be dropped, got %+v", got)
+	}
+	if got := closures.since(time.Time{}); len(got) != 1 || got[0].Title != "New" || !got[0].Closed {
+		t.Errorf("closed issue should be recorded as listed, got %+v", got)
+	}
+
+	// Reopening puts it back at the end
+	item.State = "open"
+	applyIssueEvent(context.Background(), c, "reopened", item, repo)
+	if got, _ := cache.get(everything.key()); len(got) != 2 || got[1].Title != "New" {
+		t.Errorf("reopened issue should be added, got %+v", got)
+	}
+	if got := closures.since(time.Time{}); len(got) != 0 {
+		t.Errorf("reopened issue should be forgotten, got %+v", got)
+	}
+
+	// Losing the label takes it out again
+	item.Labels = Labels{{Name: "bug"}}
+	applyIssueEvent(context.Background(), c, "unlabeled", item, repo)
+	if got, _ := cache.get(everything.key()); len(got) != 1 {
+		t.Errorf("unlabeled issue should be dropped, got %+v", got)
+	}
+}
+
+func TestSearchParamsCovers(t *testing.T) {
+	p := searchParams{scope: "org:devict repo:br0xen/boltbrowser"}
+	tests := []struct {
+		repo Repo
+		want bool
+	}{
+		{Repo{Owner: "devict", Name: "anything"}, true},
+		{Repo{Owner: "DevICT", Name: "anything"}, true},
+		{Repo{Owner: "br0xen", Name: "boltbrowser"}, true},
+		{Repo{Owner: "br0xen", Name: "other"}, false},
+	}
+	for _, test := range tests {
+		if got := p.covers(test.repo); got != test.want {
+			t.Errorf("%+v: got %v, want %v", test.repo, got, test.want)
+		}
+	}
+}
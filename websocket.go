@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// This is just enough of RFC 6455 for the server to push text messages to
+// browsers. Anything the client sends other than control frames is read and
+// thrown away.
+
+// wsGUID is mixed into the handshake key to prove we speak WebSocket.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes we care about.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsConn is a server side WebSocket connection. Writes are safe to make from
+// more than one goroutine.
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	mu sync.Mutex
+}
+
+// upgradeWebSocket takes over the connection behind w and completes the
+// WebSocket handshake for r. If it can't, an error response has already been
+// written by the time it returns.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+		return nil, errors.New("not a websocket upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing websocket key")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websockets are not supported", http.StatusInternalServerError)
+		return nil, errors.New("response can't be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not hijack connection")
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "could not finish handshake")
+	}
+
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// wsAccept is the Sec-WebSocket-Accept value for a Sec-WebSocket-Key.
+func wsAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+wsGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerHas reports whether the comma separated header name includes token,
+// ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeText sends msg as a single text frame.
+func (ws *wsConn) writeText(msg []byte) error {
+	return ws.writeFrame(wsText, msg)
+}
+
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// Frames from the server are never masked or fragmented
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	ws.rw.Write(header)
+	ws.rw.Write(payload)
+	return errors.Wrap(ws.rw.Flush(), "could not write frame")
+}
+
+// readUntilClose reads frames from the client until it closes the connection
+// or something goes wrong, answering pings and close frames along the way.
+func (ws *wsConn) readUntilClose() error {
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(ws.rw, head[:]); err != nil {
+			return err
+		}
+		opcode := head[0] & 0x0F
+		masked := head[1]&0x80 != 0
+
+		n := uint64(head[1] & 0x7F)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+				return err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+				return err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+
+		// Clients have to mask everything they send
+		if !masked {
+			ws.writeFrame(wsClose, nil)
+			return errors.New("client sent an unmasked frame")
+		}
+		var mask [4]byte
+		if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+			return err
+		}
+
+		// We don't want anything the client has to say, only control frames
+		// need answering
+		if opcode < wsClose {
+			if _, err := io.CopyN(ioutil.Discard, ws.rw, int64(n)); err != nil {
+				return err
+			}
+			continue
+		}
+		if n > 125 {
+			return errors.New("control frame too long")
+		}
+
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(ws.rw, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case wsClose:
+			ws.writeFrame(wsClose, payload)
+			return nil
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close closes the underlying connection.
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWSAccept(t *testing.T) {
+	// The example from RFC 6455
+	if got, want := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer ws.Close()
+		ws.writeText([]byte("hello"))
+		done <- ws.readUntilClose()
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("got accept %q", got)
+	}
+
+	frame := make([]byte, 7)
+	if _, err := io.ReadFull(br, frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != 0x81 || frame[1] != 5 || string(frame[2:]) != "hello" {
+		t.Errorf("got frame %v, want a text frame saying hello", frame)
+	}
+
+	// A masked ping, then a masked close with no payload
+	conn.Write([]byte{0x89, 0x82, 1, 2, 3, 4, 'h' ^ 1, 'i' ^ 2})
+	conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+
+	pong := make([]byte, 4)
+	if _, err := io.ReadFull(br, pong); err != nil {
+		t.Fatal(err)
+	}
+	if pong[0] != 0x8A || string(pong[2:]) != "hi" {
+		t.Errorf("got %v, want a pong saying hi", pong)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("error should be nil, got %v", err)
+	}
+}
+
+func TestUpgradeWebSocketRejects(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws", nil)
+	w := httptest.NewRecorder()
+	if _, err := upgradeWebSocket(w, r); err == nil || w.Code != http.StatusBadRequest {
+		t.Errorf("plain request should be a 400, got %d %v", w.Code, err)
+	}
+}